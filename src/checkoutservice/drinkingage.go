@@ -0,0 +1,107 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultDrinkingAges are used unless LEGAL_DRINKING_AGES is set. The
+// frontend's age gate has the same default.
+const defaultDrinkingAges = "US=21,CA=19,JP=20,KR=19,*=18"
+
+// drinkingAges are the legal drinking ages by ISO country code, with a
+// fallback for the other countries.
+type drinkingAges struct {
+	byCountry map[string]int
+	fallback  int
+}
+
+// parseDrinkingAges parses ages written as "US=21,CA=19,*=18", where "*" is
+// every other country.
+func parseDrinkingAges(s string) (drinkingAges, error) {
+	ages := drinkingAges{byCountry: map[string]int{}}
+	fallback := false
+	for _, kv := range strings.Split(s, ",") {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 {
+			return drinkingAges{}, fmt.Errorf("invalid drinking age %q: want COUNTRY=AGE", kv)
+		}
+		country := strings.ToUpper(strings.TrimSpace(parts[0]))
+		age, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil || age < 0 || country == "" {
+			return drinkingAges{}, fmt.Errorf("invalid drinking age %q: want COUNTRY=AGE", kv)
+		}
+		if country == "*" {
+			ages.fallback, fallback = age, true
+		} else {
+			ages.byCountry[country] = age
+		}
+	}
+	if !fallback {
+		return drinkingAges{}, errors.New("drinking ages need a fallback for other countries, such as *=18")
+	}
+	return ages, nil
+}
+
+// minimum returns the legal drinking age of country.
+func (a drinkingAges) minimum(country string) int {
+	if age, ok := a.byCountry[strings.ToUpper(strings.TrimSpace(country))]; ok {
+		return age
+	}
+	return a.fallback
+}
+
+// ageOn returns how old someone born on dob is on the day of now. People
+// born on February 29 age on March 1 in other years.
+func ageOn(dob, now time.Time) int {
+	age := now.Year() - dob.Year()
+	if now.Month() < dob.Month() || now.Month() == dob.Month() && now.Day() < dob.Day() {
+		age--
+	}
+	return age
+}
+
+var (
+	// errInvalidDateOfBirth is returned for missing and impossible dates of
+	// birth.
+	errInvalidDateOfBirth = errors.New("invalid date of birth")
+	// errUnderage is returned for customers under the legal drinking age.
+	errUnderage = errors.New("under the legal drinking age")
+)
+
+// checkAge returns an error unless someone born on dateOfBirth, written as
+// YYYY-MM-DD, is of legal drinking age in country on the day of now.
+func (a drinkingAges) checkAge(dateOfBirth, country string, now time.Time) error {
+	if dateOfBirth == "" {
+		return fmt.Errorf("%w: date of birth is required", errInvalidDateOfBirth)
+	}
+	dob, err := time.Parse("2006-01-02", dateOfBirth)
+	if err != nil {
+		return fmt.Errorf("%w: %q is not a date written as YYYY-MM-DD", errInvalidDateOfBirth, dateOfBirth)
+	}
+	age := ageOn(dob, now)
+	if dob.After(now) || age > 150 {
+		return fmt.Errorf("%w: %s is not a possible date of birth", errInvalidDateOfBirth, dateOfBirth)
+	}
+	if legal := a.minimum(country); age < legal {
+		return fmt.Errorf("%w: orders shipped to %s need a customer of %d or older", errUnderage, country, legal)
+	}
+	return nil
+}
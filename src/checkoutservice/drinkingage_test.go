@@ -0,0 +1,76 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseDrinkingAges(t *testing.T) {
+	ages, err := parseDrinkingAges(defaultDrinkingAges)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for country, want := range map[string]int{"US": 21, "us": 21, "CA": 19, "GB": 18, "": 18} {
+		if got := ages.minimum(country); got != want {
+			t.Errorf("minimum(%q) = %d, want %d", country, got, want)
+		}
+	}
+	for _, s := range []string{"US=21", "US=21,*", "US=old,*=18", "=21,*=18"} {
+		if _, err := parseDrinkingAges(s); err == nil {
+			t.Errorf("parseDrinkingAges(%q) succeeded, want an error", s)
+		}
+	}
+}
+
+func TestCheckAge(t *testing.T) {
+	ages, err := parseDrinkingAges(defaultDrinkingAges)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		dob, country string
+		wantErr      error
+		wantMsg      string
+	}{
+		{"2003-03-15", "US", nil, ""},
+		{"2003-03-16", "US", errUnderage, "orders shipped to US need a customer of 21 or older"},
+		{"2003-03-16", "GB", nil, ""},
+		{"2006-03-16", "GB", errUnderage, "18 or older"},
+		{"2000-02-29", "US", nil, ""},
+		{"", "US", errInvalidDateOfBirth, "date of birth is required"},
+		{"15/03/1990", "US", errInvalidDateOfBirth, "YYYY-MM-DD"},
+		{"2030-01-01", "US", errInvalidDateOfBirth, "not a possible date of birth"},
+		{"1800-01-01", "US", errInvalidDateOfBirth, "not a possible date of birth"},
+	}
+	for _, tt := range tests {
+		err := ages.checkAge(tt.dob, tt.country, now)
+		if !errors.Is(err, tt.wantErr) || tt.wantErr == nil && err != nil || err != nil && !strings.Contains(err.Error(), tt.wantMsg) {
+			t.Errorf("checkAge(%q, %q) = %v, want %v containing %q", tt.dob, tt.country, err, tt.wantErr, tt.wantMsg)
+		}
+	}
+
+	// Born on February 29, one comes of age on March 1.
+	if err := ages.checkAge("2003-02-29", "US", time.Date(2024, 2, 28, 0, 0, 0, 0, time.UTC)); err == nil {
+		t.Error("checkAge accepted an invalid date")
+	}
+	if got := ageOn(time.Date(2000, 2, 29, 0, 0, 0, 0, time.UTC), time.Date(2021, 2, 28, 0, 0, 0, 0, time.UTC)); got != 20 {
+		t.Errorf("ageOn the day before a leap-day birthday = %d, want 20", got)
+	}
+}
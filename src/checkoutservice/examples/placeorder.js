@@ -11,9 +11,10 @@ client.placeOrder(
       city: 'san francisco',
       state: 'CA',
       country: 'USA',
-      zip_code: 94329,
+      postal_code: '94329',
     },
     email: 'foo@bar.com',
+    date_of_birth: '1990-04-01',
     credit_card: {
       credit_card_number: '4009366231016609',
       credit_card_cvv: 123,
@@ -16,9 +16,11 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"os"
+	"strings"
 	"time"
 	"encoding/json"
 	"math/rand"
@@ -71,6 +73,9 @@ type checkoutService struct {
 	emailSvcAddr          string
 	paymentSvcAddr        string
 	paymentSvcStableAddr  string
+
+	orders       *orderStore
+	drinkingAges drinkingAges
 }
 
 func main() {
@@ -103,6 +108,19 @@ func main() {
 	mustMapEnv(&svc.paymentSvcAddr, "PAYMENT_SERVICE_ADDR")
 	mustMapEnv(&svc.paymentSvcStableAddr, "PAYMENT_SERVICE_ADDR_STABLE")
 
+	orders, err := openOrderStore(os.Getenv("ORDER_STORE_FILE"))
+	if err != nil {
+		logger.Fatal(err)
+	}
+	svc.orders = orders
+	agesConfig := defaultDrinkingAges
+	if value, ok := os.LookupEnv("LEGAL_DRINKING_AGES"); ok {
+		agesConfig = value
+	}
+	if svc.drinkingAges, err = parseDrinkingAges(agesConfig); err != nil {
+		logger.Fatal(err)
+	}
+
 	logger.Infof("service config: %+v", svc)
 
 	lis, err := net.Listen("tcp", fmt.Sprintf(":%s", port))
@@ -201,8 +219,27 @@ func (cs *checkoutService) PlaceOrder(ctx context.Context, req *pb.PlaceOrderReq
 		return nil, status.Errorf(codes.Internal, "failed to generate order uuid")
 	}
 
-	prep, err := cs.prepareOrderItemsAndShippingQuoteFromCart(ctx, req.UserId, req.UserCurrency, req.Address)
-	if err != nil {
+	address, err := cs.validateAddress(ctx, req.Address)
+	if errors.Is(err, errInvalidAddress) {
+		return nil, status.Errorf(codes.InvalidArgument, err.Error())
+	} else if err != nil {
+		return nil, status.Errorf(codes.Internal, err.Error())
+	}
+
+	// Customers must be old enough to drink where the order goes.
+	if err := cs.drinkingAges.checkAge(req.DateOfBirth, address.GetCountry(), time.Now()); errors.Is(err, errUnderage) {
+		log.WithField("country", address.GetCountry()).Info("refused an order from an underage customer")
+		return nil, status.Errorf(codes.FailedPrecondition, err.Error())
+	} else if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, err.Error())
+	}
+
+	prep, err := cs.prepareOrderItemsAndShippingQuoteFromCart(ctx, req.UserId, req.UserCurrency, address, req.ShippingOptionId)
+	if errors.Is(err, errUnknownShippingOption) {
+		return nil, status.Errorf(codes.InvalidArgument, err.Error())
+	} else if errors.Is(err, errCannotShip) {
+		return nil, status.Errorf(codes.FailedPrecondition, err.Error())
+	} else if err != nil {
 		return nil, status.Errorf(codes.Internal, err.Error())
 	}
 
@@ -222,7 +259,7 @@ func (cs *checkoutService) PlaceOrder(ctx context.Context, req *pb.PlaceOrderReq
 	}
 	log.Infof("payment went through (transaction_id: %s)", txID)
 
-	shippingTrackingID, err := cs.shipOrder(ctx, req.Address, prep.cartItems)
+	shippingTrackingID, err := cs.shipOrder(ctx, address, prep.cartItems, prep.shippingOption.GetId())
 	if err != nil {
 		return nil, status.Errorf(codes.Unavailable, "shipping error: %+v", err)
 	}
@@ -233,8 +270,20 @@ func (cs *checkoutService) PlaceOrder(ctx context.Context, req *pb.PlaceOrderReq
 		OrderId:            orderID.String(),
 		ShippingTrackingId: shippingTrackingID,
 		ShippingCost:       prep.shippingCostLocalized,
-		ShippingAddress:    req.Address,
+		ShippingAddress:    address,
 		Items:              prep.orderItems,
+		ShippingOption:     prep.shippingOption,
+	}
+
+	if err := cs.orders.add(&pb.StoredOrder{
+		Order:    orderResult,
+		UserId:   req.UserId,
+		Email:    req.Email,
+		Total:    &total,
+		PlacedAt: time.Now().Unix(),
+		Status:   orderStatusPlaced,
+	}); err != nil {
+		log.Errorf("failed to store order %s: %+v", orderResult.OrderId, err)
 	}
 
 	if err := cs.sendOrderConfirmation(ctx, req.Email, orderResult); err != nil {
@@ -250,9 +299,11 @@ type orderPrep struct {
 	orderItems            []*pb.OrderItem
 	cartItems             []*pb.CartItem
 	shippingCostLocalized *pb.Money
+	// shippingOption is priced in USD.
+	shippingOption *pb.ShippingOption
 }
 
-func (cs *checkoutService) prepareOrderItemsAndShippingQuoteFromCart(ctx context.Context, userID, userCurrency string, address *pb.Address) (orderPrep, error) {
+func (cs *checkoutService) prepareOrderItemsAndShippingQuoteFromCart(ctx context.Context, userID, userCurrency string, address *pb.Address, shippingOptionID string) (orderPrep, error) {
 	var out orderPrep
 	cartItems, err := cs.getUserCart(ctx, userID)
 	if err != nil {
@@ -262,22 +313,60 @@ func (cs *checkoutService) prepareOrderItemsAndShippingQuoteFromCart(ctx context
 	if err != nil {
 		return out, fmt.Errorf("failed to prepare order: %+v", err)
 	}
-	shippingUSD, err := cs.quoteShipping(ctx, address, cartItems)
+	shippingOption, err := cs.quoteShipping(ctx, address, cartItems, shippingOptionID)
 	if err != nil {
-		return out, fmt.Errorf("shipping quote failure: %+v", err)
+		return out, fmt.Errorf("shipping quote failure: %w", err)
 	}
-	shippingPrice, err := cs.convertCurrency(ctx, shippingUSD, userCurrency)
+	shippingPrice, err := cs.convertCurrency(ctx, shippingOption.GetCostUsd(), userCurrency)
 	if err != nil {
 		return out, fmt.Errorf("failed to convert shipping cost to currency: %+v", err)
 	}
 
 	out.shippingCostLocalized = shippingPrice
+	out.shippingOption = shippingOption
 	out.cartItems = cartItems
 	out.orderItems = orderItems
 	return out, nil
 }
 
-func (cs *checkoutService) quoteShipping(ctx context.Context, address *pb.Address, items []*pb.CartItem) (*pb.Money, error) {
+// errInvalidAddress is returned for addresses the shipping service rejects.
+var errInvalidAddress = errors.New("invalid address")
+
+// validateAddress returns address as the shipping service normalizes it.
+func (cs *checkoutService) validateAddress(ctx context.Context, address *pb.Address) (*pb.Address, error) {
+	conn, err := grpc.DialContext(ctx, cs.shippingSvcAddr,
+		grpc.WithInsecure(),
+		grpc.WithStatsHandler(clientStatsHandler()))
+	if err != nil {
+		return nil, fmt.Errorf("could not connect shipping service: %+v", err)
+	}
+	defer conn.Close()
+
+	res, err := pb.NewShippingServiceClient(conn).ValidateAddress(ctx, &pb.ValidateAddressRequest{Address: address})
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate address: %+v", err)
+	}
+	if !res.GetValid() {
+		var problems []string
+		for _, e := range res.GetErrors() {
+			problems = append(problems, e.GetField()+": "+e.GetMessage())
+		}
+		return nil, fmt.Errorf("%w: %s", errInvalidAddress, strings.Join(problems, "; "))
+	}
+	return res.GetNormalized(), nil
+}
+
+// errUnknownShippingOption is returned for shipping options the shipping
+// service does not offer for the order.
+var errUnknownShippingOption = errors.New("unknown shipping option")
+
+// errCannotShip is returned for carts the shipping service refuses to ship
+// to the address, such as alcohol to a state that bans shipping it.
+var errCannotShip = errors.New("cannot ship the order")
+
+// quoteShipping returns the shipping option with the given ID, or the first
+// one offered if the ID is empty.
+func (cs *checkoutService) quoteShipping(ctx context.Context, address *pb.Address, items []*pb.CartItem, optionID string) (*pb.ShippingOption, error) {
 	conn, err := grpc.DialContext(ctx, cs.shippingSvcAddr,
 		grpc.WithInsecure(),
 		grpc.WithStatsHandler(clientStatsHandler()))
@@ -290,10 +379,24 @@ func (cs *checkoutService) quoteShipping(ctx context.Context, address *pb.Addres
 		GetQuote(ctx, &pb.GetQuoteRequest{
 			Address: address,
 			Items:   items})
-	if err != nil {
+	if status.Code(err) == codes.FailedPrecondition {
+		return nil, fmt.Errorf("%w: %s", errCannotShip, status.Convert(err).Message())
+	} else if err != nil {
 		return nil, fmt.Errorf("failed to get shipping quote: %+v", err)
 	}
-	return shippingQuote.GetCostUsd(), nil
+	options := shippingQuote.GetOptions()
+	if len(options) == 0 {
+		return nil, errors.New("no shipping options offered")
+	}
+	if optionID == "" {
+		return options[0], nil
+	}
+	for _, o := range options {
+		if o.GetId() == optionID {
+			return o, nil
+		}
+	}
+	return nil, fmt.Errorf("%w %q", errUnknownShippingOption, optionID)
 }
 
 func (cs *checkoutService) getUserCart(ctx context.Context, userID string) ([]*pb.CartItem, error) {
@@ -434,15 +537,16 @@ func (cs *checkoutService) sendOrderConfirmation(ctx context.Context, email stri
 	return err
 }
 
-func (cs *checkoutService) shipOrder(ctx context.Context, address *pb.Address, items []*pb.CartItem) (string, error) {
+func (cs *checkoutService) shipOrder(ctx context.Context, address *pb.Address, items []*pb.CartItem, shippingOptionID string) (string, error) {
 	conn, err := grpc.DialContext(ctx, cs.shippingSvcAddr, grpc.WithInsecure(), grpc.WithStatsHandler(clientStatsHandler()))
 	if err != nil {
 		return "", fmt.Errorf("failed to connect email service: %+v", err)
 	}
 	defer conn.Close()
 	resp, err := pb.NewShippingServiceClient(conn).ShipOrder(ctx, &pb.ShipOrderRequest{
-		Address: address,
-		Items:   items})
+		Address:          address,
+		Items:            items,
+		ShippingOptionId: shippingOptionID})
 	if err != nil {
 		return "", fmt.Errorf("shipment failed: %+v", err)
 	}
@@ -0,0 +1,170 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/golang/protobuf/jsonpb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/signalfx/microservices-demo/src/checkoutservice/genproto"
+)
+
+const orderStatusPlaced = "placed"
+
+// orderStore keeps a copy of every order placed. With a file, each order is
+// also appended to it as a line of JSON, and the file is replayed on open so
+// that orders survive restarts.
+type orderStore struct {
+	mu     sync.Mutex
+	byID   map[string]*pb.StoredOrder
+	byUser map[string][]*pb.StoredOrder // oldest first
+	f      *os.File                     // nil when orders are only kept in memory
+}
+
+// openOrderStore opens the store backed by the file at path, creating it if
+// needed, or an in-memory store if path is empty. A last line cut short by
+// a crash is dropped; any other line that is not an order is an error.
+func openOrderStore(path string) (*orderStore, error) {
+	s := &orderStore{byID: map[string]*pb.StoredOrder{}, byUser: map[string][]*pb.StoredOrder{}}
+	if path == "" {
+		return s, nil
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open order store: %w", err)
+	}
+	if err := s.replay(f, path); err != nil {
+		f.Close()
+		return nil, err
+	}
+	s.f = f
+	return s, nil
+}
+
+// replay indexes the orders in f, truncating a torn last line and
+// terminating a last line that is whole but lacks its newline.
+func (s *orderStore) replay(f *os.File, path string) error {
+	r := bufio.NewReader(f)
+	var off int64
+	for line := 1; ; line++ {
+		raw, err := r.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("failed to read order store: %w", err)
+		}
+		if len(raw) == 0 {
+			return nil
+		}
+		o := new(pb.StoredOrder)
+		if uerr := jsonpb.Unmarshal(bytes.NewReader(raw), o); uerr != nil {
+			if err != io.EOF {
+				return fmt.Errorf("%s:%d: invalid order: %w", path, line, uerr)
+			}
+			logger.Warnf("%s:%d: dropping an order that was not completely written", path, line)
+			if err := f.Truncate(off); err != nil {
+				return fmt.Errorf("failed to truncate order store: %w", err)
+			}
+			return nil
+		}
+		s.index(o)
+		off += int64(len(raw))
+		if err == io.EOF {
+			if _, err := f.Write([]byte{'\n'}); err != nil {
+				return fmt.Errorf("failed to write order store: %w", err)
+			}
+			return nil
+		}
+	}
+}
+
+func (s *orderStore) index(o *pb.StoredOrder) {
+	id := o.GetOrder().GetOrderId()
+	if _, ok := s.byID[id]; !ok {
+		s.byUser[o.GetUserId()] = append(s.byUser[o.GetUserId()], o)
+	}
+	s.byID[id] = o
+}
+
+// add stores o, writing it durably to the store's file first if it has
+// one.
+func (s *orderStore) add(o *pb.StoredOrder) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f != nil {
+		var buf bytes.Buffer
+		if err := (&jsonpb.Marshaler{}).Marshal(&buf, o); err != nil {
+			return err
+		}
+		buf.WriteByte('\n')
+		// A failed write or sync may leave part of the order in the file;
+		// cut it back off so that the next order starts on its own line.
+		fi, err := s.f.Stat()
+		if err != nil {
+			return fmt.Errorf("failed to stat order store: %w", err)
+		}
+		if _, err := s.f.Write(buf.Bytes()); err != nil {
+			s.f.Truncate(fi.Size())
+			return fmt.Errorf("failed to write order: %w", err)
+		}
+		if err := s.f.Sync(); err != nil {
+			s.f.Truncate(fi.Size())
+			return fmt.Errorf("failed to sync order store: %w", err)
+		}
+	}
+	s.index(o)
+	return nil
+}
+
+func (s *orderStore) get(id string) (*pb.StoredOrder, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o, ok := s.byID[id]
+	return o, ok
+}
+
+// list returns the user's orders, newest first.
+func (s *orderStore) list(userID string) []*pb.StoredOrder {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	orders := s.byUser[userID]
+	out := make([]*pb.StoredOrder, len(orders))
+	for i, o := range orders {
+		out[len(orders)-1-i] = o
+	}
+	return out
+}
+
+func (cs *checkoutService) GetOrder(ctx context.Context, req *pb.GetOrderRequest) (*pb.StoredOrder, error) {
+	o, ok := cs.orders.get(req.GetOrderId())
+	if !ok || req.GetUserId() != "" && o.GetUserId() != req.GetUserId() {
+		return nil, status.Errorf(codes.NotFound, "no order with ID %q", req.GetOrderId())
+	}
+	return o, nil
+}
+
+func (cs *checkoutService) ListOrders(ctx context.Context, req *pb.ListOrdersRequest) (*pb.ListOrdersResponse, error) {
+	if req.GetUserId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "user ID is required")
+	}
+	return &pb.ListOrdersResponse{Orders: cs.orders.list(req.GetUserId())}, nil
+}
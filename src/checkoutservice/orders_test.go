@@ -0,0 +1,136 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/signalfx/microservices-demo/src/checkoutservice/genproto"
+)
+
+func storedOrder(id, user string) *pb.StoredOrder {
+	return &pb.StoredOrder{
+		Order:    &pb.OrderResult{OrderId: id, ShippingTrackingId: "TRK-" + id},
+		UserId:   user,
+		Total:    &pb.Money{CurrencyCode: "USD", Units: 42},
+		PlacedAt: 1700000000,
+		Status:   orderStatusPlaced,
+	}
+}
+
+func TestOrderStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "orders.jsonl")
+
+	s, err := openOrderStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, o := range []*pb.StoredOrder{storedOrder("o1", "u1"), storedOrder("o2", "u2"), storedOrder("o3", "u1")} {
+		if err := s.add(o); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.f.Close()
+
+	// Reopening replays the file.
+	s, err = openOrderStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.f.Close()
+	cs := &checkoutService{orders: s}
+	ctx := context.Background()
+
+	got, err := cs.GetOrder(ctx, &pb.GetOrderRequest{OrderId: "o2"})
+	if err != nil || !proto.Equal(got, storedOrder("o2", "u2")) {
+		t.Errorf("GetOrder(o2) = %v, %v", got, err)
+	}
+	if _, err := cs.GetOrder(ctx, &pb.GetOrderRequest{OrderId: "o2", UserId: "u1"}); status.Code(err) != codes.NotFound {
+		t.Errorf("GetOrder(o2) for another user: got %v, want NotFound", err)
+	}
+	if _, err := cs.GetOrder(ctx, &pb.GetOrderRequest{OrderId: "o9"}); status.Code(err) != codes.NotFound {
+		t.Errorf("GetOrder(o9): got %v, want NotFound", err)
+	}
+
+	resp, err := cs.ListOrders(ctx, &pb.ListOrdersRequest{UserId: "u1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, o := range resp.Orders {
+		ids = append(ids, o.Order.OrderId)
+	}
+	if strings.Join(ids, ",") != "o3,o1" {
+		t.Errorf("ListOrders(u1) = %v, want newest first", ids)
+	}
+	if _, err := cs.ListOrders(ctx, &pb.ListOrdersRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("ListOrders without user: got %v, want InvalidArgument", err)
+	}
+}
+
+func TestOpenOrderStoreRejectsCorruptFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("{\"order\":{\"orderId\":\"o1\"}}\nnot json\n")
+	f.Close()
+	if _, err := openOrderStore(f.Name()); err == nil || !strings.Contains(err.Error(), ":2: invalid order") {
+		t.Errorf("got %v, want an error for line 2", err)
+	}
+}
+
+func TestOpenOrderStoreDropsTornOrder(t *testing.T) {
+	f, err := ioutil.TempFile("", "orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("{\"order\":{\"orderId\":\"o1\"}}\n{\"order\":{\"ord")
+	f.Close()
+
+	s, err := openOrderStore(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.add(storedOrder("o2", "u1")); err != nil {
+		t.Fatal(err)
+	}
+	s.f.Close()
+
+	s, err = openOrderStore(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.f.Close()
+	if _, ok := s.get("o1"); !ok || len(s.byID) != 2 {
+		t.Errorf("orders after reopening: %v", s.byID)
+	}
+}
@@ -0,0 +1,412 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+)
+
+const peerFetchTimeout = 2 * time.Second
+
+// behaviorPreset is a named merge patch offered as a one-click button in the
+// admin console. Presets go through the same validation as PATCH requests.
+type behaviorPreset struct {
+	Name        string
+	Title       string
+	Description string
+	Patch       string
+}
+
+var behaviorPresets = []behaviorPreset{
+	{
+		Name:        "healthy",
+		Title:       "Healthy",
+		Description: "Reset every knob to its default.",
+		Patch:       `{"checkoutService":null}`,
+	},
+	{
+		Name:        "flaky-payments",
+		Title:       "Flaky payments",
+		Description: "30% of charges fail; the default retries mostly hide it.",
+		Patch:       `{"checkoutService":{"paymentFailureRate":0.3,"maxRetryAttempts":15,"retryInitialSleepMillis":200}}`,
+	},
+	{
+		Name:        "payment-outage",
+		Title:       "Payment outage",
+		Description: "Every charge fails and checkout gives up after three tries.",
+		Patch:       `{"checkoutService":{"paymentFailureRate":1,"maxRetryAttempts":3,"retryInitialSleepMillis":200}}`,
+	},
+	{
+		Name:        "retry-storm",
+		Title:       "Retry storm",
+		Description: "Half the charges fail and checkout hammers payments with fast retries.",
+		Patch:       `{"checkoutService":{"paymentFailureRate":0.5,"maxRetryAttempts":50,"retryInitialSleepMillis":10}}`,
+	},
+}
+
+func lookupPreset(name string) (behaviorPreset, bool) {
+	for _, p := range behaviorPresets {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return behaviorPreset{}, false
+}
+
+// adminFieldView is a SystemBehavior field rendered as a form control.
+type adminFieldView struct {
+	Name        string
+	Description string
+	Min, Max    float64
+	Step        string
+	Value       interface{}
+	Error       string
+}
+
+// replicaView is the behavior reported by one frontend replica.
+type replicaView struct {
+	Address string        `json:"address"`
+	Self    bool          `json:"self"`
+	Values  []interface{} `json:"values"`
+	ETag    string        `json:"etag"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// dependencyView is the state of one gRPC dependency connection.
+type dependencyView struct {
+	Name    string `json:"name"`
+	Address string `json:"address"`
+	State   string `json:"state"`
+}
+
+type adminStatus struct {
+	Fields       []string         `json:"fields"`
+	Replicas     []replicaView    `json:"replicas"`
+	Dependencies []dependencyView `json:"dependencies"`
+}
+
+func (fe *frontendServer) adminHandler(w http.ResponseWriter, r *http.Request) {
+	cur, etag := behavior.get()
+	fe.renderAdmin(w, r, http.StatusOK, cur, etag, nil, r.FormValue("msg"))
+}
+
+func (fe *frontendServer) renderAdmin(w http.ResponseWriter, r *http.Request, code int, cur SystemBehavior, etag string, fieldErrs []fieldError, msg string) {
+	log := getLoggerWithTraceFields(r.Context())
+	doc := behaviorDocument(cur)
+	errByField := map[string]string{}
+	for _, e := range fieldErrs {
+		errByField[e.Field] = e.Message
+	}
+	fields := make([]adminFieldView, len(behaviorFields))
+	for i, f := range behaviorFields {
+		step := "1"
+		if f.Type == "number" {
+			step = "0.01"
+		}
+		fields[i] = adminFieldView{
+			Name:        f.name(),
+			Description: f.Description,
+			Min:         f.Minimum,
+			Max:         f.Maximum,
+			Step:        step,
+			Value:       documentValue(doc, f.Path),
+			Error:       errByField[f.name()],
+		}
+	}
+
+	w.WriteHeader(code)
+	if err := templates.ExecuteTemplate(w, "admin", map[string]interface{}{
+		"session_id":    sessionID(r),
+		"request_id":    r.Context().Value(ctxKeyRequestID{}),
+		"principal":     requestActor(r),
+		"fields":        fields,
+		"etag":          etag,
+		"presets":       behaviorPresets,
+		"status":        fe.adminStatus(r),
+		"message":       msg,
+		"errors":        fieldErrs,
+		"platform_css":  plat.css,
+		"platform_name": plat.provider,
+	}); err != nil {
+		log.Error(err)
+	}
+}
+
+func (fe *frontendServer) adminStatusHandler(w http.ResponseWriter, r *http.Request) {
+	renderJSON(w, http.StatusOK, fe.adminStatus(r))
+}
+
+func (fe *frontendServer) adminStatus(r *http.Request) adminStatus {
+	names := make([]string, len(behaviorFields))
+	for i, f := range behaviorFields {
+		names[i] = f.name()
+	}
+	return adminStatus{
+		Fields:       names,
+		Replicas:     fe.replicaBehaviors(r),
+		Dependencies: fe.dependencyStates(),
+	}
+}
+
+// adminBehaviorHandler applies the console's behavior form. The form values
+// are turned into a merge patch so they get exactly the same validation as
+// PATCH /system-behavior.
+func (fe *frontendServer) adminBehaviorHandler(w http.ResponseWriter, r *http.Request) {
+	patch := map[string]interface{}{}
+	for _, f := range behaviorFields {
+		raw := strings.TrimSpace(r.FormValue(f.name()))
+		if raw == "" {
+			continue
+		}
+		var v interface{} = raw
+		if n, err := strconv.ParseFloat(raw, 64); err == nil {
+			v = n
+		}
+		setDocumentValue(patch, f.Path, v)
+	}
+	body, _ := json.Marshal(patch)
+	fe.applyAdminPatch(w, r, "behavior.patch", body, "Behavior updated.")
+}
+
+func (fe *frontendServer) adminPresetHandler(w http.ResponseWriter, r *http.Request) {
+	p, ok := lookupPreset(r.FormValue("preset"))
+	if !ok {
+		cur, etag := behavior.get()
+		fe.renderAdmin(w, r, http.StatusUnprocessableEntity, cur, etag,
+			[]fieldError{{Field: "preset", Message: "unknown preset"}}, "")
+		return
+	}
+	fe.applyAdminPatch(w, r, "behavior.preset", []byte(p.Patch), "Applied preset "+p.Title+".")
+}
+
+func (fe *frontendServer) applyAdminPatch(w http.ResponseWriter, r *http.Request, action string, patch []byte, msg string) {
+	log := getLoggerWithTraceFields(r.Context())
+	if !sameOrigin(r) {
+		renderHTTPError(log, r, w, errors.New("cross-origin form submission rejected"), http.StatusForbidden)
+		return
+	}
+	prev, cur, err := behavior.replace(r.FormValue("etag"), func(b SystemBehavior) (SystemBehavior, error) {
+		return applyBehaviorPatch(b, patch)
+	})
+	switch e := err.(type) {
+	case nil:
+	case *behaviorValidationError:
+		cur, etag := behavior.get()
+		fe.renderAdmin(w, r, http.StatusUnprocessableEntity, cur, etag, e.fields, "")
+		return
+	default:
+		if err == errPreconditionFailed {
+			err = errors.New("the behavior was changed by someone else; review the current values and try again")
+		}
+		cur, etag := behavior.get()
+		fe.renderAdmin(w, r, http.StatusConflict, cur, etag, []fieldError{{Field: "behavior", Message: err.Error()}}, "")
+		return
+	}
+	log.WithField("behavior.old", prev).WithField("behavior.new", cur).Info("system behavior changed from admin console")
+	fe.recordAudit(r, requestActor(r), action, "/admin", prev, cur)
+	w.Header().Set("Location", "/admin?msg="+url.QueryEscape(msg))
+	w.WriteHeader(http.StatusSeeOther)
+}
+
+// sameOrigin reports whether a form POST came from a page served by this
+// host. Browsers resend basic credentials on cross-site requests, so the
+// console's forms need this in addition to authentication.
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		origin = r.Header.Get("Referer")
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host == r.Host
+}
+
+// replicaBehaviors returns this replica's behavior followed by that of every
+// peer listed by ADMIN_PEERS (comma separated host:port) or resolved from
+// ADMIN_PEERS_DNS (a host:port whose host resolves to all replicas, such as
+// a headless service). Addresses matching POD_IP are skipped. Peers are
+// queried with requests signed as the principal ADMIN_PEER_KEY_ID names, so
+// no credential travels over the unencrypted connection.
+func (fe *frontendServer) replicaBehaviors(r *http.Request) []replicaView {
+	cur, etag := behavior.get()
+	out := []replicaView{{Address: "this replica", Self: true, Values: behaviorValues(cur), ETag: etag}}
+
+	peers := peerAddresses(r.Context())
+	views := make([]replicaView, len(peers))
+	signer, err := fe.peerPrincipal()
+	var wg sync.WaitGroup
+	for i, addr := range peers {
+		if err != nil {
+			views[i] = replicaView{Address: addr, Error: err.Error()}
+			continue
+		}
+		wg.Add(1)
+		go func(i int, addr string) {
+			defer wg.Done()
+			views[i] = fetchPeerBehavior(r.Context(), addr, signer)
+		}(i, addr)
+	}
+	wg.Wait()
+	return append(out, views...)
+}
+
+// peerPrincipal returns the principal peers are queried as.
+func (fe *frontendServer) peerPrincipal() (*principal, error) {
+	id := os.Getenv("ADMIN_PEER_KEY_ID")
+	if id == "" {
+		return nil, errors.New("ADMIN_PEER_KEY_ID is not set")
+	}
+	var p *principal
+	if fe.auth != nil {
+		p = fe.auth.principals[id]
+	}
+	if p == nil || p.Secret == "" || !p.has(roleViewer) {
+		return nil, errors.Errorf("peer principal %q needs a signing secret and the viewer role", id)
+	}
+	return p, nil
+}
+
+func peerAddresses(ctx context.Context) []string {
+	var peers []string
+	for _, p := range strings.Split(os.Getenv("ADMIN_PEERS"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			peers = append(peers, p)
+		}
+	}
+	if hostport := os.Getenv("ADMIN_PEERS_DNS"); hostport != "" {
+		host, port, err := net.SplitHostPort(hostport)
+		if err == nil {
+			ctx, cancel := context.WithTimeout(ctx, peerFetchTimeout)
+			defer cancel()
+			ips, _ := net.DefaultResolver.LookupHost(ctx, host)
+			for _, ip := range ips {
+				if ip == os.Getenv("POD_IP") {
+					continue
+				}
+				peers = append(peers, net.JoinHostPort(ip, port))
+			}
+		}
+	}
+	sort.Strings(peers)
+	return peers
+}
+
+func fetchPeerBehavior(ctx context.Context, addr string, signer *principal) replicaView {
+	view := replicaView{Address: addr}
+	ctx, cancel := context.WithTimeout(ctx, peerFetchTimeout)
+	defer cancel()
+	req, err := http.NewRequest(http.MethodGet, "http://"+addr+"/system-behavior", nil)
+	if err != nil {
+		view.Error = err.Error()
+		return view
+	}
+	req = req.WithContext(ctx)
+	signHTTPRequest(req, signer, time.Now(), nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		view.Error = err.Error()
+		return view
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		view.Error = resp.Status
+		return view
+	}
+	var b SystemBehavior
+	if err := json.NewDecoder(resp.Body).Decode(&b); err != nil {
+		view.Error = err.Error()
+		return view
+	}
+	view.Values, view.ETag = behaviorValues(b), resp.Header.Get("ETag")
+	return view
+}
+
+func behaviorValues(b SystemBehavior) []interface{} {
+	doc := behaviorDocument(b)
+	out := make([]interface{}, len(behaviorFields))
+	for i, f := range behaviorFields {
+		out[i] = documentValue(doc, f.Path)
+	}
+	return out
+}
+
+func (fe *frontendServer) dependencyStates() []dependencyView {
+	deps := []struct {
+		name string
+		addr string
+		conn *grpc.ClientConn
+	}{
+		{"productcatalogservice", fe.productCatalogSvcAddr, fe.productCatalogSvcConn},
+		{"currencyservice", fe.currencySvcAddr, fe.currencySvcConn},
+		{"cartservice", fe.cartSvcAddr, fe.cartSvcConn},
+		{"recommendationservice", fe.recommendationSvcAddr, fe.recommendationSvcConn},
+		{"checkoutservice", fe.checkoutSvcAddr, fe.checkoutSvcConn},
+		{"shippingservice", fe.shippingSvcAddr, fe.shippingSvcConn},
+		{"adservice", fe.adSvcAddr, fe.adSvcConn},
+	}
+	out := make([]dependencyView, len(deps))
+	for i, d := range deps {
+		state := "NOT_CONNECTED"
+		if d.conn != nil {
+			state = d.conn.GetState().String()
+		}
+		out[i] = dependencyView{Name: d.name, Address: d.addr, State: state}
+	}
+	return out
+}
+
+func documentValue(doc interface{}, path []string) interface{} {
+	for _, p := range path {
+		obj, ok := doc.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		doc = obj[p]
+	}
+	return doc
+}
+
+func setDocumentValue(doc map[string]interface{}, path []string, v interface{}) {
+	for _, p := range path[:len(path)-1] {
+		child, ok := doc[p].(map[string]interface{})
+		if !ok {
+			child = map[string]interface{}{}
+			doc[p] = child
+		}
+		doc = child
+	}
+	doc[path[len(path)-1]] = v
+}
+
+// formatValue renders a behavior value for display.
+func formatValue(v interface{}) string {
+	if n, ok := v.(float64); ok {
+		return strconv.FormatFloat(n, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,117 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func adminForm(target, origin string, form url.Values) *http.Request {
+	r := behaviorRequest(http.MethodPost, form.Encode(), map[string]string{
+		"Content-Type": "application/x-www-form-urlencoded",
+		"Origin":       origin,
+	})
+	r.URL.Path = target
+	r.Host = "shop.example"
+	return r
+}
+
+func TestAdminConsole(t *testing.T) {
+	behavior = newBehaviorStore()
+	defer func() { behavior = newBehaviorStore() }()
+	fe := testFrontendServer(t)
+	_, etag := behavior.get()
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		origin  string
+		form    url.Values
+		code    int
+		rate    float32
+	}{
+		{"cross-origin form", fe.adminBehaviorHandler, "https://evil.example",
+			url.Values{"etag": {etag}, "checkoutService.paymentFailureRate": {"0.5"}}, http.StatusForbidden, 0},
+		{"out of range value", fe.adminBehaviorHandler, "http://shop.example",
+			url.Values{"etag": {etag}, "checkoutService.paymentFailureRate": {"2"}}, http.StatusUnprocessableEntity, 0},
+		{"valid form", fe.adminBehaviorHandler, "http://shop.example",
+			url.Values{"etag": {etag}, "checkoutService.paymentFailureRate": {"0.5"}}, http.StatusSeeOther, 0.5},
+		{"stale etag", fe.adminPresetHandler, "http://shop.example",
+			url.Values{"etag": {etag}, "preset": {"payment-outage"}}, http.StatusConflict, 0.5},
+		{"unknown preset", fe.adminPresetHandler, "http://shop.example",
+			url.Values{"preset": {"nope"}}, http.StatusUnprocessableEntity, 0.5},
+		{"preset", fe.adminPresetHandler, "http://shop.example",
+			url.Values{"preset": {"payment-outage"}}, http.StatusSeeOther, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tt.handler(w, adminForm("/admin", tt.origin, tt.form))
+			if w.Code != tt.code {
+				t.Fatalf("status=%d, want %d (body %s)", w.Code, tt.code, w.Body)
+			}
+			if got, _ := behavior.get(); got.CheckoutService.PaymentFailureRate != tt.rate {
+				t.Fatalf("paymentFailureRate=%v, want %v", got.CheckoutService.PaymentFailureRate, tt.rate)
+			}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	fe.adminHandler(w, behaviorRequest(http.MethodGet, "", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "checkoutService.maxRetryAttempts") {
+		t.Fatalf("GET /admin: status=%d", w.Code)
+	}
+}
+
+func TestReplicaBehaviors(t *testing.T) {
+	fe := testFrontendServer(t)
+	fe.auth = testAuthenticator(time.Now())
+	fe.auth.principals["peer"] = &principal{ID: "peer", Secret: "peer-secret", Roles: []role{roleViewer}}
+	fe.auth.principals["keyonly"] = &principal{ID: "keyonly", APIKeySHA256: strings.Repeat("0", 64), Roles: []role{roleViewer}}
+	get := fe.auth.require(roleViewer, fe.getSystemBehaviorHandler)
+	peer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), hmacScheme+" keyId=peer,") {
+			t.Errorf("peer got Authorization %q", r.Header.Get("Authorization"))
+		}
+		// Borrow the test logger.
+		get(w, r.WithContext(behaviorRequest(r.Method, "", nil).Context()))
+	}))
+	defer peer.Close()
+	t.Setenv("ADMIN_PEERS", strings.TrimPrefix(peer.URL, "http://"))
+
+	for _, tt := range []struct {
+		keyID, wantErr string
+	}{
+		{"", "ADMIN_PEER_KEY_ID is not set"},
+		{"nobody", `peer principal "nobody" needs a signing secret and the viewer role`},
+		{"keyonly", `peer principal "keyonly" needs a signing secret and the viewer role`},
+		{"peer", ""},
+	} {
+		t.Setenv("ADMIN_PEER_KEY_ID", tt.keyID)
+		r := behaviorRequest(http.MethodGet, "", map[string]string{"Authorization": "Bearer ops-key"})
+		views := fe.replicaBehaviors(r)
+		if len(views) != 2 {
+			t.Fatalf("ADMIN_PEER_KEY_ID=%q: got %d replicas", tt.keyID, len(views))
+		}
+		if got := views[1]; got.Error != tt.wantErr || tt.wantErr == "" && got.ETag == "" {
+			t.Errorf("ADMIN_PEER_KEY_ID=%q: got %+v, want error %q", tt.keyID, got, tt.wantErr)
+		}
+	}
+}
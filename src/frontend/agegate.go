@@ -0,0 +1,314 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	ageGatePath       = "/age-gate"
+	cookieAgeVerified = cookiePrefix + "age-verified"
+	// ageVerificationMaxAge is how long a verification lasts.
+	ageVerificationMaxAge = 30 * 24 * time.Hour
+
+	// defaultDrinkingAges are used unless LEGAL_DRINKING_AGES is set.
+	// checkoutservice has the same default.
+	defaultDrinkingAges = "US=21,CA=19,JP=20,KR=19,*=18"
+)
+
+// drinkingAges are the legal drinking ages by ISO country code, with a
+// fallback for the other countries.
+type drinkingAges struct {
+	byCountry map[string]int
+	fallback  int
+}
+
+// parseDrinkingAges parses ages written as "US=21,CA=19,*=18", where "*" is
+// every other country.
+func parseDrinkingAges(s string) (drinkingAges, error) {
+	ages := drinkingAges{byCountry: map[string]int{}}
+	fallback := false
+	for _, kv := range strings.Split(s, ",") {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) != 2 {
+			return drinkingAges{}, errors.Errorf("invalid drinking age %q: want COUNTRY=AGE", kv)
+		}
+		country := strings.ToUpper(strings.TrimSpace(parts[0]))
+		age, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil || age < 0 || country == "" {
+			return drinkingAges{}, errors.Errorf("invalid drinking age %q: want COUNTRY=AGE", kv)
+		}
+		if country == "*" {
+			ages.fallback, fallback = age, true
+		} else {
+			ages.byCountry[country] = age
+		}
+	}
+	if !fallback {
+		return drinkingAges{}, errors.New("drinking ages need a fallback for other countries, such as *=18")
+	}
+	return ages, nil
+}
+
+// minimum returns the legal drinking age of country.
+func (a drinkingAges) minimum(country string) int {
+	if age, ok := a.byCountry[strings.ToUpper(strings.TrimSpace(country))]; ok {
+		return age
+	}
+	return a.fallback
+}
+
+// countries lists the countries with their own drinking age, sorted.
+func (a drinkingAges) countries() []string {
+	out := make([]string, 0, len(a.byCountry))
+	for c := range a.byCountry {
+		out = append(out, c)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// ageFromBirthDate returns how old someone born on dateOfBirth, written as
+// YYYY-MM-DD, is on the day of now. People born on February 29 age on
+// March 1 in other years.
+func ageFromBirthDate(dateOfBirth string, now time.Time) (int, error) {
+	if dateOfBirth == "" {
+		return 0, errors.New("date of birth is required")
+	}
+	dob, err := time.Parse("2006-01-02", dateOfBirth)
+	if err != nil {
+		return 0, errors.Errorf("%q is not a date written as YYYY-MM-DD", dateOfBirth)
+	}
+	age := now.Year() - dob.Year()
+	if now.Month() < dob.Month() || now.Month() == dob.Month() && now.Day() < dob.Day() {
+		age--
+	}
+	if dob.After(now) || age > 150 {
+		return 0, errors.Errorf("%s is not a possible date of birth", dateOfBirth)
+	}
+	return age, nil
+}
+
+// ageGate keeps visitors out of the storefront until they have shown they
+// are of legal drinking age in their country, by giving their date of birth
+// or, if askBirthDate is false, by confirming it. Verifications are kept in
+// a cookie signed with key.
+type ageGate struct {
+	ages         drinkingAges
+	askBirthDate bool
+	key          func() []byte
+	now          func() time.Time
+}
+
+// newAgeGate returns a gate signing cookies with key, or with a key of its
+// own while key is not configured. Verifications signed with its own key
+// are lost on restart.
+func newAgeGate(ages drinkingAges, askBirthDate bool, key *secret) (*ageGate, error) {
+	own := make([]byte, 32)
+	if _, err := rand.Read(own); err != nil {
+		return nil, errors.Wrap(err, "could not generate an age gate key")
+	}
+	return &ageGate{
+		ages:         ages,
+		askBirthDate: askBirthDate,
+		key: func() []byte {
+			if v, err := key.get(); err == nil {
+				return []byte(v)
+			}
+			return own
+		},
+		now: time.Now,
+	}, nil
+}
+
+// ageVerification is what the age gate's cookie records.
+type ageVerification struct {
+	Country string
+	// DateOfBirth is empty if the visitor only confirmed their age.
+	DateOfBirth string
+	VerifiedAt  time.Time
+}
+
+// sign returns v as a cookie value: its fields and their hex HMAC-SHA256,
+// separated by "|".
+func (g *ageGate) sign(v ageVerification) string {
+	payload := strings.Join([]string{v.Country, v.DateOfBirth, strconv.FormatInt(v.VerifiedAt.Unix(), 10)}, "|")
+	mac := hmac.New(sha256.New, g.key())
+	mac.Write([]byte(payload))
+	return payload + "|" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// verified returns the verification in r's cookie, if it is signed, not
+// expired, and, with a date of birth, still of legal age.
+func (g *ageGate) verified(r *http.Request) (ageVerification, bool) {
+	c, err := r.Cookie(cookieAgeVerified)
+	if err != nil {
+		return ageVerification{}, false
+	}
+	parts := strings.Split(c.Value, "|")
+	if len(parts) != 4 {
+		return ageVerification{}, false
+	}
+	secs, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return ageVerification{}, false
+	}
+	v := ageVerification{Country: parts[0], DateOfBirth: parts[1], VerifiedAt: time.Unix(secs, 0)}
+	if !hmac.Equal([]byte(g.sign(v)), []byte(c.Value)) {
+		return ageVerification{}, false
+	}
+	if since := g.now().Sub(v.VerifiedAt); since < 0 || since > ageVerificationMaxAge {
+		return ageVerification{}, false
+	}
+	if v.DateOfBirth != "" {
+		// The legal age may have been raised since.
+		if age, err := ageFromBirthDate(v.DateOfBirth, g.now()); err != nil || age < g.ages.minimum(v.Country) {
+			return ageVerification{}, false
+		}
+	}
+	return v, true
+}
+
+// requireAge sends visitors who have not verified their age to the age
+// gate, which brings them back afterwards.
+func (fe *frontendServer) requireAge(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := fe.ageGate.verified(r); ok {
+			h(w, r)
+			return
+		}
+		next := "/"
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			next = r.URL.RequestURI()
+		}
+		http.Redirect(w, r, ageGatePath+"?next="+url.QueryEscape(next), http.StatusSeeOther)
+	}
+}
+
+// ageAuditRecord is the record of an age verification. Dates of birth are
+// not recorded.
+type ageAuditRecord struct {
+	Method     string `json:"method"` // "birthdate" or "confirmation"
+	Country    string `json:"country"`
+	Age        int    `json:"age,omitempty"`
+	MinimumAge int    `json:"minimumAge"`
+}
+
+// logAgeCheck logs a verification at the gate. Anyone can submit the gate
+// as often as they like, so its attempts go to the request log rather than
+// the audit log, whose records are each synced to disk.
+func logAgeCheck(r *http.Request, passed bool, rec ageAuditRecord) {
+	log := getLoggerWithTraceFields(r.Context()).WithField("country", rec.Country).
+		WithField("method", rec.Method).WithField("minimumAge", rec.MinimumAge)
+	if rec.Age > 0 {
+		log = log.WithField("age", rec.Age)
+	}
+	if !passed {
+		log.Info("age verification failed")
+		return
+	}
+	log.Info("age verified")
+}
+
+// recordAgeRefusal audits a checkout refused because the customer is too
+// young for the address's country.
+func (fe *frontendServer) recordAgeRefusal(r *http.Request, rec ageAuditRecord) {
+	fe.recordAudit(r, "session:"+sessionID(r), "age.rejected", "/cart/checkout", nil, rec)
+}
+
+func (fe *frontendServer) ageGateHandler(w http.ResponseWriter, r *http.Request) {
+	fe.renderAgeGate(w, r, "", http.StatusOK)
+}
+
+// verifyAgeHandler checks the gate's form, and on success sets the cookie
+// and returns to the page the visitor came from.
+func (fe *frontendServer) verifyAgeHandler(w http.ResponseWriter, r *http.Request) {
+	g := fe.ageGate
+	v := ageVerification{Country: strings.ToUpper(strings.TrimSpace(r.FormValue("country"))), VerifiedAt: g.now()}
+	if _, ok := g.ages.byCountry[v.Country]; !ok {
+		v.Country = "" // elsewhere
+	}
+	rec := ageAuditRecord{Method: "confirmation", Country: v.Country, MinimumAge: g.ages.minimum(v.Country)}
+	if g.askBirthDate {
+		age, err := ageFromBirthDate(r.FormValue("date_of_birth"), g.now())
+		if err != nil {
+			fe.renderAgeGate(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		v.DateOfBirth = r.FormValue("date_of_birth")
+		rec.Method, rec.Age = "birthdate", age
+	}
+	if g.askBirthDate && rec.Age < rec.MinimumAge || !g.askBirthDate && r.FormValue("confirm") != "yes" {
+		logAgeCheck(r, false, rec)
+		fe.renderAgeGate(w, r, "Sorry, you must be "+strconv.Itoa(rec.MinimumAge)+" or older to shop here.", http.StatusForbidden)
+		return
+	}
+	logAgeCheck(r, true, rec)
+	http.SetCookie(w, &http.Cookie{
+		Name:     cookieAgeVerified,
+		Value:    g.sign(v),
+		Path:     "/",
+		MaxAge:   int(ageVerificationMaxAge / time.Second),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, safeNext(r.FormValue("next")), http.StatusSeeOther)
+}
+
+// safeNext returns next if it is a path on this site, so that the gate
+// cannot redirect elsewhere, or else "/".
+func safeNext(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/"
+	}
+	return next
+}
+
+func (fe *frontendServer) renderAgeGate(w http.ResponseWriter, r *http.Request, message string, code int) {
+	log := getLoggerWithTraceFields(r.Context())
+	w.WriteHeader(code)
+	if err := templates.ExecuteTemplate(w, "agegate", map[string]interface{}{
+		"session_id":      sessionID(r),
+		"request_id":      r.Context().Value(ctxKeyRequestID{}),
+		"next":            safeNext(r.FormValue("next")),
+		"countries":       fe.ageGate.ages.countries(),
+		"country":         strings.ToUpper(r.FormValue("country")),
+		"date_of_birth":   r.FormValue("date_of_birth"),
+		"ask_birth_date":  fe.ageGate.askBirthDate,
+		"message":         message,
+		"platform_css":    plat.css,
+		"platform_name":   plat.provider,
+		"rum_realm":       os.Getenv("RUM_REALM"),
+		"rum_auth":        os.Getenv("RUM_AUTH"),
+		"rum_app_name":    os.Getenv("RUM_APP_NAME"),
+		"rum_environment": os.Getenv("RUM_ENVIRONMENT"),
+		"rum_debug":       os.Getenv("RUM_DEBUG"),
+	}); err != nil {
+		log.Println(err)
+	}
+}
@@ -0,0 +1,194 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/signalfx/microservices-demo/src/frontend/audit"
+	pb "github.com/signalfx/microservices-demo/src/frontend/genproto"
+)
+
+func TestAgeFromBirthDate(t *testing.T) {
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		dob     string
+		want    int
+		wantErr string
+	}{
+		{"2003-03-15", 21, ""},
+		{"2003-03-16", 20, ""},
+		{"2000-02-29", 24, ""},
+		{"", 0, "date of birth is required"},
+		{"03/15/2003", 0, "YYYY-MM-DD"},
+		{"2025-01-01", 0, "not a possible date of birth"},
+	}
+	for _, tt := range tests {
+		got, err := ageFromBirthDate(tt.dob, now)
+		if got != tt.want || tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("ageFromBirthDate(%q) = %d, %v, want %d, %q", tt.dob, got, err, tt.want, tt.wantErr)
+		}
+	}
+	if _, err := parseDrinkingAges("US=21"); err == nil {
+		t.Error("parseDrinkingAges accepted ages without a fallback")
+	}
+}
+
+func TestAgeGate(t *testing.T) {
+	fe := testFrontendServer(t)
+	now := time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)
+	fe.ageGate.now = func() time.Time { return now }
+	shop := fe.requireAge(func(w http.ResponseWriter, _ *http.Request) { w.Write([]byte("the shop")) })
+
+	request := func(method, target, form string, cookie *http.Cookie) *httptest.ResponseRecorder {
+		r := behaviorRequest(method, form, map[string]string{"Content-Type": "application/x-www-form-urlencoded"})
+		r.URL.Path, r.URL.RawQuery = target, ""
+		if i := strings.IndexByte(target, '?'); i >= 0 {
+			r.URL.Path, r.URL.RawQuery = target[:i], target[i+1:]
+		}
+		r = r.WithContext(context.WithValue(r.Context(), ctxKeySessionID{}, "s1"))
+		if cookie != nil {
+			r.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		if r.URL.Path == ageGatePath {
+			fe.verifyAgeHandler(w, r)
+		} else {
+			shop(w, r)
+		}
+		return w
+	}
+	cookieOf := func(w *httptest.ResponseRecorder) *http.Cookie {
+		for _, c := range w.Result().Cookies() {
+			if c.Name == cookieAgeVerified {
+				return c
+			}
+		}
+		return nil
+	}
+
+	if w := request(http.MethodGet, "/cart?shipping_option=express", "", nil); w.Code != http.StatusSeeOther ||
+		w.Header().Get("Location") != "/age-gate?next=%2Fcart%3Fshipping_option%3Dexpress" {
+		t.Fatalf("unverified: got %d to %q, want the age gate", w.Code, w.Header().Get("Location"))
+	}
+
+	w := request(http.MethodPost, ageGatePath, "country=US&date_of_birth=2003-03-16&next=%2Fcart", nil)
+	if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "you must be 21 or older") || cookieOf(w) != nil {
+		t.Errorf("20 in the US: got %d, cookie %v, want 403 without one", w.Code, cookieOf(w))
+	}
+	if w := request(http.MethodPost, ageGatePath, "country=US&date_of_birth=someday", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid date of birth: got %d, want 400", w.Code)
+	}
+
+	// 20 is old enough elsewhere.
+	w = request(http.MethodPost, ageGatePath, "country=&date_of_birth=2003-03-16&next=%2Fcart", nil)
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/cart" || cookieOf(w) == nil {
+		t.Fatalf("20 elsewhere: got %d to %q, want the cart with a cookie", w.Code, w.Header().Get("Location"))
+	}
+	verified := cookieOf(w)
+	if w := request(http.MethodGet, "/cart", "", verified); w.Body.String() != "the shop" {
+		t.Errorf("verified: got %d %q, want the shop", w.Code, w.Body)
+	}
+
+	tampered := *verified
+	tampered.Value = strings.Replace(tampered.Value, "2003-03-16", "2000-03-16", 1)
+	if w := request(http.MethodGet, "/cart", "", &tampered); w.Code != http.StatusSeeOther {
+		t.Errorf("tampered cookie: got %d, want the age gate", w.Code)
+	}
+	now = now.Add(ageVerificationMaxAge + time.Hour)
+	if w := request(http.MethodGet, "/cart", "", verified); w.Code != http.StatusSeeOther {
+		t.Errorf("expired cookie: got %d, want the age gate", w.Code)
+	}
+
+	if w := request(http.MethodPost, ageGatePath, "country=US&date_of_birth=1990-04-01&next=%2F%2Fevil.example", nil); w.Header().Get("Location") != "/" {
+		t.Errorf("next elsewhere: redirected to %q, want /", w.Header().Get("Location"))
+	}
+
+	// Asking for a confirmation only.
+	fe.ageGate.askBirthDate = false
+	if w := request(http.MethodPost, ageGatePath, "country=JP&confirm=no", nil); w.Code != http.StatusForbidden {
+		t.Errorf("not confirmed: got %d, want 403", w.Code)
+	}
+	w = request(http.MethodPost, ageGatePath, "country=JP&confirm=yes", nil)
+	if v, ok := fe.ageGate.verified(withCookie(cookieOf(w))); !ok || v.Country != "JP" || v.DateOfBirth != "" {
+		t.Errorf("confirmed: verification = %+v, %v, want JP without a date of birth", v, ok)
+	}
+
+	// Anyone can submit the gate, so its attempts are not audited.
+	if page, err := fe.auditLog.Query(audit.Query{Limit: 10}); err != nil || len(page.Records) != 0 {
+		t.Errorf("audited %+v, %v, want nothing", page.Records, err)
+	}
+}
+
+func withCookie(c *http.Cookie) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if c != nil {
+		r.AddCookie(c)
+	}
+	return r
+}
+
+func TestCheckoutAgeCheck(t *testing.T) {
+	fe := testFrontendServer(t)
+	shop := newFakeShop()
+	shop.serve(t, fe)
+	if _, err := shop.AddItem(context.Background(), &pb.AddItemRequest{UserId: "s1", Item: &pb.CartItem{ProductId: "L9ECAV7KIM", Quantity: 1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	checkout := func(dob string) *httptest.ResponseRecorder {
+		form := "email=someone%40example.com&credit_card_number=4432-8015-6152-0454&date_of_birth=" + dob +
+			"&street_address=1+Main+St&city=Boston&state=MA&country=US&postal_code=02134"
+		r := behaviorRequest(http.MethodPost, form, map[string]string{"Content-Type": "application/x-www-form-urlencoded"})
+		r = r.WithContext(context.WithValue(r.Context(), ctxKeySessionID{}, "s1"))
+		w := httptest.NewRecorder()
+		fe.placeOrderHandler(w, r)
+		return w
+	}
+
+	young := time.Now().AddDate(-20, 0, 0).Format("2006-01-02")
+	w := checkout(young)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("20 to the US: got %d, want 422", w.Code)
+	}
+	for _, want := range []string{"you must be 21 or older to order to US", `value="` + young + `"`} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("cart page does not contain %q", want)
+		}
+	}
+	if w := checkout(""); w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), "date of birth is required") {
+		t.Errorf("no date of birth: got %d, want 422 asking for it", w.Code)
+	}
+	if len(shop.orders) != 0 {
+		t.Fatalf("placed %d orders, want none", len(shop.orders))
+	}
+
+	if w := checkout("1990-04-01"); !strings.Contains(w.Body.String(), "Your order is complete") {
+		t.Errorf("of age: got %d, want the order page", w.Code)
+	}
+	page, err := fe.auditLog.Query(audit.Query{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Records) != 1 || page.Records[0].Action != "age.rejected" || page.Records[0].Target != "/cart/checkout" ||
+		string(page.Records[0].After) != `{"method":"birthdate","country":"US","age":20,"minimumAge":21}` {
+		t.Errorf("audited %+v, want only the refusal", page.Records)
+	}
+}
@@ -0,0 +1,593 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/signalfx/microservices-demo/src/frontend/genproto"
+	"github.com/signalfx/microservices-demo/src/frontend/money"
+)
+
+const apiPrefix = "/api/v1"
+
+var errInvalidSessionToken = errors.New("session token must be a session ID issued by POST " + apiPrefix + "/sessions")
+
+// openAPIDocument describes every route registered by registerAPI.
+//
+//go:embed openapi.json
+var openAPIDocument []byte
+
+// registerAPI adds the JSON API to r, which must only match paths under
+// apiPrefix. The API shares the storefront's session: a session cookie or a
+// bearer session token (see ensureSessionID).
+func (fe *frontendServer) registerAPI(r *mux.Router) {
+	r.HandleFunc("/openapi.json", apiOpenAPIHandler).Methods(http.MethodGet)
+	r.HandleFunc("/sessions", apiCreateSessionHandler).Methods(http.MethodPost)
+	r.HandleFunc("/products", fe.apiProductsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/products/{id}", fe.apiProductHandler).Methods(http.MethodGet)
+	r.HandleFunc("/cart", fe.apiCartHandler).Methods(http.MethodGet)
+	r.HandleFunc("/cart", fe.apiEmptyCartHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/cart/items", fe.apiAddToCartHandler).Methods(http.MethodPost)
+	r.HandleFunc("/cart/items/{productId}", fe.apiUpdateCartItemHandler).Methods(http.MethodPut)
+	r.HandleFunc("/cart/items/{productId}", fe.apiRemoveCartItemHandler).Methods(http.MethodDelete)
+	r.HandleFunc("/currencies", fe.apiCurrenciesHandler).Methods(http.MethodGet)
+	r.HandleFunc("/shipping/quote", fe.apiShippingQuoteHandler).Methods(http.MethodGet)
+	r.HandleFunc("/checkout", fe.apiCheckoutHandler).Methods(http.MethodPost)
+	r.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		renderJSON(w, http.StatusNotFound, newJSONError(errors.New("no such endpoint"), http.StatusNotFound))
+	})
+	r.MethodNotAllowedHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		renderJSON(w, http.StatusMethodNotAllowed, newJSONError(errors.New("method not allowed"), http.StatusMethodNotAllowed))
+	})
+}
+
+// apiMoney is a pb.Money with its decimal amount spelled out.
+type apiMoney struct {
+	CurrencyCode string `json:"currencyCode"`
+	Units        int64  `json:"units"`
+	Nanos        int32  `json:"nanos"`
+	Amount       string `json:"amount"`
+}
+
+func newAPIMoney(m pb.Money) apiMoney {
+	return apiMoney{CurrencyCode: m.GetCurrencyCode(), Units: m.GetUnits(), Nanos: m.GetNanos(), Amount: money.Format(m)}
+}
+
+type apiProduct struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Picture     string   `json:"picture"`
+	Categories  []string `json:"categories"`
+	Price       apiMoney `json:"price"`
+	MaxQuantity int32    `json:"maxQuantity"`
+}
+
+type apiCartItem struct {
+	Product  apiProduct `json:"product"`
+	Quantity int32      `json:"quantity"`
+	Total    apiMoney   `json:"total"`
+}
+
+type apiCart struct {
+	Items     []apiCartItem `json:"items"`
+	ItemCount int           `json:"itemCount"`
+	Subtotal  apiMoney      `json:"subtotal"`
+}
+
+type apiAddress struct {
+	StreetAddress string `json:"streetAddress"`
+	City          string `json:"city"`
+	State         string `json:"state"`
+	Country       string `json:"country"`
+	PostalCode    string `json:"postalCode"`
+	// Deprecated: ZipCode cannot hold leading zeros or letters; it is read
+	// only when PostalCode is empty.
+	ZipCode int32 `json:"zipCode,omitempty"`
+}
+
+func newAPIAddress(a *pb.Address) apiAddress {
+	return apiAddress{
+		StreetAddress: a.GetStreetAddress(),
+		City:          a.GetCity(),
+		State:         a.GetState(),
+		Country:       a.GetCountry(),
+		PostalCode:    a.GetPostalCode(),
+		ZipCode:       a.GetZipCode(),
+	}
+}
+
+// apiAddressFields are the API names of the fields ValidateAddress reports.
+var apiAddressFields = map[string]string{
+	"street_address": "address.streetAddress",
+	"city":           "address.city",
+	"state":          "address.state",
+	"country":        "address.country",
+	"postal_code":    "address.postalCode",
+}
+
+type apiCreditCard struct {
+	Number          string `json:"number"`
+	CVV             int32  `json:"cvv"`
+	ExpirationYear  int32  `json:"expirationYear"`
+	ExpirationMonth int32  `json:"expirationMonth"`
+}
+
+// apiShippingOption is a shipping method offered for the cart.
+type apiShippingOption struct {
+	ID               string   `json:"id"`
+	Name             string   `json:"name"`
+	Cost             apiMoney `json:"cost"`
+	Carrier          string   `json:"carrier"`
+	EarliestDelivery string   `json:"earliestDelivery"`
+	LatestDelivery   string   `json:"latestDelivery"`
+	AdultSignature   bool     `json:"adultSignature"`
+}
+
+// apiCheckoutInput is the body of POST /api/v1/checkout.
+type apiCheckoutInput struct {
+	Email       string        `json:"email"`
+	DateOfBirth string        `json:"dateOfBirth"`
+	Address     apiAddress    `json:"address"`
+	CreditCard  apiCreditCard `json:"creditCard"`
+	// ShippingOptionID is an option from GET /shipping/quote; empty for the
+	// first.
+	ShippingOptionID string `json:"shippingOptionId"`
+}
+
+type apiOrderItem struct {
+	ProductID string   `json:"productId"`
+	Quantity  int32    `json:"quantity"`
+	Cost      apiMoney `json:"cost"`
+}
+
+type apiOrder struct {
+	OrderID            string         `json:"orderId"`
+	ShippingTrackingID string         `json:"shippingTrackingId"`
+	ShippingCost       apiMoney       `json:"shippingCost"`
+	ShippingAddress    apiAddress     `json:"shippingAddress"`
+	Items              []apiOrderItem `json:"items"`
+	Total              apiMoney       `json:"total"`
+}
+
+func apiOpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/json")
+	w.Write(openAPIDocument)
+}
+
+// apiCreateSessionHandler issues a session token for clients that do not
+// keep cookies.
+func apiCreateSessionHandler(w http.ResponseWriter, r *http.Request) {
+	u, _ := uuid.NewRandom()
+	renderJSON(w, http.StatusCreated, struct {
+		Token string `json:"token"`
+	}{u.String()})
+}
+
+// apiCurrency returns the currency requested with the currency query
+// parameter, falling back to the storefront's currency cookie.
+func apiCurrency(w http.ResponseWriter, r *http.Request) (string, bool) {
+	cur := r.URL.Query().Get("currency")
+	if cur == "" {
+		return currentCurrency(r), true
+	}
+	if !whitelistedCurrencies[cur] {
+		renderJSONError(getLoggerWithTraceFields(r.Context()), w, errors.New("unsupported currency"), http.StatusBadRequest,
+			fieldError{Field: "currency", Message: "is not a supported currency"})
+		return "", false
+	}
+	return cur, true
+}
+
+// renderAPIError reports a failed RPC, keeping its gRPC status where there
+// is an HTTP equivalent.
+func renderAPIError(w http.ResponseWriter, r *http.Request, err error, msg string) {
+	code := http.StatusInternalServerError
+	switch status.Code(errors.Cause(err)) {
+	case codes.NotFound:
+		code = http.StatusNotFound
+	case codes.InvalidArgument:
+		code = http.StatusBadRequest
+	case codes.FailedPrecondition:
+		code = http.StatusUnprocessableEntity
+	case codes.Unavailable:
+		code = http.StatusServiceUnavailable
+	case codes.DeadlineExceeded:
+		code = http.StatusGatewayTimeout
+	}
+	renderJSONError(getLoggerWithTraceFields(r.Context()), w, errors.Wrap(err, msg), code)
+}
+
+func newAPIProduct(p *pb.Product, price pb.Money) apiProduct {
+	return apiProduct{
+		ID:          p.GetId(),
+		Name:        p.GetName(),
+		Description: p.GetDescription(),
+		Picture:     p.GetPicture(),
+		Categories:  p.GetCategories(),
+		Price:       newAPIMoney(price),
+		MaxQuantity: maxQuantity(p),
+	}
+}
+
+// apiProduct prices p in currency.
+func (fe *frontendServer) apiProduct(ctx context.Context, p *pb.Product, currency string) (apiProduct, error) {
+	price, err := fe.convertCurrency(ctx, p.GetPriceUsd(), currency)
+	if err != nil {
+		return apiProduct{}, errors.Wrapf(err, "could not convert currency for product #%s", p.GetId())
+	}
+	return newAPIProduct(p, *price), nil
+}
+
+// apiProductsHandler lists the catalog, or searches it when q is given.
+func (fe *frontendServer) apiProductsHandler(w http.ResponseWriter, r *http.Request) {
+	currency, ok := apiCurrency(w, r)
+	if !ok {
+		return
+	}
+	var (
+		products []*pb.Product
+		err      error
+	)
+	if q := r.URL.Query().Get("q"); q != "" {
+		products, err = fe.searchProducts(r.Context(), q)
+	} else {
+		products, err = fe.getProducts(r.Context())
+	}
+	if err != nil {
+		renderAPIError(w, r, err, "could not retrieve products")
+		return
+	}
+	out := make([]apiProduct, len(products))
+	for i, p := range products {
+		if out[i], err = fe.apiProduct(r.Context(), p, currency); err != nil {
+			renderAPIError(w, r, err, "could not price products")
+			return
+		}
+	}
+	renderJSON(w, http.StatusOK, struct {
+		Products []apiProduct `json:"products"`
+	}{out})
+}
+
+func (fe *frontendServer) apiProductHandler(w http.ResponseWriter, r *http.Request) {
+	currency, ok := apiCurrency(w, r)
+	if !ok {
+		return
+	}
+	p, err := fe.getProduct(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		renderAPIError(w, r, err, "could not retrieve product")
+		return
+	}
+	out, err := fe.apiProduct(r.Context(), p, currency)
+	if err != nil {
+		renderAPIError(w, r, err, "could not price product")
+		return
+	}
+	renderJSON(w, http.StatusOK, out)
+}
+
+// apiCart prices the session's cart in currency.
+func (fe *frontendServer) apiCart(ctx context.Context, userID, currency string) (apiCart, error) {
+	items, err := fe.getCart(ctx, userID)
+	if err != nil {
+		return apiCart{}, errors.Wrap(err, "could not retrieve cart")
+	}
+	out := apiCart{Items: make([]apiCartItem, len(items)), ItemCount: cartSize(items)}
+	subtotal := pb.Money{CurrencyCode: currency}
+	for i, item := range items {
+		p, err := fe.getProduct(ctx, item.GetProductId())
+		if err != nil {
+			return apiCart{}, errors.Wrapf(err, "could not retrieve product #%s", item.GetProductId())
+		}
+		price, err := fe.convertCurrency(ctx, p.GetPriceUsd(), currency)
+		if err != nil {
+			return apiCart{}, errors.Wrapf(err, "could not convert currency for product #%s", p.GetId())
+		}
+		total := money.MultiplySlow(*price, uint32(item.GetQuantity()))
+		subtotal = money.Must(money.Sum(subtotal, total))
+		out.Items[i] = apiCartItem{Product: newAPIProduct(p, *price), Quantity: item.GetQuantity(), Total: newAPIMoney(total)}
+	}
+	out.Subtotal = newAPIMoney(subtotal)
+	return out, nil
+}
+
+func (fe *frontendServer) apiCartHandler(w http.ResponseWriter, r *http.Request) {
+	currency, ok := apiCurrency(w, r)
+	if !ok {
+		return
+	}
+	fe.renderAPICart(w, r, currency)
+}
+
+func (fe *frontendServer) apiAddToCartHandler(w http.ResponseWriter, r *http.Request) {
+	log := getLoggerWithTraceFields(r.Context())
+	currency, ok := apiCurrency(w, r)
+	if !ok {
+		return
+	}
+	var in struct {
+		ProductID string `json:"productId"`
+		Quantity  int32  `json:"quantity"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&in); err != nil {
+		renderJSONError(log, w, errors.Wrap(err, "invalid cart item"), http.StatusBadRequest)
+		return
+	}
+	if in.ProductID == "" {
+		renderJSONError(log, w, errors.New("invalid cart item"), http.StatusBadRequest, fieldError{Field: "productId", Message: "is required"})
+		return
+	}
+	p, err := fe.getProduct(r.Context(), in.ProductID)
+	if err != nil {
+		renderAPIError(w, r, err, "could not retrieve product")
+		return
+	}
+	inCart, err := fe.cartQuantity(r.Context(), sessionID(r), in.ProductID)
+	if err != nil {
+		renderAPIError(w, r, err, "could not retrieve cart")
+		return
+	}
+	if err := checkQuantity(p, inCart, in.Quantity); err != nil {
+		renderJSONError(log, w, errors.New("invalid cart item"), http.StatusBadRequest, fieldError{Field: "quantity", Message: err.Error()})
+		return
+	}
+	if err := fe.insertCart(r.Context(), sessionID(r), in.ProductID, in.Quantity); err != nil {
+		renderAPIError(w, r, err, "failed to add to cart")
+		return
+	}
+	cart, err := fe.apiCart(r.Context(), sessionID(r), currency)
+	if err != nil {
+		renderAPIError(w, r, err, "could not retrieve cart")
+		return
+	}
+	renderJSON(w, http.StatusCreated, cart)
+}
+
+func (fe *frontendServer) apiUpdateCartItemHandler(w http.ResponseWriter, r *http.Request) {
+	log := getLoggerWithTraceFields(r.Context())
+	currency, ok := apiCurrency(w, r)
+	if !ok {
+		return
+	}
+	productID := mux.Vars(r)["productId"]
+	var in struct {
+		Quantity *int32 `json:"quantity"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&in); err != nil {
+		renderJSONError(log, w, errors.Wrap(err, "invalid cart item"), http.StatusBadRequest)
+		return
+	}
+	if in.Quantity == nil || *in.Quantity < 0 {
+		renderJSONError(log, w, errors.New("invalid cart item"), http.StatusBadRequest, fieldError{Field: "quantity", Message: "must be 0 or more"})
+		return
+	}
+	if *in.Quantity > 0 {
+		p, err := fe.getProduct(r.Context(), productID)
+		if err != nil {
+			renderAPIError(w, r, err, "could not retrieve product")
+			return
+		}
+		if err := checkQuantity(p, 0, *in.Quantity); err != nil {
+			renderJSONError(log, w, errors.New("invalid cart item"), http.StatusBadRequest, fieldError{Field: "quantity", Message: err.Error()})
+			return
+		}
+	}
+	if err := fe.updateCartItem(r.Context(), sessionID(r), productID, *in.Quantity); err != nil {
+		renderAPIError(w, r, err, "failed to update cart")
+		return
+	}
+	fe.renderAPICart(w, r, currency)
+}
+
+func (fe *frontendServer) apiRemoveCartItemHandler(w http.ResponseWriter, r *http.Request) {
+	currency, ok := apiCurrency(w, r)
+	if !ok {
+		return
+	}
+	if err := fe.removeCartItem(r.Context(), sessionID(r), mux.Vars(r)["productId"]); err != nil {
+		renderAPIError(w, r, err, "failed to remove from cart")
+		return
+	}
+	fe.renderAPICart(w, r, currency)
+}
+
+func (fe *frontendServer) renderAPICart(w http.ResponseWriter, r *http.Request, currency string) {
+	cart, err := fe.apiCart(r.Context(), sessionID(r), currency)
+	if err != nil {
+		renderAPIError(w, r, err, "could not retrieve cart")
+		return
+	}
+	renderJSON(w, http.StatusOK, cart)
+}
+
+func (fe *frontendServer) apiEmptyCartHandler(w http.ResponseWriter, r *http.Request) {
+	if err := fe.emptyCart(r.Context(), sessionID(r)); err != nil {
+		renderAPIError(w, r, err, "failed to empty cart")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (fe *frontendServer) apiCurrenciesHandler(w http.ResponseWriter, r *http.Request) {
+	currencies, err := fe.getCurrencies(r.Context())
+	if err != nil {
+		renderAPIError(w, r, err, "could not retrieve currencies")
+		return
+	}
+	renderJSON(w, http.StatusOK, struct {
+		CurrencyCodes []string `json:"currencyCodes"`
+		Current       string   `json:"current"`
+	}{currencies, currentCurrency(r)})
+}
+
+// apiQuoteAddress returns the address given in the query to quote
+// shipping to, or nil to quote to shippingservice's default zone. Checkout
+// quotes to its own address, so a quote is only as good as the address
+// given here.
+func apiQuoteAddress(r *http.Request) *pb.Address {
+	q := r.URL.Query()
+	if q.Get("country") == "" {
+		return nil
+	}
+	return &pb.Address{
+		StreetAddress: q.Get("streetAddress"),
+		City:          q.Get("city"),
+		State:         q.Get("state"),
+		Country:       q.Get("country"),
+		PostalCode:    q.Get("postalCode"),
+	}
+}
+
+// apiShippingQuoteHandler lists the shipping options for the session's
+// cart. cost, the first option's, is kept for clients that predate them.
+func (fe *frontendServer) apiShippingQuoteHandler(w http.ResponseWriter, r *http.Request) {
+	currency, ok := apiCurrency(w, r)
+	if !ok {
+		return
+	}
+	items, err := fe.getCart(r.Context(), sessionID(r))
+	if err != nil {
+		renderAPIError(w, r, err, "could not retrieve cart")
+		return
+	}
+	options, err := fe.getShippingOptions(r.Context(), apiQuoteAddress(r), items, currency)
+	if err != nil {
+		renderAPIError(w, r, err, "failed to get shipping quote")
+		return
+	}
+	out := struct {
+		Cost    *apiMoney           `json:"cost,omitempty"`
+		Options []apiShippingOption `json:"options"`
+	}{Options: make([]apiShippingOption, len(options))}
+	for i, o := range options {
+		out.Options[i] = apiShippingOption{
+			ID:               o.GetId(),
+			Name:             o.GetName(),
+			Cost:             newAPIMoney(*o.Cost),
+			Carrier:          o.GetCarrier(),
+			EarliestDelivery: o.GetEarliestDelivery(),
+			LatestDelivery:   o.GetLatestDelivery(),
+			AdultSignature:   o.GetAdultSignature(),
+		}
+	}
+	if len(out.Options) > 0 {
+		out.Cost = &out.Options[0].Cost
+	}
+	renderJSON(w, http.StatusOK, out)
+}
+
+func (fe *frontendServer) apiCheckoutHandler(w http.ResponseWriter, r *http.Request) {
+	log := getLoggerWithTraceFields(r.Context())
+	currency, ok := apiCurrency(w, r)
+	if !ok {
+		return
+	}
+	var in apiCheckoutInput
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&in); err != nil {
+		renderJSONError(log, w, errors.Wrap(err, "invalid order"), http.StatusBadRequest)
+		return
+	}
+	var fields []fieldError
+	for _, f := range []struct {
+		name  string
+		empty bool
+	}{
+		{"email", in.Email == ""},
+		{"dateOfBirth", in.DateOfBirth == ""},
+		{"address.streetAddress", in.Address.StreetAddress == ""},
+		{"address.city", in.Address.City == ""},
+		{"address.country", in.Address.Country == ""},
+		{"address.postalCode", in.Address.PostalCode == "" && in.Address.ZipCode == 0},
+		{"creditCard.number", in.CreditCard.Number == ""},
+		{"creditCard.cvv", in.CreditCard.CVV == 0},
+		{"creditCard.expirationYear", in.CreditCard.ExpirationYear == 0},
+	} {
+		if f.empty {
+			fields = append(fields, fieldError{Field: f.name, Message: "is required"})
+		}
+	}
+	if m := in.CreditCard.ExpirationMonth; m < 1 || m > 12 {
+		fields = append(fields, fieldError{Field: "creditCard.expirationMonth", Message: "must be between 1 and 12"})
+	}
+	if len(fields) > 0 {
+		renderJSONError(log, w, errors.New("invalid order"), http.StatusBadRequest, fields...)
+		return
+	}
+
+	address := &pb.Address{
+		StreetAddress: in.Address.StreetAddress,
+		City:          in.Address.City,
+		State:         in.Address.State,
+		Country:       in.Address.Country,
+		PostalCode:    in.Address.PostalCode,
+		ZipCode:       in.Address.ZipCode}
+	validation, err := fe.validateAddress(r.Context(), address)
+	if err != nil {
+		renderAPIError(w, r, err, "failed to validate the address")
+		return
+	}
+	if !validation.GetValid() {
+		for _, e := range validation.GetErrors() {
+			fields = append(fields, fieldError{Field: apiAddressFields[e.GetField()], Message: e.GetMessage()})
+		}
+		renderJSONError(log, w, errors.New("invalid address"), http.StatusBadRequest, fields...)
+		return
+	}
+
+	order, total, err := fe.placeOrder(r.Context(), &pb.PlaceOrderRequest{
+		Email: in.Email,
+		CreditCard: &pb.CreditCardInfo{
+			CreditCardNumber:          in.CreditCard.Number,
+			CreditCardCvv:             in.CreditCard.CVV,
+			CreditCardExpirationYear:  in.CreditCard.ExpirationYear,
+			CreditCardExpirationMonth: in.CreditCard.ExpirationMonth},
+		UserId:           sessionID(r),
+		UserCurrency:     currency,
+		Address:          validation.GetNormalized(),
+		DateOfBirth:      in.DateOfBirth,
+		ShippingOptionId: in.ShippingOptionID,
+	})
+	if err != nil {
+		renderAPIError(w, r, err, "failed to complete the order")
+		return
+	}
+	log.WithField("order", order.GetOrderId()).Info("order placed")
+	addOrderIDToSpan(r.Context(), order.GetOrderId())
+
+	out := apiOrder{
+		OrderID:            order.GetOrderId(),
+		ShippingTrackingID: order.GetShippingTrackingId(),
+		ShippingCost:       newAPIMoney(*order.GetShippingCost()),
+		ShippingAddress:    newAPIAddress(order.GetShippingAddress()),
+		Items:              make([]apiOrderItem, len(order.GetItems())),
+		Total:              newAPIMoney(total),
+	}
+	for i, v := range order.GetItems() {
+		out.Items[i] = apiOrderItem{ProductID: v.GetItem().GetProductId(), Quantity: v.GetItem().GetQuantity(), Cost: newAPIMoney(*v.GetCost())}
+	}
+	renderJSON(w, http.StatusCreated, out)
+}
@@ -0,0 +1,154 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+)
+
+// testAPIHandler serves the API of fe behind the same middleware as main.
+func testAPIHandler(fe *frontendServer) http.Handler {
+	r := mux.NewRouter()
+	fe.registerAPI(r.PathPrefix(apiPrefix).Subrouter())
+	log := logrus.New()
+	log.Out = ioutil.Discard
+	return ensureSessionID(&logHandler{log: log, next: r})
+}
+
+func TestAPI(t *testing.T) {
+	fe := testFrontendServer(t)
+	newFakeShop().serve(t, fe)
+	h := testAPIHandler(fe)
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, apiPrefix+"/sessions", nil))
+	var session struct{ Token string }
+	if err := json.Unmarshal(w.Body.Bytes(), &session); w.Code != http.StatusCreated || err != nil {
+		t.Fatalf("POST /sessions: %d %s", w.Code, w.Body)
+	}
+
+	const checkout = `{"email":"someone@example.com","dateOfBirth":"1990-04-01","address":{"streetAddress":"1600 Amphitheatre Parkway","city":"Mountain View","state":"CA","country":"United States","zipCode":94043},"creditCard":{"number":"4432801561520454","cvv":672,"expirationYear":2030,"expirationMonth":1}}`
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		auth   string // "token", "cookie" or a raw Authorization header
+		code   int
+		want   string
+	}{
+		{"list", "GET", "/products", "", "token", 200, `"name":"Vintage Typewriter"`},
+		{"search", "GET", "/products?q=barista", "", "token", 200, `"products":[{"id":"1YMWWN1N4O"`},
+		{"converted price", "GET", "/products/OLJCESPC7Z?currency=EUR", "", "token", 200, `"amount":"33.995"`},
+		{"product limit", "GET", "/products/OLJCESPC7Z", "", "token", 200, `"maxQuantity":2`},
+		{"unknown product", "GET", "/products/nope", "", "token", 404, `"status":"Not Found"`},
+		{"unsupported currency", "GET", "/products?currency=XYZ", "", "token", 400, `"field":"currency"`},
+		{"add to cart", "POST", "/cart/items", `{"productId":"L9ECAV7KIM","quantity":2}`, "token", 201, `"itemCount":2,"subtotal":{"currencyCode":"USD","units":72,"nanos":900000000,"amount":"72.90"}`},
+		{"invalid quantity", "POST", "/cart/items", `{"productId":"L9ECAV7KIM","quantity":0}`, "token", 400, `"field":"quantity"`},
+		{"over the default limit", "POST", "/cart/items", `{"productId":"L9ECAV7KIM","quantity":9}`, "token", 400, "quantity must be between 1 and 8"},
+		{"over the product's limit", "POST", "/cart/items", `{"productId":"OLJCESPC7Z","quantity":3}`, "token", 400, "quantity must be between 1 and 2"},
+		{"update quantity", "PUT", "/cart/items/L9ECAV7KIM", `{"quantity":10}`, "token", 200, `"itemCount":10`},
+		{"update over the limit", "PUT", "/cart/items/L9ECAV7KIM", `{"quantity":11}`, "token", 400, "quantity must be between 1 and 10"},
+		{"update without quantity", "PUT", "/cart/items/L9ECAV7KIM", `{}`, "token", 400, `"field":"quantity"`},
+		{"update product not in cart", "PUT", "/cart/items/1YMWWN1N4O", `{"quantity":1}`, "token", 404, "not in the cart"},
+		{"add another product", "POST", "/cart/items", `{"productId":"OLJCESPC7Z","quantity":2}`, "token", 201, `"itemCount":12`},
+		{"remove product", "DELETE", "/cart/items/OLJCESPC7Z", "", "token", 200, `"itemCount":10`},
+		{"update to zero removes", "PUT", "/cart/items/L9ECAV7KIM", `{"quantity":0}`, "token", 200, `"itemCount":0`},
+		{"add again", "POST", "/cart/items", `{"productId":"L9ECAV7KIM","quantity":2}`, "token", 201, `"itemCount":2`},
+		{"add unknown product", "POST", "/cart/items", `{"productId":"nope","quantity":1}`, "token", 404, "no product with ID nope"},
+		{"cookie shares the token's cart", "GET", "/cart", "", "cookie", 200, `"quantity":2`},
+		{"other session", "GET", "/cart", "", "", 200, `"items":[]`},
+		{"invalid token", "GET", "/cart", "", "Bearer not-a-session", 401, "session token"},
+		{"shipping quote", "GET", "/shipping/quote", "", "token", 200, `"cost":{"currencyCode":"USD","units":8,"nanos":990000000,"amount":"8.99"},"options":[{"id":"standard","name":"Standard"`},
+		{"shipping quote to an address", "GET", "/shipping/quote?country=United+States&state=AK&postalCode=99501", "", "token", 200, `"cost":{"currencyCode":"USD","units":18,"nanos":990000000,"amount":"18.99"}`},
+		{"shipping options", "GET", "/shipping/quote?currency=EUR", "", "token", 200, `{"id":"express","name":"Express","cost":{"currencyCode":"EUR","units":9,"nanos":995000000,"amount":"9.995"},"carrier":"Frothly Freight","earliestDelivery":"2024-12-24","latestDelivery":"2024-12-24","adultSignature":false}`},
+		{"currencies", "GET", "/currencies", "", "token", 200, `"currencyCodes":["EUR","USD"]`},
+		{"incomplete order", "POST", "/checkout", `{"email":"someone@example.com"}`, "token", 400, `"field":"creditCard.expirationMonth"`},
+		{"declined card", "POST", "/checkout", strings.Replace(checkout, "4432", "0432", 1), "token", 400, "credit card is invalid"},
+		{"invalid address", "POST", "/checkout", strings.Replace(checkout, `"zipCode":94043`, `"postalCode":"4043"`, 1), "token", 400, `"field":"address.postalCode","message":"ZIP codes have five digits"`},
+		{"unknown shipping option", "POST", "/checkout", strings.Replace(checkout, "{", `{"shippingOptionId":"drone",`, 1), "token", 400, `unknown shipping option \"drone\"`},
+		{"checkout", "POST", "/checkout", strings.Replace(checkout, "{", `{"shippingOptionId":"express",`, 1), "token", 201, `"total":{"currencyCode":"USD","units":92,"nanos":890000000,"amount":"92.89"}`},
+		{"cart is empty after checkout", "GET", "/cart", "", "token", 200, `"itemCount":0`},
+		{"checkout empty cart", "POST", "/checkout", checkout, "token", 422, "cart is empty"},
+		{"empty cart", "DELETE", "/cart", "", "token", 204, ""},
+		{"unknown endpoint", "GET", "/nope", "", "token", 404, `"error":"no such endpoint"`},
+		{"wrong method", "PUT", "/cart", "", "token", 405, `"status":"Method Not Allowed"`},
+		{"openapi", "GET", "/openapi.json", "", "", 200, `"openapi": "3.0.3"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, apiPrefix+tt.path, strings.NewReader(tt.body))
+			switch tt.auth {
+			case "token":
+				req.Header.Set("Authorization", "Bearer "+session.Token)
+			case "cookie":
+				req.AddCookie(&http.Cookie{Name: cookieSessionID, Value: session.Token})
+			case "":
+			default:
+				req.Header.Set("Authorization", tt.auth)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+			if w.Code != tt.code || !strings.Contains(w.Body.String(), tt.want) {
+				t.Fatalf("got %d %s, want %d containing %q", w.Code, w.Body, tt.code, tt.want)
+			}
+			if tt.auth == "token" && w.Header().Get("Set-Cookie") != "" {
+				t.Errorf("token session was given a cookie: %s", w.Header().Get("Set-Cookie"))
+			}
+		})
+	}
+}
+
+func TestOpenAPIDocumentCoversRoutes(t *testing.T) {
+	var doc struct {
+		Paths map[string]map[string]json.RawMessage
+	}
+	if err := json.Unmarshal(openAPIDocument, &doc); err != nil {
+		t.Fatal(err)
+	}
+	r := mux.NewRouter()
+	new(frontendServer).registerAPI(r.PathPrefix(apiPrefix).Subrouter())
+	routes := 0
+	r.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		methods, _ := route.GetMethods()
+		if err != nil || len(methods) == 0 {
+			return nil
+		}
+		path = strings.TrimPrefix(path, apiPrefix)
+		for _, m := range methods {
+			routes++
+			if _, ok := doc.Paths[path][strings.ToLower(m)]; !ok {
+				t.Errorf("openapi.json does not document %s %s", m, path)
+			}
+		}
+		return nil
+	})
+	operations := 0
+	for _, ops := range doc.Paths {
+		operations += len(ops)
+	}
+	if operations != routes {
+		t.Errorf("openapi.json documents %d operations, the router has %d", operations, routes)
+	}
+}
@@ -0,0 +1,284 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	pb "github.com/signalfx/microservices-demo/src/frontend/genproto"
+	"github.com/signalfx/microservices-demo/src/frontend/money"
+)
+
+const (
+	defaultApprovalThreshold = "1000"
+	defaultApprovalTTL       = 24 * time.Hour
+	defaultApprovalsFile     = "supplier-payment-approvals.jsonl"
+	approvalSweepInterval    = time.Minute
+)
+
+// Payment request statuses. Only pending requests can be decided; only an
+// approval leads to a payment.
+const (
+	paymentPending  = "pending"
+	paymentApproved = "approved"
+	paymentRejected = "rejected"
+	paymentExpired  = "expired"
+	paymentPaid     = "paid"
+	paymentFailed   = "failed"
+)
+
+var (
+	errPaymentNotFound = errors.New("this payment request no longer exists")
+	errSelfApproval    = errors.New("you requested this payment, so a different approver is needed")
+)
+
+// paymentRequest is a supplier payment above the approval threshold that is
+// waiting for, or has received, a second person's decision. Its ID is the
+// payment's ID in the ledger.
+type paymentRequest struct {
+	ID          string    `json:"id"`
+	SupplierID  string    `json:"supplierId"`
+	Amount      string    `json:"amount"`
+	RequestedBy string    `json:"requestedBy"`
+	RequestedAt time.Time `json:"requestedAt"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+	Status      string    `json:"status"`
+	DecidedBy   string    `json:"decidedBy,omitempty"`
+	Result      string    `json:"result,omitempty"`
+}
+
+// paymentApprovals holds supplier payment requests. With a file, every
+// change is also appended to it, one JSON object per line, so that pending
+// requests survive restarts.
+type paymentApprovals struct {
+	threshold pb.Money
+	ttl       time.Duration
+	now       func() time.Time
+	// onExpire, if set, is called with the lock held for every request that
+	// expires, so that its invoice can be released.
+	onExpire func(paymentRequest)
+
+	mu   sync.Mutex
+	byID map[string]*paymentRequest
+	f    *os.File
+}
+
+// newPaymentApprovals reads the threshold and expiry from
+// SUPPLIER_PAYMENT_APPROVAL_THRESHOLD and SUPPLIER_PAYMENT_APPROVAL_TTL and
+// loads the requests kept in the file at path, creating it if needed.
+func newPaymentApprovals(path string) (*paymentApprovals, error) {
+	a := &paymentApprovals{
+		ttl:  defaultApprovalTTL,
+		now:  time.Now,
+		byID: make(map[string]*paymentRequest),
+	}
+	v := envOr("SUPPLIER_PAYMENT_APPROVAL_THRESHOLD", defaultApprovalThreshold)
+	t, err := money.Parse(v, supplierPaymentCurrency)
+	if err != nil || money.IsNegative(t) {
+		return nil, errors.Errorf("invalid SUPPLIER_PAYMENT_APPROVAL_THRESHOLD %q", v)
+	}
+	a.threshold = t
+	if v := os.Getenv("SUPPLIER_PAYMENT_APPROVAL_TTL"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, errors.Errorf("invalid SUPPLIER_PAYMENT_APPROVAL_TTL %q", v)
+		}
+		a.ttl = d
+	}
+	if err := a.load(path); err != nil {
+		return nil, errors.Wrapf(err, "approvals: failed to load %s", path)
+	}
+	return a, nil
+}
+
+// load replays the file at path, in which the last line about a request
+// is its current state. A line torn by a crash is skipped and terminated.
+func (a *paymentApprovals) load(path string) error {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var p paymentRequest
+		if json.Unmarshal(sc.Bytes(), &p) == nil && p.ID != "" {
+			a.byID[p.ID] = &p
+		}
+	}
+	if err := sc.Err(); err != nil {
+		f.Close()
+		return err
+	}
+	if fi, err := f.Stat(); err == nil && fi.Size() > 0 {
+		b := make([]byte, 1)
+		if _, err = f.ReadAt(b, fi.Size()-1); err == nil && b[0] != '\n' {
+			_, err = f.Write([]byte{'\n'})
+		}
+		if err != nil {
+			f.Close()
+			return err
+		}
+	}
+	a.f = f
+	return nil
+}
+
+// saveLocked durably records p, then makes it the current state.
+func (a *paymentApprovals) saveLocked(p *paymentRequest) error {
+	if a.f != nil {
+		raw, err := json.Marshal(p)
+		if err != nil {
+			return err
+		}
+		if _, err := a.f.Write(append(raw, '\n')); err != nil {
+			return errors.Wrap(err, "approvals: write")
+		}
+		if err := a.f.Sync(); err != nil {
+			return errors.Wrap(err, "approvals: sync")
+		}
+	}
+	a.byID[p.ID] = p
+	return nil
+}
+
+// needsApproval reports whether a payment of amount needs a second person.
+// An amount that cannot be compared with the threshold, such as one in
+// another currency, needs one too.
+func (a *paymentApprovals) needsApproval(amount pb.Money) bool {
+	c, err := money.Compare(amount, a.threshold)
+	return err != nil || c > 0
+}
+
+// request files a request to approve the ledger payment id. Filing the
+// same payment again returns the existing request.
+func (a *paymentApprovals) request(id, supplierID, amount, by string) (paymentRequest, bool, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := a.now()
+	if err := a.expireLocked(now); err != nil {
+		return paymentRequest{}, false, err
+	}
+	if p, ok := a.byID[id]; ok {
+		return *p, false, nil
+	}
+	p := &paymentRequest{
+		ID:          id,
+		SupplierID:  supplierID,
+		Amount:      amount,
+		RequestedBy: by,
+		RequestedAt: now,
+		ExpiresAt:   now.Add(a.ttl),
+		Status:      paymentPending,
+	}
+	if err := a.saveLocked(p); err != nil {
+		return paymentRequest{}, false, err
+	}
+	return *p, true, nil
+}
+
+// decide approves or rejects a pending request. Only the call that moves a
+// request out of pending gets changed == true; repeated clicks see the
+// current state and change nothing.
+func (a *paymentApprovals) decide(id, by string, approve bool) (p paymentRequest, changed bool, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err := a.expireLocked(a.now()); err != nil {
+		return paymentRequest{}, false, err
+	}
+	cur, ok := a.byID[id]
+	if !ok {
+		return paymentRequest{}, false, errPaymentNotFound
+	}
+	if cur.Status != paymentPending {
+		return *cur, false, nil
+	}
+	if approve && by == cur.RequestedBy {
+		return *cur, false, errSelfApproval
+	}
+	next := *cur
+	next.DecidedBy = by
+	next.Status = paymentRejected
+	if approve {
+		next.Status = paymentApproved
+	}
+	if err := a.saveLocked(&next); err != nil {
+		return *cur, false, err
+	}
+	return next, true, nil
+}
+
+// finish records the outcome of an approved payment. The outcome is kept
+// in memory even if it cannot be written; the ledger has it too.
+func (a *paymentApprovals) finish(id, result string, sendErr error) (paymentRequest, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	p := *a.byID[id]
+	p.Status, p.Result = paymentPaid, result
+	if sendErr != nil {
+		p.Status, p.Result = paymentFailed, sendErr.Error()
+	}
+	err := a.saveLocked(&p)
+	a.byID[id] = &p
+	return p, err
+}
+
+// expire expires overdue requests now rather than on the next request or
+// decision.
+func (a *paymentApprovals) expire() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.expireLocked(a.now())
+}
+
+// expireEvery calls expire every interval for as long as the process runs,
+// so that an invoice nobody decides on is released once its request
+// expires.
+func (a *paymentApprovals) expireEvery(interval time.Duration, log logrus.FieldLogger) {
+	for range time.Tick(interval) {
+		if err := a.expire(); err != nil {
+			log.WithField("error", err).Warn("could not expire payment requests")
+		}
+	}
+}
+
+// expireLocked durably marks overdue pending requests as expired, passing
+// each to onExpire, and forgets decided requests once they are older than
+// the TTL. A request whose expiry cannot be written stays pending and is
+// tried again next time.
+func (a *paymentApprovals) expireLocked(now time.Time) error {
+	for id, p := range a.byID {
+		switch {
+		case p.Status == paymentPending && !now.Before(p.ExpiresAt):
+			next := *p
+			next.Status = paymentExpired
+			if err := a.saveLocked(&next); err != nil {
+				return err
+			}
+			if a.onExpire != nil {
+				a.onExpire(next)
+			}
+		case p.Status != paymentPending && p.Status != paymentApproved && now.Sub(p.ExpiresAt) > a.ttl:
+			delete(a.byID, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,242 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pb "github.com/signalfx/microservices-demo/src/frontend/genproto"
+	"github.com/signalfx/microservices-demo/src/frontend/ledger"
+	"github.com/signalfx/microservices-demo/src/frontend/money"
+	"github.com/signalfx/microservices-demo/src/frontend/slack"
+	"github.com/signalfx/microservices-demo/src/frontend/slack/slacktest"
+)
+
+func testApprovals(now *time.Time) *paymentApprovals {
+	return &paymentApprovals{
+		threshold: money.Must(money.Parse("1000", supplierPaymentCurrency)),
+		ttl:       time.Hour,
+		now:       func() time.Time { return *now },
+		byID:      make(map[string]*paymentRequest),
+	}
+}
+
+func TestPaymentApprovals(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	a := testApprovals(&now)
+
+	usd := func(s string) pb.Money { return money.Must(money.Parse(s, supplierPaymentCurrency)) }
+	if a.needsApproval(usd("1000")) || !a.needsApproval(usd("1000.000000001")) {
+		t.Fatal("threshold should be exclusive and exact")
+	}
+	if !a.needsApproval(money.Must(money.Parse("1", "EUR"))) {
+		t.Fatal("an amount in another currency should need approval")
+	}
+
+	p, created, _ := a.request("p1", "7", "5000", "alice")
+	if !created || p.Status != paymentPending {
+		t.Fatalf("request: %+v created=%v", p, created)
+	}
+	if dup, created, _ := a.request("p1", "7", "5000", "alice"); created || dup.ID != p.ID {
+		t.Fatal("repeating a pending request should return it")
+	}
+
+	if _, _, err := a.decide(p.ID, "alice", true); err != errSelfApproval {
+		t.Fatalf("self approval: err=%v", err)
+	}
+	if got, changed, err := a.decide(p.ID, "bob", true); err != nil || !changed || got.Status != paymentApproved {
+		t.Fatalf("approve: %+v changed=%v err=%v", got, changed, err)
+	}
+	if got, changed, _ := a.decide(p.ID, "carol", false); changed || got.Status != paymentApproved || got.DecidedBy != "bob" {
+		t.Fatalf("second decision should change nothing: %+v changed=%v", got, changed)
+	}
+	if got, _ := a.finish(p.ID, "ok", nil); got.Status != paymentPaid {
+		t.Fatalf("finish: %+v", got)
+	}
+
+	late, _, _ := a.request("p2", "8", "2000", "alice")
+	now = now.Add(2 * time.Hour)
+	if got, changed, _ := a.decide(late.ID, "bob", true); changed || got.Status != paymentExpired {
+		t.Fatalf("expired request: %+v changed=%v", got, changed)
+	}
+	now = now.Add(2 * time.Hour)
+	if _, _, err := a.decide(late.ID, "bob", true); err != errPaymentNotFound {
+		t.Fatalf("forgotten request: err=%v", err)
+	}
+}
+
+func TestPaymentApprovalsReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "approvals.jsonl")
+	a, err := newPaymentApprovals(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := a.request("p1", "7", "5000", "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := a.request("p2", "8", "2000", "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := a.decide("p2", "bob", false); err != nil {
+		t.Fatal(err)
+	}
+	a.f.WriteString(`{"id":"p3","supp`)
+	a.f.Close()
+
+	a, err = newPaymentApprovals(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.f.Close()
+	if got, changed, err := a.decide("p1", "bob", true); err != nil || !changed || got.Status != paymentApproved {
+		t.Fatalf("pending request after reopening: %+v changed=%v err=%v", got, changed, err)
+	}
+	if got, changed, _ := a.decide("p2", "bob", true); changed || got.Status != paymentRejected {
+		t.Fatalf("decided request after reopening: %+v changed=%v", got, changed)
+	}
+	if _, _, err := a.decide("p3", "bob", true); err != errPaymentNotFound {
+		t.Fatalf("torn request: err=%v", err)
+	}
+
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSuffix(string(raw), "\n"), "\n"); len(lines) != 5 || !strings.HasPrefix(lines[4], `{"id":"p1"`) {
+		t.Fatalf("file after reopening:\n%s", raw)
+	}
+}
+
+func clickButton(t *testing.T, h http.Handler, s *slacktest.Server, user, actionID, value string) slack.Message {
+	t.Helper()
+	payload, _ := json.Marshal(map[string]interface{}{
+		"type":         "block_actions",
+		"user":         map[string]string{"id": user},
+		"response_url": s.ResponseURL(),
+		"actions":      []map[string]string{{"action_id": actionID, "value": value}},
+	})
+	form := url.Values{"payload": {string(payload)}}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, behaviorRequest(http.MethodPost, form.Encode(), map[string]string{"Content-Type": "application/x-www-form-urlencoded"}))
+	if w.Code != http.StatusOK {
+		t.Fatalf("interaction status=%d", w.Code)
+	}
+	select {
+	case m := <-s.Responses:
+		return m
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message posted to response_url")
+	}
+	return slack.Message{}
+}
+
+func TestSupplierPaymentApproval(t *testing.T) {
+	s := slacktest.NewServer()
+	defer s.Close()
+	s.AddGroup("G1", defaultPayablesGroup, "U-alice", "U-bob")
+
+	now := time.Now()
+	fe := testFrontendServer(t)
+	fe.suppliers = fakeSupplierService(t)
+	fe.approvals = testApprovals(&now)
+	var payments int32
+	fe.paySupplier = func(_ context.Context, supplierID, amount string) (string, error) {
+		atomic.AddInt32(&payments, 1)
+		return "paid " + supplierID + " " + amount, nil
+	}
+	rt := fe.newSlackRouter(slack.NewDirectory(s.Client()))
+	interactions := rt.Interactions()
+
+	if body := slackCommand(rt, "U-alice", "supplier pay 7:50").Body.String(); !strings.Contains(body, "invoice reference is required") {
+		t.Fatalf("pay_someone form: %s", body)
+	}
+	if body := slackCommand(rt, "U-alice", "supplier pay 7 50 INV-1").Body.String(); !strings.Contains(body, "paid") {
+		t.Fatalf("small payment: %s", body)
+	}
+	if n := atomic.LoadInt32(&payments); n != 1 {
+		t.Fatalf("small payment made %d payments", n)
+	}
+
+	var reply slack.Message
+	json.NewDecoder(slackCommand(rt, "U-alice", "supplier pay 7:5000:INV-2").Body).Decode(&reply)
+	var requestID string
+	for _, b := range reply.Blocks {
+		for _, e := range b.Elements {
+			if e.ActionID == approvePaymentAction {
+				requestID = e.Value
+			}
+		}
+	}
+	if requestID == "" || atomic.LoadInt32(&payments) != 1 {
+		t.Fatalf("large payment should wait for approval: %+v", reply)
+	}
+
+	tests := []struct {
+		name, user, action string
+		want               string
+		replace            bool
+		payments           int32
+	}{
+		{"outsider", "U-mallory", approvePaymentAction, "only members of #accounts-payable", false, 1},
+		{"requester cannot approve", "U-alice", approvePaymentAction, "different approver", false, 1},
+		{"second approver", "U-bob", approvePaymentAction, "Approved by <@U-bob> and paid", true, 2},
+		{"repeated click", "U-bob", approvePaymentAction, "Approved by <@U-bob> and paid", true, 2},
+		{"late reject", "U-alice", rejectPaymentAction, "Approved by <@U-bob> and paid", true, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := clickButton(t, interactions, s, tt.user, tt.action, requestID)
+			var b strings.Builder
+			enc := json.NewEncoder(&b)
+			enc.SetEscapeHTML(false)
+			enc.Encode(m)
+			if raw := b.String(); !strings.Contains(raw, tt.want) || m.ReplaceOriginal != tt.replace {
+				t.Fatalf("reply %s, want %q with replace_original=%v", raw, tt.want, tt.replace)
+			}
+			if n := atomic.LoadInt32(&payments); n != tt.payments {
+				t.Fatalf("%d payments made, want %d", n, tt.payments)
+			}
+		})
+	}
+
+	// A request nobody decides on expires without a click and releases the
+	// invoice.
+	fe.approvals.onExpire = fe.releaseExpiredPayment
+	json.NewDecoder(slackCommand(rt, "U-alice", "supplier pay 8 5000 INV-3").Body).Decode(&reply)
+	lp := fe.payments.Supplier("8")[0]
+	if lp.InvoiceRef != "INV-3" || lp.Status != ledger.Requested {
+		t.Fatalf("large payment: %+v", lp)
+	}
+	now = now.Add(2 * time.Hour)
+	if err := fe.approvals.expire(); err != nil {
+		t.Fatal(err)
+	}
+	if lp, _ = fe.payments.Get(lp.ID); lp.Status != ledger.Failed || lp.Result != "approval expired" {
+		t.Fatalf("expired payment: %+v", lp)
+	}
+	if _, created, err := fe.payments.Request(ledger.Payment{SupplierID: "8", Amount: lp.Amount, InvoiceRef: "INV-3", IdempotencyKey: "retry"}, ledger.Limits{}); err != nil || !created {
+		t.Fatalf("paying the invoice again: created=%v err=%v", created, err)
+	}
+}
@@ -0,0 +1,424 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package audit implements an append-only, hash-chained log of
+// administrative actions stored as JSON lines.
+//
+// Every record carries the hash of its predecessor, so removing, reordering
+// or editing a line breaks the chain and is reported by Verify.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GenesisHash is the PrevHash of the first record in a log.
+var GenesisHash = strings.Repeat("0", sha256.Size*2)
+
+// maxLineBytes bounds the size of a single record when reading a log.
+const maxLineBytes = 1 << 20
+
+// Record is a single audited action.
+type Record struct {
+	Seq       uint64          `json:"seq"`
+	Time      time.Time       `json:"time"`
+	Actor     string          `json:"actor"`
+	SourceIP  string          `json:"sourceIp,omitempty"`
+	Action    string          `json:"action"`
+	Target    string          `json:"target,omitempty"`
+	RequestID string          `json:"requestId,omitempty"`
+	Before    json.RawMessage `json:"before,omitempty"`
+	After     json.RawMessage `json:"after,omitempty"`
+	PrevHash  string          `json:"prevHash"`
+	Hash      string          `json:"hash"`
+}
+
+// computeHash returns the hash of r, which covers every field but Hash.
+func (r Record) computeHash() (string, error) {
+	r.Hash = ""
+	raw, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Log is an audit log backed by a JSON-lines file. It is safe for
+// concurrent use.
+type Log struct {
+	mu       sync.Mutex
+	path     string
+	f        *os.File
+	lastSeq  uint64
+	lastHash string
+	now      func() time.Time
+}
+
+// Head is the sequence number and hash of the last record appended to a
+// log. It is kept in a file next to the log so that records removed from
+// the end of the log, which leave an intact chain behind, are detected.
+type Head struct {
+	Seq  uint64 `json:"seq"`
+	Hash string `json:"hash"`
+}
+
+// headPath returns the path of the head file for the log at path.
+func headPath(path string) string { return path + ".head" }
+
+// ReadHead returns the head recorded for the log at path. ok is false if
+// none has been recorded yet.
+func ReadHead(path string) (h Head, ok bool, err error) {
+	raw, err := ioutil.ReadFile(headPath(path))
+	if os.IsNotExist(err) {
+		return Head{}, false, nil
+	}
+	if err != nil {
+		return Head{}, false, err
+	}
+	if err := json.Unmarshal(raw, &h); err != nil {
+		return Head{}, false, fmt.Errorf("invalid head %s: %v", headPath(path), err)
+	}
+	return h, true, nil
+}
+
+// writeHead durably replaces the head file of the log at path.
+func writeHead(path string, h Head) error {
+	raw, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+	tmp := headPath(path) + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(raw); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, headPath(path))
+}
+
+// checkHead reports whether the records seen in a log, given as the hash
+// of each sequence number and the last sequence number, still include h.
+// The head may lag the log by the record whose append was interrupted
+// before the head was written, but never lead it.
+func checkHead(h Head, hashes map[uint64]string, lastSeq uint64) error {
+	if h.Seq > lastSeq {
+		return fmt.Errorf("the log ends at seq %d but seq %d was appended, records were removed from its end", lastSeq, h.Seq)
+	}
+	if h.Seq > 0 && hashes[h.Seq] != h.Hash {
+		return fmt.Errorf("seq %d does not have the hash recorded in the head, the end of the log was rewritten", h.Seq)
+	}
+	return nil
+}
+
+// Open opens (creating if necessary) the log at path and positions it after
+// the last record. A torn last line, left by a write that never completed,
+// is truncated. Other unreadable lines do not stop the log from opening;
+// use Verify to find those.
+//
+// Open refuses a log that no longer reaches its recorded head, since
+// appending to it would chain new records onto the shortened log and hide
+// the removal.
+func Open(path string) (*Log, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	l := &Log{path: path, f: f, lastHash: GenesisHash, now: time.Now}
+	if err := l.replay(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("audit: reading %s: %v", path, err)
+	}
+	return l, nil
+}
+
+// replay positions l after the last record in its file, truncating a torn
+// last line and terminating a last line that is whole but lacks its
+// newline, and checks the result against the recorded head.
+func (l *Log) replay() error {
+	head, hasHead, err := ReadHead(l.path)
+	if err != nil {
+		return err
+	}
+	hashes := make(map[uint64]string)
+	r := bufio.NewReader(l.f)
+	var off int64
+	for {
+		raw, err := r.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if len(raw) == 0 {
+			break
+		}
+		var rec Record
+		if uerr := json.Unmarshal(raw, &rec); uerr != nil && len(bytes.TrimSpace(raw)) > 0 {
+			if err == io.EOF {
+				if err := l.f.Truncate(off); err != nil {
+					return err
+				}
+				break
+			}
+		} else if uerr == nil {
+			l.lastSeq, l.lastHash = rec.Seq, rec.Hash
+			hashes[rec.Seq] = rec.Hash
+		}
+		off += int64(len(raw))
+		if err == io.EOF {
+			if _, err := l.f.Write([]byte{'\n'}); err != nil {
+				return err
+			}
+			break
+		}
+	}
+	if hasHead {
+		if err := checkHead(head, hashes, l.lastSeq); err != nil {
+			return err
+		}
+	}
+	return writeHead(l.path, Head{Seq: l.lastSeq, Hash: l.lastHash})
+}
+
+// Close closes the underlying file.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.f.Close()
+}
+
+// Append assigns rec the next sequence number, timestamp and chain hashes,
+// durably writes it, records it as the log's head and returns the stored
+// record. If the write fails, the log is truncated back to its previous
+// end so that the next record does not follow a torn line.
+func (l *Log) Append(rec Record) (Record, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	rec.Seq = l.lastSeq + 1
+	rec.Time = l.now().UTC()
+	rec.PrevHash = l.lastHash
+	hash, err := rec.computeHash()
+	if err != nil {
+		return Record{}, err
+	}
+	rec.Hash = hash
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return Record{}, err
+	}
+	fi, err := l.f.Stat()
+	if err != nil {
+		return Record{}, err
+	}
+	if _, err := l.f.Write(append(line, '\n')); err != nil {
+		l.f.Truncate(fi.Size())
+		return Record{}, err
+	}
+	if err := l.f.Sync(); err != nil {
+		l.f.Truncate(fi.Size())
+		return Record{}, err
+	}
+	l.lastSeq, l.lastHash = rec.Seq, rec.Hash
+	if err := writeHead(l.path, Head{Seq: rec.Seq, Hash: rec.Hash}); err != nil {
+		// The record is durable; the head only lags it until the next
+		// Append or Open.
+		return rec, fmt.Errorf("audit: recording head: %v", err)
+	}
+	return rec, nil
+}
+
+// Query selects records from a log.
+type Query struct {
+	// Before, if non-zero, only returns records with a smaller sequence
+	// number. Pass a page's NextCursor to fetch the following page.
+	Before uint64
+	// Limit caps the number of records returned.
+	Limit int
+	// Actor and Action, if set, must match exactly.
+	Actor  string
+	Action string
+}
+
+// Page is a page of query results, newest first.
+type Page struct {
+	Records    []Record `json:"records"`
+	NextCursor uint64   `json:"nextCursor,omitempty"`
+}
+
+// Query returns the newest records matching q. Like Open, it skips lines
+// that are not records, such as a torn write.
+func (l *Log) Query(q Query) (Page, error) {
+	// Hold the lock while reading so that a concurrent Append is never seen
+	// half written.
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f, err := os.Open(l.path)
+	if err != nil {
+		return Page{}, err
+	}
+	defer f.Close()
+
+	var matches []Record
+	err = scan(f, func(r Record, _ int) error {
+		if (q.Before == 0 || r.Seq < q.Before) &&
+			(q.Actor == "" || r.Actor == q.Actor) &&
+			(q.Action == "" || r.Action == q.Action) {
+			matches = append(matches, r)
+		}
+		return nil
+	})
+	if err != nil {
+		return Page{}, err
+	}
+
+	page := Page{Records: []Record{}}
+	for i := len(matches) - 1; i >= 0 && len(page.Records) < q.Limit; i-- {
+		page.Records = append(page.Records, matches[i])
+	}
+	if len(page.Records) > 0 && len(page.Records) < len(matches) {
+		page.NextCursor = page.Records[len(page.Records)-1].Seq
+	}
+	return page, nil
+}
+
+// Problem describes a single integrity violation found by Verify.
+type Problem struct {
+	Line    int
+	Seq     uint64
+	Message string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("line %d (seq %d): %s", p.Line, p.Seq, p.Message)
+}
+
+// Verify reads a log from r and reports gaps in the sequence, records whose
+// content no longer matches their hash, unparsable lines and breaks in the
+// hash chain. It returns the number of lines holding a record.
+//
+// An unparsable line is reported on its own: the record after it is checked
+// against the last record that could be read, so a torn write that was
+// followed by further appends is a single problem. Verify cannot tell that
+// records were removed from the end of the log; VerifyFile can.
+func Verify(r io.Reader) (int, []Problem, error) {
+	return verify(r, func(Record) {})
+}
+
+// verify implements Verify, calling fn for every record it reads.
+func verify(r io.Reader, fn func(Record)) (int, []Problem, error) {
+	var (
+		problems []Problem
+		n        int
+		wantSeq  uint64 = 1
+		prevHash        = GenesisHash
+	)
+	err := scanLines(r, func(raw []byte, line int) error {
+		n++
+		var rec Record
+		if err := json.Unmarshal(raw, &rec); err != nil {
+			problems = append(problems, Problem{line, 0, "not a valid record: " + err.Error()})
+			return nil
+		}
+		if rec.Seq != wantSeq {
+			problems = append(problems, Problem{line, rec.Seq, fmt.Sprintf("expected seq %d, records are missing or reordered", wantSeq)})
+		}
+		if rec.PrevHash != prevHash {
+			problems = append(problems, Problem{line, rec.Seq, "prevHash does not match the preceding record"})
+		}
+		if h, err := rec.computeHash(); err != nil || h != rec.Hash {
+			problems = append(problems, Problem{line, rec.Seq, "record content does not match its hash"})
+		}
+		fn(rec)
+		wantSeq, prevHash = rec.Seq+1, rec.Hash
+		return nil
+	})
+	return n, problems, err
+}
+
+// VerifyFile verifies the log at path like Verify and also checks that it
+// still reaches the head recorded by the last Append, which detects records
+// removed from its end.
+func VerifyFile(path string) (int, []Problem, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer f.Close()
+	head, hasHead, err := ReadHead(path)
+	if err != nil {
+		return 0, nil, err
+	}
+	hashes := make(map[uint64]string)
+	var lastSeq uint64
+	n, problems, err := verify(f, func(rec Record) {
+		hashes[rec.Seq], lastSeq = rec.Hash, rec.Seq
+	})
+	if err != nil || !hasHead {
+		return n, problems, err
+	}
+	if err := checkHead(head, hashes, lastSeq); err != nil {
+		problems = append(problems, Problem{n, lastSeq, err.Error()})
+	}
+	return n, problems, nil
+}
+
+// scan calls fn for every record in r along with its 1-based line number,
+// skipping lines that do not hold one. Verify reports those.
+func scan(r io.Reader, fn func(Record, int) error) error {
+	return scanLines(r, func(raw []byte, line int) error {
+		var rec Record
+		if json.Unmarshal(raw, &rec) != nil {
+			return nil
+		}
+		return fn(rec, line)
+	})
+}
+
+// scanLines calls fn for every non-blank line in r.
+func scanLines(r io.Reader, fn func([]byte, int) error) error {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64<<10), maxLineBytes)
+	line := 0
+	for s.Scan() {
+		line++
+		if len(bytes.TrimSpace(s.Bytes())) == 0 {
+			continue
+		}
+		if err := fn(s.Bytes(), line); err != nil {
+			return err
+		}
+	}
+	return s.Err()
+}
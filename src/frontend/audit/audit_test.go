@@ -0,0 +1,200 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeLog(t *testing.T, n int) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	for i := 0; i < n; i++ {
+		_, err := l.Append(Record{
+			Actor:  "ops",
+			Action: "behavior.patch",
+			Before: json.RawMessage(`{"rate": 0}`),
+			After:  json.RawMessage(`{"rate": 0.5}`),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path
+}
+
+func verifyLines(t *testing.T, lines []string) []Problem {
+	t.Helper()
+	_, problems, err := Verify(strings.NewReader(strings.Join(lines, "\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return problems
+}
+
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSpace(string(raw)), "\n")
+}
+
+func TestVerify(t *testing.T) {
+	lines := readLines(t, writeLog(t, 5))
+
+	if p := verifyLines(t, lines); len(p) != 0 {
+		t.Fatalf("intact log reported problems: %v", p)
+	}
+
+	gap := append(append([]string{}, lines[:2]...), lines[3:]...)
+	if p := verifyLines(t, gap); len(p) == 0 {
+		t.Fatal("removed record was not detected")
+	}
+
+	edited := append([]string{}, lines...)
+	edited[1] = strings.Replace(edited[1], `"actor":"ops"`, `"actor":"someone-else"`, 1)
+	p := verifyLines(t, edited)
+	if len(p) != 1 || p[0].Line != 2 {
+		t.Fatalf("edited record: got %v, want one problem on line 2", p)
+	}
+
+	truncated := append([]string{}, lines...)
+	truncated[4] = truncated[4][:10]
+	if p := verifyLines(t, truncated); len(p) != 1 {
+		t.Fatalf("torn record: got %v, want one problem", p)
+	}
+}
+
+func TestReopenContinuesChain(t *testing.T) {
+	path := writeLog(t, 2)
+
+	// Simulate a torn write before reopening.
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"seq":3,"ti`)
+	f.Close()
+
+	l, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec, err := l.Append(Record{Actor: "fin", Action: "supplier.payment"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec.Seq != 3 {
+		t.Fatalf("seq = %d, want 3", rec.Seq)
+	}
+	// Queries skip the torn line too.
+	page, err := l.Query(Query{Limit: 10})
+	l.Close()
+	if err != nil || len(page.Records) != 3 || page.Records[0].Seq != 3 {
+		t.Fatalf("Query = %+v, %v, want seqs 3, 2 and 1", page.Records, err)
+	}
+
+	// The torn line was truncated, so the chain continues across it.
+	if lines := readLines(t, path); len(lines) != 3 {
+		t.Fatalf("got %d lines, want 3", len(lines))
+	}
+	if _, p, err := VerifyFile(path); err != nil || len(p) != 0 {
+		t.Fatalf("chain across reopen reported %v, %v", p, err)
+	}
+}
+
+func TestVerifyTornLineInMiddle(t *testing.T) {
+	lines := readLines(t, writeLog(t, 4))
+	// A torn write followed by further appends, as left by a log that was
+	// never reopened to truncate it.
+	torn := append(append(append([]string{}, lines[:2]...), `{"seq":3,"ti`), lines[2:]...)
+	p := verifyLines(t, torn)
+	if len(p) != 1 || p[0].Line != 3 {
+		t.Fatalf("torn line: got %v, want one problem on line 3", p)
+	}
+}
+
+func TestTailTruncation(t *testing.T) {
+	path := writeLog(t, 4)
+	lines := readLines(t, path)
+	if err := ioutil.WriteFile(path, []byte(strings.Join(lines[:2], "\n")+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// The remaining chain is intact, so only the head gives it away.
+	if p := verifyLines(t, lines[:2]); len(p) != 0 {
+		t.Fatalf("Verify = %v, want no problems", p)
+	}
+	if _, p, err := VerifyFile(path); err != nil || len(p) != 1 {
+		t.Fatalf("VerifyFile = %v, %v, want one problem", p, err)
+	}
+	if l, err := Open(path); err == nil {
+		l.Close()
+		t.Fatal("Open accepted a log missing its last records")
+	}
+
+	// Replacing the last record is caught the same way.
+	other := readLines(t, writeLog(t, 4))
+	if err := ioutil.WriteFile(path, []byte(strings.Join(append(lines[:3], other[3]), "\n")+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if l, err := Open(path); err == nil {
+		l.Close()
+		t.Fatal("Open accepted a log whose last record was replaced")
+	}
+}
+
+func TestQueryPagination(t *testing.T) {
+	path := writeLog(t, 5)
+	l, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	l.Append(Record{Actor: "fin", Action: "supplier.payment"})
+
+	var seqs []uint64
+	q := Query{Limit: 2, Action: "behavior.patch"}
+	for {
+		page, err := l.Query(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range page.Records {
+			seqs = append(seqs, r.Seq)
+		}
+		if page.NextCursor == 0 {
+			break
+		}
+		q.Before = page.NextCursor
+	}
+	got, _ := json.Marshal(seqs)
+	if !bytes.Equal(got, []byte("[5,4,3,2,1]")) {
+		t.Fatalf("paged seqs = %s, want [5,4,3,2,1]", got)
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/signalfx/microservices-demo/src/frontend/audit"
+)
+
+const (
+	defaultAuditLogFile = "audit.jsonl"
+	defaultAuditLimit   = 50
+	maxAuditLimit       = 500
+)
+
+func mustOpenAuditLog(path string) *audit.Log {
+	if path == "" {
+		path = defaultAuditLogFile
+	}
+	l, err := audit.Open(path)
+	if err != nil {
+		panic(errors.Wrapf(err, "audit: failed to open %s", path))
+	}
+	return l
+}
+
+// recordAudit appends an administrative action to the audit log. before
+// and after are serialized as JSON. Failures are logged rather than returned
+// because the action has already taken effect.
+func (fe *frontendServer) recordAudit(r *http.Request, actor, action, target string, before, after interface{}) {
+	log := getLoggerWithTraceFields(r.Context())
+	rec := audit.Record{
+		Actor:    actor,
+		SourceIP: sourceIP(r, fe.trustedProxies),
+		Action:   action,
+		Target:   target,
+	}
+	if id, ok := r.Context().Value(ctxKeyRequestID{}).(string); ok {
+		rec.RequestID = id
+	}
+	var err error
+	if rec.Before, err = auditJSON(before); err == nil {
+		rec.After, err = auditJSON(after)
+	}
+	if err == nil {
+		rec, err = fe.auditLog.Append(rec)
+	}
+	if err != nil {
+		log.WithField("error", err).WithField("audit.action", action).Error("failed to write audit record")
+		return
+	}
+	log.WithField("audit.seq", rec.Seq).WithField("audit.action", action).Debug("audit record written")
+}
+
+func auditJSON(v interface{}) (json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	return json.Marshal(v)
+}
+
+// requestActor names the authenticated caller of r for audit records.
+func requestActor(r *http.Request) string {
+	if p := currentPrincipal(r); p != nil {
+		return p.ID
+	}
+	return "anonymous"
+}
+
+// parseTrustedProxies parses TRUSTED_PROXIES, a comma-separated list of
+// the addresses or CIDR networks of the proxies in front of the frontend.
+func parseTrustedProxies(s string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if !strings.Contains(field, "/") {
+			ip := net.ParseIP(field)
+			if ip == nil {
+				return nil, errors.Errorf("trusted proxy %q is not an address or a network", field)
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(field)
+		if err != nil {
+			return nil, errors.Errorf("trusted proxy %q is not an address or a network", field)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+func isTrustedProxy(addr string, trusted []*net.IPNet) bool {
+	ip := net.ParseIP(addr)
+	for _, n := range trusted {
+		if ip != nil && n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// sourceIP returns the client address of r. X-Forwarded-For is only
+// believed from trusted proxies, since anyone can send one: the client is
+// the last hop it lists that is not a trusted proxy itself.
+func sourceIP(r *http.Request, trusted []*net.IPNet) string {
+	addr := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	if !isTrustedProxy(addr, trusted) {
+		return addr
+	}
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			continue
+		}
+		addr = hop
+		if !isTrustedProxy(hop, trusted) {
+			break
+		}
+	}
+	return addr
+}
+
+// auditQueryHandler serves a page of audit records, newest first. Query
+// parameters: limit, cursor (a previous page's nextCursor), actor, action.
+func (fe *frontendServer) auditQueryHandler(w http.ResponseWriter, r *http.Request) {
+	log := getLoggerWithTraceFields(r.Context())
+	q := audit.Query{
+		Limit:  defaultAuditLimit,
+		Actor:  r.FormValue("actor"),
+		Action: r.FormValue("action"),
+	}
+	var fields []fieldError
+	if v := r.FormValue("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxAuditLimit {
+			fields = append(fields, fieldError{Field: "limit", Message: "must be an integer between 1 and " + strconv.Itoa(maxAuditLimit)})
+		}
+		q.Limit = n
+	}
+	if v := r.FormValue("cursor"); v != "" {
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil || n == 0 {
+			fields = append(fields, fieldError{Field: "cursor", Message: "must be a positive integer"})
+		}
+		q.Before = n
+	}
+	if len(fields) > 0 {
+		renderJSONError(log, w, errors.New("invalid query"), http.StatusBadRequest, fields...)
+		return
+	}
+
+	page, err := fe.auditLog.Query(q)
+	if err != nil {
+		renderJSONError(log, w, errors.Wrap(err, "could not read audit log"), http.StatusInternalServerError)
+		return
+	}
+	renderJSON(w, http.StatusOK, page)
+}
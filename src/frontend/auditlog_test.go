@@ -0,0 +1,54 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSourceIP(t *testing.T) {
+	trusted, err := parseTrustedProxies("10.0.0.0/8, 192.0.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		name, remote string
+		xff          []string
+		want         string
+	}{
+		{"direct", "203.0.113.7:4711", nil, "203.0.113.7"},
+		{"forged by a client", "203.0.113.7:4711", []string{"198.51.100.1"}, "203.0.113.7"},
+		{"via a proxy", "10.1.2.3:80", []string{"198.51.100.1"}, "198.51.100.1"},
+		{"client prepends a hop", "10.1.2.3:80", []string{"6.6.6.6, 198.51.100.1"}, "198.51.100.1"},
+		{"chain of proxies", "192.0.2.1:80", []string{"198.51.100.1, 10.9.9.9", "10.1.1.1"}, "198.51.100.1"},
+		{"proxy without a header", "10.1.2.3:80", nil, "10.1.2.3"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remote
+			for _, v := range tt.xff {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+			if got := sourceIP(r, trusted); got != tt.want {
+				t.Errorf("sourceIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := parseTrustedProxies("10.0.0.0/8,proxy"); err == nil {
+		t.Error("parsed a host name")
+	}
+}
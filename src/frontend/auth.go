@@ -0,0 +1,327 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultAuthKeysFile = "auth-keys.json"
+
+	// hmacScheme is the Authorization scheme for signed requests:
+	//
+	//   Authorization: FROTHLY-HMAC-SHA256 keyId=<id>, signature=<hex>
+	//   X-Frothly-Timestamp: <unix seconds>
+	//
+	// where signature is the hex HMAC-SHA256, keyed with the principal's
+	// secret, of "METHOD\nREQUEST-URI\nTIMESTAMP\nhex(sha256(body))".
+	hmacScheme          = "FROTHLY-HMAC-SHA256"
+	hmacTimestampHeader = "X-Frothly-Timestamp"
+	hmacMaxSkew         = 5 * time.Minute
+	maxSignedBodyBytes  = 1 << 20
+)
+
+type role string
+
+const (
+	roleViewer   role = "viewer"
+	roleOperator role = "operator"
+	roleFinance  role = "finance"
+)
+
+// roleGrants lists, for each role, the roles it implies.
+var roleGrants = map[role][]role{
+	roleViewer:   {roleViewer},
+	roleOperator: {roleOperator, roleViewer},
+	roleFinance:  {roleFinance, roleViewer},
+}
+
+// principal is an API caller as configured in the keys file. Secret only
+// keys request signatures and never travels with a request; callers that
+// send a credential, as a bearer token or a basic password, send the API
+// key, of which the file holds just the hex SHA-256.
+type principal struct {
+	ID           string `json:"id"`
+	Secret       string `json:"secret"`
+	APIKeySHA256 string `json:"api_key_sha256"`
+	Roles        []role `json:"roles"`
+}
+
+func (p *principal) has(want role) bool {
+	for _, r := range p.Roles {
+		for _, g := range roleGrants[r] {
+			if g == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type ctxKeyPrincipal struct{}
+
+// authenticator authenticates admin API callers with either a bearer API key
+// or an HMAC-signed request and enforces per-route roles.
+type authenticator struct {
+	principals map[string]*principal
+	now        func() time.Time
+
+	mu sync.Mutex
+	// seen holds the signatures accepted within hmacMaxSkew, by their
+	// request timestamp, so that captured requests cannot be replayed.
+	seen map[string]time.Time
+}
+
+// newAuthenticator loads principals from the JSON keys file at path. A
+// missing or invalid file leaves the authenticator without principals, so
+// every protected route is denied rather than left open.
+func newAuthenticator(log logrus.FieldLogger, path string) *authenticator {
+	a := &authenticator{principals: map[string]*principal{}, now: time.Now}
+	if path == "" {
+		path = defaultAuthKeysFile
+	}
+	ps, err := loadPrincipals(path)
+	if err != nil {
+		log.WithField("error", err).Warn("no API principals loaded, protected routes will deny all requests")
+		return a
+	}
+	for _, p := range ps {
+		a.principals[p.ID] = p
+	}
+	log.WithField("auth.principals", len(ps)).Info("loaded API principals")
+	return a
+}
+
+func loadPrincipals(path string) ([]*principal, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read keys file")
+	}
+	var f struct {
+		Principals []*principal `json:"principals"`
+	}
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return nil, errors.Wrapf(err, "could not parse keys file %s", path)
+	}
+	for _, p := range f.Principals {
+		if p.ID == "" || p.Secret == "" && p.APIKeySHA256 == "" {
+			return nil, errors.Errorf("keys file %s: every principal needs an id and a secret or an API key", path)
+		}
+		if p.APIKeySHA256 != "" {
+			if b, err := hex.DecodeString(p.APIKeySHA256); err != nil || len(b) != sha256.Size {
+				return nil, errors.Errorf("keys file %s: principal %q: api_key_sha256 must be a hex SHA-256", path, p.ID)
+			}
+			p.APIKeySHA256 = strings.ToLower(p.APIKeySHA256)
+		}
+		for _, r := range p.Roles {
+			if _, ok := roleGrants[r]; !ok {
+				return nil, errors.Errorf("keys file %s: principal %q has unknown role %q", path, p.ID, r)
+			}
+		}
+	}
+	return f.Principals, nil
+}
+
+// require wraps next so that it only runs for callers holding role want.
+func (a *authenticator) require(want role, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log := getLoggerWithTraceFields(r.Context()).WithField("auth.required_role", want)
+
+		p, scheme, err := a.authenticate(r)
+		if scheme != "" {
+			log = log.WithField("auth.scheme", scheme)
+		}
+		if err != nil {
+			log.WithField("auth.decision", "deny").WithField("auth.reason", err.Error()).Warn("authentication failed")
+			w.Header().Set("WWW-Authenticate", `Bearer realm="frothly", `+hmacScheme+` realm="frothly", Basic realm="frothly"`)
+			renderJSON(w, http.StatusUnauthorized, newJSONError(err, http.StatusUnauthorized))
+			return
+		}
+		log = log.WithField("auth.principal", p.ID)
+		if !p.has(want) {
+			log.WithField("auth.decision", "deny").Warn("principal lacks required role")
+			renderJSON(w, http.StatusForbidden, newJSONError(errors.Errorf("role %q required", want), http.StatusForbidden))
+			return
+		}
+		log.WithField("auth.decision", "allow").Info("request authorized")
+		next(w, r.WithContext(context.WithValue(r.Context(), ctxKeyPrincipal{}, p)))
+	}
+}
+
+// authenticate identifies the caller of r. It returns the scheme it tried
+// alongside any error so that failures can be logged meaningfully.
+func (a *authenticator) authenticate(r *http.Request) (*principal, string, error) {
+	h := r.Header.Get("Authorization")
+	scheme, params := h, ""
+	if i := strings.IndexByte(h, ' '); i >= 0 {
+		scheme, params = h[:i], strings.TrimSpace(h[i+1:])
+	}
+	switch {
+	case h == "":
+		return nil, "", errors.New("missing credentials")
+	case strings.EqualFold(scheme, "Bearer"):
+		p, err := a.byAPIKey(params)
+		return p, "api-key", err
+	case strings.EqualFold(scheme, hmacScheme):
+		p, err := a.bySignature(r, params)
+		return p, "hmac", err
+	case strings.EqualFold(scheme, "Basic"):
+		p, err := a.byPassword(r)
+		return p, "basic", err
+	default:
+		return nil, "", errors.Errorf("unsupported authorization scheme %q", scheme)
+	}
+}
+
+func (a *authenticator) byAPIKey(key string) (*principal, error) {
+	var found *principal
+	for _, p := range a.principals {
+		// Keep looping after a match so timing does not leak which key matched.
+		if p.hasAPIKey(key) {
+			found = p
+		}
+	}
+	if found == nil {
+		return nil, errors.New("invalid API key")
+	}
+	return found, nil
+}
+
+// hasAPIKey reports whether key is p's API key.
+func (p *principal) hasAPIKey(key string) bool {
+	if p.APIKeySHA256 == "" || key == "" {
+		return false
+	}
+	sum := sha256.Sum256([]byte(key))
+	return subtle.ConstantTimeCompare([]byte(hex.EncodeToString(sum[:])), []byte(p.APIKeySHA256)) == 1
+}
+
+// byPassword accepts a principal's ID and API key as basic credentials so
+// that the admin console can be used from a browser.
+func (a *authenticator) byPassword(r *http.Request) (*principal, error) {
+	id, key, ok := r.BasicAuth()
+	if !ok {
+		return nil, errors.New("malformed basic credentials")
+	}
+	p, found := a.principals[id]
+	if !found || !p.hasAPIKey(key) {
+		return nil, errors.New("invalid username or password")
+	}
+	return p, nil
+}
+
+func (a *authenticator) bySignature(r *http.Request, params string) (*principal, error) {
+	var keyID, sig string
+	for _, kv := range strings.Split(params, ",") {
+		kv = strings.TrimSpace(kv)
+		switch {
+		case strings.HasPrefix(kv, "keyId="):
+			keyID = strings.TrimPrefix(kv, "keyId=")
+		case strings.HasPrefix(kv, "signature="):
+			sig = strings.TrimPrefix(kv, "signature=")
+		}
+	}
+	if keyID == "" || sig == "" {
+		return nil, errors.New("signed request needs keyId and signature")
+	}
+	p, ok := a.principals[keyID]
+	if !ok || p.Secret == "" {
+		return nil, errors.New("unknown key id")
+	}
+
+	ts := r.Header.Get(hmacTimestampHeader)
+	secs, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return nil, errors.Errorf("missing or invalid %s header", hmacTimestampHeader)
+	}
+	if skew := a.now().Sub(time.Unix(secs, 0)); skew > hmacMaxSkew || skew < -hmacMaxSkew {
+		return nil, errors.New("request timestamp outside the allowed window")
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(nil, r.Body, maxSignedBodyBytes))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read request body")
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	want := signRequest(p.Secret, r.Method, r.URL.RequestURI(), ts, body)
+	if !hmac.Equal([]byte(want), []byte(strings.ToLower(sig))) {
+		return nil, errors.New("signature mismatch")
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.seen == nil {
+		a.seen = make(map[string]time.Time)
+	}
+	// Anything older than the window is refused as stale anyway.
+	now := a.now()
+	for s, t := range a.seen {
+		if now.Sub(t) > hmacMaxSkew {
+			delete(a.seen, s)
+		}
+	}
+	if _, ok := a.seen[want]; ok {
+		return nil, errors.New("signed request was already accepted")
+	}
+	a.seen[want] = time.Unix(secs, 0)
+	return p, nil
+}
+
+// signRequest computes the hex signature for a signed request.
+func signRequest(secret, method, requestURI, timestamp string, body []byte) string {
+	bodySum := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(method + "\n" + requestURI + "\n" + timestamp + "\n" + hex.EncodeToString(bodySum[:])))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// signHTTPRequest signs req, whose body is body, as p at now. Each signature
+// is accepted once, so it first adds a random nonce query parameter to keep
+// two identical requests in the same second apart.
+func signHTTPRequest(req *http.Request, p *principal, now time.Time, body []byte) {
+	var nonce [8]byte
+	rand.Read(nonce[:])
+	q := req.URL.Query()
+	q.Set("nonce", hex.EncodeToString(nonce[:]))
+	req.URL.RawQuery = q.Encode()
+	ts := strconv.FormatInt(now.Unix(), 10)
+	sig := signRequest(p.Secret, req.Method, req.URL.RequestURI(), ts, body)
+	req.Header.Set("Authorization", hmacScheme+" keyId="+p.ID+", signature="+sig)
+	req.Header.Set(hmacTimestampHeader, ts)
+}
+
+// currentPrincipal returns the authenticated caller of r, if any.
+func currentPrincipal(r *http.Request) *principal {
+	p, _ := r.Context().Value(ctxKeyPrincipal{}).(*principal)
+	return p
+}
@@ -0,0 +1,439 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	behaviorSchemaPath    = "/system-behavior/schema"
+	mergePatchContentType = "application/merge-patch+json"
+	maxBehaviorPatchBytes = 64 << 10
+)
+
+type CheckoutServiceBehavior struct {
+	PaymentFailureRate      float32 `json:"paymentFailureRate"`
+	MaxRetryAttempts        int     `json:"maxRetryAttempts"`
+	RetryInitialSleepMillis int     `json:"retryInitialSleepMillis"`
+}
+
+type SystemBehavior struct {
+	CheckoutService CheckoutServiceBehavior `json:"checkoutService"`
+}
+
+// behaviorField describes a single tunable leaf of SystemBehavior. The table
+// below drives both the published JSON Schema and patch validation, so the
+// two cannot drift apart.
+type behaviorField struct {
+	Path        []string
+	Type        string // "number" or "integer"
+	Minimum     float64
+	Maximum     float64
+	Description string
+}
+
+func (f behaviorField) name() string { return strings.Join(f.Path, ".") }
+
+var behaviorFields = []behaviorField{
+	{
+		Path:        []string{"checkoutService", "paymentFailureRate"},
+		Type:        "number",
+		Minimum:     0,
+		Maximum:     1,
+		Description: "Probability that a single payment charge attempt fails.",
+	},
+	{
+		Path:        []string{"checkoutService", "maxRetryAttempts"},
+		Type:        "integer",
+		Minimum:     0,
+		Maximum:     100,
+		Description: "Number of times checkout retries a failed payment charge.",
+	},
+	{
+		Path:        []string{"checkoutService", "retryInitialSleepMillis"},
+		Type:        "integer",
+		Minimum:     0,
+		Maximum:     60000,
+		Description: "Initial backoff between payment retries, in milliseconds.",
+	},
+}
+
+func defaultSystemBehavior() SystemBehavior {
+	return SystemBehavior{
+		CheckoutService: CheckoutServiceBehavior{
+			PaymentFailureRate:      0.0,
+			MaxRetryAttempts:        15,
+			RetryInitialSleepMillis: 200,
+		},
+	}
+}
+
+// System behavior. Propagated downstream through x-system-behavior request headers as json.
+var behavior = newBehaviorStore()
+
+// behaviorStore guards the process-wide SystemBehavior.
+type behaviorStore struct {
+	mu      sync.RWMutex
+	current SystemBehavior
+}
+
+func newBehaviorStore() *behaviorStore {
+	return &behaviorStore{current: defaultSystemBehavior()}
+}
+
+// get returns a copy of the current behavior and its entity tag.
+func (s *behaviorStore) get() (SystemBehavior, string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current, behaviorETag(s.current)
+}
+
+// replace swaps the current behavior for next if ifMatch (the raw If-Match
+// header, possibly empty) matches the current entity tag. It returns the
+// previous value.
+func (s *behaviorStore) replace(ifMatch string, next func(SystemBehavior) (SystemBehavior, error)) (prev, cur SystemBehavior, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev = s.current
+	if !etagMatches(ifMatch, behaviorETag(prev)) {
+		return prev, prev, errPreconditionFailed
+	}
+	cur, err = next(prev)
+	if err != nil {
+		return prev, prev, err
+	}
+	s.current = cur
+	return prev, cur, nil
+}
+
+func behaviorETag(b SystemBehavior) string {
+	raw, _ := json.Marshal(b)
+	sum := sha256.Sum256(raw)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+var errPreconditionFailed = errors.New("If-Match does not match the current system behavior")
+
+// etagMatches implements the strong comparison of RFC 7232 section 3.1, so
+// weak tags (W/"...") never match. An empty header always matches.
+func etagMatches(header, etag string) bool {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return true
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimSpace(candidate) == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// fieldError reports a problem with a single field of a request document.
+type fieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// behaviorValidationError carries the field-level errors of a patch that
+// produced a document violating the schema.
+type behaviorValidationError struct {
+	fields []fieldError
+}
+
+func (e *behaviorValidationError) Error() string {
+	msgs := make([]string, len(e.fields))
+	for i, f := range e.fields {
+		msgs[i] = f.Field + ": " + f.Message
+	}
+	return "invalid system behavior: " + strings.Join(msgs, "; ")
+}
+
+// applyBehaviorPatch applies an RFC 7396 JSON Merge Patch to cur. A null
+// member resets the corresponding field (or whole section) to its default.
+// The patched document is validated against behaviorFields before it is
+// decoded.
+func applyBehaviorPatch(cur SystemBehavior, patch []byte) (SystemBehavior, error) {
+	var p interface{}
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return cur, errors.Wrap(err, "patch is not valid JSON")
+	}
+	if _, ok := p.(map[string]interface{}); !ok {
+		return cur, errors.New("patch must be a JSON object")
+	}
+
+	doc := mergePatch(behaviorDocument(cur), p)
+	doc = fillDefaults(doc, behaviorDocument(defaultSystemBehavior()))
+	if fields := validateBehaviorDocument(doc); len(fields) > 0 {
+		return cur, &behaviorValidationError{fields: fields}
+	}
+
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return cur, err
+	}
+	var next SystemBehavior
+	if err := json.Unmarshal(raw, &next); err != nil {
+		return cur, err
+	}
+	return next, nil
+}
+
+func behaviorDocument(b SystemBehavior) interface{} {
+	raw, _ := json.Marshal(b)
+	var doc interface{}
+	json.Unmarshal(raw, &doc)
+	return doc
+}
+
+// mergePatch implements the MergePatch algorithm of RFC 7396 section 2.
+func mergePatch(target, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = map[string]interface{}{}
+	}
+	for k, v := range patchObj {
+		if v == nil {
+			delete(targetObj, k)
+			continue
+		}
+		targetObj[k] = mergePatch(targetObj[k], v)
+	}
+	return targetObj
+}
+
+// fillDefaults copies members of defaults that are missing from doc.
+func fillDefaults(doc, defaults interface{}) interface{} {
+	docObj, ok := doc.(map[string]interface{})
+	if !ok {
+		return doc
+	}
+	defObj, _ := defaults.(map[string]interface{})
+	for k, v := range defObj {
+		if _, ok := docObj[k]; !ok {
+			docObj[k] = v
+			continue
+		}
+		docObj[k] = fillDefaults(docObj[k], v)
+	}
+	return docObj
+}
+
+func validateBehaviorDocument(doc interface{}) []fieldError {
+	var errs []fieldError
+	known := map[string]bool{}
+	for _, f := range behaviorFields {
+		for i := 1; i < len(f.Path); i++ {
+			known[strings.Join(f.Path[:i], ".")] = true
+		}
+		known[f.name()] = true
+	}
+	var walk func(prefix string, v interface{})
+	walk = func(prefix string, v interface{}) {
+		obj, ok := v.(map[string]interface{})
+		if !ok {
+			errs = append(errs, fieldError{Field: prefix, Message: "must be an object"})
+			return
+		}
+		keys := make([]string, 0, len(obj))
+		for k := range obj {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			name := k
+			if prefix != "" {
+				name = prefix + "." + k
+			}
+			if !known[name] {
+				errs = append(errs, fieldError{Field: name, Message: "unknown field"})
+				continue
+			}
+			if f, ok := lookupBehaviorField(name); ok {
+				if msg := f.check(obj[k]); msg != "" {
+					errs = append(errs, fieldError{Field: name, Message: msg})
+				}
+				continue
+			}
+			walk(name, obj[k])
+		}
+	}
+	walk("", doc)
+	return errs
+}
+
+func lookupBehaviorField(name string) (behaviorField, bool) {
+	for _, f := range behaviorFields {
+		if f.name() == name {
+			return f, true
+		}
+	}
+	return behaviorField{}, false
+}
+
+// check returns a human readable message if v is not an acceptable value for
+// the field, or "" if it is.
+func (f behaviorField) check(v interface{}) string {
+	n, ok := v.(float64)
+	if !ok {
+		return "must be a " + f.Type
+	}
+	if f.Type == "integer" && n != math.Trunc(n) {
+		return "must be an integer"
+	}
+	if n < f.Minimum || n > f.Maximum {
+		return fmt.Sprintf("must be between %v and %v", f.Minimum, f.Maximum)
+	}
+	return ""
+}
+
+// behaviorSchema renders behaviorFields as a JSON Schema (draft 2020-12)
+// document.
+func behaviorSchema() map[string]interface{} {
+	defaults := behaviorDocument(defaultSystemBehavior())
+	root := map[string]interface{}{
+		"$schema":              "https://json-schema.org/draft/2020-12/schema",
+		"$id":                  behaviorSchemaPath,
+		"title":                "SystemBehavior",
+		"description":          "Fault-injection knobs propagated to downstream services in the x-system-behavior header.",
+		"type":                 "object",
+		"additionalProperties": false,
+		"properties":           map[string]interface{}{},
+	}
+	for _, f := range behaviorFields {
+		node, def := root, defaults
+		for _, p := range f.Path[:len(f.Path)-1] {
+			props := node["properties"].(map[string]interface{})
+			child, ok := props[p].(map[string]interface{})
+			if !ok {
+				child = map[string]interface{}{
+					"type":                 "object",
+					"additionalProperties": false,
+					"properties":           map[string]interface{}{},
+				}
+				props[p] = child
+			}
+			node = child
+			def = def.(map[string]interface{})[p]
+		}
+		leaf := f.Path[len(f.Path)-1]
+		node["properties"].(map[string]interface{})[leaf] = map[string]interface{}{
+			"type":        f.Type,
+			"minimum":     f.Minimum,
+			"maximum":     f.Maximum,
+			"default":     def.(map[string]interface{})[leaf],
+			"description": f.Description,
+		}
+	}
+	return root
+}
+
+func writeBehavior(w http.ResponseWriter, b SystemBehavior, etag string) {
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Link", "<"+behaviorSchemaPath+`>; rel="describedby"`)
+	w.Header().Set("Accept-Patch", mergePatchContentType)
+	renderJSON(w, http.StatusOK, b)
+}
+
+func (fe *frontendServer) getSystemBehaviorHandler(w http.ResponseWriter, r *http.Request) {
+	cur, etag := behavior.get()
+	if inm := r.Header.Get("If-None-Match"); inm != "" && etagMatches(inm, etag) {
+		w.Header().Set("ETag", etag)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	writeBehavior(w, cur, etag)
+}
+
+func (fe *frontendServer) getSystemBehaviorSchemaHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("content-type", "application/schema+json")
+	json.NewEncoder(w).Encode(behaviorSchema())
+}
+
+func (fe *frontendServer) patchSystemBehaviorHandler(w http.ResponseWriter, r *http.Request) {
+	log := getLoggerWithTraceFields(r.Context())
+
+	if ct := r.Header.Get("content-type"); ct != "" {
+		mt, _, err := mime.ParseMediaType(ct)
+		if err != nil || (mt != mergePatchContentType && mt != "application/json") {
+			renderJSONError(log, w, errors.Errorf("unsupported content type %q, use %s", ct, mergePatchContentType), http.StatusUnsupportedMediaType)
+			return
+		}
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxBehaviorPatchBytes))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		renderJSONError(log, w, errors.Errorf("patch is larger than %d bytes", maxBehaviorPatchBytes), http.StatusRequestEntityTooLarge)
+		return
+	} else if err != nil {
+		renderJSONError(log, w, errors.Wrap(err, "could not read patch"), http.StatusBadRequest)
+		return
+	}
+
+	prev, cur, err := behavior.replace(r.Header.Get("If-Match"), func(b SystemBehavior) (SystemBehavior, error) {
+		return applyBehaviorPatch(b, body)
+	})
+	if err != nil {
+		renderBehaviorError(log, w, err)
+		return
+	}
+	log.WithField("behavior.old", prev).WithField("behavior.new", cur).Info("system behavior patched")
+	fe.recordAudit(r, requestActor(r), "behavior.patch", "/system-behavior", prev, cur)
+	writeBehavior(w, cur, behaviorETag(cur))
+}
+
+func (fe *frontendServer) deleteSystemBehaviorHandler(w http.ResponseWriter, r *http.Request) {
+	log := getLoggerWithTraceFields(r.Context())
+	prev, cur, err := behavior.replace(r.Header.Get("If-Match"), func(SystemBehavior) (SystemBehavior, error) {
+		return defaultSystemBehavior(), nil
+	})
+	if err != nil {
+		renderBehaviorError(log, w, err)
+		return
+	}
+	log.WithField("behavior.old", prev).Info("system behavior reset to defaults")
+	fe.recordAudit(r, requestActor(r), "behavior.reset", "/system-behavior", prev, cur)
+	writeBehavior(w, cur, behaviorETag(cur))
+}
+
+func renderBehaviorError(log logrus.FieldLogger, w http.ResponseWriter, err error) {
+	if err == errPreconditionFailed {
+		renderJSONError(log, w, err, http.StatusPreconditionFailed)
+		return
+	}
+	if verr, ok := err.(*behaviorValidationError); ok {
+		renderJSONError(log, w, verr, http.StatusUnprocessableEntity, verr.fields...)
+		return
+	}
+	renderJSONError(log, w, err, http.StatusBadRequest)
+}
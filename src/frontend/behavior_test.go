@@ -0,0 +1,259 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/signalfx/microservices-demo/src/frontend/audit"
+	"github.com/signalfx/microservices-demo/src/frontend/ledger"
+)
+
+func TestApplyBehaviorPatch(t *testing.T) {
+	cur := defaultSystemBehavior()
+	cur.CheckoutService.PaymentFailureRate = 0.5
+	cur.CheckoutService.MaxRetryAttempts = 3
+
+	tests := []struct {
+		name       string
+		patch      string
+		want       SystemBehavior
+		wantFields []string
+		wantErr    bool
+	}{
+		{
+			name:  "empty patch keeps state",
+			patch: `{}`,
+			want:  cur,
+		},
+		{
+			name:  "sets a single field",
+			patch: `{"checkoutService":{"retryInitialSleepMillis":1000}}`,
+			want: SystemBehavior{CheckoutService: CheckoutServiceBehavior{
+				PaymentFailureRate: 0.5, MaxRetryAttempts: 3, RetryInitialSleepMillis: 1000}},
+		},
+		{
+			name:  "null resets a field to default",
+			patch: `{"checkoutService":{"paymentFailureRate":null}}`,
+			want: SystemBehavior{CheckoutService: CheckoutServiceBehavior{
+				PaymentFailureRate: 0, MaxRetryAttempts: 3, RetryInitialSleepMillis: 200}},
+		},
+		{
+			name:  "null resets a section to default",
+			patch: `{"checkoutService":null}`,
+			want:  defaultSystemBehavior(),
+		},
+		{
+			name:       "out of range values",
+			patch:      `{"checkoutService":{"paymentFailureRate":7,"maxRetryAttempts":-1}}`,
+			wantFields: []string{"checkoutService.maxRetryAttempts", "checkoutService.paymentFailureRate"},
+		},
+		{
+			name:       "wrong types and unknown fields",
+			patch:      `{"checkoutService":{"maxRetryAttempts":1.5,"retryInitialSleepMillis":"10"},"bogus":1}`,
+			wantFields: []string{"bogus", "checkoutService.maxRetryAttempts", "checkoutService.retryInitialSleepMillis"},
+		},
+		{
+			name:       "section replaced by scalar",
+			patch:      `{"checkoutService":3}`,
+			wantFields: []string{"checkoutService"},
+		},
+		{
+			name:    "not an object",
+			patch:   `[1,2]`,
+			wantErr: true,
+		},
+		{
+			name:    "malformed",
+			patch:   `{"checkoutService":`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyBehaviorPatch(cur, []byte(tt.patch))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if tt.wantFields != nil {
+				verr, ok := err.(*behaviorValidationError)
+				if !ok {
+					t.Fatalf("got err=%v, want validation error", err)
+				}
+				var fields []string
+				for _, f := range verr.fields {
+					fields = append(fields, f.Field)
+				}
+				if !reflect.DeepEqual(fields, tt.wantFields) {
+					t.Fatalf("invalid fields = %v, want %v", fields, tt.wantFields)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func behaviorRequest(method, body string, header map[string]string) *http.Request {
+	r := httptest.NewRequest(method, "/system-behavior", strings.NewReader(body))
+	for k, v := range header {
+		r.Header.Set(k, v)
+	}
+	log := logrus.New()
+	log.Out = ioutil.Discard
+	return r.WithContext(context.WithValue(r.Context(), ctxKeyLog{}, logrus.FieldLogger(log)))
+}
+
+func TestSystemBehaviorHandlers(t *testing.T) {
+	behavior = newBehaviorStore()
+	defer func() { behavior = newBehaviorStore() }()
+	fe := testFrontendServer(t)
+
+	w := httptest.NewRecorder()
+	fe.getSystemBehaviorHandler(w, behaviorRequest(http.MethodGet, "", nil))
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || etag == "" {
+		t.Fatalf("GET: status=%d etag=%q", w.Code, etag)
+	}
+
+	w = httptest.NewRecorder()
+	fe.patchSystemBehaviorHandler(w, behaviorRequest(http.MethodPatch, `{"checkoutService":{"paymentFailureRate":7}}`,
+		map[string]string{"Content-Type": mergePatchContentType}))
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("invalid PATCH: status=%d, want %d", w.Code, http.StatusUnprocessableEntity)
+	}
+	var body struct {
+		Fields []fieldError `json:"fields"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil || len(body.Fields) != 1 {
+		t.Fatalf("invalid PATCH: fields=%+v err=%v", body.Fields, err)
+	}
+
+	w = httptest.NewRecorder()
+	fe.patchSystemBehaviorHandler(w, behaviorRequest(http.MethodPatch, `nope`, nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("malformed PATCH: status=%d, want %d", w.Code, http.StatusBadRequest)
+	}
+
+	w = httptest.NewRecorder()
+	fe.patchSystemBehaviorHandler(w, behaviorRequest(http.MethodPatch, `{}`, map[string]string{"Content-Type": "text/plain"}))
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("text/plain PATCH: status=%d, want %d", w.Code, http.StatusUnsupportedMediaType)
+	}
+
+	w = httptest.NewRecorder()
+	fe.patchSystemBehaviorHandler(w, behaviorRequest(http.MethodPatch, `{"comment":"`+strings.Repeat("a", maxBehaviorPatchBytes)+`"}`, nil))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("oversized PATCH: status=%d, want %d", w.Code, http.StatusRequestEntityTooLarge)
+	}
+
+	// If-Match uses the strong comparison: a weak tag never matches.
+	w = httptest.NewRecorder()
+	fe.patchSystemBehaviorHandler(w, behaviorRequest(http.MethodPatch, `{"checkoutService":{"paymentFailureRate":0.25}}`,
+		map[string]string{"If-Match": "W/" + etag}))
+	if w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("weak PATCH: status=%d, want %d", w.Code, http.StatusPreconditionFailed)
+	}
+
+	w = httptest.NewRecorder()
+	fe.patchSystemBehaviorHandler(w, behaviorRequest(http.MethodPatch, `{"checkoutService":{"paymentFailureRate":0.25}}`,
+		map[string]string{"If-Match": etag}))
+	if w.Code != http.StatusOK {
+		t.Fatalf("PATCH: status=%d body=%s", w.Code, w.Body)
+	}
+	if got, _ := behavior.get(); got.CheckoutService.PaymentFailureRate != 0.25 {
+		t.Fatalf("PATCH did not apply: %+v", got)
+	}
+
+	w = httptest.NewRecorder()
+	fe.deleteSystemBehaviorHandler(w, behaviorRequest(http.MethodDelete, "", map[string]string{"If-Match": etag}))
+	if w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("stale DELETE: status=%d, want %d", w.Code, http.StatusPreconditionFailed)
+	}
+
+	w = httptest.NewRecorder()
+	fe.deleteSystemBehaviorHandler(w, behaviorRequest(http.MethodDelete, "", nil))
+	if got, _ := behavior.get(); w.Code != http.StatusOK || got != defaultSystemBehavior() {
+		t.Fatalf("DELETE: status=%d behavior=%+v", w.Code, got)
+	}
+	if w.Header().Get("ETag") != etag {
+		t.Fatalf("DELETE: etag=%q, want the default etag %q", w.Header().Get("ETag"), etag)
+	}
+
+	page, err := fe.auditLog.Query(audit.Query{Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actions []string
+	for _, rec := range page.Records {
+		actions = append(actions, rec.Action)
+	}
+	if want := []string{"behavior.reset", "behavior.patch"}; !reflect.DeepEqual(actions, want) {
+		t.Fatalf("audited actions = %v, want %v", actions, want)
+	}
+}
+
+func testFrontendServer(t *testing.T) *frontendServer {
+	t.Helper()
+	l, err := audit.Open(filepath.Join(t.TempDir(), "audit.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	payments, err := ledger.Open(filepath.Join(t.TempDir(), "payments.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { payments.Close() })
+	ages, err := parseDrinkingAges(defaultDrinkingAges)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gate, err := newAgeGate(ages, true, newSecret("age gate cookie key", "TEST_AGE_GATE_SECRET", filepath.Join(t.TempDir(), "none")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &frontendServer{auditLog: l, payments: payments, ageGate: gate}
+}
+
+func TestBehaviorSchemaCoversAllFields(t *testing.T) {
+	raw, err := json.Marshal(behaviorSchema())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range behaviorFields {
+		if !strings.Contains(string(raw), `"`+f.Path[len(f.Path)-1]+`"`) {
+			t.Errorf("schema is missing %s", f.name())
+		}
+	}
+}
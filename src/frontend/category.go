@@ -0,0 +1,220 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	pb "github.com/signalfx/microservices-demo/src/frontend/genproto"
+	"github.com/signalfx/microservices-demo/src/frontend/money"
+)
+
+// productSort is one order a product listing can be shown in.
+type productSort struct {
+	Value string // of the sort query parameter
+	Label string
+	less  func(a, b productView) bool
+}
+
+// productSorts are the orders offered on category pages. The first, the
+// catalog's own order, is the default.
+var productSorts = []productSort{
+	{"", "Featured", nil},
+	{"price-asc", "Price: low to high", func(a, b productView) bool { return comparePrices(a, b) < 0 }},
+	{"price-desc", "Price: high to low", func(a, b productView) bool { return comparePrices(a, b) > 0 }},
+	{"name", "Name", func(a, b productView) bool {
+		return strings.ToLower(a.Item.GetName()) < strings.ToLower(b.Item.GetName())
+	}},
+}
+
+func comparePrices(a, b productView) int {
+	c, _ := money.Compare(*a.Price, *b.Price)
+	return c
+}
+
+// productFilter narrows and orders a listing of product cards. Prices are
+// in the currency the cards are priced in.
+type productFilter struct {
+	Min, Max *pb.Money
+	Sort     productSort
+}
+
+// parseProductFilter reads a productFilter from the min, max and sort query
+// parameters, with min and max in currency.
+func parseProductFilter(q url.Values, currency string) (productFilter, error) {
+	var f productFilter
+	for _, p := range []struct {
+		name string
+		dst  **pb.Money
+	}{{"min", &f.Min}, {"max", &f.Max}} {
+		v := strings.TrimSpace(q.Get(p.name))
+		if v == "" {
+			continue
+		}
+		m, err := money.Parse(v, currency)
+		if err != nil || money.IsNegative(m) {
+			return productFilter{}, errors.Errorf("invalid %s price %q", p.name, v)
+		}
+		*p.dst = &m
+	}
+	if f.Min != nil && f.Max != nil {
+		if c, _ := money.Compare(*f.Min, *f.Max); c > 0 {
+			return productFilter{}, errors.New("min price is above max price")
+		}
+	}
+	sortBy := q.Get("sort")
+	for _, s := range productSorts {
+		if s.Value == sortBy {
+			f.Sort = s
+			return f, nil
+		}
+	}
+	return productFilter{}, errors.Errorf("invalid sort %q", sortBy)
+}
+
+// apply returns the cards priced within the filter's range, in its order.
+func (f productFilter) apply(ps []productView) []productView {
+	out := []productView{}
+	for _, p := range ps {
+		if f.Min != nil && comparePrices(p, productView{Price: f.Min}) < 0 ||
+			f.Max != nil && comparePrices(p, productView{Price: f.Max}) > 0 {
+			continue
+		}
+		out = append(out, p)
+	}
+	if f.Sort.less != nil {
+		sort.SliceStable(out, func(i, j int) bool { return f.Sort.less(out[i], out[j]) })
+	}
+	return out
+}
+
+// categoryLink is an entry of the category nav.
+type categoryLink struct {
+	Name   string
+	URL    string
+	Active bool
+}
+
+// categoryLinks returns a link to every category of products, sorted by
+// name. The links keep the price and sort parameters of q, so moving between
+// categories keeps the other filters.
+func categoryLinks(products []*pb.Product, active string, q url.Values) []categoryLink {
+	seen := map[string]bool{}
+	var names []string
+	for _, p := range products {
+		for _, c := range p.GetCategories() {
+			if !seen[c] {
+				seen[c] = true
+				names = append(names, c)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	keep := url.Values{}
+	for _, k := range []string{"min", "max", "sort"} {
+		if v := q.Get(k); v != "" {
+			keep.Set(k, v)
+		}
+	}
+	links := make([]categoryLink, len(names))
+	for i, name := range names {
+		u := url.URL{Path: "/category/" + name, RawQuery: keep.Encode()}
+		links[i] = categoryLink{Name: name, URL: u.String(), Active: name == active}
+	}
+	return links
+}
+
+func inCategory(p *pb.Product, category string) bool {
+	for _, c := range p.GetCategories() {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+func (fe *frontendServer) categoryHandler(w http.ResponseWriter, r *http.Request) {
+	log := getLoggerWithTraceFields(r.Context())
+	category := mux.Vars(r)["name"]
+	filter, err := parseProductFilter(r.URL.Query(), currentCurrency(r))
+	if err != nil {
+		renderHTTPError(log, r, w, err, http.StatusBadRequest)
+		return
+	}
+	log.WithField("category", category).WithField("query", r.URL.RawQuery).Debug("serving category page")
+
+	products, err := fe.getProducts(r.Context())
+	if err != nil {
+		renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve products"), http.StatusInternalServerError)
+		return
+	}
+	var matching []*pb.Product
+	for _, p := range products {
+		if inCategory(p, category) {
+			matching = append(matching, p)
+		}
+	}
+	if len(matching) == 0 {
+		renderHTTPError(log, r, w, errors.Errorf("no category %q", category), http.StatusNotFound)
+		return
+	}
+	currencies, err := fe.getCurrencies(r.Context())
+	if err != nil {
+		renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve currencies"), http.StatusInternalServerError)
+		return
+	}
+	cart, err := fe.getCart(r.Context(), sessionID(r))
+	if err != nil {
+		renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve cart"), http.StatusInternalServerError)
+		return
+	}
+	ps, err := fe.productViews(r.Context(), matching, currentCurrency(r))
+	if err != nil {
+		renderHTTPError(log, r, w, err, http.StatusInternalServerError)
+		return
+	}
+
+	if err := templates.ExecuteTemplate(w, "category", map[string]interface{}{
+		"session_id":      sessionID(r),
+		"request_id":      r.Context().Value(ctxKeyRequestID{}),
+		"user_currency":   currentCurrency(r),
+		"currencies":      currencies,
+		"category":        category,
+		"categories":      categoryLinks(products, category, r.URL.Query()),
+		"min_price":       r.URL.Query().Get("min"),
+		"max_price":       r.URL.Query().Get("max"),
+		"sort":            filter.Sort.Value,
+		"sorts":           productSorts,
+		"products":        filter.apply(ps),
+		"cart_size":       cartSize(cart),
+		"platform_css":    plat.css,
+		"platform_name":   plat.provider,
+		"rum_realm":       os.Getenv("RUM_REALM"),
+		"rum_auth":        os.Getenv("RUM_AUTH"),
+		"rum_app_name":    os.Getenv("RUM_APP_NAME"),
+		"rum_environment": os.Getenv("RUM_ENVIRONMENT"),
+		"rum_debug":       os.Getenv("RUM_DEBUG"),
+	}); err != nil {
+		log.Error(err)
+	}
+}
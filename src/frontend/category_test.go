@@ -0,0 +1,138 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	pb "github.com/signalfx/microservices-demo/src/frontend/genproto"
+)
+
+func TestProductFilter(t *testing.T) {
+	card := func(name string, units int64, nanos int32) productView {
+		return productView{&pb.Product{Name: name}, &pb.Money{CurrencyCode: "EUR", Units: units, Nanos: nanos}}
+	}
+	cards := []productView{card("Typewriter", 33, 995000000), card("barista kit", 62, 0), card("Terrarium", 18, 225000000)}
+
+	tests := []struct {
+		query   string
+		want    []string
+		wantErr string
+	}{
+		{"", []string{"Typewriter", "barista kit", "Terrarium"}, ""},
+		{"sort=price-asc", []string{"Terrarium", "Typewriter", "barista kit"}, ""},
+		{"sort=price-desc", []string{"barista kit", "Typewriter", "Terrarium"}, ""},
+		{"sort=name", []string{"barista kit", "Terrarium", "Typewriter"}, ""},
+		{"min=20&sort=name", []string{"barista kit", "Typewriter"}, ""},
+		{"max=33.995", []string{"Typewriter", "Terrarium"}, ""},
+		{"min=18.23&max=40", []string{"Typewriter"}, ""},
+		{"min=100", []string{}, ""},
+		{"min=40&max=20", nil, "min price is above max price"},
+		{"max=-1", nil, `invalid max price "-1"`},
+		{"min=cheap", nil, `invalid min price "cheap"`},
+		{"sort=rating", nil, `invalid sort "rating"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, _ := url.ParseQuery(tt.query)
+			f, err := parseProductFilter(q, "EUR")
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, p := range f.apply(cards) {
+				got = append(got, p.Item.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCategoryLinks(t *testing.T) {
+	products := []*pb.Product{
+		{Categories: []string{"vintage", "music"}},
+		{Categories: []string{"home decor", "vintage"}},
+	}
+	q, _ := url.ParseQuery("max=50&sort=name&utm_source=mail")
+	want := []categoryLink{
+		{"home decor", "/category/home%20decor?max=50&sort=name", false},
+		{"music", "/category/music?max=50&sort=name", true},
+		{"vintage", "/category/vintage?max=50&sort=name", false},
+	}
+	if got := categoryLinks(products, "music", q); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestCategoryHandler(t *testing.T) {
+	fe := testFrontendServer(t)
+	newFakeShop().serve(t, fe)
+
+	tests := []struct {
+		name   string
+		target string
+		code   int
+		want   []string
+		absent []string
+	}{
+		{"category", "/category/vintage", 200,
+			[]string{"Vintage Typewriter", "EUR 33.99", `href="/category/gardening"`, `class="category-link active" aria-current="page">vintage`},
+			[]string{"Terrarium"}},
+		{"filters are kept in the nav", "/category/gardening?max=20&sort=price-desc", 200,
+			[]string{"Terrarium", `href="/category/cookware?max=20&amp;sort=price-desc"`, `value="20"`, `value="price-desc" selected="selected"`},
+			nil},
+		{"nothing in range", "/category/cookware?max=20", 200, []string{"No cookware products are priced in this range"}, []string{"Home Barista Kit"}},
+		{"unknown category", "/category/pets", 404, []string{"no category &#34;pets&#34;"}, nil},
+		{"invalid filter", "/category/vintage?min=abc", 400, []string{"invalid min price"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := behaviorRequest(http.MethodGet, "", nil)
+			r.URL, _ = r.URL.Parse(tt.target)
+			r.AddCookie(&http.Cookie{Name: cookieCurrency, Value: "EUR"})
+			r = mux.SetURLVars(r, map[string]string{"name": strings.Split(strings.TrimPrefix(r.URL.Path, "/category/"), "?")[0]})
+			w := httptest.NewRecorder()
+			fe.categoryHandler(w, r)
+			if w.Code != tt.code {
+				t.Fatalf("got %d %s, want %d", w.Code, w.Body, tt.code)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(w.Body.String(), want) {
+					t.Errorf("body does not contain %q:\n%s", want, w.Body)
+				}
+			}
+			for _, absent := range tt.absent {
+				if strings.Contains(w.Body.String(), absent) {
+					t.Errorf("body contains %q", absent)
+				}
+			}
+		})
+	}
+}
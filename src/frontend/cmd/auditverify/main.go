@@ -0,0 +1,49 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command auditverify checks the integrity of a frontend audit log.
+//
+//	auditverify /var/log/frontend/audit.jsonl
+//
+// It exits with status 1 if any record is missing, reordered or edited, or
+// if the log no longer reaches the head recorded next to it
+// (audit.jsonl.head).
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/signalfx/microservices-demo/src/frontend/audit"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: auditverify <audit log file>")
+		os.Exit(2)
+	}
+	n, problems, err := audit.VerifyFile(os.Args[1])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+	if len(problems) > 0 {
+		fmt.Printf("%d records, %d problems\n", n, len(problems))
+		os.Exit(1)
+	}
+	fmt.Printf("%d records, chain intact\n", n)
+}
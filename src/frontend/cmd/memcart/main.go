@@ -0,0 +1,55 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command memcart serves an in-memory CartService for local runs of the
+// frontend:
+//
+//	PORT=7070 memcart &
+//	CART_SERVICE_ADDR=localhost:7070 frontend
+//
+// Carts are lost when it exits.
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	pb "github.com/signalfx/microservices-demo/src/frontend/genproto"
+	"github.com/signalfx/microservices-demo/src/frontend/memcart"
+)
+
+func main() {
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "7070"
+	}
+	lis, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	srv := grpc.NewServer()
+	pb.RegisterCartServiceServer(srv, memcart.New())
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	fmt.Printf("in-memory cart service listening on :%s\n", port)
+	if err := srv.Serve(lis); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
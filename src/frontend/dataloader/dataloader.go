@@ -0,0 +1,193 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package dataloader batches and caches the lookups made while serving a
+// single request, in the manner of Facebook's DataLoader: keys requested
+// close together are fetched with one call, and each key is fetched at most
+// once.
+package dataloader
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultWait is how long a Loader collects keys before fetching them.
+const DefaultWait = 2 * time.Millisecond
+
+// Result is the value or error fetched for one key.
+type Result[V any] struct {
+	Value V
+	Err   error
+}
+
+// FetchFunc fetches keys and returns one Result per key, in the same order.
+type FetchFunc[K comparable, V any] func(ctx context.Context, keys []K) []Result[V]
+
+type entry[V any] struct {
+	done chan struct{}
+	Result[V]
+}
+
+// Loader loads values by key. Keys requested within Wait of the first
+// pending key are fetched together, at most MaxBatch at a time when
+// MaxBatch is positive. Results, including errors, are cached for the life
+// of the Loader, so a Loader should not outlive the request it serves.
+type Loader[K comparable, V any] struct {
+	Wait     time.Duration
+	MaxBatch int
+
+	ctx   context.Context
+	fetch FetchFunc[K, V]
+
+	mu      sync.Mutex
+	cache   map[K]*entry[V]
+	pending []K
+	batch   int // incremented whenever pending is dispatched
+}
+
+// New returns a Loader that fetches with fetch under ctx, which should be
+// the context of the request the Loader serves.
+func New[K comparable, V any](ctx context.Context, fetch FetchFunc[K, V]) *Loader[K, V] {
+	return &Loader[K, V]{Wait: DefaultWait, ctx: ctx, fetch: fetch, cache: map[K]*entry[V]{}}
+}
+
+// Load returns the value for key, waiting for the batch it joins to be
+// fetched unless ctx is done first.
+func (l *Loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	l.mu.Lock()
+	e, ok := l.cache[key]
+	if !ok {
+		e = &entry[V]{done: make(chan struct{})}
+		l.cache[key] = e
+		l.pending = append(l.pending, key)
+		switch {
+		case l.MaxBatch > 0 && len(l.pending) >= l.MaxBatch:
+			keys := l.dispatch()
+			go l.run(keys)
+		case len(l.pending) == 1:
+			batch := l.batch
+			time.AfterFunc(l.Wait, func() {
+				l.mu.Lock()
+				if l.batch != batch {
+					l.mu.Unlock()
+					return
+				}
+				keys := l.dispatch()
+				l.mu.Unlock()
+				l.run(keys)
+			})
+		}
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-e.done:
+		return e.Value, e.Err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// Prime caches value for key unless key was already loaded, so that values
+// found some other way (a listing, say) need not be fetched again.
+func (l *Loader[K, V]) Prime(key K, value V) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.cache[key]; !ok {
+		e := &entry[V]{done: make(chan struct{}), Result: Result[V]{Value: value}}
+		close(e.done)
+		l.cache[key] = e
+	}
+}
+
+// LoadMany loads every key, returning the values in the order of keys or
+// the first error.
+func (l *Loader[K, V]) LoadMany(ctx context.Context, keys []K) ([]V, error) {
+	type loaded struct {
+		i int
+		Result[V]
+	}
+	ch := make(chan loaded, len(keys))
+	for i, k := range keys {
+		go func(i int, k K) {
+			v, err := l.Load(ctx, k)
+			ch <- loaded{i, Result[V]{v, err}}
+		}(i, k)
+	}
+	out := make([]V, len(keys))
+	var err error
+	for range keys {
+		r := <-ch
+		if r.Err != nil && err == nil {
+			err = r.Err
+		}
+		out[r.i] = r.Value
+	}
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Each adapts a lookup of a single key into a FetchFunc that looks up every
+// key of a batch concurrently, for backends without batch lookups. The
+// Loader still ensures each key is looked up once.
+func Each[K comparable, V any](lookup func(context.Context, K) (V, error)) FetchFunc[K, V] {
+	return func(ctx context.Context, keys []K) []Result[V] {
+		out := make([]Result[V], len(keys))
+		var wg sync.WaitGroup
+		for i, k := range keys {
+			wg.Add(1)
+			go func(i int, k K) {
+				defer wg.Done()
+				out[i].Value, out[i].Err = lookup(ctx, k)
+			}(i, k)
+		}
+		wg.Wait()
+		return out
+	}
+}
+
+// dispatch takes the pending keys; l.mu must be held.
+func (l *Loader[K, V]) dispatch() []K {
+	keys := l.pending
+	l.pending = nil
+	l.batch++
+	return keys
+}
+
+func (l *Loader[K, V]) run(keys []K) {
+	results := l.fetch(l.ctx, keys)
+	if len(results) != len(keys) {
+		err := fmt.Errorf("dataloader: fetch returned %d results for %d keys", len(results), len(keys))
+		results = make([]Result[V], len(keys))
+		for i := range results {
+			results[i].Err = err
+		}
+	}
+	l.mu.Lock()
+	entries := make([]*entry[V], len(keys))
+	for i, k := range keys {
+		entries[i] = l.cache[k]
+	}
+	l.mu.Unlock()
+	for i, e := range entries {
+		e.Result = results[i]
+		close(e.done)
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dataloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+)
+
+func TestLoader(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		batches [][]int
+	)
+	errOdd := errors.New("odd")
+	fetch := func(_ context.Context, keys []int) []Result[string] {
+		sorted := append([]int(nil), keys...)
+		sort.Ints(sorted)
+		mu.Lock()
+		batches = append(batches, sorted)
+		mu.Unlock()
+		out := make([]Result[string], len(keys))
+		for i, k := range keys {
+			if k%2 == 1 {
+				out[i].Err = errOdd
+			} else {
+				out[i].Value = fmt.Sprint("v", k)
+			}
+		}
+		return out
+	}
+
+	tests := []struct {
+		name     string
+		maxBatch int
+		keys     []int
+		fetched  []int // every key fetched, sorted
+		sizes    []int // batch sizes, smallest first
+	}{
+		{"one batch, duplicates fetched once", 0, []int{4, 2, 4, 6, 2}, []int{2, 4, 6}, []int{3}},
+		{"split by MaxBatch", 2, []int{2, 4, 6}, []int{2, 4, 6}, []int{1, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batches = nil
+			l := New(context.Background(), fetch)
+			l.MaxBatch = tt.maxBatch
+			got, err := l.LoadMany(context.Background(), tt.keys)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, k := range tt.keys {
+				if got[i] != fmt.Sprint("v", k) {
+					t.Fatalf("LoadMany = %v", got)
+				}
+			}
+			var fetched, sizes []int
+			for _, b := range batches {
+				fetched = append(fetched, b...)
+				sizes = append(sizes, len(b))
+			}
+			sort.Ints(fetched)
+			sort.Ints(sizes)
+			if !reflect.DeepEqual(fetched, tt.fetched) || !reflect.DeepEqual(sizes, tt.sizes) {
+				t.Fatalf("batches = %v, want keys %v in batches of %v", batches, tt.fetched, tt.sizes)
+			}
+
+			// Cached values and errors are not fetched again.
+			batches = nil
+			if v, err := l.Load(context.Background(), tt.keys[0]); err != nil || v != fmt.Sprint("v", tt.keys[0]) {
+				t.Fatalf("cached Load = %q, %v", v, err)
+			}
+			for i := 0; i < 2; i++ {
+				if _, err := l.Load(context.Background(), 1); err != errOdd {
+					t.Fatalf("Load(1) err = %v", err)
+				}
+			}
+			if fmt.Sprint(batches) != "[[1]]" {
+				t.Fatalf("batches after cached loads = %v", batches)
+			}
+		})
+	}
+}
+
+func TestLoaderPrimeAndEach(t *testing.T) {
+	var mu sync.Mutex
+	var looked []string
+	l := New(context.Background(), Each(func(_ context.Context, k string) (int, error) {
+		mu.Lock()
+		looked = append(looked, k)
+		mu.Unlock()
+		return len(k), nil
+	}))
+	l.Prime("primed", 42)
+	got, err := l.LoadMany(context.Background(), []string{"primed", "a", "bb"})
+	if err != nil || !reflect.DeepEqual(got, []int{42, 1, 2}) {
+		t.Fatalf("LoadMany = %v, %v", got, err)
+	}
+	sort.Strings(looked)
+	if !reflect.DeepEqual(looked, []string{"a", "bb"}) {
+		t.Fatalf("looked up %v", looked)
+	}
+}
+
+func TestLoaderShortResults(t *testing.T) {
+	l := New(context.Background(), func(context.Context, []string) []Result[int] { return nil })
+	if _, err := l.Load(context.Background(), "a"); err == nil {
+		t.Fatal("want an error when fetch returns too few results")
+	}
+}
+
+func TestLoaderCanceled(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	l := New(context.Background(), func(_ context.Context, keys []string) []Result[int] {
+		<-release
+		return make([]Result[int], len(keys))
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := l.Load(ctx, "a"); err != context.Canceled {
+		t.Fatalf("Load err = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,350 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	pb "github.com/signalfx/microservices-demo/src/frontend/genproto"
+	"github.com/signalfx/microservices-demo/src/frontend/memcart"
+	"github.com/signalfx/microservices-demo/src/frontend/money"
+)
+
+// fakeShop implements the catalog, cart, currency, shipping, checkout,
+// recommendation and ad services in memory, the cart with memcart.
+// Converting to EUR halves prices; shipping costs USD 8.99 per order, or
+// USD 10 more to Alaska.
+type fakeShop struct {
+	*memcart.Server
+	products []*pb.Product
+	// legacyCart makes UpdateItemQuantity and RemoveItem unimplemented, as
+	// in the C# cart service.
+	legacyCart bool
+
+	mu     sync.Mutex
+	calls  map[string]int    // by method name
+	orders []*pb.StoredOrder // oldest first
+}
+
+func newFakeShop() *fakeShop {
+	return &fakeShop{
+		Server: memcart.New(),
+		products: []*pb.Product{
+			{Id: "OLJCESPC7Z", Name: "Vintage Typewriter", Description: "This typewriter looks good in your living room.",
+				Picture: "/static/img/products/typewriter.jpg", PriceUsd: &pb.Money{CurrencyCode: "USD", Units: 67, Nanos: 990000000}, Categories: []string{"vintage"}, MaxQuantity: 2},
+			{Id: "1YMWWN1N4O", Name: "Home Barista Kit", Description: "Always wanted to brew coffee with Chemex and Aeropress at home?",
+				Picture: "/static/img/products/barista-kit.jpg", PriceUsd: &pb.Money{CurrencyCode: "USD", Units: 124}, Categories: []string{"cookware"}},
+			{Id: "L9ECAV7KIM", Name: "Terrarium", Description: "This terrarium will looks great in your white painted living room.",
+				Picture: "/static/img/products/terrarium.jpg", PriceUsd: &pb.Money{CurrencyCode: "USD", Units: 36, Nanos: 450000000}, Categories: []string{"gardening"}},
+		},
+		calls: map[string]int{},
+	}
+}
+
+// count records every call, by method name.
+func (s *fakeShop) count(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, h grpc.UnaryHandler) (interface{}, error) {
+	s.mu.Lock()
+	s.calls[info.FullMethod[strings.LastIndexByte(info.FullMethod, '/')+1:]]++
+	s.mu.Unlock()
+	return h(ctx, req)
+}
+
+// takeCalls returns the calls counted since the last takeCalls.
+func (s *fakeShop) takeCalls() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	calls := s.calls
+	s.calls = map[string]int{}
+	return calls
+}
+
+// serve starts the fake on an in-memory listener and points every gRPC
+// connection of fe at it.
+func (s *fakeShop) serve(t *testing.T, fe *frontendServer) {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(grpc.UnaryInterceptor(s.count))
+	pb.RegisterProductCatalogServiceServer(srv, s)
+	pb.RegisterCartServiceServer(srv, s)
+	pb.RegisterCurrencyServiceServer(srv, s)
+	pb.RegisterShippingServiceServer(srv, s)
+	pb.RegisterCheckoutServiceServer(srv, s)
+	pb.RegisterRecommendationServiceServer(srv, s)
+	pb.RegisterAdServiceServer(srv, s)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	fe.productCatalogSvcConn, fe.cartSvcConn, fe.currencySvcConn = conn, conn, conn
+	fe.shippingSvcConn, fe.checkoutSvcConn, fe.recommendationSvcConn, fe.adSvcConn = conn, conn, conn, conn
+}
+
+func (s *fakeShop) UpdateItemQuantity(ctx context.Context, req *pb.UpdateItemQuantityRequest) (*pb.Empty, error) {
+	if s.legacyCart {
+		return nil, status.Error(codes.Unimplemented, "")
+	}
+	return s.Server.UpdateItemQuantity(ctx, req)
+}
+
+func (s *fakeShop) RemoveItem(ctx context.Context, req *pb.RemoveItemRequest) (*pb.Empty, error) {
+	if s.legacyCart {
+		return nil, status.Error(codes.Unimplemented, "")
+	}
+	return s.Server.RemoveItem(ctx, req)
+}
+
+func (s *fakeShop) ListProducts(context.Context, *pb.Empty) (*pb.ListProductsResponse, error) {
+	return &pb.ListProductsResponse{Products: s.products}, nil
+}
+
+func (s *fakeShop) GetProduct(_ context.Context, req *pb.GetProductRequest) (*pb.Product, error) {
+	for _, p := range s.products {
+		if p.Id == req.Id {
+			return p, nil
+		}
+	}
+	return nil, status.Errorf(codes.NotFound, "no product with ID %s", req.Id)
+}
+
+func (s *fakeShop) SearchProducts(_ context.Context, req *pb.SearchProductsRequest) (*pb.SearchProductsResponse, error) {
+	var out []*pb.Product
+	for _, p := range s.products {
+		if strings.Contains(strings.ToLower(p.Name+" "+p.Description), strings.ToLower(req.Query)) {
+			out = append(out, p)
+		}
+	}
+	return &pb.SearchProductsResponse{Results: out}, nil
+}
+
+func (s *fakeShop) GetSupportedCurrencies(context.Context, *pb.Empty) (*pb.GetSupportedCurrenciesResponse, error) {
+	return &pb.GetSupportedCurrenciesResponse{CurrencyCodes: []string{"EUR", "USD", "XXX"}}, nil
+}
+
+func (s *fakeShop) Convert(_ context.Context, req *pb.CurrencyConversionRequest) (*pb.Money, error) {
+	out := &pb.Money{CurrencyCode: req.ToCode, Units: req.From.Units, Nanos: req.From.Nanos}
+	if req.ToCode == "EUR" && req.From.CurrencyCode != "EUR" {
+		nanos := (req.From.Units*1e9 + int64(req.From.Nanos)) / 2
+		out.Units, out.Nanos = nanos/1e9, int32(nanos%1e9)
+	}
+	return out, nil
+}
+
+// shippingOptions are what GetQuote offers for any order.
+var shippingOptions = []*pb.ShippingOption{
+	{Id: "standard", Name: "Standard", CostUsd: &pb.Money{CurrencyCode: "USD", Units: 8, Nanos: 990000000},
+		Carrier: "Hopscotch Express", EarliestDelivery: "2024-12-26", LatestDelivery: "2024-12-30"},
+	{Id: "express", Name: "Express", CostUsd: &pb.Money{CurrencyCode: "USD", Units: 19, Nanos: 990000000},
+		Carrier: "Frothly Freight", EarliestDelivery: "2024-12-24", LatestDelivery: "2024-12-24"},
+}
+
+func (s *fakeShop) GetQuote(_ context.Context, req *pb.GetQuoteRequest) (*pb.GetQuoteResponse, error) {
+	if req.Address.GetState() != "AK" {
+		return &pb.GetQuoteResponse{CostUsd: shippingOptions[0].CostUsd, Options: shippingOptions}, nil
+	}
+	res := &pb.GetQuoteResponse{}
+	for _, o := range shippingOptions {
+		o := *o
+		o.CostUsd = &pb.Money{CurrencyCode: "USD", Units: o.CostUsd.Units + 10, Nanos: o.CostUsd.Nanos}
+		res.Options = append(res.Options, &o)
+	}
+	res.CostUsd = res.Options[0].CostUsd
+	return res, nil
+}
+
+func (s *fakeShop) ShipOrder(context.Context, *pb.ShipOrderRequest) (*pb.ShipOrderResponse, error) {
+	return &pb.ShipOrderResponse{TrackingId: "TRACK-1"}, nil
+}
+
+// CheckRestrictions requires an adult's signature for products with an abv
+// attribute, and denies them to Utah.
+func (s *fakeShop) CheckRestrictions(_ context.Context, req *pb.CheckRestrictionsRequest) (*pb.CheckRestrictionsResponse, error) {
+	res := &pb.CheckRestrictionsResponse{}
+	for _, item := range req.Items {
+		for _, p := range s.products {
+			if p.Id != item.ProductId || p.Attributes["abv"] == "" {
+				continue
+			}
+			if req.Address.GetState() == "UT" {
+				res.Restrictions = append(res.Restrictions, &pb.ItemRestriction{ProductId: p.Id, Action: pb.ItemRestriction_DENY,
+					Rule: "dry-states", Reason: "No alcohol to Utah."})
+			}
+			res.Restrictions = append(res.Restrictions, &pb.ItemRestriction{ProductId: p.Id, Action: pb.ItemRestriction_ADULT_SIGNATURE,
+				Rule: "adult-signature", Reason: "Alcohol needs an adult's signature."})
+		}
+	}
+	return res, nil
+}
+
+// ValidateAddress requires every field but the state, and US ZIP codes of
+// five digits. It suggests padding four-digit ones, and normalizes the
+// country to US.
+func (s *fakeShop) ValidateAddress(_ context.Context, req *pb.ValidateAddressRequest) (*pb.ValidateAddressResponse, error) {
+	a := *req.Address
+	if a.PostalCode == "" && a.ZipCode != 0 {
+		a.PostalCode = fmt.Sprintf("%05d", a.ZipCode)
+	}
+	res := &pb.ValidateAddressResponse{}
+	for _, f := range []struct{ name, value string }{
+		{"street_address", a.StreetAddress}, {"city", a.City}, {"country", a.Country}, {"postal_code", a.PostalCode},
+	} {
+		if f.value == "" {
+			res.Errors = append(res.Errors, &pb.AddressFieldError{Field: f.name, Message: f.name + " is required"})
+		}
+	}
+	if us := map[string]bool{"US": true, "USA": true, "United States": true}[a.Country]; us {
+		a.Country = "US"
+		if len(a.PostalCode) == 4 {
+			res.Errors = append(res.Errors, &pb.AddressFieldError{Field: "postal_code", Message: "ZIP codes have five digits"})
+			suggestion := a
+			suggestion.PostalCode = "0" + a.PostalCode
+			res.Suggestions = append(res.Suggestions, &suggestion)
+		}
+	}
+	if len(res.Errors) == 0 {
+		res.Valid, res.Normalized = true, &a
+	}
+	return res, nil
+}
+
+// TrackShipment knows TRACK-1, which has been picked up.
+func (s *fakeShop) TrackShipment(_ context.Context, req *pb.TrackShipmentRequest) (*pb.TrackShipmentResponse, error) {
+	if req.TrackingId != "TRACK-1" {
+		return nil, status.Errorf(codes.NotFound, "no shipment with tracking ID %q", req.TrackingId)
+	}
+	return &pb.TrackShipmentResponse{
+		TrackingId: req.TrackingId,
+		Status:     pb.ShipmentEvent_PICKED_UP,
+		Events: []*pb.ShipmentEvent{
+			{Status: pb.ShipmentEvent_LABEL_CREATED, Time: 1700000000, Description: "Shipping label created"},
+			{Status: pb.ShipmentEvent_PICKED_UP, Time: 1700003600, Location: "Frothly fulfillment center", Description: "Picked up by the carrier"},
+		},
+		Address: &pb.Address{City: "Mountain View", State: "CA"},
+	}, nil
+}
+
+// GetLabel knows TRACK-1's label and packing slip.
+func (s *fakeShop) GetLabel(_ context.Context, req *pb.GetLabelRequest) (*pb.GetLabelResponse, error) {
+	if req.TrackingId != "TRACK-1" {
+		return nil, status.Errorf(codes.NotFound, "no shipment with tracking ID %q", req.TrackingId)
+	}
+	if req.Format == pb.GetLabelRequest_PDF {
+		return &pb.GetLabelResponse{Content: []byte("%PDF-1.4 TRACK-1"), ContentType: "application/pdf"}, nil
+	}
+	return &pb.GetLabelResponse{Content: []byte("^XA^FDTRACK-1^FS^XZ"), ContentType: "application/x-zpl"}, nil
+}
+
+func (s *fakeShop) PlaceOrder(ctx context.Context, req *pb.PlaceOrderRequest) (*pb.PlaceOrderResponse, error) {
+	if strings.HasPrefix(req.CreditCard.CreditCardNumber, "0") {
+		return nil, status.Error(codes.InvalidArgument, "credit card is invalid")
+	}
+	if req.DateOfBirth > "2000-01-01" {
+		return nil, status.Error(codes.FailedPrecondition, "under the legal drinking age")
+	} else if req.DateOfBirth == "" {
+		return nil, status.Error(codes.InvalidArgument, "date of birth is required")
+	}
+	cart, _ := s.GetCart(ctx, &pb.GetCartRequest{UserId: req.UserId})
+	if len(cart.Items) == 0 {
+		return nil, status.Error(codes.FailedPrecondition, "cart is empty")
+	}
+	s.mu.Lock()
+	n := len(s.orders) + 1
+	s.mu.Unlock()
+	option := shippingOptions[0]
+	if req.ShippingOptionId != "" {
+		option = nil
+		for _, o := range shippingOptions {
+			if o.Id == req.ShippingOptionId {
+				option = o
+			}
+		}
+		if option == nil {
+			return nil, status.Errorf(codes.InvalidArgument, "unknown shipping option %q", req.ShippingOptionId)
+		}
+	}
+	order := &pb.OrderResult{OrderId: fmt.Sprintf("order-%d", n), ShippingTrackingId: fmt.Sprintf("TRACK-%d", n), ShippingAddress: req.Address, ShippingOption: option}
+	order.ShippingCost, _ = s.Convert(ctx, &pb.CurrencyConversionRequest{From: option.CostUsd, ToCode: req.UserCurrency})
+	total := *order.ShippingCost
+	for _, item := range cart.Items {
+		p, _ := s.GetProduct(ctx, &pb.GetProductRequest{Id: item.ProductId})
+		cost, _ := s.Convert(ctx, &pb.CurrencyConversionRequest{From: p.PriceUsd, ToCode: req.UserCurrency})
+		order.Items = append(order.Items, &pb.OrderItem{Item: item, Cost: cost})
+		total = money.Must(money.Sum(total, money.MultiplySlow(*cost, uint32(item.Quantity))))
+	}
+	s.EmptyCart(ctx, &pb.EmptyCartRequest{UserId: req.UserId})
+	s.mu.Lock()
+	s.orders = append(s.orders, &pb.StoredOrder{Order: order, UserId: req.UserId, Email: req.Email, Total: &total,
+		PlacedAt: int64(1700000000 + n*3600), Status: "placed"})
+	s.mu.Unlock()
+	return &pb.PlaceOrderResponse{Order: order}, nil
+}
+
+func (s *fakeShop) GetOrder(_ context.Context, req *pb.GetOrderRequest) (*pb.StoredOrder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, o := range s.orders {
+		if o.Order.OrderId == req.OrderId && (req.UserId == "" || req.UserId == o.UserId) {
+			return o, nil
+		}
+	}
+	return nil, status.Errorf(codes.NotFound, "no order with ID %q", req.OrderId)
+}
+
+// ListOrders lists the user's orders, newest first.
+func (s *fakeShop) ListOrders(_ context.Context, req *pb.ListOrdersRequest) (*pb.ListOrdersResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	resp := &pb.ListOrdersResponse{}
+	for i := len(s.orders) - 1; i >= 0; i-- {
+		if s.orders[i].UserId == req.UserId {
+			resp.Orders = append(resp.Orders, s.orders[i])
+		}
+	}
+	return resp, nil
+}
+
+// ListRecommendations recommends every product not asked about.
+func (s *fakeShop) ListRecommendations(_ context.Context, req *pb.ListRecommendationsRequest) (*pb.ListRecommendationsResponse, error) {
+	resp := &pb.ListRecommendationsResponse{}
+	for _, p := range s.products {
+		known := false
+		for _, id := range req.ProductIds {
+			known = known || id == p.Id
+		}
+		if !known {
+			resp.ProductIds = append(resp.ProductIds, p.Id)
+		}
+	}
+	return resp, nil
+}
+
+func (s *fakeShop) GetAds(context.Context, *pb.AdRequest) (*pb.AdResponse, error) {
+	return &pb.AdResponse{Ads: []*pb.Ad{{RedirectUrl: "/product/OLJCESPC7Z", Text: "Typewriter for sale. 50% off."}}}, nil
+}
@@ -25,6 +25,106 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
+type ShipmentEvent_Status int32
+
+const (
+	ShipmentEvent_STATUS_UNSPECIFIED ShipmentEvent_Status = 0
+	ShipmentEvent_LABEL_CREATED      ShipmentEvent_Status = 1
+	ShipmentEvent_PICKED_UP          ShipmentEvent_Status = 2
+	ShipmentEvent_IN_TRANSIT         ShipmentEvent_Status = 3
+	ShipmentEvent_OUT_FOR_DELIVERY   ShipmentEvent_Status = 4
+	ShipmentEvent_DELIVERED          ShipmentEvent_Status = 5
+	ShipmentEvent_EXCEPTION          ShipmentEvent_Status = 6
+)
+
+var ShipmentEvent_Status_name = map[int32]string{
+	0: "STATUS_UNSPECIFIED",
+	1: "LABEL_CREATED",
+	2: "PICKED_UP",
+	3: "IN_TRANSIT",
+	4: "OUT_FOR_DELIVERY",
+	5: "DELIVERED",
+	6: "EXCEPTION",
+}
+
+var ShipmentEvent_Status_value = map[string]int32{
+	"STATUS_UNSPECIFIED": 0,
+	"LABEL_CREATED":      1,
+	"PICKED_UP":          2,
+	"IN_TRANSIT":         3,
+	"OUT_FOR_DELIVERY":   4,
+	"DELIVERED":          5,
+	"EXCEPTION":          6,
+}
+
+func (x ShipmentEvent_Status) String() string {
+	return proto.EnumName(ShipmentEvent_Status_name, int32(x))
+}
+
+func (ShipmentEvent_Status) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{21, 0}
+}
+
+type ItemRestriction_Action int32
+
+const (
+	ItemRestriction_ACTION_UNSPECIFIED ItemRestriction_Action = 0
+	// The product cannot be shipped to the address.
+	ItemRestriction_DENY ItemRestriction_Action = 1
+	// An adult must sign for the delivery, at a surcharge.
+	ItemRestriction_ADULT_SIGNATURE ItemRestriction_Action = 2
+)
+
+var ItemRestriction_Action_name = map[int32]string{
+	0: "ACTION_UNSPECIFIED",
+	1: "DENY",
+	2: "ADULT_SIGNATURE",
+}
+
+var ItemRestriction_Action_value = map[string]int32{
+	"ACTION_UNSPECIFIED": 0,
+	"DENY":               1,
+	"ADULT_SIGNATURE":    2,
+}
+
+func (x ItemRestriction_Action) String() string {
+	return proto.EnumName(ItemRestriction_Action_name, int32(x))
+}
+
+func (ItemRestriction_Action) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{24, 0}
+}
+
+type GetLabelRequest_Format int32
+
+const (
+	GetLabelRequest_FORMAT_UNSPECIFIED GetLabelRequest_Format = 0
+	// A 4x6 inch shipping label in Zebra's ZPL II, for 203 dpi printers.
+	GetLabelRequest_ZPL GetLabelRequest_Format = 1
+	// A letter-size packing slip.
+	GetLabelRequest_PDF GetLabelRequest_Format = 2
+)
+
+var GetLabelRequest_Format_name = map[int32]string{
+	0: "FORMAT_UNSPECIFIED",
+	1: "ZPL",
+	2: "PDF",
+}
+
+var GetLabelRequest_Format_value = map[string]int32{
+	"FORMAT_UNSPECIFIED": 0,
+	"ZPL":                1,
+	"PDF":                2,
+}
+
+func (x GetLabelRequest_Format) String() string {
+	return proto.EnumName(GetLabelRequest_Format_name, int32(x))
+}
+
+func (GetLabelRequest_Format) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{26, 0}
+}
+
 type CartItem struct {
 	ProductId            string   `protobuf:"bytes,1,opt,name=product_id,json=productId,proto3" json:"product_id,omitempty"`
 	Quantity             int32    `protobuf:"varint,2,opt,name=quantity,proto3" json:"quantity,omitempty"`
@@ -197,6 +297,108 @@ func (m *GetCartRequest) GetUserId() string {
 	return ""
 }
 
+type UpdateItemQuantityRequest struct {
+	UserId               string   `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	ProductId            string   `protobuf:"bytes,2,opt,name=product_id,json=productId,proto3" json:"product_id,omitempty"`
+	Quantity             int32    `protobuf:"varint,3,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *UpdateItemQuantityRequest) Reset()         { *m = UpdateItemQuantityRequest{} }
+func (m *UpdateItemQuantityRequest) String() string { return proto.CompactTextString(m) }
+func (*UpdateItemQuantityRequest) ProtoMessage()    {}
+func (*UpdateItemQuantityRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{4}
+}
+
+func (m *UpdateItemQuantityRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UpdateItemQuantityRequest.Unmarshal(m, b)
+}
+func (m *UpdateItemQuantityRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UpdateItemQuantityRequest.Marshal(b, m, deterministic)
+}
+func (m *UpdateItemQuantityRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UpdateItemQuantityRequest.Merge(m, src)
+}
+func (m *UpdateItemQuantityRequest) XXX_Size() int {
+	return xxx_messageInfo_UpdateItemQuantityRequest.Size(m)
+}
+func (m *UpdateItemQuantityRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_UpdateItemQuantityRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UpdateItemQuantityRequest proto.InternalMessageInfo
+
+func (m *UpdateItemQuantityRequest) GetUserId() string {
+	if m != nil {
+		return m.UserId
+	}
+	return ""
+}
+
+func (m *UpdateItemQuantityRequest) GetProductId() string {
+	if m != nil {
+		return m.ProductId
+	}
+	return ""
+}
+
+func (m *UpdateItemQuantityRequest) GetQuantity() int32 {
+	if m != nil {
+		return m.Quantity
+	}
+	return 0
+}
+
+type RemoveItemRequest struct {
+	UserId               string   `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	ProductId            string   `protobuf:"bytes,2,opt,name=product_id,json=productId,proto3" json:"product_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RemoveItemRequest) Reset()         { *m = RemoveItemRequest{} }
+func (m *RemoveItemRequest) String() string { return proto.CompactTextString(m) }
+func (*RemoveItemRequest) ProtoMessage()    {}
+func (*RemoveItemRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{5}
+}
+
+func (m *RemoveItemRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RemoveItemRequest.Unmarshal(m, b)
+}
+func (m *RemoveItemRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RemoveItemRequest.Marshal(b, m, deterministic)
+}
+func (m *RemoveItemRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RemoveItemRequest.Merge(m, src)
+}
+func (m *RemoveItemRequest) XXX_Size() int {
+	return xxx_messageInfo_RemoveItemRequest.Size(m)
+}
+func (m *RemoveItemRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RemoveItemRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RemoveItemRequest proto.InternalMessageInfo
+
+func (m *RemoveItemRequest) GetUserId() string {
+	if m != nil {
+		return m.UserId
+	}
+	return ""
+}
+
+func (m *RemoveItemRequest) GetProductId() string {
+	if m != nil {
+		return m.ProductId
+	}
+	return ""
+}
+
 type Cart struct {
 	UserId               string      `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
 	Items                []*CartItem `protobuf:"bytes,2,rep,name=items,proto3" json:"items,omitempty"`
@@ -209,7 +411,7 @@ func (m *Cart) Reset()         { *m = Cart{} }
 func (m *Cart) String() string { return proto.CompactTextString(m) }
 func (*Cart) ProtoMessage()    {}
 func (*Cart) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{4}
+	return fileDescriptor_ca53982754088a9d, []int{6}
 }
 
 func (m *Cart) XXX_Unmarshal(b []byte) error {
@@ -254,7 +456,7 @@ func (m *Empty) Reset()         { *m = Empty{} }
 func (m *Empty) String() string { return proto.CompactTextString(m) }
 func (*Empty) ProtoMessage()    {}
 func (*Empty) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{5}
+	return fileDescriptor_ca53982754088a9d, []int{7}
 }
 
 func (m *Empty) XXX_Unmarshal(b []byte) error {
@@ -287,7 +489,7 @@ func (m *ListRecommendationsRequest) Reset()         { *m = ListRecommendationsR
 func (m *ListRecommendationsRequest) String() string { return proto.CompactTextString(m) }
 func (*ListRecommendationsRequest) ProtoMessage()    {}
 func (*ListRecommendationsRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{6}
+	return fileDescriptor_ca53982754088a9d, []int{8}
 }
 
 func (m *ListRecommendationsRequest) XXX_Unmarshal(b []byte) error {
@@ -333,7 +535,7 @@ func (m *ListRecommendationsResponse) Reset()         { *m = ListRecommendations
 func (m *ListRecommendationsResponse) String() string { return proto.CompactTextString(m) }
 func (*ListRecommendationsResponse) ProtoMessage()    {}
 func (*ListRecommendationsResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{7}
+	return fileDescriptor_ca53982754088a9d, []int{9}
 }
 
 func (m *ListRecommendationsResponse) XXX_Unmarshal(b []byte) error {
@@ -369,17 +571,27 @@ type Product struct {
 	PriceUsd    *Money `protobuf:"bytes,5,opt,name=price_usd,json=priceUsd,proto3" json:"price_usd,omitempty"`
 	// Categories such as "vintage" or "gardening" that can be used to look up
 	// other related products.
-	Categories           []string `protobuf:"bytes,6,rep,name=categories,proto3" json:"categories,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	Categories []string `protobuf:"bytes,6,rep,name=categories,proto3" json:"categories,omitempty"`
+	// The most units of the product a single cart may hold, or 0 for the
+	// storefront's default.
+	MaxQuantity int32 `protobuf:"varint,7,opt,name=max_quantity,json=maxQuantity,proto3" json:"max_quantity,omitempty"`
+	// The shipping weight and packed volume of one unit, or 0 for the
+	// shipping rate table's defaults.
+	WeightGrams int32 `protobuf:"varint,8,opt,name=weight_grams,json=weightGrams,proto3" json:"weight_grams,omitempty"`
+	VolumeCm3   int32 `protobuf:"varint,9,opt,name=volume_cm3,json=volumeCm3,proto3" json:"volume_cm3,omitempty"`
+	// Facts about the product other services act on, such as "abv", the
+	// alcohol by volume in percent, which shipping restrictions match.
+	Attributes           map[string]string `protobuf:"bytes,10,rep,name=attributes,proto3" json:"attributes,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
 }
 
 func (m *Product) Reset()         { *m = Product{} }
 func (m *Product) String() string { return proto.CompactTextString(m) }
 func (*Product) ProtoMessage()    {}
 func (*Product) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{8}
+	return fileDescriptor_ca53982754088a9d, []int{10}
 }
 
 func (m *Product) XXX_Unmarshal(b []byte) error {
@@ -442,6 +654,34 @@ func (m *Product) GetCategories() []string {
 	return nil
 }
 
+func (m *Product) GetMaxQuantity() int32 {
+	if m != nil {
+		return m.MaxQuantity
+	}
+	return 0
+}
+
+func (m *Product) GetWeightGrams() int32 {
+	if m != nil {
+		return m.WeightGrams
+	}
+	return 0
+}
+
+func (m *Product) GetVolumeCm3() int32 {
+	if m != nil {
+		return m.VolumeCm3
+	}
+	return 0
+}
+
+func (m *Product) GetAttributes() map[string]string {
+	if m != nil {
+		return m.Attributes
+	}
+	return nil
+}
+
 type ListProductsResponse struct {
 	Products             []*Product `protobuf:"bytes,1,rep,name=products,proto3" json:"products,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
@@ -453,7 +693,7 @@ func (m *ListProductsResponse) Reset()         { *m = ListProductsResponse{} }
 func (m *ListProductsResponse) String() string { return proto.CompactTextString(m) }
 func (*ListProductsResponse) ProtoMessage()    {}
 func (*ListProductsResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{9}
+	return fileDescriptor_ca53982754088a9d, []int{11}
 }
 
 func (m *ListProductsResponse) XXX_Unmarshal(b []byte) error {
@@ -492,7 +732,7 @@ func (m *GetProductRequest) Reset()         { *m = GetProductRequest{} }
 func (m *GetProductRequest) String() string { return proto.CompactTextString(m) }
 func (*GetProductRequest) ProtoMessage()    {}
 func (*GetProductRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{10}
+	return fileDescriptor_ca53982754088a9d, []int{12}
 }
 
 func (m *GetProductRequest) XXX_Unmarshal(b []byte) error {
@@ -531,7 +771,7 @@ func (m *SearchProductsRequest) Reset()         { *m = SearchProductsRequest{} }
 func (m *SearchProductsRequest) String() string { return proto.CompactTextString(m) }
 func (*SearchProductsRequest) ProtoMessage()    {}
 func (*SearchProductsRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{11}
+	return fileDescriptor_ca53982754088a9d, []int{13}
 }
 
 func (m *SearchProductsRequest) XXX_Unmarshal(b []byte) error {
@@ -570,7 +810,7 @@ func (m *SearchProductsResponse) Reset()         { *m = SearchProductsResponse{}
 func (m *SearchProductsResponse) String() string { return proto.CompactTextString(m) }
 func (*SearchProductsResponse) ProtoMessage()    {}
 func (*SearchProductsResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{12}
+	return fileDescriptor_ca53982754088a9d, []int{14}
 }
 
 func (m *SearchProductsResponse) XXX_Unmarshal(b []byte) error {
@@ -610,7 +850,7 @@ func (m *GetQuoteRequest) Reset()         { *m = GetQuoteRequest{} }
 func (m *GetQuoteRequest) String() string { return proto.CompactTextString(m) }
 func (*GetQuoteRequest) ProtoMessage()    {}
 func (*GetQuoteRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{13}
+	return fileDescriptor_ca53982754088a9d, []int{15}
 }
 
 func (m *GetQuoteRequest) XXX_Unmarshal(b []byte) error {
@@ -646,17 +886,21 @@ func (m *GetQuoteRequest) GetItems() []*CartItem {
 }
 
 type GetQuoteResponse struct {
-	CostUsd              *Money   `protobuf:"bytes,1,opt,name=cost_usd,json=costUsd,proto3" json:"cost_usd,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	// The cost of the first option.
+	CostUsd *Money `protobuf:"bytes,1,opt,name=cost_usd,json=costUsd,proto3" json:"cost_usd,omitempty"`
+	// The shipping methods available for the address, in the order they
+	// should be offered.
+	Options              []*ShippingOption `protobuf:"bytes,2,rep,name=options,proto3" json:"options,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}          `json:"-"`
+	XXX_unrecognized     []byte            `json:"-"`
+	XXX_sizecache        int32             `json:"-"`
 }
 
 func (m *GetQuoteResponse) Reset()         { *m = GetQuoteResponse{} }
 func (m *GetQuoteResponse) String() string { return proto.CompactTextString(m) }
 func (*GetQuoteResponse) ProtoMessage()    {}
 func (*GetQuoteResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{14}
+	return fileDescriptor_ca53982754088a9d, []int{16}
 }
 
 func (m *GetQuoteResponse) XXX_Unmarshal(b []byte) error {
@@ -684,19 +928,119 @@ func (m *GetQuoteResponse) GetCostUsd() *Money {
 	return nil
 }
 
+func (m *GetQuoteResponse) GetOptions() []*ShippingOption {
+	if m != nil {
+		return m.Options
+	}
+	return nil
+}
+
+type ShippingOption struct {
+	// Such as "standard" or "express".
+	Id      string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name    string `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	CostUsd *Money `protobuf:"bytes,3,opt,name=cost_usd,json=costUsd,proto3" json:"cost_usd,omitempty"`
+	Carrier string `protobuf:"bytes,4,opt,name=carrier,proto3" json:"carrier,omitempty"`
+	// The delivery window for an order placed now, as YYYY-MM-DD dates.
+	EarliestDelivery string `protobuf:"bytes,5,opt,name=earliest_delivery,json=earliestDelivery,proto3" json:"earliest_delivery,omitempty"`
+	LatestDelivery   string `protobuf:"bytes,6,opt,name=latest_delivery,json=latestDelivery,proto3" json:"latest_delivery,omitempty"`
+	// Whether an adult must sign for the delivery; cost_usd includes the
+	// surcharge.
+	AdultSignature       bool     `protobuf:"varint,7,opt,name=adult_signature,json=adultSignature,proto3" json:"adult_signature,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ShippingOption) Reset()         { *m = ShippingOption{} }
+func (m *ShippingOption) String() string { return proto.CompactTextString(m) }
+func (*ShippingOption) ProtoMessage()    {}
+func (*ShippingOption) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{17}
+}
+
+func (m *ShippingOption) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ShippingOption.Unmarshal(m, b)
+}
+func (m *ShippingOption) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ShippingOption.Marshal(b, m, deterministic)
+}
+func (m *ShippingOption) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ShippingOption.Merge(m, src)
+}
+func (m *ShippingOption) XXX_Size() int {
+	return xxx_messageInfo_ShippingOption.Size(m)
+}
+func (m *ShippingOption) XXX_DiscardUnknown() {
+	xxx_messageInfo_ShippingOption.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ShippingOption proto.InternalMessageInfo
+
+func (m *ShippingOption) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *ShippingOption) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *ShippingOption) GetCostUsd() *Money {
+	if m != nil {
+		return m.CostUsd
+	}
+	return nil
+}
+
+func (m *ShippingOption) GetCarrier() string {
+	if m != nil {
+		return m.Carrier
+	}
+	return ""
+}
+
+func (m *ShippingOption) GetEarliestDelivery() string {
+	if m != nil {
+		return m.EarliestDelivery
+	}
+	return ""
+}
+
+func (m *ShippingOption) GetLatestDelivery() string {
+	if m != nil {
+		return m.LatestDelivery
+	}
+	return ""
+}
+
+func (m *ShippingOption) GetAdultSignature() bool {
+	if m != nil {
+		return m.AdultSignature
+	}
+	return false
+}
+
 type ShipOrderRequest struct {
-	Address              *Address    `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
-	Items                []*CartItem `protobuf:"bytes,2,rep,name=items,proto3" json:"items,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
-	XXX_unrecognized     []byte      `json:"-"`
-	XXX_sizecache        int32       `json:"-"`
+	Address *Address    `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	Items   []*CartItem `protobuf:"bytes,2,rep,name=items,proto3" json:"items,omitempty"`
+	// An option ID from GetQuote; empty for the first option.
+	ShippingOptionId     string   `protobuf:"bytes,3,opt,name=shipping_option_id,json=shippingOptionId,proto3" json:"shipping_option_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
 func (m *ShipOrderRequest) Reset()         { *m = ShipOrderRequest{} }
 func (m *ShipOrderRequest) String() string { return proto.CompactTextString(m) }
 func (*ShipOrderRequest) ProtoMessage()    {}
 func (*ShipOrderRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{15}
+	return fileDescriptor_ca53982754088a9d, []int{18}
 }
 
 func (m *ShipOrderRequest) XXX_Unmarshal(b []byte) error {
@@ -731,6 +1075,13 @@ func (m *ShipOrderRequest) GetItems() []*CartItem {
 	return nil
 }
 
+func (m *ShipOrderRequest) GetShippingOptionId() string {
+	if m != nil {
+		return m.ShippingOptionId
+	}
+	return ""
+}
+
 type ShipOrderResponse struct {
 	TrackingId           string   `protobuf:"bytes,1,opt,name=tracking_id,json=trackingId,proto3" json:"tracking_id,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
@@ -742,7 +1093,7 @@ func (m *ShipOrderResponse) Reset()         { *m = ShipOrderResponse{} }
 func (m *ShipOrderResponse) String() string { return proto.CompactTextString(m) }
 func (*ShipOrderResponse) ProtoMessage()    {}
 func (*ShipOrderResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{16}
+	return fileDescriptor_ca53982754088a9d, []int{19}
 }
 
 func (m *ShipOrderResponse) XXX_Unmarshal(b []byte) error {
@@ -770,66 +1121,637 @@ func (m *ShipOrderResponse) GetTrackingId() string {
 	return ""
 }
 
-type Address struct {
-	StreetAddress        string   `protobuf:"bytes,1,opt,name=street_address,json=streetAddress,proto3" json:"street_address,omitempty"`
-	City                 string   `protobuf:"bytes,2,opt,name=city,proto3" json:"city,omitempty"`
-	State                string   `protobuf:"bytes,3,opt,name=state,proto3" json:"state,omitempty"`
-	Country              string   `protobuf:"bytes,4,opt,name=country,proto3" json:"country,omitempty"`
-	ZipCode              int32    `protobuf:"varint,5,opt,name=zip_code,json=zipCode,proto3" json:"zip_code,omitempty"`
+type TrackShipmentRequest struct {
+	TrackingId           string   `protobuf:"bytes,1,opt,name=tracking_id,json=trackingId,proto3" json:"tracking_id,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *Address) Reset()         { *m = Address{} }
-func (m *Address) String() string { return proto.CompactTextString(m) }
-func (*Address) ProtoMessage()    {}
-func (*Address) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{17}
+func (m *TrackShipmentRequest) Reset()         { *m = TrackShipmentRequest{} }
+func (m *TrackShipmentRequest) String() string { return proto.CompactTextString(m) }
+func (*TrackShipmentRequest) ProtoMessage()    {}
+func (*TrackShipmentRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{20}
 }
 
-func (m *Address) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_Address.Unmarshal(m, b)
+func (m *TrackShipmentRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TrackShipmentRequest.Unmarshal(m, b)
 }
-func (m *Address) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_Address.Marshal(b, m, deterministic)
+func (m *TrackShipmentRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TrackShipmentRequest.Marshal(b, m, deterministic)
 }
-func (m *Address) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_Address.Merge(m, src)
+func (m *TrackShipmentRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TrackShipmentRequest.Merge(m, src)
 }
-func (m *Address) XXX_Size() int {
-	return xxx_messageInfo_Address.Size(m)
+func (m *TrackShipmentRequest) XXX_Size() int {
+	return xxx_messageInfo_TrackShipmentRequest.Size(m)
 }
-func (m *Address) XXX_DiscardUnknown() {
-	xxx_messageInfo_Address.DiscardUnknown(m)
+func (m *TrackShipmentRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_TrackShipmentRequest.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_Address proto.InternalMessageInfo
+var xxx_messageInfo_TrackShipmentRequest proto.InternalMessageInfo
 
-func (m *Address) GetStreetAddress() string {
+func (m *TrackShipmentRequest) GetTrackingId() string {
 	if m != nil {
-		return m.StreetAddress
+		return m.TrackingId
 	}
 	return ""
 }
 
-func (m *Address) GetCity() string {
-	if m != nil {
-		return m.City
-	}
-	return ""
+type ShipmentEvent struct {
+	Status ShipmentEvent_Status `protobuf:"varint,1,opt,name=status,proto3,enum=hipstershop.ShipmentEvent_Status" json:"status,omitempty"`
+	// Unix seconds.
+	Time                 int64    `protobuf:"varint,2,opt,name=time,proto3" json:"time,omitempty"`
+	Location             string   `protobuf:"bytes,3,opt,name=location,proto3" json:"location,omitempty"`
+	Description          string   `protobuf:"bytes,4,opt,name=description,proto3" json:"description,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *Address) GetState() string {
-	if m != nil {
-		return m.State
-	}
-	return ""
+func (m *ShipmentEvent) Reset()         { *m = ShipmentEvent{} }
+func (m *ShipmentEvent) String() string { return proto.CompactTextString(m) }
+func (*ShipmentEvent) ProtoMessage()    {}
+func (*ShipmentEvent) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{21}
 }
 
-func (m *Address) GetCountry() string {
-	if m != nil {
-		return m.Country
+func (m *ShipmentEvent) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ShipmentEvent.Unmarshal(m, b)
+}
+func (m *ShipmentEvent) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ShipmentEvent.Marshal(b, m, deterministic)
+}
+func (m *ShipmentEvent) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ShipmentEvent.Merge(m, src)
+}
+func (m *ShipmentEvent) XXX_Size() int {
+	return xxx_messageInfo_ShipmentEvent.Size(m)
+}
+func (m *ShipmentEvent) XXX_DiscardUnknown() {
+	xxx_messageInfo_ShipmentEvent.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ShipmentEvent proto.InternalMessageInfo
+
+func (m *ShipmentEvent) GetStatus() ShipmentEvent_Status {
+	if m != nil {
+		return m.Status
+	}
+	return ShipmentEvent_STATUS_UNSPECIFIED
+}
+
+func (m *ShipmentEvent) GetTime() int64 {
+	if m != nil {
+		return m.Time
+	}
+	return 0
+}
+
+func (m *ShipmentEvent) GetLocation() string {
+	if m != nil {
+		return m.Location
+	}
+	return ""
+}
+
+func (m *ShipmentEvent) GetDescription() string {
+	if m != nil {
+		return m.Description
+	}
+	return ""
+}
+
+type TrackShipmentResponse struct {
+	TrackingId string `protobuf:"bytes,1,opt,name=tracking_id,json=trackingId,proto3" json:"tracking_id,omitempty"`
+	// The status of the latest event.
+	Status ShipmentEvent_Status `protobuf:"varint,2,opt,name=status,proto3,enum=hipstershop.ShipmentEvent_Status" json:"status,omitempty"`
+	// Oldest first.
+	Events               []*ShipmentEvent `protobuf:"bytes,3,rep,name=events,proto3" json:"events,omitempty"`
+	Address              *Address         `protobuf:"bytes,4,opt,name=address,proto3" json:"address,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}         `json:"-"`
+	XXX_unrecognized     []byte           `json:"-"`
+	XXX_sizecache        int32            `json:"-"`
+}
+
+func (m *TrackShipmentResponse) Reset()         { *m = TrackShipmentResponse{} }
+func (m *TrackShipmentResponse) String() string { return proto.CompactTextString(m) }
+func (*TrackShipmentResponse) ProtoMessage()    {}
+func (*TrackShipmentResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{22}
+}
+
+func (m *TrackShipmentResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TrackShipmentResponse.Unmarshal(m, b)
+}
+func (m *TrackShipmentResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TrackShipmentResponse.Marshal(b, m, deterministic)
+}
+func (m *TrackShipmentResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TrackShipmentResponse.Merge(m, src)
+}
+func (m *TrackShipmentResponse) XXX_Size() int {
+	return xxx_messageInfo_TrackShipmentResponse.Size(m)
+}
+func (m *TrackShipmentResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_TrackShipmentResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TrackShipmentResponse proto.InternalMessageInfo
+
+func (m *TrackShipmentResponse) GetTrackingId() string {
+	if m != nil {
+		return m.TrackingId
+	}
+	return ""
+}
+
+func (m *TrackShipmentResponse) GetStatus() ShipmentEvent_Status {
+	if m != nil {
+		return m.Status
+	}
+	return ShipmentEvent_STATUS_UNSPECIFIED
+}
+
+func (m *TrackShipmentResponse) GetEvents() []*ShipmentEvent {
+	if m != nil {
+		return m.Events
+	}
+	return nil
+}
+
+func (m *TrackShipmentResponse) GetAddress() *Address {
+	if m != nil {
+		return m.Address
+	}
+	return nil
+}
+
+type CheckRestrictionsRequest struct {
+	// Without an address, only restrictions that apply everywhere are
+	// listed.
+	Address              *Address    `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	Items                []*CartItem `protobuf:"bytes,2,rep,name=items,proto3" json:"items,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
+}
+
+func (m *CheckRestrictionsRequest) Reset()         { *m = CheckRestrictionsRequest{} }
+func (m *CheckRestrictionsRequest) String() string { return proto.CompactTextString(m) }
+func (*CheckRestrictionsRequest) ProtoMessage()    {}
+func (*CheckRestrictionsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{23}
+}
+
+func (m *CheckRestrictionsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CheckRestrictionsRequest.Unmarshal(m, b)
+}
+func (m *CheckRestrictionsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CheckRestrictionsRequest.Marshal(b, m, deterministic)
+}
+func (m *CheckRestrictionsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CheckRestrictionsRequest.Merge(m, src)
+}
+func (m *CheckRestrictionsRequest) XXX_Size() int {
+	return xxx_messageInfo_CheckRestrictionsRequest.Size(m)
+}
+func (m *CheckRestrictionsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CheckRestrictionsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CheckRestrictionsRequest proto.InternalMessageInfo
+
+func (m *CheckRestrictionsRequest) GetAddress() *Address {
+	if m != nil {
+		return m.Address
+	}
+	return nil
+}
+
+func (m *CheckRestrictionsRequest) GetItems() []*CartItem {
+	if m != nil {
+		return m.Items
+	}
+	return nil
+}
+
+type ItemRestriction struct {
+	ProductId string                 `protobuf:"bytes,1,opt,name=product_id,json=productId,proto3" json:"product_id,omitempty"`
+	Action    ItemRestriction_Action `protobuf:"varint,2,opt,name=action,proto3,enum=hipstershop.ItemRestriction_Action" json:"action,omitempty"`
+	// The name of the rule that applies.
+	Rule                 string   `protobuf:"bytes,3,opt,name=rule,proto3" json:"rule,omitempty"`
+	Reason               string   `protobuf:"bytes,4,opt,name=reason,proto3" json:"reason,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ItemRestriction) Reset()         { *m = ItemRestriction{} }
+func (m *ItemRestriction) String() string { return proto.CompactTextString(m) }
+func (*ItemRestriction) ProtoMessage()    {}
+func (*ItemRestriction) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{24}
+}
+
+func (m *ItemRestriction) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ItemRestriction.Unmarshal(m, b)
+}
+func (m *ItemRestriction) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ItemRestriction.Marshal(b, m, deterministic)
+}
+func (m *ItemRestriction) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ItemRestriction.Merge(m, src)
+}
+func (m *ItemRestriction) XXX_Size() int {
+	return xxx_messageInfo_ItemRestriction.Size(m)
+}
+func (m *ItemRestriction) XXX_DiscardUnknown() {
+	xxx_messageInfo_ItemRestriction.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ItemRestriction proto.InternalMessageInfo
+
+func (m *ItemRestriction) GetProductId() string {
+	if m != nil {
+		return m.ProductId
+	}
+	return ""
+}
+
+func (m *ItemRestriction) GetAction() ItemRestriction_Action {
+	if m != nil {
+		return m.Action
+	}
+	return ItemRestriction_ACTION_UNSPECIFIED
+}
+
+func (m *ItemRestriction) GetRule() string {
+	if m != nil {
+		return m.Rule
+	}
+	return ""
+}
+
+func (m *ItemRestriction) GetReason() string {
+	if m != nil {
+		return m.Reason
+	}
+	return ""
+}
+
+type CheckRestrictionsResponse struct {
+	Restrictions         []*ItemRestriction `protobuf:"bytes,1,rep,name=restrictions,proto3" json:"restrictions,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
+}
+
+func (m *CheckRestrictionsResponse) Reset()         { *m = CheckRestrictionsResponse{} }
+func (m *CheckRestrictionsResponse) String() string { return proto.CompactTextString(m) }
+func (*CheckRestrictionsResponse) ProtoMessage()    {}
+func (*CheckRestrictionsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{25}
+}
+
+func (m *CheckRestrictionsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CheckRestrictionsResponse.Unmarshal(m, b)
+}
+func (m *CheckRestrictionsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CheckRestrictionsResponse.Marshal(b, m, deterministic)
+}
+func (m *CheckRestrictionsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CheckRestrictionsResponse.Merge(m, src)
+}
+func (m *CheckRestrictionsResponse) XXX_Size() int {
+	return xxx_messageInfo_CheckRestrictionsResponse.Size(m)
+}
+func (m *CheckRestrictionsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_CheckRestrictionsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CheckRestrictionsResponse proto.InternalMessageInfo
+
+func (m *CheckRestrictionsResponse) GetRestrictions() []*ItemRestriction {
+	if m != nil {
+		return m.Restrictions
+	}
+	return nil
+}
+
+type GetLabelRequest struct {
+	TrackingId           string                 `protobuf:"bytes,1,opt,name=tracking_id,json=trackingId,proto3" json:"tracking_id,omitempty"`
+	Format               GetLabelRequest_Format `protobuf:"varint,2,opt,name=format,proto3,enum=hipstershop.GetLabelRequest_Format" json:"format,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}               `json:"-"`
+	XXX_unrecognized     []byte                 `json:"-"`
+	XXX_sizecache        int32                  `json:"-"`
+}
+
+func (m *GetLabelRequest) Reset()         { *m = GetLabelRequest{} }
+func (m *GetLabelRequest) String() string { return proto.CompactTextString(m) }
+func (*GetLabelRequest) ProtoMessage()    {}
+func (*GetLabelRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{26}
+}
+
+func (m *GetLabelRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetLabelRequest.Unmarshal(m, b)
+}
+func (m *GetLabelRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetLabelRequest.Marshal(b, m, deterministic)
+}
+func (m *GetLabelRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetLabelRequest.Merge(m, src)
+}
+func (m *GetLabelRequest) XXX_Size() int {
+	return xxx_messageInfo_GetLabelRequest.Size(m)
+}
+func (m *GetLabelRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetLabelRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetLabelRequest proto.InternalMessageInfo
+
+func (m *GetLabelRequest) GetTrackingId() string {
+	if m != nil {
+		return m.TrackingId
+	}
+	return ""
+}
+
+func (m *GetLabelRequest) GetFormat() GetLabelRequest_Format {
+	if m != nil {
+		return m.Format
+	}
+	return GetLabelRequest_FORMAT_UNSPECIFIED
+}
+
+type GetLabelResponse struct {
+	Content []byte `protobuf:"bytes,1,opt,name=content,proto3" json:"content,omitempty"`
+	// The MIME type of content, such as "application/pdf".
+	ContentType          string   `protobuf:"bytes,2,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetLabelResponse) Reset()         { *m = GetLabelResponse{} }
+func (m *GetLabelResponse) String() string { return proto.CompactTextString(m) }
+func (*GetLabelResponse) ProtoMessage()    {}
+func (*GetLabelResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{27}
+}
+
+func (m *GetLabelResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetLabelResponse.Unmarshal(m, b)
+}
+func (m *GetLabelResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetLabelResponse.Marshal(b, m, deterministic)
+}
+func (m *GetLabelResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetLabelResponse.Merge(m, src)
+}
+func (m *GetLabelResponse) XXX_Size() int {
+	return xxx_messageInfo_GetLabelResponse.Size(m)
+}
+func (m *GetLabelResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetLabelResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetLabelResponse proto.InternalMessageInfo
+
+func (m *GetLabelResponse) GetContent() []byte {
+	if m != nil {
+		return m.Content
+	}
+	return nil
+}
+
+func (m *GetLabelResponse) GetContentType() string {
+	if m != nil {
+		return m.ContentType
+	}
+	return ""
+}
+
+type ValidateAddressRequest struct {
+	Address              *Address `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ValidateAddressRequest) Reset()         { *m = ValidateAddressRequest{} }
+func (m *ValidateAddressRequest) String() string { return proto.CompactTextString(m) }
+func (*ValidateAddressRequest) ProtoMessage()    {}
+func (*ValidateAddressRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{28}
+}
+
+func (m *ValidateAddressRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ValidateAddressRequest.Unmarshal(m, b)
+}
+func (m *ValidateAddressRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ValidateAddressRequest.Marshal(b, m, deterministic)
+}
+func (m *ValidateAddressRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ValidateAddressRequest.Merge(m, src)
+}
+func (m *ValidateAddressRequest) XXX_Size() int {
+	return xxx_messageInfo_ValidateAddressRequest.Size(m)
+}
+func (m *ValidateAddressRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ValidateAddressRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ValidateAddressRequest proto.InternalMessageInfo
+
+func (m *ValidateAddressRequest) GetAddress() *Address {
+	if m != nil {
+		return m.Address
+	}
+	return nil
+}
+
+type AddressFieldError struct {
+	// The Address field at fault, such as "postal_code".
+	Field                string   `protobuf:"bytes,1,opt,name=field,proto3" json:"field,omitempty"`
+	Message              string   `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *AddressFieldError) Reset()         { *m = AddressFieldError{} }
+func (m *AddressFieldError) String() string { return proto.CompactTextString(m) }
+func (*AddressFieldError) ProtoMessage()    {}
+func (*AddressFieldError) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{29}
+}
+
+func (m *AddressFieldError) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_AddressFieldError.Unmarshal(m, b)
+}
+func (m *AddressFieldError) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_AddressFieldError.Marshal(b, m, deterministic)
+}
+func (m *AddressFieldError) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_AddressFieldError.Merge(m, src)
+}
+func (m *AddressFieldError) XXX_Size() int {
+	return xxx_messageInfo_AddressFieldError.Size(m)
+}
+func (m *AddressFieldError) XXX_DiscardUnknown() {
+	xxx_messageInfo_AddressFieldError.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_AddressFieldError proto.InternalMessageInfo
+
+func (m *AddressFieldError) GetField() string {
+	if m != nil {
+		return m.Field
+	}
+	return ""
+}
+
+func (m *AddressFieldError) GetMessage() string {
+	if m != nil {
+		return m.Message
+	}
+	return ""
+}
+
+type ValidateAddressResponse struct {
+	Valid bool `protobuf:"varint,1,opt,name=valid,proto3" json:"valid,omitempty"`
+	// The address with casing, spacing and abbreviations normalized; set
+	// only when it is valid.
+	Normalized *Address             `protobuf:"bytes,2,opt,name=normalized,proto3" json:"normalized,omitempty"`
+	Errors     []*AddressFieldError `protobuf:"bytes,3,rep,name=errors,proto3" json:"errors,omitempty"`
+	// Valid addresses the input may have meant, most likely first.
+	Suggestions          []*Address `protobuf:"bytes,4,rep,name=suggestions,proto3" json:"suggestions,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *ValidateAddressResponse) Reset()         { *m = ValidateAddressResponse{} }
+func (m *ValidateAddressResponse) String() string { return proto.CompactTextString(m) }
+func (*ValidateAddressResponse) ProtoMessage()    {}
+func (*ValidateAddressResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{30}
+}
+
+func (m *ValidateAddressResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ValidateAddressResponse.Unmarshal(m, b)
+}
+func (m *ValidateAddressResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ValidateAddressResponse.Marshal(b, m, deterministic)
+}
+func (m *ValidateAddressResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ValidateAddressResponse.Merge(m, src)
+}
+func (m *ValidateAddressResponse) XXX_Size() int {
+	return xxx_messageInfo_ValidateAddressResponse.Size(m)
+}
+func (m *ValidateAddressResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ValidateAddressResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ValidateAddressResponse proto.InternalMessageInfo
+
+func (m *ValidateAddressResponse) GetValid() bool {
+	if m != nil {
+		return m.Valid
+	}
+	return false
+}
+
+func (m *ValidateAddressResponse) GetNormalized() *Address {
+	if m != nil {
+		return m.Normalized
+	}
+	return nil
+}
+
+func (m *ValidateAddressResponse) GetErrors() []*AddressFieldError {
+	if m != nil {
+		return m.Errors
+	}
+	return nil
+}
+
+func (m *ValidateAddressResponse) GetSuggestions() []*Address {
+	if m != nil {
+		return m.Suggestions
+	}
+	return nil
+}
+
+type Address struct {
+	StreetAddress string `protobuf:"bytes,1,opt,name=street_address,json=streetAddress,proto3" json:"street_address,omitempty"`
+	City          string `protobuf:"bytes,2,opt,name=city,proto3" json:"city,omitempty"`
+	State         string `protobuf:"bytes,3,opt,name=state,proto3" json:"state,omitempty"`
+	Country       string `protobuf:"bytes,4,opt,name=country,proto3" json:"country,omitempty"`
+	// Deprecated: zip_code loses leading zeros and cannot hold letters; use
+	// postal_code. Services still read it when postal_code is empty.
+	ZipCode              int32    `protobuf:"varint,5,opt,name=zip_code,json=zipCode,proto3" json:"zip_code,omitempty"`
+	PostalCode           string   `protobuf:"bytes,6,opt,name=postal_code,json=postalCode,proto3" json:"postal_code,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Address) Reset()         { *m = Address{} }
+func (m *Address) String() string { return proto.CompactTextString(m) }
+func (*Address) ProtoMessage()    {}
+func (*Address) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{31}
+}
+
+func (m *Address) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Address.Unmarshal(m, b)
+}
+func (m *Address) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Address.Marshal(b, m, deterministic)
+}
+func (m *Address) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Address.Merge(m, src)
+}
+func (m *Address) XXX_Size() int {
+	return xxx_messageInfo_Address.Size(m)
+}
+func (m *Address) XXX_DiscardUnknown() {
+	xxx_messageInfo_Address.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Address proto.InternalMessageInfo
+
+func (m *Address) GetStreetAddress() string {
+	if m != nil {
+		return m.StreetAddress
+	}
+	return ""
+}
+
+func (m *Address) GetCity() string {
+	if m != nil {
+		return m.City
+	}
+	return ""
+}
+
+func (m *Address) GetState() string {
+	if m != nil {
+		return m.State
+	}
+	return ""
+}
+
+func (m *Address) GetCountry() string {
+	if m != nil {
+		return m.Country
 	}
 	return ""
 }
@@ -841,6 +1763,13 @@ func (m *Address) GetZipCode() int32 {
 	return 0
 }
 
+func (m *Address) GetPostalCode() string {
+	if m != nil {
+		return m.PostalCode
+	}
+	return ""
+}
+
 // Represents an amount of money with its currency type.
 type Money struct {
 	// The 3-letter currency code defined in ISO 4217.
@@ -864,7 +1793,7 @@ func (m *Money) Reset()         { *m = Money{} }
 func (m *Money) String() string { return proto.CompactTextString(m) }
 func (*Money) ProtoMessage()    {}
 func (*Money) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{18}
+	return fileDescriptor_ca53982754088a9d, []int{32}
 }
 
 func (m *Money) XXX_Unmarshal(b []byte) error {
@@ -918,7 +1847,7 @@ func (m *GetSupportedCurrenciesResponse) Reset()         { *m = GetSupportedCurr
 func (m *GetSupportedCurrenciesResponse) String() string { return proto.CompactTextString(m) }
 func (*GetSupportedCurrenciesResponse) ProtoMessage()    {}
 func (*GetSupportedCurrenciesResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{19}
+	return fileDescriptor_ca53982754088a9d, []int{33}
 }
 
 func (m *GetSupportedCurrenciesResponse) XXX_Unmarshal(b []byte) error {
@@ -959,7 +1888,7 @@ func (m *CurrencyConversionRequest) Reset()         { *m = CurrencyConversionReq
 func (m *CurrencyConversionRequest) String() string { return proto.CompactTextString(m) }
 func (*CurrencyConversionRequest) ProtoMessage()    {}
 func (*CurrencyConversionRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{20}
+	return fileDescriptor_ca53982754088a9d, []int{34}
 }
 
 func (m *CurrencyConversionRequest) XXX_Unmarshal(b []byte) error {
@@ -1008,7 +1937,7 @@ func (m *CreditCardInfo) Reset()         { *m = CreditCardInfo{} }
 func (m *CreditCardInfo) String() string { return proto.CompactTextString(m) }
 func (*CreditCardInfo) ProtoMessage()    {}
 func (*CreditCardInfo) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{21}
+	return fileDescriptor_ca53982754088a9d, []int{35}
 }
 
 func (m *CreditCardInfo) XXX_Unmarshal(b []byte) error {
@@ -1069,7 +1998,7 @@ func (m *ChargeRequest) Reset()         { *m = ChargeRequest{} }
 func (m *ChargeRequest) String() string { return proto.CompactTextString(m) }
 func (*ChargeRequest) ProtoMessage()    {}
 func (*ChargeRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{22}
+	return fileDescriptor_ca53982754088a9d, []int{36}
 }
 
 func (m *ChargeRequest) XXX_Unmarshal(b []byte) error {
@@ -1115,7 +2044,7 @@ func (m *ChargeResponse) Reset()         { *m = ChargeResponse{} }
 func (m *ChargeResponse) String() string { return proto.CompactTextString(m) }
 func (*ChargeResponse) ProtoMessage()    {}
 func (*ChargeResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{23}
+	return fileDescriptor_ca53982754088a9d, []int{37}
 }
 
 func (m *ChargeResponse) XXX_Unmarshal(b []byte) error {
@@ -1155,7 +2084,7 @@ func (m *OrderItem) Reset()         { *m = OrderItem{} }
 func (m *OrderItem) String() string { return proto.CompactTextString(m) }
 func (*OrderItem) ProtoMessage()    {}
 func (*OrderItem) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{24}
+	return fileDescriptor_ca53982754088a9d, []int{38}
 }
 
 func (m *OrderItem) XXX_Unmarshal(b []byte) error {
@@ -1191,21 +2120,24 @@ func (m *OrderItem) GetCost() *Money {
 }
 
 type OrderResult struct {
-	OrderId              string       `protobuf:"bytes,1,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
-	ShippingTrackingId   string       `protobuf:"bytes,2,opt,name=shipping_tracking_id,json=shippingTrackingId,proto3" json:"shipping_tracking_id,omitempty"`
-	ShippingCost         *Money       `protobuf:"bytes,3,opt,name=shipping_cost,json=shippingCost,proto3" json:"shipping_cost,omitempty"`
-	ShippingAddress      *Address     `protobuf:"bytes,4,opt,name=shipping_address,json=shippingAddress,proto3" json:"shipping_address,omitempty"`
-	Items                []*OrderItem `protobuf:"bytes,5,rep,name=items,proto3" json:"items,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
-	XXX_unrecognized     []byte       `json:"-"`
-	XXX_sizecache        int32        `json:"-"`
+	OrderId            string       `protobuf:"bytes,1,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	ShippingTrackingId string       `protobuf:"bytes,2,opt,name=shipping_tracking_id,json=shippingTrackingId,proto3" json:"shipping_tracking_id,omitempty"`
+	ShippingCost       *Money       `protobuf:"bytes,3,opt,name=shipping_cost,json=shippingCost,proto3" json:"shipping_cost,omitempty"`
+	ShippingAddress    *Address     `protobuf:"bytes,4,opt,name=shipping_address,json=shippingAddress,proto3" json:"shipping_address,omitempty"`
+	Items              []*OrderItem `protobuf:"bytes,5,rep,name=items,proto3" json:"items,omitempty"`
+	// How the order ships. Its cost is in USD; shipping_cost is what the
+	// user paid.
+	ShippingOption       *ShippingOption `protobuf:"bytes,6,opt,name=shipping_option,json=shippingOption,proto3" json:"shipping_option,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
 }
 
 func (m *OrderResult) Reset()         { *m = OrderResult{} }
 func (m *OrderResult) String() string { return proto.CompactTextString(m) }
 func (*OrderResult) ProtoMessage()    {}
 func (*OrderResult) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{25}
+	return fileDescriptor_ca53982754088a9d, []int{39}
 }
 
 func (m *OrderResult) XXX_Unmarshal(b []byte) error {
@@ -1261,6 +2193,13 @@ func (m *OrderResult) GetItems() []*OrderItem {
 	return nil
 }
 
+func (m *OrderResult) GetShippingOption() *ShippingOption {
+	if m != nil {
+		return m.ShippingOption
+	}
+	return nil
+}
+
 type SendOrderConfirmationRequest struct {
 	Email                string       `protobuf:"bytes,1,opt,name=email,proto3" json:"email,omitempty"`
 	Order                *OrderResult `protobuf:"bytes,2,opt,name=order,proto3" json:"order,omitempty"`
@@ -1273,7 +2212,7 @@ func (m *SendOrderConfirmationRequest) Reset()         { *m = SendOrderConfirmat
 func (m *SendOrderConfirmationRequest) String() string { return proto.CompactTextString(m) }
 func (*SendOrderConfirmationRequest) ProtoMessage()    {}
 func (*SendOrderConfirmationRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{26}
+	return fileDescriptor_ca53982754088a9d, []int{40}
 }
 
 func (m *SendOrderConfirmationRequest) XXX_Unmarshal(b []byte) error {
@@ -1301,119 +2240,347 @@ func (m *SendOrderConfirmationRequest) GetEmail() string {
 	return ""
 }
 
-func (m *SendOrderConfirmationRequest) GetOrder() *OrderResult {
+func (m *SendOrderConfirmationRequest) GetOrder() *OrderResult {
+	if m != nil {
+		return m.Order
+	}
+	return nil
+}
+
+type PlaceOrderRequest struct {
+	UserId       string          `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	UserCurrency string          `protobuf:"bytes,2,opt,name=user_currency,json=userCurrency,proto3" json:"user_currency,omitempty"`
+	Address      *Address        `protobuf:"bytes,3,opt,name=address,proto3" json:"address,omitempty"`
+	Email        string          `protobuf:"bytes,5,opt,name=email,proto3" json:"email,omitempty"`
+	CreditCard   *CreditCardInfo `protobuf:"bytes,6,opt,name=credit_card,json=creditCard,proto3" json:"credit_card,omitempty"`
+	// An option ID from ShippingService.GetQuote; empty for the first
+	// option.
+	ShippingOptionId string `protobuf:"bytes,7,opt,name=shipping_option_id,json=shippingOptionId,proto3" json:"shipping_option_id,omitempty"`
+	// The customer's, as YYYY-MM-DD. Checkout refuses customers under the
+	// legal drinking age of the address's country.
+	DateOfBirth          string   `protobuf:"bytes,8,opt,name=date_of_birth,json=dateOfBirth,proto3" json:"date_of_birth,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *PlaceOrderRequest) Reset()         { *m = PlaceOrderRequest{} }
+func (m *PlaceOrderRequest) String() string { return proto.CompactTextString(m) }
+func (*PlaceOrderRequest) ProtoMessage()    {}
+func (*PlaceOrderRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{41}
+}
+
+func (m *PlaceOrderRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PlaceOrderRequest.Unmarshal(m, b)
+}
+func (m *PlaceOrderRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PlaceOrderRequest.Marshal(b, m, deterministic)
+}
+func (m *PlaceOrderRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PlaceOrderRequest.Merge(m, src)
+}
+func (m *PlaceOrderRequest) XXX_Size() int {
+	return xxx_messageInfo_PlaceOrderRequest.Size(m)
+}
+func (m *PlaceOrderRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_PlaceOrderRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PlaceOrderRequest proto.InternalMessageInfo
+
+func (m *PlaceOrderRequest) GetUserId() string {
+	if m != nil {
+		return m.UserId
+	}
+	return ""
+}
+
+func (m *PlaceOrderRequest) GetUserCurrency() string {
+	if m != nil {
+		return m.UserCurrency
+	}
+	return ""
+}
+
+func (m *PlaceOrderRequest) GetAddress() *Address {
+	if m != nil {
+		return m.Address
+	}
+	return nil
+}
+
+func (m *PlaceOrderRequest) GetEmail() string {
+	if m != nil {
+		return m.Email
+	}
+	return ""
+}
+
+func (m *PlaceOrderRequest) GetCreditCard() *CreditCardInfo {
+	if m != nil {
+		return m.CreditCard
+	}
+	return nil
+}
+
+func (m *PlaceOrderRequest) GetShippingOptionId() string {
+	if m != nil {
+		return m.ShippingOptionId
+	}
+	return ""
+}
+
+func (m *PlaceOrderRequest) GetDateOfBirth() string {
+	if m != nil {
+		return m.DateOfBirth
+	}
+	return ""
+}
+
+type PlaceOrderResponse struct {
+	Order                *OrderResult `protobuf:"bytes,1,opt,name=order,proto3" json:"order,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
+	XXX_unrecognized     []byte       `json:"-"`
+	XXX_sizecache        int32        `json:"-"`
+}
+
+func (m *PlaceOrderResponse) Reset()         { *m = PlaceOrderResponse{} }
+func (m *PlaceOrderResponse) String() string { return proto.CompactTextString(m) }
+func (*PlaceOrderResponse) ProtoMessage()    {}
+func (*PlaceOrderResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{42}
+}
+
+func (m *PlaceOrderResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_PlaceOrderResponse.Unmarshal(m, b)
+}
+func (m *PlaceOrderResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_PlaceOrderResponse.Marshal(b, m, deterministic)
+}
+func (m *PlaceOrderResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PlaceOrderResponse.Merge(m, src)
+}
+func (m *PlaceOrderResponse) XXX_Size() int {
+	return xxx_messageInfo_PlaceOrderResponse.Size(m)
+}
+func (m *PlaceOrderResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_PlaceOrderResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PlaceOrderResponse proto.InternalMessageInfo
+
+func (m *PlaceOrderResponse) GetOrder() *OrderResult {
+	if m != nil {
+		return m.Order
+	}
+	return nil
+}
+
+// StoredOrder is checkoutservice's copy of a placed order.
+type StoredOrder struct {
+	Order  *OrderResult `protobuf:"bytes,1,opt,name=order,proto3" json:"order,omitempty"`
+	UserId string       `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Email  string       `protobuf:"bytes,3,opt,name=email,proto3" json:"email,omitempty"`
+	// Shipping cost plus the cost of every item, in the user's currency.
+	Total *Money `protobuf:"bytes,4,opt,name=total,proto3" json:"total,omitempty"`
+	// When the order was placed, in seconds since the Unix epoch.
+	PlacedAt int64 `protobuf:"varint,5,opt,name=placed_at,json=placedAt,proto3" json:"placed_at,omitempty"`
+	// "placed" once the card is charged and the order handed to shipping.
+	Status               string   `protobuf:"bytes,6,opt,name=status,proto3" json:"status,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *StoredOrder) Reset()         { *m = StoredOrder{} }
+func (m *StoredOrder) String() string { return proto.CompactTextString(m) }
+func (*StoredOrder) ProtoMessage()    {}
+func (*StoredOrder) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{43}
+}
+
+func (m *StoredOrder) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StoredOrder.Unmarshal(m, b)
+}
+func (m *StoredOrder) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StoredOrder.Marshal(b, m, deterministic)
+}
+func (m *StoredOrder) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StoredOrder.Merge(m, src)
+}
+func (m *StoredOrder) XXX_Size() int {
+	return xxx_messageInfo_StoredOrder.Size(m)
+}
+func (m *StoredOrder) XXX_DiscardUnknown() {
+	xxx_messageInfo_StoredOrder.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StoredOrder proto.InternalMessageInfo
+
+func (m *StoredOrder) GetOrder() *OrderResult {
+	if m != nil {
+		return m.Order
+	}
+	return nil
+}
+
+func (m *StoredOrder) GetUserId() string {
+	if m != nil {
+		return m.UserId
+	}
+	return ""
+}
+
+func (m *StoredOrder) GetEmail() string {
+	if m != nil {
+		return m.Email
+	}
+	return ""
+}
+
+func (m *StoredOrder) GetTotal() *Money {
+	if m != nil {
+		return m.Total
+	}
+	return nil
+}
+
+func (m *StoredOrder) GetPlacedAt() int64 {
+	if m != nil {
+		return m.PlacedAt
+	}
+	return 0
+}
+
+func (m *StoredOrder) GetStatus() string {
 	if m != nil {
-		return m.Order
+		return m.Status
 	}
-	return nil
+	return ""
 }
 
-type PlaceOrderRequest struct {
-	UserId               string          `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
-	UserCurrency         string          `protobuf:"bytes,2,opt,name=user_currency,json=userCurrency,proto3" json:"user_currency,omitempty"`
-	Address              *Address        `protobuf:"bytes,3,opt,name=address,proto3" json:"address,omitempty"`
-	Email                string          `protobuf:"bytes,5,opt,name=email,proto3" json:"email,omitempty"`
-	CreditCard           *CreditCardInfo `protobuf:"bytes,6,opt,name=credit_card,json=creditCard,proto3" json:"credit_card,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
-	XXX_unrecognized     []byte          `json:"-"`
-	XXX_sizecache        int32           `json:"-"`
+type GetOrderRequest struct {
+	OrderId              string   `protobuf:"bytes,1,opt,name=order_id,json=orderId,proto3" json:"order_id,omitempty"`
+	UserId               string   `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *PlaceOrderRequest) Reset()         { *m = PlaceOrderRequest{} }
-func (m *PlaceOrderRequest) String() string { return proto.CompactTextString(m) }
-func (*PlaceOrderRequest) ProtoMessage()    {}
-func (*PlaceOrderRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{27}
+func (m *GetOrderRequest) Reset()         { *m = GetOrderRequest{} }
+func (m *GetOrderRequest) String() string { return proto.CompactTextString(m) }
+func (*GetOrderRequest) ProtoMessage()    {}
+func (*GetOrderRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{44}
 }
 
-func (m *PlaceOrderRequest) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_PlaceOrderRequest.Unmarshal(m, b)
+func (m *GetOrderRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetOrderRequest.Unmarshal(m, b)
 }
-func (m *PlaceOrderRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_PlaceOrderRequest.Marshal(b, m, deterministic)
+func (m *GetOrderRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetOrderRequest.Marshal(b, m, deterministic)
 }
-func (m *PlaceOrderRequest) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_PlaceOrderRequest.Merge(m, src)
+func (m *GetOrderRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetOrderRequest.Merge(m, src)
 }
-func (m *PlaceOrderRequest) XXX_Size() int {
-	return xxx_messageInfo_PlaceOrderRequest.Size(m)
+func (m *GetOrderRequest) XXX_Size() int {
+	return xxx_messageInfo_GetOrderRequest.Size(m)
 }
-func (m *PlaceOrderRequest) XXX_DiscardUnknown() {
-	xxx_messageInfo_PlaceOrderRequest.DiscardUnknown(m)
+func (m *GetOrderRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetOrderRequest.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_PlaceOrderRequest proto.InternalMessageInfo
+var xxx_messageInfo_GetOrderRequest proto.InternalMessageInfo
 
-func (m *PlaceOrderRequest) GetUserId() string {
+func (m *GetOrderRequest) GetOrderId() string {
 	if m != nil {
-		return m.UserId
+		return m.OrderId
 	}
 	return ""
 }
 
-func (m *PlaceOrderRequest) GetUserCurrency() string {
+func (m *GetOrderRequest) GetUserId() string {
 	if m != nil {
-		return m.UserCurrency
+		return m.UserId
 	}
 	return ""
 }
 
-func (m *PlaceOrderRequest) GetAddress() *Address {
-	if m != nil {
-		return m.Address
-	}
-	return nil
+type ListOrdersRequest struct {
+	UserId               string   `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *PlaceOrderRequest) GetEmail() string {
-	if m != nil {
-		return m.Email
-	}
-	return ""
+func (m *ListOrdersRequest) Reset()         { *m = ListOrdersRequest{} }
+func (m *ListOrdersRequest) String() string { return proto.CompactTextString(m) }
+func (*ListOrdersRequest) ProtoMessage()    {}
+func (*ListOrdersRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{45}
 }
 
-func (m *PlaceOrderRequest) GetCreditCard() *CreditCardInfo {
+func (m *ListOrdersRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListOrdersRequest.Unmarshal(m, b)
+}
+func (m *ListOrdersRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListOrdersRequest.Marshal(b, m, deterministic)
+}
+func (m *ListOrdersRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListOrdersRequest.Merge(m, src)
+}
+func (m *ListOrdersRequest) XXX_Size() int {
+	return xxx_messageInfo_ListOrdersRequest.Size(m)
+}
+func (m *ListOrdersRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListOrdersRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListOrdersRequest proto.InternalMessageInfo
+
+func (m *ListOrdersRequest) GetUserId() string {
 	if m != nil {
-		return m.CreditCard
+		return m.UserId
 	}
-	return nil
+	return ""
 }
 
-type PlaceOrderResponse struct {
-	Order                *OrderResult `protobuf:"bytes,1,opt,name=order,proto3" json:"order,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}     `json:"-"`
-	XXX_unrecognized     []byte       `json:"-"`
-	XXX_sizecache        int32        `json:"-"`
+type ListOrdersResponse struct {
+	Orders               []*StoredOrder `protobuf:"bytes,1,rep,name=orders,proto3" json:"orders,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}       `json:"-"`
+	XXX_unrecognized     []byte         `json:"-"`
+	XXX_sizecache        int32          `json:"-"`
 }
 
-func (m *PlaceOrderResponse) Reset()         { *m = PlaceOrderResponse{} }
-func (m *PlaceOrderResponse) String() string { return proto.CompactTextString(m) }
-func (*PlaceOrderResponse) ProtoMessage()    {}
-func (*PlaceOrderResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{28}
+func (m *ListOrdersResponse) Reset()         { *m = ListOrdersResponse{} }
+func (m *ListOrdersResponse) String() string { return proto.CompactTextString(m) }
+func (*ListOrdersResponse) ProtoMessage()    {}
+func (*ListOrdersResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ca53982754088a9d, []int{46}
 }
 
-func (m *PlaceOrderResponse) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_PlaceOrderResponse.Unmarshal(m, b)
+func (m *ListOrdersResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListOrdersResponse.Unmarshal(m, b)
 }
-func (m *PlaceOrderResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_PlaceOrderResponse.Marshal(b, m, deterministic)
+func (m *ListOrdersResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListOrdersResponse.Marshal(b, m, deterministic)
 }
-func (m *PlaceOrderResponse) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_PlaceOrderResponse.Merge(m, src)
+func (m *ListOrdersResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListOrdersResponse.Merge(m, src)
 }
-func (m *PlaceOrderResponse) XXX_Size() int {
-	return xxx_messageInfo_PlaceOrderResponse.Size(m)
+func (m *ListOrdersResponse) XXX_Size() int {
+	return xxx_messageInfo_ListOrdersResponse.Size(m)
 }
-func (m *PlaceOrderResponse) XXX_DiscardUnknown() {
-	xxx_messageInfo_PlaceOrderResponse.DiscardUnknown(m)
+func (m *ListOrdersResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListOrdersResponse.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_PlaceOrderResponse proto.InternalMessageInfo
+var xxx_messageInfo_ListOrdersResponse proto.InternalMessageInfo
 
-func (m *PlaceOrderResponse) GetOrder() *OrderResult {
+func (m *ListOrdersResponse) GetOrders() []*StoredOrder {
 	if m != nil {
-		return m.Order
+		return m.Orders
 	}
 	return nil
 }
@@ -1430,7 +2597,7 @@ func (m *AdRequest) Reset()         { *m = AdRequest{} }
 func (m *AdRequest) String() string { return proto.CompactTextString(m) }
 func (*AdRequest) ProtoMessage()    {}
 func (*AdRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{29}
+	return fileDescriptor_ca53982754088a9d, []int{47}
 }
 
 func (m *AdRequest) XXX_Unmarshal(b []byte) error {
@@ -1469,7 +2636,7 @@ func (m *AdResponse) Reset()         { *m = AdResponse{} }
 func (m *AdResponse) String() string { return proto.CompactTextString(m) }
 func (*AdResponse) ProtoMessage()    {}
 func (*AdResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{30}
+	return fileDescriptor_ca53982754088a9d, []int{48}
 }
 
 func (m *AdResponse) XXX_Unmarshal(b []byte) error {
@@ -1511,7 +2678,7 @@ func (m *Ad) Reset()         { *m = Ad{} }
 func (m *Ad) String() string { return proto.CompactTextString(m) }
 func (*Ad) ProtoMessage()    {}
 func (*Ad) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ca53982754088a9d, []int{31}
+	return fileDescriptor_ca53982754088a9d, []int{49}
 }
 
 func (m *Ad) XXX_Unmarshal(b []byte) error {
@@ -1547,23 +2714,41 @@ func (m *Ad) GetText() string {
 }
 
 func init() {
+	proto.RegisterEnum("hipstershop.ShipmentEvent_Status", ShipmentEvent_Status_name, ShipmentEvent_Status_value)
+	proto.RegisterEnum("hipstershop.ItemRestriction_Action", ItemRestriction_Action_name, ItemRestriction_Action_value)
+	proto.RegisterEnum("hipstershop.GetLabelRequest_Format", GetLabelRequest_Format_name, GetLabelRequest_Format_value)
 	proto.RegisterType((*CartItem)(nil), "hipstershop.CartItem")
 	proto.RegisterType((*AddItemRequest)(nil), "hipstershop.AddItemRequest")
 	proto.RegisterType((*EmptyCartRequest)(nil), "hipstershop.EmptyCartRequest")
 	proto.RegisterType((*GetCartRequest)(nil), "hipstershop.GetCartRequest")
+	proto.RegisterType((*UpdateItemQuantityRequest)(nil), "hipstershop.UpdateItemQuantityRequest")
+	proto.RegisterType((*RemoveItemRequest)(nil), "hipstershop.RemoveItemRequest")
 	proto.RegisterType((*Cart)(nil), "hipstershop.Cart")
 	proto.RegisterType((*Empty)(nil), "hipstershop.Empty")
 	proto.RegisterType((*ListRecommendationsRequest)(nil), "hipstershop.ListRecommendationsRequest")
 	proto.RegisterType((*ListRecommendationsResponse)(nil), "hipstershop.ListRecommendationsResponse")
 	proto.RegisterType((*Product)(nil), "hipstershop.Product")
+	proto.RegisterMapType((map[string]string)(nil), "hipstershop.Product.AttributesEntry")
 	proto.RegisterType((*ListProductsResponse)(nil), "hipstershop.ListProductsResponse")
 	proto.RegisterType((*GetProductRequest)(nil), "hipstershop.GetProductRequest")
 	proto.RegisterType((*SearchProductsRequest)(nil), "hipstershop.SearchProductsRequest")
 	proto.RegisterType((*SearchProductsResponse)(nil), "hipstershop.SearchProductsResponse")
 	proto.RegisterType((*GetQuoteRequest)(nil), "hipstershop.GetQuoteRequest")
 	proto.RegisterType((*GetQuoteResponse)(nil), "hipstershop.GetQuoteResponse")
+	proto.RegisterType((*ShippingOption)(nil), "hipstershop.ShippingOption")
 	proto.RegisterType((*ShipOrderRequest)(nil), "hipstershop.ShipOrderRequest")
 	proto.RegisterType((*ShipOrderResponse)(nil), "hipstershop.ShipOrderResponse")
+	proto.RegisterType((*TrackShipmentRequest)(nil), "hipstershop.TrackShipmentRequest")
+	proto.RegisterType((*ShipmentEvent)(nil), "hipstershop.ShipmentEvent")
+	proto.RegisterType((*TrackShipmentResponse)(nil), "hipstershop.TrackShipmentResponse")
+	proto.RegisterType((*CheckRestrictionsRequest)(nil), "hipstershop.CheckRestrictionsRequest")
+	proto.RegisterType((*ItemRestriction)(nil), "hipstershop.ItemRestriction")
+	proto.RegisterType((*CheckRestrictionsResponse)(nil), "hipstershop.CheckRestrictionsResponse")
+	proto.RegisterType((*GetLabelRequest)(nil), "hipstershop.GetLabelRequest")
+	proto.RegisterType((*GetLabelResponse)(nil), "hipstershop.GetLabelResponse")
+	proto.RegisterType((*ValidateAddressRequest)(nil), "hipstershop.ValidateAddressRequest")
+	proto.RegisterType((*AddressFieldError)(nil), "hipstershop.AddressFieldError")
+	proto.RegisterType((*ValidateAddressResponse)(nil), "hipstershop.ValidateAddressResponse")
 	proto.RegisterType((*Address)(nil), "hipstershop.Address")
 	proto.RegisterType((*Money)(nil), "hipstershop.Money")
 	proto.RegisterType((*GetSupportedCurrenciesResponse)(nil), "hipstershop.GetSupportedCurrenciesResponse")
@@ -1576,6 +2761,10 @@ func init() {
 	proto.RegisterType((*SendOrderConfirmationRequest)(nil), "hipstershop.SendOrderConfirmationRequest")
 	proto.RegisterType((*PlaceOrderRequest)(nil), "hipstershop.PlaceOrderRequest")
 	proto.RegisterType((*PlaceOrderResponse)(nil), "hipstershop.PlaceOrderResponse")
+	proto.RegisterType((*StoredOrder)(nil), "hipstershop.StoredOrder")
+	proto.RegisterType((*GetOrderRequest)(nil), "hipstershop.GetOrderRequest")
+	proto.RegisterType((*ListOrdersRequest)(nil), "hipstershop.ListOrdersRequest")
+	proto.RegisterType((*ListOrdersResponse)(nil), "hipstershop.ListOrdersResponse")
 	proto.RegisterType((*AdRequest)(nil), "hipstershop.AdRequest")
 	proto.RegisterType((*AdResponse)(nil), "hipstershop.AdResponse")
 	proto.RegisterType((*Ad)(nil), "hipstershop.Ad")
@@ -1596,6 +2785,12 @@ type CartServiceClient interface {
 	AddItem(ctx context.Context, in *AddItemRequest, opts ...grpc.CallOption) (*Empty, error)
 	GetCart(ctx context.Context, in *GetCartRequest, opts ...grpc.CallOption) (*Cart, error)
 	EmptyCart(ctx context.Context, in *EmptyCartRequest, opts ...grpc.CallOption) (*Empty, error)
+	// Sets the quantity of a product already in the cart; 0 removes it.
+	// Fails with NOT_FOUND if the product is not in the cart.
+	UpdateItemQuantity(ctx context.Context, in *UpdateItemQuantityRequest, opts ...grpc.CallOption) (*Empty, error)
+	// Removes a product from the cart. Removing a product that is not in the
+	// cart succeeds.
+	RemoveItem(ctx context.Context, in *RemoveItemRequest, opts ...grpc.CallOption) (*Empty, error)
 }
 
 type cartServiceClient struct {
@@ -1633,11 +2828,35 @@ func (c *cartServiceClient) EmptyCart(ctx context.Context, in *EmptyCartRequest,
 	return out, nil
 }
 
+func (c *cartServiceClient) UpdateItemQuantity(ctx context.Context, in *UpdateItemQuantityRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/hipstershop.CartService/UpdateItemQuantity", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cartServiceClient) RemoveItem(ctx context.Context, in *RemoveItemRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/hipstershop.CartService/RemoveItem", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // CartServiceServer is the server API for CartService service.
 type CartServiceServer interface {
 	AddItem(context.Context, *AddItemRequest) (*Empty, error)
 	GetCart(context.Context, *GetCartRequest) (*Cart, error)
 	EmptyCart(context.Context, *EmptyCartRequest) (*Empty, error)
+	// Sets the quantity of a product already in the cart; 0 removes it.
+	// Fails with NOT_FOUND if the product is not in the cart.
+	UpdateItemQuantity(context.Context, *UpdateItemQuantityRequest) (*Empty, error)
+	// Removes a product from the cart. Removing a product that is not in the
+	// cart succeeds.
+	RemoveItem(context.Context, *RemoveItemRequest) (*Empty, error)
 }
 
 func RegisterCartServiceServer(s *grpc.Server, srv CartServiceServer) {
@@ -1698,6 +2917,42 @@ func _CartService_EmptyCart_Handler(srv interface{}, ctx context.Context, dec fu
 	return interceptor(ctx, in, info, handler)
 }
 
+func _CartService_UpdateItemQuantity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateItemQuantityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CartServiceServer).UpdateItemQuantity(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hipstershop.CartService/UpdateItemQuantity",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CartServiceServer).UpdateItemQuantity(ctx, req.(*UpdateItemQuantityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CartService_RemoveItem_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RemoveItemRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CartServiceServer).RemoveItem(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hipstershop.CartService/RemoveItem",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CartServiceServer).RemoveItem(ctx, req.(*RemoveItemRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _CartService_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "hipstershop.CartService",
 	HandlerType: (*CartServiceServer)(nil),
@@ -1714,6 +2969,14 @@ var _CartService_serviceDesc = grpc.ServiceDesc{
 			MethodName: "EmptyCart",
 			Handler:    _CartService_EmptyCart_Handler,
 		},
+		{
+			MethodName: "UpdateItemQuantity",
+			Handler:    _CartService_UpdateItemQuantity_Handler,
+		},
+		{
+			MethodName: "RemoveItem",
+			Handler:    _CartService_RemoveItem_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "demo.proto",
@@ -1919,6 +3182,19 @@ var _ProductCatalogService_serviceDesc = grpc.ServiceDesc{
 type ShippingServiceClient interface {
 	GetQuote(ctx context.Context, in *GetQuoteRequest, opts ...grpc.CallOption) (*GetQuoteResponse, error)
 	ShipOrder(ctx context.Context, in *ShipOrderRequest, opts ...grpc.CallOption) (*ShipOrderResponse, error)
+	// TrackShipment returns a shipment's status history; NOT_FOUND for
+	// unknown tracking IDs and INVALID_ARGUMENT for malformed ones.
+	TrackShipment(ctx context.Context, in *TrackShipmentRequest, opts ...grpc.CallOption) (*TrackShipmentResponse, error)
+	// ValidateAddress checks an address and returns it normalized, or what
+	// is wrong with it.
+	ValidateAddress(ctx context.Context, in *ValidateAddressRequest, opts ...grpc.CallOption) (*ValidateAddressResponse, error)
+	// CheckRestrictions lists the shipping restrictions that apply to items
+	// going to address. GetQuote and ShipOrder refuse items with DENY ones
+	// as FAILED_PRECONDITION.
+	CheckRestrictions(ctx context.Context, in *CheckRestrictionsRequest, opts ...grpc.CallOption) (*CheckRestrictionsResponse, error)
+	// GetLabel returns a shipment's shipping label or packing slip for the
+	// warehouse to print; NOT_FOUND for unknown tracking IDs.
+	GetLabel(ctx context.Context, in *GetLabelRequest, opts ...grpc.CallOption) (*GetLabelResponse, error)
 }
 
 type shippingServiceClient struct {
@@ -1947,10 +3223,59 @@ func (c *shippingServiceClient) ShipOrder(ctx context.Context, in *ShipOrderRequ
 	return out, nil
 }
 
+func (c *shippingServiceClient) TrackShipment(ctx context.Context, in *TrackShipmentRequest, opts ...grpc.CallOption) (*TrackShipmentResponse, error) {
+	out := new(TrackShipmentResponse)
+	err := c.cc.Invoke(ctx, "/hipstershop.ShippingService/TrackShipment", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shippingServiceClient) ValidateAddress(ctx context.Context, in *ValidateAddressRequest, opts ...grpc.CallOption) (*ValidateAddressResponse, error) {
+	out := new(ValidateAddressResponse)
+	err := c.cc.Invoke(ctx, "/hipstershop.ShippingService/ValidateAddress", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shippingServiceClient) CheckRestrictions(ctx context.Context, in *CheckRestrictionsRequest, opts ...grpc.CallOption) (*CheckRestrictionsResponse, error) {
+	out := new(CheckRestrictionsResponse)
+	err := c.cc.Invoke(ctx, "/hipstershop.ShippingService/CheckRestrictions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shippingServiceClient) GetLabel(ctx context.Context, in *GetLabelRequest, opts ...grpc.CallOption) (*GetLabelResponse, error) {
+	out := new(GetLabelResponse)
+	err := c.cc.Invoke(ctx, "/hipstershop.ShippingService/GetLabel", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ShippingServiceServer is the server API for ShippingService service.
 type ShippingServiceServer interface {
 	GetQuote(context.Context, *GetQuoteRequest) (*GetQuoteResponse, error)
 	ShipOrder(context.Context, *ShipOrderRequest) (*ShipOrderResponse, error)
+	// TrackShipment returns a shipment's status history; NOT_FOUND for
+	// unknown tracking IDs and INVALID_ARGUMENT for malformed ones.
+	TrackShipment(context.Context, *TrackShipmentRequest) (*TrackShipmentResponse, error)
+	// ValidateAddress checks an address and returns it normalized, or what
+	// is wrong with it.
+	ValidateAddress(context.Context, *ValidateAddressRequest) (*ValidateAddressResponse, error)
+	// CheckRestrictions lists the shipping restrictions that apply to items
+	// going to address. GetQuote and ShipOrder refuse items with DENY ones
+	// as FAILED_PRECONDITION.
+	CheckRestrictions(context.Context, *CheckRestrictionsRequest) (*CheckRestrictionsResponse, error)
+	// GetLabel returns a shipment's shipping label or packing slip for the
+	// warehouse to print; NOT_FOUND for unknown tracking IDs.
+	GetLabel(context.Context, *GetLabelRequest) (*GetLabelResponse, error)
 }
 
 func RegisterShippingServiceServer(s *grpc.Server, srv ShippingServiceServer) {
@@ -1993,6 +3318,78 @@ func _ShippingService_ShipOrder_Handler(srv interface{}, ctx context.Context, de
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ShippingService_TrackShipment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TrackShipmentRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShippingServiceServer).TrackShipment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hipstershop.ShippingService/TrackShipment",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShippingServiceServer).TrackShipment(ctx, req.(*TrackShipmentRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShippingService_ValidateAddress_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ValidateAddressRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShippingServiceServer).ValidateAddress(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hipstershop.ShippingService/ValidateAddress",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShippingServiceServer).ValidateAddress(ctx, req.(*ValidateAddressRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShippingService_CheckRestrictions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CheckRestrictionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShippingServiceServer).CheckRestrictions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hipstershop.ShippingService/CheckRestrictions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShippingServiceServer).CheckRestrictions(ctx, req.(*CheckRestrictionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ShippingService_GetLabel_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLabelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShippingServiceServer).GetLabel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hipstershop.ShippingService/GetLabel",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShippingServiceServer).GetLabel(ctx, req.(*GetLabelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _ShippingService_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "hipstershop.ShippingService",
 	HandlerType: (*ShippingServiceServer)(nil),
@@ -2005,6 +3402,22 @@ var _ShippingService_serviceDesc = grpc.ServiceDesc{
 			MethodName: "ShipOrder",
 			Handler:    _ShippingService_ShipOrder_Handler,
 		},
+		{
+			MethodName: "TrackShipment",
+			Handler:    _ShippingService_TrackShipment_Handler,
+		},
+		{
+			MethodName: "ValidateAddress",
+			Handler:    _ShippingService_ValidateAddress_Handler,
+		},
+		{
+			MethodName: "CheckRestrictions",
+			Handler:    _ShippingService_CheckRestrictions_Handler,
+		},
+		{
+			MethodName: "GetLabel",
+			Handler:    _ShippingService_GetLabel_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "demo.proto",
@@ -2240,6 +3653,11 @@ var _EmailService_serviceDesc = grpc.ServiceDesc{
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
 type CheckoutServiceClient interface {
 	PlaceOrder(ctx context.Context, in *PlaceOrderRequest, opts ...grpc.CallOption) (*PlaceOrderResponse, error)
+	// Returns an order placed with PlaceOrder, or fails with NOT_FOUND. When
+	// user_id is set, other users' orders are NOT_FOUND as well.
+	GetOrder(ctx context.Context, in *GetOrderRequest, opts ...grpc.CallOption) (*StoredOrder, error)
+	// Returns a user's orders, newest first.
+	ListOrders(ctx context.Context, in *ListOrdersRequest, opts ...grpc.CallOption) (*ListOrdersResponse, error)
 }
 
 type checkoutServiceClient struct {
@@ -2259,9 +3677,32 @@ func (c *checkoutServiceClient) PlaceOrder(ctx context.Context, in *PlaceOrderRe
 	return out, nil
 }
 
+func (c *checkoutServiceClient) GetOrder(ctx context.Context, in *GetOrderRequest, opts ...grpc.CallOption) (*StoredOrder, error) {
+	out := new(StoredOrder)
+	err := c.cc.Invoke(ctx, "/hipstershop.CheckoutService/GetOrder", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *checkoutServiceClient) ListOrders(ctx context.Context, in *ListOrdersRequest, opts ...grpc.CallOption) (*ListOrdersResponse, error) {
+	out := new(ListOrdersResponse)
+	err := c.cc.Invoke(ctx, "/hipstershop.CheckoutService/ListOrders", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // CheckoutServiceServer is the server API for CheckoutService service.
 type CheckoutServiceServer interface {
 	PlaceOrder(context.Context, *PlaceOrderRequest) (*PlaceOrderResponse, error)
+	// Returns an order placed with PlaceOrder, or fails with NOT_FOUND. When
+	// user_id is set, other users' orders are NOT_FOUND as well.
+	GetOrder(context.Context, *GetOrderRequest) (*StoredOrder, error)
+	// Returns a user's orders, newest first.
+	ListOrders(context.Context, *ListOrdersRequest) (*ListOrdersResponse, error)
 }
 
 func RegisterCheckoutServiceServer(s *grpc.Server, srv CheckoutServiceServer) {
@@ -2286,6 +3727,42 @@ func _CheckoutService_PlaceOrder_Handler(srv interface{}, ctx context.Context, d
 	return interceptor(ctx, in, info, handler)
 }
 
+func _CheckoutService_GetOrder_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetOrderRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckoutServiceServer).GetOrder(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hipstershop.CheckoutService/GetOrder",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckoutServiceServer).GetOrder(ctx, req.(*GetOrderRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CheckoutService_ListOrders_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListOrdersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CheckoutServiceServer).ListOrders(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/hipstershop.CheckoutService/ListOrders",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CheckoutServiceServer).ListOrders(ctx, req.(*ListOrdersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _CheckoutService_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "hipstershop.CheckoutService",
 	HandlerType: (*CheckoutServiceServer)(nil),
@@ -2294,6 +3771,14 @@ var _CheckoutService_serviceDesc = grpc.ServiceDesc{
 			MethodName: "PlaceOrder",
 			Handler:    _CheckoutService_PlaceOrder_Handler,
 		},
+		{
+			MethodName: "GetOrder",
+			Handler:    _CheckoutService_GetOrder_Handler,
+		},
+		{
+			MethodName: "ListOrders",
+			Handler:    _CheckoutService_ListOrders_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "demo.proto",
@@ -2366,99 +3851,169 @@ var _AdService_serviceDesc = grpc.ServiceDesc{
 func init() { proto.RegisterFile("demo.proto", fileDescriptor_ca53982754088a9d) }
 
 var fileDescriptor_ca53982754088a9d = []byte{
-	// 1500 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xbc, 0x58, 0xef, 0x72, 0x13, 0xb7,
-	0x16, 0xcf, 0x26, 0xb1, 0x1d, 0x1f, 0xc7, 0x4e, 0xa2, 0x9b, 0x04, 0xb3, 0x81, 0x10, 0x94, 0x81,
-	0x0b, 0x17, 0x08, 0x4c, 0xee, 0x9d, 0xe1, 0x03, 0xdc, 0xd2, 0x8c, 0xc9, 0x18, 0x4f, 0xa1, 0xd0,
-	0x0d, 0xe9, 0xd0, 0xa1, 0x53, 0xcf, 0xb2, 0x12, 0xf1, 0x96, 0xec, 0x6a, 0x91, 0xb4, 0x19, 0xcc,
-	0xc7, 0xf6, 0x01, 0xfa, 0x1e, 0x7d, 0x81, 0xce, 0xf4, 0x11, 0xfa, 0xbd, 0xaf, 0xd0, 0xe7, 0xe8,
-	0x48, 0xbb, 0xda, 0x7f, 0xb1, 0x13, 0xf8, 0xd2, 0x6f, 0xab, 0xa3, 0x9f, 0xce, 0xf9, 0xe9, 0xe8,
-	0xfc, 0xb3, 0x01, 0x08, 0x0d, 0xd8, 0x4e, 0xc4, 0x99, 0x64, 0xa8, 0x35, 0xf2, 0x23, 0x21, 0x29,
-	0x17, 0x23, 0x16, 0xe1, 0x7d, 0x58, 0xe8, 0xb9, 0x5c, 0x0e, 0x24, 0x0d, 0xd0, 0x65, 0x80, 0x88,
-	0x33, 0x12, 0x7b, 0x72, 0xe8, 0x93, 0xae, 0xb5, 0x65, 0xdd, 0x68, 0x3a, 0xcd, 0x54, 0x32, 0x20,
-	0xc8, 0x86, 0x85, 0xf7, 0xb1, 0x1b, 0x4a, 0x5f, 0x8e, 0xbb, 0xb3, 0x5b, 0xd6, 0x8d, 0x9a, 0x93,
-	0xad, 0xf1, 0x4b, 0xe8, 0xec, 0x11, 0xa2, 0xb4, 0x38, 0xf4, 0x7d, 0x4c, 0x85, 0x44, 0x17, 0xa0,
-	0x11, 0x0b, 0xca, 0x73, 0x4d, 0x75, 0xb5, 0x1c, 0x10, 0x74, 0x13, 0xe6, 0x7d, 0x49, 0x03, 0xad,
-	0xa2, 0xb5, 0xbb, 0xb6, 0x53, 0x60, 0xb3, 0x63, 0xa8, 0x38, 0x1a, 0x82, 0x6f, 0xc1, 0xf2, 0x7e,
-	0x10, 0xc9, 0xb1, 0x12, 0x9f, 0xa7, 0x17, 0xdf, 0x84, 0x4e, 0x9f, 0xca, 0x4f, 0x82, 0x3e, 0x85,
-	0x79, 0x85, 0x9b, 0xce, 0xf1, 0x16, 0xd4, 0x14, 0x01, 0xd1, 0x9d, 0xdd, 0x9a, 0x9b, 0x4e, 0x32,
-	0xc1, 0xe0, 0x06, 0xd4, 0x34, 0x4b, 0xfc, 0x2d, 0xd8, 0x4f, 0x7d, 0x21, 0x1d, 0xea, 0xb1, 0x20,
-	0xa0, 0x21, 0x71, 0xa5, 0xcf, 0x42, 0x71, 0xae, 0x43, 0xae, 0x40, 0x2b, 0x77, 0x7b, 0x62, 0xb2,
-	0xe9, 0x40, 0xe6, 0x77, 0x81, 0xbf, 0x80, 0x8d, 0x89, 0x7a, 0x45, 0xc4, 0x42, 0x41, 0xab, 0xe7,
-	0xad, 0x53, 0xe7, 0x7f, 0xb7, 0xa0, 0xf1, 0x22, 0x59, 0xa2, 0x0e, 0xcc, 0x66, 0x04, 0x66, 0x7d,
-	0x82, 0x10, 0xcc, 0x87, 0x6e, 0x40, 0xf5, 0x6b, 0x34, 0x1d, 0xfd, 0x8d, 0xb6, 0xa0, 0x45, 0xa8,
-	0xf0, 0xb8, 0x1f, 0x29, 0x43, 0xdd, 0x39, 0xbd, 0x55, 0x14, 0xa1, 0x2e, 0x34, 0x22, 0xdf, 0x93,
-	0x31, 0xa7, 0xdd, 0x79, 0xbd, 0x6b, 0x96, 0xe8, 0x2e, 0x34, 0x23, 0xee, 0x7b, 0x74, 0x18, 0x0b,
-	0xd2, 0xad, 0xe9, 0x27, 0x46, 0x25, 0xef, 0x3d, 0x63, 0x21, 0x1d, 0x3b, 0x0b, 0x1a, 0x74, 0x28,
-	0x08, 0xda, 0x04, 0xf0, 0x5c, 0x49, 0x8f, 0x18, 0xf7, 0xa9, 0xe8, 0xd6, 0x13, 0xf2, 0xb9, 0x04,
-	0x3f, 0x81, 0x55, 0x75, 0xf9, 0x94, 0x7f, 0x7e, 0xeb, 0x7b, 0xb0, 0x90, 0x5e, 0x31, 0xb9, 0x72,
-	0x6b, 0x77, 0xb5, 0x64, 0x27, 0x3d, 0xe0, 0x64, 0x28, 0xbc, 0x0d, 0x2b, 0x7d, 0x6a, 0x14, 0x99,
-	0x57, 0xa9, 0xf8, 0x03, 0xdf, 0x81, 0xb5, 0x03, 0xea, 0x72, 0x6f, 0x94, 0x1b, 0x4c, 0x80, 0xab,
-	0x50, 0x7b, 0x1f, 0x53, 0x3e, 0x4e, 0xb1, 0xc9, 0x02, 0x3f, 0x81, 0xf5, 0x2a, 0x3c, 0xe5, 0xb7,
-	0x03, 0x0d, 0x4e, 0x45, 0x7c, 0x7c, 0x0e, 0x3d, 0x03, 0xc2, 0x21, 0x2c, 0xf5, 0xa9, 0xfc, 0x26,
-	0x66, 0x92, 0x1a, 0x93, 0x3b, 0xd0, 0x70, 0x09, 0xe1, 0x54, 0x08, 0x6d, 0xb4, 0xaa, 0x62, 0x2f,
-	0xd9, 0x73, 0x0c, 0xe8, 0xf3, 0xa2, 0x76, 0x0f, 0x96, 0x73, 0x7b, 0x29, 0xe7, 0x3b, 0xb0, 0xe0,
-	0x31, 0x21, 0xf5, 0xdb, 0x59, 0x53, 0xdf, 0xae, 0xa1, 0x30, 0x87, 0x82, 0x60, 0x06, 0xcb, 0x07,
-	0x23, 0x3f, 0x7a, 0xce, 0x09, 0xe5, 0xff, 0x08, 0xe7, 0xff, 0xc1, 0x4a, 0xc1, 0x60, 0x1e, 0xfe,
-	0x92, 0xbb, 0xde, 0x3b, 0x3f, 0x3c, 0xca, 0x73, 0x0b, 0x8c, 0x68, 0x40, 0xf0, 0x2f, 0x16, 0x34,
-	0x52, 0xbb, 0xe8, 0x1a, 0x74, 0x84, 0xe4, 0x94, 0xca, 0x61, 0x91, 0x65, 0xd3, 0x69, 0x27, 0x52,
-	0x03, 0x43, 0x30, 0xef, 0x99, 0x32, 0xd7, 0x74, 0xf4, 0xb7, 0x0a, 0x00, 0x21, 0x5d, 0x49, 0xd3,
-	0x7c, 0x48, 0x16, 0x2a, 0x13, 0x3c, 0x16, 0x87, 0x92, 0x8f, 0x4d, 0x26, 0xa4, 0x4b, 0x74, 0x11,
-	0x16, 0x3e, 0xfa, 0xd1, 0xd0, 0x63, 0x84, 0xea, 0x44, 0xa8, 0x39, 0x8d, 0x8f, 0x7e, 0xd4, 0x63,
-	0x84, 0xe2, 0x57, 0x50, 0xd3, 0xae, 0x44, 0xdb, 0xd0, 0xf6, 0x62, 0xce, 0x69, 0xe8, 0x8d, 0x13,
-	0x60, 0xc2, 0x66, 0xd1, 0x08, 0x15, 0x5a, 0x19, 0x8e, 0x43, 0x5f, 0x0a, 0xcd, 0x66, 0xce, 0x49,
-	0x16, 0x4a, 0x1a, 0xba, 0x21, 0x13, 0x9a, 0x4e, 0xcd, 0x49, 0x16, 0xb8, 0x0f, 0x9b, 0x7d, 0x2a,
-	0x0f, 0xe2, 0x28, 0x62, 0x5c, 0x52, 0xd2, 0x4b, 0xf4, 0xf8, 0x34, 0x8f, 0xcb, 0x6b, 0xd0, 0x29,
-	0x99, 0x34, 0x05, 0xa3, 0x5d, 0xb4, 0x29, 0xf0, 0xf7, 0x70, 0xb1, 0x97, 0x09, 0xc2, 0x13, 0xca,
-	0x85, 0xcf, 0x42, 0xf3, 0xc8, 0xd7, 0x61, 0xfe, 0x2d, 0x67, 0xc1, 0x19, 0x31, 0xa2, 0xf7, 0x55,
-	0xc9, 0x93, 0x2c, 0xb9, 0x58, 0xe2, 0xc9, 0xba, 0x64, 0xda, 0x01, 0x7f, 0x59, 0xd0, 0xe9, 0x71,
-	0x4a, 0x7c, 0x55, 0xaf, 0xc9, 0x20, 0x7c, 0xcb, 0xd0, 0x6d, 0x40, 0x9e, 0x96, 0x0c, 0x3d, 0x97,
-	0x93, 0x61, 0x18, 0x07, 0x6f, 0x28, 0x4f, 0xfd, 0xb1, 0xec, 0x65, 0xd8, 0xaf, 0xb5, 0x1c, 0x5d,
-	0x87, 0xa5, 0x22, 0xda, 0x3b, 0x39, 0x49, 0x5b, 0x52, 0x3b, 0x87, 0xf6, 0x4e, 0x4e, 0xd0, 0xff,
-	0x61, 0xa3, 0x88, 0xa3, 0x1f, 0x22, 0x9f, 0xeb, 0xf2, 0x39, 0x1c, 0x53, 0x97, 0xa7, 0xbe, 0xeb,
-	0xe6, 0x67, 0xf6, 0x33, 0xc0, 0x77, 0xd4, 0xe5, 0xe8, 0x11, 0x5c, 0x9a, 0x72, 0x3c, 0x60, 0xa1,
-	0x1c, 0xe9, 0x27, 0xaf, 0x39, 0x17, 0x27, 0x9d, 0x7f, 0xa6, 0x00, 0x78, 0x0c, 0xed, 0xde, 0xc8,
-	0xe5, 0x47, 0x59, 0x4e, 0xff, 0x07, 0xea, 0x6e, 0xa0, 0x22, 0xe4, 0x0c, 0xe7, 0xa5, 0x08, 0xf4,
-	0x10, 0x5a, 0x05, 0xeb, 0x69, 0xc3, 0xdc, 0x28, 0x67, 0x48, 0xc9, 0x89, 0x0e, 0xe4, 0x4c, 0xf0,
-	0x7d, 0xe8, 0x18, 0xd3, 0xf9, 0xd3, 0x4b, 0xee, 0x86, 0xc2, 0xf5, 0xf4, 0x15, 0xb2, 0x64, 0x69,
-	0x17, 0xa4, 0x03, 0x82, 0x7f, 0x80, 0xa6, 0xce, 0x30, 0x3d, 0x13, 0x98, 0x6e, 0x6d, 0x9d, 0xdb,
-	0xad, 0x55, 0x54, 0xa8, 0xca, 0x90, 0xf2, 0x9c, 0x18, 0x15, 0x6a, 0x1f, 0xff, 0x34, 0x0b, 0x2d,
-	0x93, 0xc2, 0xf1, 0xb1, 0x54, 0x89, 0xc2, 0xd4, 0x32, 0x27, 0xd4, 0xd0, 0xeb, 0x01, 0x41, 0xf7,
-	0x60, 0x55, 0x8c, 0xfc, 0x28, 0x52, 0xb9, 0x5d, 0x4c, 0xf2, 0x24, 0x9a, 0x90, 0xd9, 0x7b, 0x99,
-	0x25, 0x3b, 0xba, 0x0f, 0xed, 0xec, 0x84, 0x66, 0x33, 0x37, 0x95, 0xcd, 0xa2, 0x01, 0xf6, 0x98,
-	0x90, 0xe8, 0x11, 0x2c, 0x67, 0x07, 0x4d, 0x6d, 0x98, 0x3f, 0xa3, 0x82, 0x2d, 0x19, 0xb4, 0xa9,
-	0x19, 0xb7, 0x4d, 0x25, 0xab, 0xe9, 0x4a, 0xb6, 0x5e, 0x3a, 0x95, 0x39, 0xd4, 0x94, 0x32, 0x02,
-	0x97, 0x0e, 0x68, 0x48, 0xb4, 0xbc, 0xc7, 0xc2, 0xb7, 0x3e, 0x0f, 0x74, 0xd8, 0x14, 0xda, 0x0d,
-	0x0d, 0x5c, 0xff, 0xd8, 0xb4, 0x1b, 0xbd, 0x40, 0x3b, 0x50, 0xd3, 0xae, 0x49, 0x7d, 0xdc, 0x3d,
-	0x6d, 0x23, 0xf1, 0xa9, 0x93, 0xc0, 0xf0, 0x9f, 0x16, 0xac, 0xbc, 0x38, 0x76, 0x3d, 0x5a, 0xaa,
-	0xd1, 0x53, 0x27, 0x91, 0x6d, 0x68, 0xeb, 0x0d, 0x53, 0x0a, 0x52, 0x3f, 0x2f, 0x2a, 0xa1, 0xa9,
-	0x06, 0xc5, 0x0a, 0x3f, 0xf7, 0x29, 0x15, 0x3e, 0xbb, 0x49, 0xad, 0x78, 0x93, 0x4a, 0x6c, 0xd7,
-	0x3f, 0x2f, 0xb6, 0x1f, 0x03, 0x2a, 0x5e, 0x2b, 0x6b, 0xb9, 0xa9, 0x77, 0xac, 0x4f, 0xf3, 0xce,
-	0x0e, 0x34, 0xf7, 0x88, 0x71, 0xca, 0x55, 0x58, 0xf4, 0x58, 0x28, 0xe9, 0x07, 0x39, 0x7c, 0x47,
-	0xc7, 0xa6, 0x2a, 0xb6, 0x52, 0xd9, 0x57, 0x74, 0x2c, 0xf0, 0x5d, 0x00, 0x85, 0x4f, 0xad, 0x5d,
-	0x85, 0x39, 0x97, 0x98, 0xe6, 0xbe, 0x54, 0xf1, 0x81, 0xa3, 0xf6, 0xf0, 0x03, 0x98, 0xdd, 0x23,
-	0x4a, 0xb3, 0x62, 0xce, 0xa9, 0x27, 0x87, 0x31, 0x37, 0x2f, 0xda, 0x32, 0xb2, 0x43, 0x7e, 0xac,
-	0xfa, 0x8d, 0xb2, 0x62, 0xfa, 0x8d, 0xfa, 0xde, 0xfd, 0xc3, 0x82, 0x96, 0xca, 0xb0, 0x03, 0xca,
-	0x4f, 0x7c, 0x8f, 0xa2, 0x87, 0xba, 0x8b, 0xe9, 0xa4, 0xdc, 0xa8, 0x7a, 0xbc, 0x30, 0x78, 0xdb,
-	0xe5, 0x50, 0x4f, 0x26, 0xd3, 0x19, 0xf4, 0x00, 0x1a, 0xe9, 0x74, 0x5c, 0x39, 0x5d, 0x9e, 0x99,
-	0xed, 0x95, 0x53, 0x19, 0x8e, 0x67, 0xd0, 0x97, 0xd0, 0xcc, 0xe6, 0x70, 0x74, 0xf9, 0xb4, 0xfe,
-	0xa2, 0x82, 0x89, 0xe6, 0x77, 0x7f, 0xb6, 0x60, 0xad, 0x3c, 0xbf, 0x9a, 0x6b, 0xfd, 0x08, 0xff,
-	0x9a, 0x30, 0xdc, 0xa2, 0x7f, 0x97, 0xd4, 0x4c, 0x1f, 0xab, 0xed, 0x1b, 0xe7, 0x03, 0x93, 0x07,
-	0x53, 0x2c, 0x66, 0x61, 0x2d, 0x1d, 0xbc, 0x7a, 0xae, 0x74, 0x8f, 0xd9, 0x91, 0x61, 0xd1, 0x87,
-	0xc5, 0xe2, 0x94, 0x89, 0x26, 0xdc, 0xc2, 0xbe, 0x7a, 0xca, 0x52, 0x75, 0xe8, 0xc3, 0x33, 0xe8,
-	0x31, 0x40, 0x3e, 0x64, 0xa2, 0xcd, 0xaa, 0xab, 0xcb, 0xd3, 0xa7, 0x3d, 0x71, 0x26, 0xc4, 0x33,
-	0xe8, 0x35, 0x74, 0xca, 0x63, 0x25, 0xc2, 0x25, 0xe4, 0xc4, 0x11, 0xd5, 0xde, 0x3e, 0x13, 0x93,
-	0x79, 0xe1, 0x57, 0x0b, 0x96, 0x0e, 0xd2, 0xe2, 0x65, 0xee, 0x3f, 0x80, 0x05, 0x33, 0x0d, 0xa2,
-	0x4b, 0x55, 0xd2, 0xc5, 0xa1, 0xd4, 0xbe, 0x3c, 0x65, 0x37, 0xf3, 0xc0, 0x53, 0x68, 0x66, 0x43,
-	0x5a, 0x25, 0x58, 0xaa, 0xd3, 0xa2, 0xbd, 0x39, 0x6d, 0x3b, 0x23, 0xfb, 0x9b, 0x05, 0x4b, 0xa6,
-	0xf4, 0x18, 0xb2, 0xaf, 0x61, 0x7d, 0xf2, 0x90, 0x33, 0xf1, 0xd9, 0x6e, 0x55, 0x09, 0x9f, 0x31,
-	0x1d, 0xe1, 0x19, 0xd4, 0x87, 0x46, 0x32, 0xf0, 0x48, 0x74, 0xbd, 0x9c, 0x0b, 0xd3, 0xc6, 0x21,
-	0x7b, 0x42, 0x73, 0xc1, 0x33, 0xbb, 0x87, 0xd0, 0x79, 0xe1, 0x8e, 0x03, 0x1a, 0x66, 0x19, 0xdc,
-	0x83, 0x7a, 0xd2, 0x91, 0x91, 0x5d, 0xd6, 0x5c, 0x9c, 0x10, 0xec, 0x8d, 0x89, 0x7b, 0x99, 0x43,
-	0x46, 0xb0, 0xb8, 0xaf, 0x2a, 0xa8, 0x51, 0xfa, 0x4a, 0xfd, 0x60, 0x99, 0xd0, 0x48, 0xd0, 0xcd,
-	0x4a, 0x34, 0x4c, 0x6f, 0x36, 0x53, 0x72, 0xf6, 0x0d, 0x2c, 0xf5, 0x46, 0xd4, 0x7b, 0xc7, 0xe2,
-	0xec, 0x06, 0xcf, 0x01, 0xf2, 0xba, 0x5b, 0x89, 0xee, 0x53, 0x7d, 0xc6, 0xbe, 0x32, 0x75, 0x3f,
-	0xbb, 0xcd, 0x13, 0x55, 0x82, 0x8d, 0xf6, 0x07, 0x50, 0xef, 0xab, 0x19, 0x5c, 0xa0, 0xf5, 0x6a,
-	0x39, 0x4d, 0x35, 0x5e, 0x38, 0x25, 0x37, 0x9a, 0xde, 0xd4, 0xf5, 0x9f, 0x1b, 0xff, 0xfd, 0x3b,
-	0x00, 0x00, 0xff, 0xff, 0xb2, 0xa0, 0x6e, 0x6c, 0xea, 0x10, 0x00, 0x00,
+	// 2612 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xbc, 0x59, 0xcd, 0x6e, 0x23, 0xc7,
+	0xf1, 0x17, 0xbf, 0xc9, 0xa2, 0x48, 0x51, 0x6d, 0xed, 0x9a, 0xcb, 0xfd, 0xf0, 0x6e, 0xaf, 0xbd,
+	0x5e, 0xff, 0xd7, 0x96, 0x0d, 0xd9, 0x7f, 0x3b, 0xc9, 0xda, 0xb1, 0x69, 0x8a, 0x92, 0x09, 0xcb,
+	0x92, 0x3c, 0xa4, 0x8c, 0x75, 0x9c, 0x78, 0x30, 0x3b, 0xd3, 0x92, 0x26, 0x4b, 0xce, 0xd0, 0x3d,
+	0x3d, 0xb2, 0xb8, 0xd7, 0x00, 0x79, 0x83, 0x1c, 0x73, 0x4a, 0x82, 0x5c, 0x73, 0xcb, 0x2b, 0x04,
+	0xc8, 0x39, 0x08, 0x90, 0x63, 0x0e, 0x79, 0x87, 0x00, 0x39, 0x04, 0xfd, 0x35, 0x9c, 0x19, 0x72,
+	0x24, 0x6d, 0x80, 0xe4, 0xc4, 0xa9, 0xea, 0xea, 0xaa, 0xea, 0x5f, 0x55, 0x77, 0x57, 0x17, 0x01,
+	0x1c, 0x32, 0xf1, 0x37, 0xa7, 0xd4, 0x67, 0x3e, 0xaa, 0x9f, 0xba, 0xd3, 0x80, 0x11, 0x1a, 0x9c,
+	0xfa, 0x53, 0xdc, 0x87, 0x6a, 0xcf, 0xa2, 0x6c, 0xc0, 0xc8, 0x04, 0xdd, 0x06, 0x98, 0x52, 0xdf,
+	0x09, 0x6d, 0x66, 0xba, 0x4e, 0x3b, 0x77, 0x37, 0xf7, 0xb0, 0x66, 0xd4, 0x14, 0x67, 0xe0, 0xa0,
+	0x0e, 0x54, 0xbf, 0x0b, 0x2d, 0x8f, 0xb9, 0x6c, 0xd6, 0xce, 0xdf, 0xcd, 0x3d, 0x2c, 0x19, 0x11,
+	0x8d, 0x47, 0xd0, 0xec, 0x3a, 0x0e, 0xd7, 0x62, 0x90, 0xef, 0x42, 0x12, 0x30, 0xf4, 0x32, 0x54,
+	0xc2, 0x80, 0xd0, 0xb9, 0xa6, 0x32, 0x27, 0x07, 0x0e, 0x7a, 0x03, 0x8a, 0x2e, 0x23, 0x13, 0xa1,
+	0xa2, 0xbe, 0x75, 0x6d, 0x33, 0xe6, 0xcd, 0xa6, 0x76, 0xc5, 0x10, 0x22, 0xf8, 0x11, 0xb4, 0xfa,
+	0x93, 0x29, 0x9b, 0x71, 0xf6, 0x65, 0x7a, 0xf1, 0x1b, 0xd0, 0xdc, 0x25, 0xec, 0x4a, 0xa2, 0x3e,
+	0xdc, 0x38, 0x9a, 0x3a, 0x16, 0x23, 0xdc, 0xd6, 0x97, 0x6a, 0x0d, 0x97, 0x3a, 0x9e, 0x84, 0x27,
+	0x7f, 0x11, 0x3c, 0x85, 0x14, 0x3c, 0x9f, 0xc3, 0xba, 0x41, 0x26, 0xfe, 0x19, 0xb9, 0x12, 0x42,
+	0x17, 0x1b, 0xc2, 0x7b, 0x50, 0xe4, 0xab, 0xcc, 0x9e, 0xff, 0x08, 0x4a, 0x1c, 0xbe, 0xa0, 0x9d,
+	0xbf, 0x5b, 0xc8, 0x86, 0x58, 0xca, 0xe0, 0x0a, 0x94, 0x04, 0xc6, 0xf8, 0x2b, 0xe8, 0xec, 0xb9,
+	0x01, 0x33, 0x88, 0xed, 0x4f, 0x26, 0xc4, 0x73, 0x2c, 0xe6, 0xfa, 0x5e, 0x70, 0xa9, 0xb3, 0xaf,
+	0x40, 0x7d, 0xee, 0xac, 0x34, 0x59, 0x33, 0x20, 0xf2, 0x36, 0xc0, 0x3f, 0x86, 0x9b, 0x4b, 0xf5,
+	0x06, 0x53, 0xdf, 0x0b, 0x48, 0x7a, 0x7e, 0x6e, 0x61, 0xfe, 0x6f, 0x0b, 0x50, 0x39, 0x94, 0x24,
+	0x6a, 0x42, 0x3e, 0x72, 0x20, 0xef, 0x3a, 0x08, 0x41, 0xd1, 0xb3, 0x26, 0x44, 0x61, 0x24, 0xbe,
+	0xd1, 0x5d, 0xa8, 0x3b, 0x24, 0xb0, 0xa9, 0x3b, 0xe5, 0x86, 0x44, 0x28, 0x6a, 0x46, 0x9c, 0x85,
+	0xda, 0x50, 0x99, 0xba, 0x36, 0x0b, 0x29, 0x69, 0x17, 0xc5, 0xa8, 0x26, 0xd1, 0xdb, 0x50, 0x9b,
+	0x52, 0xd7, 0x26, 0x66, 0x18, 0x38, 0xed, 0x92, 0x48, 0x50, 0x94, 0x40, 0xef, 0x0b, 0xdf, 0x23,
+	0x33, 0xa3, 0x2a, 0x84, 0x8e, 0x02, 0x07, 0xdd, 0x01, 0xb0, 0x2d, 0x46, 0x4e, 0x7c, 0xea, 0x92,
+	0xa0, 0x5d, 0x96, 0xce, 0xcf, 0x39, 0xe8, 0x1e, 0xac, 0x4e, 0xac, 0x73, 0x33, 0x4a, 0x8c, 0x8a,
+	0x48, 0x8c, 0xfa, 0xc4, 0x3a, 0xd7, 0x69, 0xc7, 0x45, 0xbe, 0x27, 0xee, 0xc9, 0x29, 0x33, 0x4f,
+	0xa8, 0x35, 0x09, 0xda, 0x55, 0x29, 0x22, 0x79, 0xbb, 0x9c, 0xc5, 0x13, 0xe2, 0xcc, 0x1f, 0x87,
+	0x13, 0x62, 0xda, 0x93, 0x77, 0xdb, 0x35, 0x21, 0x50, 0x93, 0x9c, 0xde, 0xe4, 0x5d, 0xb4, 0x0d,
+	0x60, 0x31, 0x46, 0xdd, 0xa7, 0x21, 0x23, 0x41, 0x1b, 0x44, 0xd0, 0x5f, 0x4d, 0xb8, 0xad, 0xf0,
+	0xdb, 0xec, 0x46, 0x62, 0x7d, 0x8f, 0xd1, 0x99, 0x11, 0x9b, 0xd7, 0xf9, 0x08, 0xd6, 0x52, 0xc3,
+	0xa8, 0x05, 0x85, 0x67, 0x64, 0xa6, 0xf0, 0xe6, 0x9f, 0x68, 0x03, 0x4a, 0x67, 0xd6, 0x38, 0xd4,
+	0x88, 0x4b, 0xe2, 0x47, 0xf9, 0x1f, 0xe4, 0xf0, 0x67, 0xb0, 0xc1, 0xc3, 0xac, 0x2c, 0xcd, 0xe3,
+	0xfb, 0x0e, 0x54, 0x55, 0x30, 0x65, 0x70, 0xeb, 0x5b, 0x1b, 0xcb, 0x5c, 0x33, 0x22, 0x29, 0x7c,
+	0x1f, 0xd6, 0x77, 0x89, 0x56, 0xa4, 0xf3, 0x2f, 0x15, 0x79, 0xfc, 0x16, 0x5c, 0x1b, 0x12, 0x8b,
+	0xda, 0xa7, 0x73, 0x83, 0x52, 0x70, 0x03, 0x4a, 0xdf, 0x85, 0x84, 0x6a, 0xaf, 0x25, 0x81, 0x3f,
+	0x83, 0xeb, 0x69, 0x71, 0xe5, 0xdf, 0x26, 0x54, 0x28, 0x09, 0xc2, 0xf1, 0x25, 0xee, 0x69, 0x21,
+	0xec, 0xc1, 0xda, 0x2e, 0x61, 0x5f, 0x86, 0x3e, 0x23, 0xda, 0xe4, 0x26, 0x54, 0x2c, 0xc7, 0xa1,
+	0x24, 0x08, 0x84, 0xd1, 0xb4, 0x8a, 0xae, 0x1c, 0x33, 0xb4, 0xd0, 0x8b, 0xed, 0xcf, 0x73, 0x68,
+	0xcd, 0xed, 0x29, 0x9f, 0xdf, 0x82, 0xaa, 0xed, 0x07, 0x4c, 0x64, 0x69, 0x2e, 0x33, 0x4b, 0x2b,
+	0x5c, 0x86, 0x27, 0xe9, 0xff, 0x43, 0xc5, 0x17, 0x99, 0xaf, 0x2d, 0xde, 0x4c, 0x48, 0x0f, 0x4f,
+	0xdd, 0xe9, 0xd4, 0xf5, 0x4e, 0x0e, 0x84, 0x8c, 0xa1, 0x65, 0xf1, 0xbf, 0x72, 0xd0, 0x4c, 0x8e,
+	0x5d, 0x69, 0xff, 0xc5, 0x9d, 0x2b, 0x5c, 0xee, 0x5c, 0x1b, 0x2a, 0xb6, 0x45, 0xa9, 0x4b, 0xa8,
+	0xde, 0x8c, 0x8a, 0x44, 0x8f, 0x60, 0x9d, 0x58, 0x74, 0xec, 0x92, 0x80, 0x99, 0x0e, 0x19, 0xbb,
+	0x67, 0x3c, 0xaa, 0x25, 0x21, 0xd3, 0xd2, 0x03, 0xdb, 0x8a, 0x8f, 0x5e, 0x87, 0xb5, 0xb1, 0xc5,
+	0x12, 0xa2, 0x65, 0x21, 0xda, 0x94, 0xec, 0xb8, 0xa0, 0xe5, 0x84, 0x63, 0x66, 0x06, 0xee, 0x89,
+	0x67, 0x89, 0x43, 0x80, 0x6f, 0xca, 0xaa, 0xd1, 0x14, 0xec, 0xa1, 0xe6, 0xe2, 0x5f, 0xe7, 0xa0,
+	0xc5, 0x97, 0x7f, 0x40, 0x1d, 0x42, 0xff, 0x17, 0xa1, 0x46, 0x6f, 0x02, 0x0a, 0x14, 0xde, 0xa6,
+	0x0c, 0x82, 0xe9, 0x4a, 0x0c, 0x6b, 0x46, 0x2b, 0x48, 0x44, 0x62, 0xe0, 0xe0, 0xf7, 0x60, 0x3d,
+	0xe6, 0xde, 0xfc, 0x34, 0x65, 0xd4, 0xb2, 0x9f, 0x71, 0x15, 0x51, 0xa4, 0x40, 0xb3, 0x06, 0x0e,
+	0xfe, 0x00, 0x36, 0x46, 0x9c, 0xe2, 0x53, 0x27, 0xc4, 0x8b, 0xf6, 0xd7, 0xa5, 0x13, 0x7f, 0x97,
+	0x87, 0x86, 0x9e, 0xd4, 0x3f, 0x23, 0x1e, 0x43, 0x3f, 0x84, 0x72, 0xc0, 0x2c, 0x16, 0x4a, 0x28,
+	0x9a, 0x5b, 0xf7, 0x16, 0xb2, 0x2a, 0x92, 0xdd, 0x1c, 0x0a, 0x41, 0x43, 0x4d, 0xe0, 0x79, 0xc3,
+	0x5c, 0x95, 0x37, 0x05, 0x43, 0x7c, 0xf3, 0xfb, 0x73, 0xec, 0xdb, 0x56, 0xec, 0xd0, 0x8e, 0xe8,
+	0xf4, 0x99, 0x5e, 0x5c, 0x38, 0xd3, 0xf1, 0x2f, 0x73, 0x50, 0x96, 0x46, 0xd0, 0x75, 0x40, 0xc3,
+	0x51, 0x77, 0x74, 0x34, 0x34, 0x8f, 0xf6, 0x87, 0x87, 0xfd, 0xde, 0x60, 0x67, 0xd0, 0xdf, 0x6e,
+	0xad, 0xa0, 0x75, 0x68, 0xec, 0x75, 0x3f, 0xed, 0xef, 0x99, 0x3d, 0xa3, 0xdf, 0x1d, 0xf5, 0xb7,
+	0x5b, 0x39, 0xd4, 0x80, 0xda, 0xe1, 0xa0, 0xf7, 0x79, 0x7f, 0xdb, 0x3c, 0x3a, 0x6c, 0xe5, 0x51,
+	0x13, 0x60, 0xb0, 0x6f, 0x8e, 0x8c, 0xee, 0xfe, 0x70, 0x30, 0x6a, 0x15, 0xd0, 0x06, 0xb4, 0x0e,
+	0x8e, 0x46, 0xe6, 0xce, 0x81, 0x61, 0x6e, 0xf7, 0xf7, 0x06, 0x5f, 0xf5, 0x8d, 0xaf, 0x5b, 0x45,
+	0x3e, 0x49, 0x51, 0xfd, 0xed, 0x56, 0x89, 0x93, 0xfd, 0x27, 0xbd, 0xfe, 0xe1, 0x68, 0x70, 0xb0,
+	0xdf, 0x2a, 0xe3, 0xbf, 0xe6, 0xe0, 0x5a, 0x0a, 0xe1, 0x2b, 0xc6, 0x26, 0x06, 0x68, 0xfe, 0x45,
+	0x01, 0xdd, 0x82, 0x32, 0xe1, 0xfc, 0xa0, 0x5d, 0x10, 0x89, 0xd6, 0xc9, 0x9e, 0x6a, 0x28, 0xc9,
+	0x78, 0x2e, 0x17, 0xaf, 0x90, 0xcb, 0xf8, 0x7b, 0x68, 0xf7, 0x4e, 0x89, 0xfd, 0xcc, 0x20, 0x01,
+	0xa3, 0xae, 0x9d, 0x28, 0x0f, 0xfe, 0xab, 0x47, 0xe0, 0xdf, 0x72, 0xb0, 0x26, 0xe8, 0xb9, 0xe1,
+	0xcb, 0x6a, 0xd5, 0xc7, 0x50, 0xb6, 0x84, 0xa0, 0x82, 0xf2, 0x7e, 0xc2, 0x40, 0x4a, 0xd9, 0x66,
+	0x57, 0xfc, 0x18, 0x6a, 0x0a, 0xcf, 0x4e, 0x1a, 0x8e, 0x89, 0xca, 0x42, 0xf1, 0x8d, 0xae, 0x43,
+	0x99, 0x12, 0x2b, 0x88, 0x92, 0x4f, 0x51, 0xf8, 0x63, 0x28, 0xcb, 0xd9, 0x3c, 0xed, 0xba, 0x3d,
+	0x9e, 0x04, 0xa9, 0xb4, 0xab, 0x42, 0x71, 0xbb, 0xbf, 0xff, 0x75, 0x2b, 0x87, 0x5e, 0x82, 0xb5,
+	0xee, 0xf6, 0xd1, 0xde, 0xc8, 0x1c, 0x0e, 0x76, 0xf7, 0xbb, 0xa3, 0x23, 0xa3, 0xdf, 0xca, 0xe3,
+	0x9f, 0xc1, 0x8d, 0x25, 0xa8, 0xaa, 0x94, 0xf9, 0x04, 0x56, 0x69, 0x8c, 0xaf, 0x6e, 0xa8, 0x5b,
+	0x17, 0x2d, 0xc6, 0x48, 0xcc, 0xc0, 0xbf, 0xc9, 0x89, 0xfb, 0x6a, 0xcf, 0x7a, 0x4a, 0xc6, 0x57,
+	0xdd, 0xeb, 0x1c, 0xbd, 0x63, 0x9f, 0x4e, 0x2c, 0xb6, 0x14, 0xbd, 0x94, 0xba, 0xcd, 0x1d, 0x21,
+	0x6a, 0xa8, 0x29, 0x78, 0x0b, 0xca, 0x92, 0xc3, 0x11, 0xd9, 0x39, 0x30, 0xbe, 0xe8, 0x8e, 0x52,
+	0x88, 0x54, 0xa0, 0xf0, 0x93, 0xc3, 0xbd, 0x56, 0x8e, 0x7f, 0x1c, 0x6e, 0xef, 0xb4, 0xf2, 0xf8,
+	0x00, 0x5a, 0x73, 0xad, 0x6a, 0xed, 0xfc, 0x62, 0xf0, 0x3d, 0x46, 0x3c, 0x26, 0x3c, 0x5c, 0x35,
+	0x34, 0xc9, 0x2b, 0x26, 0xf5, 0x69, 0xb2, 0xd9, 0x54, 0xdf, 0x3e, 0x75, 0xc5, 0x1b, 0xcd, 0xa6,
+	0x84, 0xdf, 0xf7, 0x5f, 0x59, 0x63, 0x97, 0xd7, 0xf8, 0x3a, 0xf7, 0xfe, 0xb3, 0x4c, 0xc5, 0x3d,
+	0x58, 0x57, 0xbc, 0x1d, 0x97, 0x8c, 0x9d, 0x3e, 0xa5, 0x3e, 0xe5, 0x45, 0xc6, 0x31, 0xa7, 0x74,
+	0x91, 0x21, 0x08, 0xee, 0xf1, 0x84, 0x04, 0x81, 0x75, 0xa2, 0x5d, 0xd2, 0x24, 0xfe, 0x4b, 0x0e,
+	0x5e, 0x5e, 0xf0, 0x47, 0xad, 0x53, 0x96, 0x54, 0x2a, 0x0e, 0x55, 0x43, 0x12, 0xe8, 0x3d, 0x00,
+	0x8f, 0xa3, 0x38, 0x76, 0x9f, 0x13, 0xa7, 0x9d, 0xbf, 0xc0, 0xd3, 0x98, 0x1c, 0x7a, 0x1f, 0xca,
+	0x84, 0x3b, 0xa8, 0x8f, 0x81, 0x3b, 0xcb, 0x66, 0xcc, 0xd7, 0x61, 0x28, 0x69, 0xf4, 0x3e, 0xd4,
+	0x83, 0xf0, 0xe4, 0x84, 0x04, 0x32, 0xcd, 0x8a, 0x4b, 0x0a, 0x21, 0x6d, 0x2e, 0x2e, 0x88, 0xff,
+	0x90, 0x83, 0x8a, 0x1a, 0x40, 0xaf, 0x41, 0x33, 0x60, 0x94, 0x10, 0x66, 0xc6, 0xf1, 0xad, 0x19,
+	0x0d, 0xc9, 0xd5, 0x62, 0x08, 0x8a, 0xb6, 0x7e, 0x41, 0xd6, 0x0c, 0xf1, 0xcd, 0x21, 0xe0, 0xe7,
+	0x98, 0xde, 0x71, 0x92, 0x90, 0x09, 0x10, 0xf2, 0x42, 0x34, 0xaa, 0x0c, 0x24, 0x89, 0x6e, 0x40,
+	0xf5, 0xb9, 0x3b, 0x35, 0x6d, 0xdf, 0x21, 0xa2, 0x20, 0x28, 0x19, 0x95, 0xe7, 0xee, 0xb4, 0xe7,
+	0x3b, 0xf2, 0x39, 0xe1, 0x07, 0xcc, 0x1a, 0xcb, 0x51, 0x59, 0x03, 0x80, 0x64, 0x71, 0x01, 0xfc,
+	0x04, 0x4a, 0xa2, 0x02, 0x41, 0xf7, 0xa1, 0x61, 0x87, 0x94, 0x12, 0xcf, 0x9e, 0x49, 0x59, 0xe9,
+	0xee, 0xaa, 0x66, 0x0a, 0x75, 0x1b, 0x50, 0x0a, 0x3d, 0x97, 0x05, 0xea, 0xa6, 0x92, 0x04, 0xe7,
+	0x7a, 0x96, 0xe7, 0x07, 0xea, 0x9d, 0x27, 0x09, 0xbc, 0x0b, 0x77, 0x76, 0x09, 0x1b, 0x86, 0xd3,
+	0xa9, 0x4f, 0x19, 0x71, 0x7a, 0x52, 0x8f, 0x4b, 0xe6, 0xa1, 0x7e, 0x0d, 0x9a, 0x09, 0x93, 0xfa,
+	0xb9, 0xd3, 0x88, 0xdb, 0x0c, 0xf0, 0x4f, 0xe1, 0x46, 0x2f, 0x62, 0x78, 0x67, 0x84, 0x06, 0x7c,
+	0x5f, 0xab, 0xfc, 0x7d, 0x00, 0xc5, 0x63, 0xea, 0x4f, 0x2e, 0xa8, 0xfb, 0xc4, 0x38, 0x7f, 0xb0,
+	0x31, 0x5f, 0x2e, 0x4c, 0x42, 0x5d, 0x66, 0xbe, 0x00, 0xe0, 0x1f, 0x39, 0x68, 0xf6, 0x28, 0x71,
+	0x5c, 0xfe, 0x56, 0x76, 0x06, 0xde, 0xb1, 0xcf, 0x0b, 0x0f, 0x5b, 0x70, 0x4c, 0xdb, 0xa2, 0x8e,
+	0xe9, 0x85, 0x93, 0xa7, 0x84, 0x2a, 0x3c, 0x5a, 0x76, 0x24, 0xbb, 0x2f, 0xf8, 0xe8, 0x01, 0xac,
+	0xc5, 0xa5, 0xed, 0xb3, 0x33, 0xd5, 0x0e, 0x68, 0xcc, 0x45, 0x7b, 0x67, 0x67, 0xe8, 0x23, 0xb8,
+	0x19, 0x97, 0x23, 0xe7, 0x53, 0x97, 0x8a, 0xeb, 0xdc, 0x9c, 0x11, 0x8b, 0x2a, 0xec, 0xda, 0xf3,
+	0x39, 0xfd, 0x48, 0xe0, 0x6b, 0x62, 0x51, 0xf4, 0x31, 0xdc, 0xca, 0x98, 0x3e, 0xf1, 0x3d, 0x76,
+	0x2a, 0x72, 0xa2, 0x64, 0xdc, 0x58, 0x36, 0xff, 0x0b, 0x2e, 0x80, 0x67, 0xd0, 0xe8, 0x9d, 0x5a,
+	0xf4, 0x24, 0xaa, 0xd3, 0xff, 0x0f, 0xca, 0xd6, 0x84, 0xa7, 0xd0, 0x05, 0xe0, 0x29, 0x09, 0xf4,
+	0x21, 0xd4, 0x63, 0xd6, 0xd5, 0x06, 0x4c, 0xd6, 0xcd, 0x49, 0x10, 0x0d, 0x98, 0x7b, 0x82, 0x3f,
+	0x80, 0xa6, 0x36, 0x3d, 0x0f, 0x3d, 0xa3, 0x96, 0x17, 0xc8, 0x2b, 0x66, 0x7e, 0xec, 0x36, 0x62,
+	0xdc, 0x81, 0x83, 0xbf, 0x85, 0x9a, 0x28, 0xe8, 0x44, 0x3f, 0x46, 0x77, 0x4a, 0x72, 0x97, 0x76,
+	0x4a, 0x78, 0x56, 0xf0, 0x82, 0xba, 0x9d, 0xcf, 0x5c, 0x98, 0x18, 0xc7, 0x7f, 0xca, 0x43, 0x5d,
+	0x57, 0x8c, 0xe1, 0x98, 0xf1, 0x9d, 0xe4, 0x73, 0x72, 0xee, 0x50, 0x45, 0xd0, 0x03, 0x07, 0xbd,
+	0x03, 0x1b, 0x51, 0x35, 0x1a, 0xbf, 0x2e, 0x64, 0x36, 0x45, 0x95, 0xea, 0x68, 0x7e, 0x6d, 0x7c,
+	0x00, 0x8d, 0x68, 0x86, 0xf0, 0x26, 0xbb, 0xfc, 0x5f, 0xd5, 0x82, 0x3d, 0x3f, 0x60, 0xe8, 0x63,
+	0x88, 0xca, 0x5b, 0xf3, 0x2a, 0x25, 0xc9, 0x9a, 0x96, 0x56, 0x0c, 0xf4, 0xa6, 0x2e, 0x27, 0x4a,
+	0xe2, 0xe4, 0xba, 0x9e, 0x98, 0x15, 0x01, 0xaa, 0xeb, 0xec, 0x6d, 0x58, 0x4b, 0xd5, 0xd9, 0xed,
+	0xf2, 0x92, 0xf8, 0xa6, 0xde, 0x45, 0xcd, 0x64, 0x05, 0x8e, 0x1d, 0xb8, 0x35, 0x24, 0x9e, 0x23,
+	0xb4, 0xf7, 0x7c, 0xef, 0xd8, 0xe5, 0x77, 0x5e, 0x6c, 0xa3, 0x6e, 0x40, 0x89, 0x4c, 0x2c, 0x77,
+	0xac, 0xef, 0x08, 0x41, 0xa0, 0x4d, 0x28, 0x09, 0x80, 0x55, 0xa4, 0xda, 0x8b, 0x9e, 0xca, 0xc8,
+	0x18, 0x52, 0x0c, 0xff, 0x3e, 0x0f, 0xeb, 0x87, 0x63, 0xcb, 0x26, 0x89, 0x67, 0x48, 0x66, 0x37,
+	0xe6, 0x3e, 0x34, 0xc4, 0x80, 0x3e, 0x50, 0x54, 0xb4, 0x56, 0x39, 0x53, 0x9f, 0x29, 0xf1, 0x2b,
+	0xb0, 0x70, 0x95, 0x62, 0x2d, 0x5a, 0x49, 0x29, 0xbe, 0x92, 0xd4, 0x0e, 0x29, 0xbf, 0xd0, 0x0e,
+	0xc9, 0x78, 0xeb, 0x54, 0x96, 0xbf, 0x75, 0x10, 0x86, 0x06, 0xbf, 0x3a, 0x4d, 0xff, 0xd8, 0x7c,
+	0xea, 0x52, 0x76, 0xda, 0xae, 0xaa, 0x17, 0x80, 0xc5, 0xc8, 0xc1, 0xf1, 0xa7, 0x9c, 0x85, 0xb7,
+	0x01, 0xc5, 0x81, 0x8a, 0x9e, 0xf7, 0x0a, 0xef, 0xdc, 0xd5, 0xf0, 0xfe, 0x73, 0x0e, 0xea, 0x43,
+	0xe6, 0x53, 0x22, 0x03, 0xfb, 0xa2, 0xf3, 0xe3, 0x91, 0xc9, 0x27, 0x22, 0x13, 0x81, 0x58, 0x88,
+	0x83, 0xf8, 0x10, 0x4a, 0xcc, 0x67, 0xd6, 0xb8, 0x5d, 0xcc, 0xdc, 0x2a, 0x52, 0x00, 0xdd, 0x84,
+	0xda, 0x94, 0x2f, 0xcf, 0x31, 0x2d, 0x26, 0x02, 0x51, 0x30, 0xaa, 0x92, 0xd1, 0xe5, 0x95, 0x96,
+	0x7e, 0x39, 0xc8, 0x0b, 0x4f, 0x51, 0xb8, 0x2f, 0x8a, 0xbf, 0x44, 0xea, 0x5c, 0xb0, 0xe3, 0xb3,
+	0x7c, 0xc7, 0x6f, 0xc2, 0x3a, 0xef, 0xed, 0x08, 0x3d, 0x97, 0x76, 0x04, 0xf1, 0x0e, 0xa0, 0xb8,
+	0x74, 0xd4, 0x07, 0x2a, 0x0b, 0x3b, 0xba, 0x88, 0x4d, 0x22, 0x19, 0x83, 0xdc, 0x50, 0x72, 0x78,
+	0x13, 0x6a, 0x5d, 0x47, 0x5b, 0xd3, 0x35, 0xdf, 0x39, 0x33, 0x9f, 0x91, 0x99, 0xbe, 0x38, 0xeb,
+	0x8a, 0xf7, 0x39, 0x99, 0x05, 0xf8, 0x6d, 0x80, 0xae, 0x13, 0xd9, 0xbb, 0x07, 0x05, 0xcb, 0xd1,
+	0xc6, 0xd6, 0x52, 0x09, 0x6e, 0xf0, 0x31, 0xfc, 0x18, 0xf2, 0x5d, 0x87, 0x6b, 0xe6, 0x69, 0x49,
+	0x89, 0xcd, 0xcc, 0x90, 0xea, 0xed, 0x5a, 0xd7, 0xbc, 0x23, 0x3a, 0x16, 0xcf, 0x55, 0x72, 0xce,
+	0x74, 0xcd, 0xc2, 0xbf, 0xb7, 0xfe, 0x9e, 0x87, 0x3a, 0x3f, 0x84, 0x87, 0x84, 0x9e, 0xb9, 0x36,
+	0x41, 0x1f, 0x8a, 0x4a, 0x48, 0x9c, 0xdb, 0x37, 0xd3, 0xdb, 0x29, 0xd6, 0xf5, 0xed, 0x24, 0x43,
+	0x2c, 0x5b, 0xaf, 0x2b, 0xe8, 0x31, 0x54, 0x54, 0xf3, 0x3a, 0x35, 0x3b, 0xd9, 0xd2, 0xee, 0xac,
+	0x2f, 0x5c, 0x02, 0x78, 0x05, 0x7d, 0x02, 0xb5, 0xa8, 0x4d, 0x8e, 0x6e, 0x2f, 0xea, 0x8f, 0x2b,
+	0x58, 0x6e, 0xde, 0x00, 0xb4, 0xd8, 0x10, 0x47, 0x0f, 0x12, 0xb2, 0x99, 0x1d, 0xf3, 0x0c, 0x9d,
+	0x9f, 0x02, 0xcc, 0x7b, 0xde, 0x28, 0x59, 0x89, 0x2e, 0x34, 0xc3, 0x97, 0xeb, 0xd8, 0xfa, 0x45,
+	0x0e, 0xae, 0x25, 0x1b, 0xc7, 0x1a, 0xee, 0x9f, 0xc3, 0x4b, 0x4b, 0xba, 0xca, 0xe8, 0xf5, 0x84,
+	0x9a, 0xec, 0x7e, 0x76, 0xe7, 0xe1, 0xe5, 0x82, 0x32, 0x91, 0xb8, 0x17, 0x79, 0xb8, 0xa6, 0xfa,
+	0x80, 0x3d, 0x8b, 0x59, 0x63, 0xff, 0x44, 0x7b, 0xb1, 0x0b, 0xab, 0xf1, 0xa6, 0x27, 0x5a, 0xb2,
+	0x8a, 0xce, 0xbd, 0x05, 0x4b, 0xe9, 0x1e, 0x24, 0x5e, 0xe1, 0x2d, 0xdc, 0x79, 0xcf, 0x33, 0x05,
+	0xd6, 0x42, 0x33, 0xb4, 0xb3, 0xb4, 0x45, 0x89, 0x57, 0xd0, 0x37, 0xd0, 0x4c, 0x76, 0x39, 0x11,
+	0x4e, 0xee, 0xb2, 0x65, 0x1d, 0xd3, 0xce, 0xfd, 0x0b, 0x65, 0x22, 0x14, 0x7e, 0x55, 0x84, 0x35,
+	0x7d, 0x25, 0xea, 0xf5, 0x0f, 0xa0, 0xaa, 0x9b, 0x93, 0xe8, 0x56, 0xda, 0xe9, 0x78, 0x8f, 0xb4,
+	0x73, 0x3b, 0x63, 0x34, 0x42, 0x60, 0x0f, 0x6a, 0x51, 0x3b, 0x2b, 0x95, 0xc4, 0xe9, 0x2e, 0x5c,
+	0xe7, 0x4e, 0xd6, 0x70, 0xa4, 0xed, 0x09, 0x34, 0x12, 0x4d, 0x18, 0x94, 0x8c, 0xc2, 0xb2, 0x16,
+	0x58, 0x07, 0x5f, 0x24, 0x12, 0x69, 0xfe, 0x16, 0xd6, 0x52, 0x2f, 0x39, 0x94, 0x04, 0x70, 0xf9,
+	0xbb, 0xb3, 0xf3, 0xea, 0xc5, 0x42, 0x91, 0x7e, 0x07, 0xd6, 0x17, 0xfa, 0x01, 0xe8, 0xb5, 0xe4,
+	0xb6, 0xcf, 0xe8, 0xc2, 0x74, 0x1e, 0x5c, 0x26, 0x16, 0x59, 0x91, 0x81, 0x13, 0x0f, 0xee, 0xc5,
+	0xc0, 0xc5, 0x5f, 0xf7, 0x9d, 0xdb, 0x19, 0xa3, 0x51, 0x5e, 0xfc, 0x31, 0x07, 0x6b, 0xba, 0xb4,
+	0xd0, 0x79, 0xf1, 0x0d, 0x5c, 0x5f, 0xfe, 0x14, 0x5a, 0xba, 0x43, 0x1e, 0xa5, 0x4d, 0x5c, 0xf0,
+	0x86, 0xc2, 0x2b, 0x68, 0x17, 0x2a, 0xf2, 0x59, 0xc4, 0x52, 0x27, 0x54, 0xe6, 0xa3, 0xa9, 0xb3,
+	0xe4, 0x5e, 0xc5, 0x2b, 0x5b, 0x47, 0xd0, 0x3c, 0xb4, 0x66, 0x3c, 0xbe, 0xda, 0xef, 0x1e, 0x94,
+	0x65, 0xdd, 0x8e, 0x3a, 0x29, 0x28, 0x63, 0xef, 0x88, 0xce, 0xcd, 0xa5, 0x63, 0x11, 0x20, 0xa7,
+	0xb0, 0xda, 0xe7, 0x97, 0xbb, 0x56, 0xfa, 0x04, 0xae, 0x2d, 0x2d, 0x14, 0xd1, 0x1b, 0xa9, 0x8d,
+	0x97, 0x5d, 0x4c, 0x66, 0x1c, 0x8f, 0xff, 0xe4, 0xd0, 0xf3, 0x28, 0xfb, 0x61, 0xb4, 0x84, 0x03,
+	0x80, 0x79, 0x19, 0x94, 0x3a, 0x49, 0x16, 0x0a, 0xc9, 0xce, 0x2b, 0x99, 0xe3, 0xb1, 0xa3, 0xa9,
+	0xaa, 0x6b, 0x88, 0xc5, 0x54, 0x49, 0x28, 0xcb, 0xbc, 0xd2, 0xf1, 0x0a, 0x77, 0x6b, 0x5e, 0x14,
+	0xa4, 0xdc, 0x5a, 0xa8, 0x2d, 0x3a, 0xaf, 0x64, 0x8e, 0x47, 0x28, 0x7f, 0xc6, 0xab, 0x03, 0xbd,
+	0xe8, 0xc7, 0x50, 0xde, 0xe5, 0x2d, 0x86, 0x00, 0x5d, 0x4f, 0xdf, 0xf4, 0x4a, 0xe3, 0xcb, 0x0b,
+	0x7c, 0xad, 0xe9, 0x69, 0x59, 0xfc, 0x2d, 0xfe, 0xee, 0xbf, 0x07, 0x00, 0xe8, 0x3c, 0xca, 0xa3,
+	0x24, 0x1f, 0x00, 0x00,
 }
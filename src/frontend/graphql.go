@@ -0,0 +1,627 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strconv"
+
+	graphql "github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/signalfx/microservices-demo/src/frontend/dataloader"
+	pb "github.com/signalfx/microservices-demo/src/frontend/genproto"
+	"github.com/signalfx/microservices-demo/src/frontend/money"
+	"github.com/signalfx/microservices-demo/src/frontend/querycost"
+)
+
+const (
+	graphqlPath = "/graphql"
+	// The standard introspection query is 12 levels deep.
+	defaultGraphQLMaxDepth      = 12
+	defaultGraphQLMaxComplexity = 1000
+)
+
+//go:embed schema.graphql
+var graphqlSchema string
+
+// graphqlListSizes is the number of items each list field is assumed to
+// return when estimating a query's complexity; other fields count once.
+var graphqlListSizes = map[string]int{
+	"products":        10,
+	"items":           10,
+	"recommendations": 5,
+	"ads":             3,
+	"shippingOptions": 3,
+}
+
+// graphqlHandler serves GraphQL queries over the storefront's services.
+// Queries deeper than the maximum depth, or whose estimated complexity
+// (see package querycost) is above the maximum, are refused before they
+// run.
+type graphqlHandler struct {
+	fe            *frontendServer
+	schema        *graphql.Schema
+	maxComplexity int
+}
+
+func newGraphQLHandler(fe *frontendServer) (*graphqlHandler, error) {
+	maxDepth := defaultGraphQLMaxDepth
+	if v := os.Getenv("GRAPHQL_MAX_DEPTH"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return nil, errors.Errorf("invalid GRAPHQL_MAX_DEPTH %q", v)
+		}
+		maxDepth = n
+	}
+	h := &graphqlHandler{fe: fe, maxComplexity: defaultGraphQLMaxComplexity}
+	if v := os.Getenv("GRAPHQL_MAX_COMPLEXITY"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return nil, errors.Errorf("invalid GRAPHQL_MAX_COMPLEXITY %q", v)
+		}
+		h.maxComplexity = n
+	}
+	schema, err := graphql.ParseSchema(graphqlSchema, &graphqlResolver{}, graphql.MaxDepth(maxDepth))
+	if err != nil {
+		return nil, errors.Wrap(err, "graphql: invalid schema")
+	}
+	h.schema = schema
+	return h, nil
+}
+
+func (h *graphqlHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log := getLoggerWithTraceFields(r.Context())
+	var params struct {
+		Query         string                 `json:"query"`
+		OperationName string                 `json:"operationName"`
+		Variables     map[string]interface{} `json:"variables"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&params); err != nil {
+		renderJSON(w, http.StatusBadRequest, &graphql.Response{Errors: []*gqlerrors.QueryError{
+			gqlerrors.Errorf("invalid request: %v", err)}})
+		return
+	}
+	complexity, err := querycost.Estimate(params.Query, params.OperationName, func(field string) int {
+		return graphqlListSizes[field]
+	})
+	if err != nil {
+		// The schema reports syntax errors in more detail. Queries only the
+		// estimator cannot read are refused: their cost is unknown.
+		if errs := h.schema.Validate(params.Query); len(errs) > 0 {
+			renderJSON(w, http.StatusOK, &graphql.Response{Errors: errs})
+			return
+		}
+		log.WithField("error", err).Warn("graphql query refused")
+		renderJSON(w, http.StatusBadRequest, &graphql.Response{Errors: []*gqlerrors.QueryError{
+			gqlerrors.Errorf("could not estimate query complexity: %v", err)}})
+		return
+	}
+	if complexity > h.maxComplexity {
+		log.WithField("complexity", complexity).Warn("graphql query refused")
+		renderJSON(w, http.StatusBadRequest, &graphql.Response{Errors: []*gqlerrors.QueryError{
+			gqlerrors.Errorf("query complexity %d exceeds the limit of %d", complexity, h.maxComplexity)}})
+		return
+	}
+	log.WithField("complexity", complexity).WithField("operation", params.OperationName).Debug("graphql query")
+
+	ctx := context.WithValue(r.Context(), ctxKeyGraphQL{}, h.fe.newGraphQLRequest(r))
+	renderJSON(w, http.StatusOK, h.schema.Exec(ctx, params.Query, params.OperationName, params.Variables))
+}
+
+type ctxKeyGraphQL struct{}
+
+// graphqlRequest is the state of one GraphQL request. Its loaders batch
+// and cache RPCs for the duration of the request, so that for example the
+// recommendations of every product in a list cost one ListProducts call.
+type graphqlRequest struct {
+	fe        *frontendServer
+	sessionID string
+	currency  string
+
+	products        *dataloader.Loader[string, *pb.Product]
+	prices          *dataloader.Loader[priceKey, *pb.Money]
+	recommendations *dataloader.Loader[string, []string]
+}
+
+// priceKey identifies a currency conversion.
+type priceKey struct {
+	from  string
+	units int64
+	nanos int32
+	to    string
+}
+
+func (fe *frontendServer) newGraphQLRequest(r *http.Request) *graphqlRequest {
+	ctx := r.Context()
+	req := &graphqlRequest{fe: fe, sessionID: sessionID(r), currency: currentCurrency(r)}
+	req.products = dataloader.New(ctx, fe.fetchProducts)
+	req.prices = dataloader.New(ctx, dataloader.Each(func(ctx context.Context, k priceKey) (*pb.Money, error) {
+		return fe.convertCurrency(ctx, &pb.Money{CurrencyCode: k.from, Units: k.units, Nanos: k.nanos}, k.to)
+	}))
+	req.recommendations = dataloader.New(ctx, dataloader.Each(func(ctx context.Context, productID string) ([]string, error) {
+		return fe.getRecommendationIDs(ctx, req.sessionID, []string{productID})
+	}))
+	return req
+}
+
+func graphqlRequestFrom(ctx context.Context) *graphqlRequest {
+	return ctx.Value(ctxKeyGraphQL{}).(*graphqlRequest)
+}
+
+// fetchProducts looks up a batch of products. A single ListProducts call
+// is cheaper than a GetProduct per ID once there is more than one.
+func (fe *frontendServer) fetchProducts(ctx context.Context, ids []string) []dataloader.Result[*pb.Product] {
+	out := make([]dataloader.Result[*pb.Product], len(ids))
+	if len(ids) == 1 {
+		out[0].Value, out[0].Err = fe.getProduct(ctx, ids[0])
+		return out
+	}
+	products, err := fe.getProducts(ctx)
+	byID := make(map[string]*pb.Product, len(products))
+	for _, p := range products {
+		byID[p.GetId()] = p
+	}
+	for i, id := range ids {
+		switch p, ok := byID[id]; {
+		case err != nil:
+			out[i].Err = err
+		case !ok:
+			out[i].Err = status.Errorf(codes.NotFound, "no product with ID %s", id)
+		default:
+			out[i].Value = p
+		}
+	}
+	return out
+}
+
+// currencyArg returns the currency named by an optional currency
+// argument, defaulting to the session's currency.
+func (req *graphqlRequest) currencyArg(currency *string) (string, error) {
+	if currency == nil {
+		return req.currency, nil
+	}
+	if !whitelistedCurrencies[*currency] {
+		return "", errors.Errorf("unsupported currency %q", *currency)
+	}
+	return *currency, nil
+}
+
+// price converts m to the currency named by an optional currency argument.
+func (req *graphqlRequest) price(ctx context.Context, m *pb.Money, currency *string) (*pb.Money, error) {
+	to, err := req.currencyArg(currency)
+	if err != nil {
+		return nil, err
+	}
+	return req.prices.Load(ctx, priceKey{m.GetCurrencyCode(), m.GetUnits(), m.GetNanos(), to})
+}
+
+func (req *graphqlRequest) productResolvers(ctx context.Context, ids []string) ([]*productResolver, error) {
+	products, err := req.products.LoadMany(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	return newProductResolvers(products), nil
+}
+
+// graphqlResolver resolves the Query and Mutation types.
+type graphqlResolver struct{}
+
+func (*graphqlResolver) Product(ctx context.Context, args struct{ ID graphql.ID }) (*productResolver, error) {
+	p, err := graphqlRequestFrom(ctx).products.Load(ctx, string(args.ID))
+	if err != nil {
+		return nil, err
+	}
+	return &productResolver{p}, nil
+}
+
+func (*graphqlResolver) Products(ctx context.Context, args struct{ Query *string }) ([]*productResolver, error) {
+	req := graphqlRequestFrom(ctx)
+	var (
+		products []*pb.Product
+		err      error
+	)
+	if args.Query != nil && *args.Query != "" {
+		products, err = req.fe.searchProducts(ctx, *args.Query)
+	} else {
+		products, err = req.fe.getProducts(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range products {
+		req.products.Prime(p.GetId(), p)
+	}
+	return newProductResolvers(products), nil
+}
+
+func (*graphqlResolver) Cart(ctx context.Context) (*cartResolver, error) {
+	req := graphqlRequestFrom(ctx)
+	items, err := req.fe.getCart(ctx, req.sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return &cartResolver{items}, nil
+}
+
+func (*graphqlResolver) Recommendations(ctx context.Context, args struct{ ProductIds *[]graphql.ID }) ([]*productResolver, error) {
+	req := graphqlRequestFrom(ctx)
+	var productIDs []string
+	if args.ProductIds != nil {
+		for _, id := range *args.ProductIds {
+			productIDs = append(productIDs, string(id))
+		}
+	}
+	ids, err := req.fe.getRecommendationIDs(ctx, req.sessionID, productIDs)
+	if err != nil {
+		return nil, err
+	}
+	return req.productResolvers(ctx, ids)
+}
+
+func (*graphqlResolver) Ads(ctx context.Context, args struct{ ContextKeys *[]string }) ([]*adResolver, error) {
+	var keys []string
+	if args.ContextKeys != nil {
+		keys = *args.ContextKeys
+	}
+	ads, err := graphqlRequestFrom(ctx).fe.getAd(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*adResolver, len(ads))
+	for i, ad := range ads {
+		out[i] = &adResolver{ad}
+	}
+	return out, nil
+}
+
+func (*graphqlResolver) Currencies(ctx context.Context) ([]string, error) {
+	return graphqlRequestFrom(ctx).fe.getCurrencies(ctx)
+}
+
+func (r *graphqlResolver) AddToCart(ctx context.Context, args struct {
+	ProductID graphql.ID
+	Quantity  int32
+}) (*cartResolver, error) {
+	req := graphqlRequestFrom(ctx)
+	p, err := req.products.Load(ctx, string(args.ProductID))
+	if err != nil {
+		return nil, err
+	}
+	inCart, err := req.fe.cartQuantity(ctx, req.sessionID, p.GetId())
+	if err != nil {
+		return nil, errors.Wrap(err, "could not retrieve cart")
+	}
+	if err := checkQuantity(p, inCart, args.Quantity); err != nil {
+		return nil, err
+	}
+	if err := req.fe.insertCart(ctx, req.sessionID, string(args.ProductID), args.Quantity); err != nil {
+		return nil, errors.Wrap(err, "failed to add to cart")
+	}
+	return r.Cart(ctx)
+}
+
+// UpdateCartItem sets the quantity of a product in the cart; 0 removes it.
+func (r *graphqlResolver) UpdateCartItem(ctx context.Context, args struct {
+	ProductID graphql.ID
+	Quantity  int32
+}) (*cartResolver, error) {
+	req := graphqlRequestFrom(ctx)
+	if args.Quantity < 0 {
+		return nil, errors.New("quantity must be 0 or more")
+	}
+	if args.Quantity > 0 {
+		p, err := req.products.Load(ctx, string(args.ProductID))
+		if err != nil {
+			return nil, err
+		}
+		if err := checkQuantity(p, 0, args.Quantity); err != nil {
+			return nil, err
+		}
+	}
+	if err := req.fe.updateCartItem(ctx, req.sessionID, string(args.ProductID), args.Quantity); err != nil {
+		return nil, errors.Wrap(err, "failed to update cart")
+	}
+	return r.Cart(ctx)
+}
+
+func (r *graphqlResolver) RemoveFromCart(ctx context.Context, args struct{ ProductID graphql.ID }) (*cartResolver, error) {
+	req := graphqlRequestFrom(ctx)
+	if err := req.fe.removeCartItem(ctx, req.sessionID, string(args.ProductID)); err != nil {
+		return nil, errors.Wrap(err, "failed to remove from cart")
+	}
+	return r.Cart(ctx)
+}
+
+func (*graphqlResolver) EmptyCart(ctx context.Context) (*cartResolver, error) {
+	req := graphqlRequestFrom(ctx)
+	if err := req.fe.emptyCart(ctx, req.sessionID); err != nil {
+		return nil, errors.Wrap(err, "failed to empty cart")
+	}
+	return &cartResolver{}, nil
+}
+
+type addressInput struct {
+	StreetAddress string
+	City          string
+	State         *string
+	Country       string
+	PostalCode    *string
+	ZipCode       *int32
+}
+
+func (in *addressInput) proto() *pb.Address {
+	if in == nil {
+		return nil
+	}
+	address := &pb.Address{
+		StreetAddress: in.StreetAddress,
+		City:          in.City,
+		Country:       in.Country,
+	}
+	if in.State != nil {
+		address.State = *in.State
+	}
+	if in.PostalCode != nil {
+		address.PostalCode = *in.PostalCode
+	}
+	if in.ZipCode != nil {
+		address.ZipCode = *in.ZipCode
+	}
+	return address
+}
+
+type placeOrderInput struct {
+	Email       string
+	DateOfBirth string
+	Address     addressInput
+	CreditCard  struct {
+		Number          string
+		CVV             int32
+		ExpirationYear  int32
+		ExpirationMonth int32
+	}
+	Currency         *string
+	ShippingOptionId *graphql.ID
+}
+
+func (*graphqlResolver) PlaceOrder(ctx context.Context, args struct{ Input placeOrderInput }) (*orderResolver, error) {
+	req := graphqlRequestFrom(ctx)
+	in := args.Input
+	currency, err := req.currencyArg(in.Currency)
+	if err != nil {
+		return nil, err
+	}
+	if m := in.CreditCard.ExpirationMonth; m < 1 || m > 12 {
+		return nil, errors.New("creditCard.expirationMonth must be between 1 and 12")
+	}
+	var optionID string
+	if in.ShippingOptionId != nil {
+		optionID = string(*in.ShippingOptionId)
+	}
+	order, total, err := req.fe.placeOrder(ctx, &pb.PlaceOrderRequest{
+		Email: in.Email,
+		CreditCard: &pb.CreditCardInfo{
+			CreditCardNumber:          in.CreditCard.Number,
+			CreditCardCvv:             in.CreditCard.CVV,
+			CreditCardExpirationYear:  in.CreditCard.ExpirationYear,
+			CreditCardExpirationMonth: in.CreditCard.ExpirationMonth},
+		UserId:           req.sessionID,
+		UserCurrency:     currency,
+		Address:          in.Address.proto(),
+		DateOfBirth:      in.DateOfBirth,
+		ShippingOptionId: optionID,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to complete the order")
+	}
+	getLoggerWithTraceFields(ctx).WithField("order", order.GetOrderId()).Info("order placed")
+	addOrderIDToSpan(ctx, order.GetOrderId())
+	return &orderResolver{order, total}, nil
+}
+
+type moneyResolver struct{ m *pb.Money }
+
+func (r *moneyResolver) CurrencyCode() string { return r.m.GetCurrencyCode() }
+func (r *moneyResolver) Amount() string       { return money.Format(*r.m) }
+
+type productResolver struct{ p *pb.Product }
+
+func newProductResolvers(products []*pb.Product) []*productResolver {
+	out := make([]*productResolver, len(products))
+	for i, p := range products {
+		out[i] = &productResolver{p}
+	}
+	return out
+}
+
+func (r *productResolver) ID() graphql.ID       { return graphql.ID(r.p.GetId()) }
+func (r *productResolver) Name() string         { return r.p.GetName() }
+func (r *productResolver) Description() string  { return r.p.GetDescription() }
+func (r *productResolver) Picture() string      { return r.p.GetPicture() }
+func (r *productResolver) Categories() []string { return r.p.GetCategories() }
+func (r *productResolver) MaxQuantity() int32   { return maxQuantity(r.p) }
+
+func (r *productResolver) Price(ctx context.Context, args struct{ Currency *string }) (*moneyResolver, error) {
+	m, err := graphqlRequestFrom(ctx).price(ctx, r.p.GetPriceUsd(), args.Currency)
+	if err != nil {
+		return nil, err
+	}
+	return &moneyResolver{m}, nil
+}
+
+func (r *productResolver) Recommendations(ctx context.Context) ([]*productResolver, error) {
+	req := graphqlRequestFrom(ctx)
+	ids, err := req.recommendations.Load(ctx, r.p.GetId())
+	if err != nil {
+		return nil, err
+	}
+	return req.productResolvers(ctx, ids)
+}
+
+type cartResolver struct{ items []*pb.CartItem }
+
+func (r *cartResolver) Items() []*cartItemResolver {
+	out := make([]*cartItemResolver, len(r.items))
+	for i, item := range r.items {
+		out[i] = &cartItemResolver{item}
+	}
+	return out
+}
+
+func (r *cartResolver) ItemCount() int32 { return int32(cartSize(r.items)) }
+
+func (r *cartResolver) Subtotal(ctx context.Context, args struct{ Currency *string }) (*moneyResolver, error) {
+	currency, err := graphqlRequestFrom(ctx).currencyArg(args.Currency)
+	if err != nil {
+		return nil, err
+	}
+	subtotal := pb.Money{CurrencyCode: currency}
+	for _, item := range r.Items() {
+		total, err := item.Total(ctx, args)
+		if err != nil {
+			return nil, err
+		}
+		subtotal = money.Must(money.Sum(subtotal, *total.m))
+	}
+	return &moneyResolver{&subtotal}, nil
+}
+
+type shippingArgs struct {
+	Currency *string
+	Address  *addressInput
+}
+
+func (r *cartResolver) ShippingQuote(ctx context.Context, args shippingArgs) (*moneyResolver, error) {
+	req := graphqlRequestFrom(ctx)
+	currency, err := req.currencyArg(args.Currency)
+	if err != nil {
+		return nil, err
+	}
+	m, err := req.fe.getShippingQuote(ctx, args.Address.proto(), r.items, currency)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get shipping quote")
+	}
+	return &moneyResolver{m}, nil
+}
+
+func (r *cartResolver) ShippingOptions(ctx context.Context, args shippingArgs) ([]*shippingOptionResolver, error) {
+	req := graphqlRequestFrom(ctx)
+	currency, err := req.currencyArg(args.Currency)
+	if err != nil {
+		return nil, err
+	}
+	options, err := req.fe.getShippingOptions(ctx, args.Address.proto(), r.items, currency)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get shipping quote")
+	}
+	out := make([]*shippingOptionResolver, len(options))
+	for i, o := range options {
+		out[i] = &shippingOptionResolver{o}
+	}
+	return out, nil
+}
+
+type shippingOptionResolver struct{ o shippingOption }
+
+func (r *shippingOptionResolver) Id() graphql.ID           { return graphql.ID(r.o.GetId()) }
+func (r *shippingOptionResolver) Name() string             { return r.o.GetName() }
+func (r *shippingOptionResolver) Cost() *moneyResolver     { return &moneyResolver{r.o.Cost} }
+func (r *shippingOptionResolver) Carrier() string          { return r.o.GetCarrier() }
+func (r *shippingOptionResolver) EarliestDelivery() string { return r.o.GetEarliestDelivery() }
+func (r *shippingOptionResolver) LatestDelivery() string   { return r.o.GetLatestDelivery() }
+func (r *shippingOptionResolver) AdultSignature() bool     { return r.o.GetAdultSignature() }
+
+type cartItemResolver struct{ item *pb.CartItem }
+
+func (r *cartItemResolver) Product(ctx context.Context) (*productResolver, error) {
+	p, err := graphqlRequestFrom(ctx).products.Load(ctx, r.item.GetProductId())
+	if err != nil {
+		return nil, err
+	}
+	return &productResolver{p}, nil
+}
+
+func (r *cartItemResolver) Quantity() int32 { return r.item.GetQuantity() }
+
+func (r *cartItemResolver) Total(ctx context.Context, args struct{ Currency *string }) (*moneyResolver, error) {
+	p, err := r.Product(ctx)
+	if err != nil {
+		return nil, err
+	}
+	price, err := p.Price(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	total := money.MultiplySlow(*price.m, uint32(r.item.GetQuantity()))
+	return &moneyResolver{&total}, nil
+}
+
+type adResolver struct{ ad *pb.Ad }
+
+func (r *adResolver) RedirectUrl() string { return r.ad.GetRedirectUrl() }
+func (r *adResolver) Text() string        { return r.ad.GetText() }
+
+type addressResolver struct{ a *pb.Address }
+
+func (r *addressResolver) StreetAddress() string { return r.a.GetStreetAddress() }
+func (r *addressResolver) City() string          { return r.a.GetCity() }
+func (r *addressResolver) State() string         { return r.a.GetState() }
+func (r *addressResolver) Country() string       { return r.a.GetCountry() }
+func (r *addressResolver) PostalCode() string    { return r.a.GetPostalCode() }
+func (r *addressResolver) ZipCode() int32        { return r.a.GetZipCode() }
+
+type orderResolver struct {
+	order *pb.OrderResult
+	total pb.Money
+}
+
+func (r *orderResolver) OrderId() graphql.ID        { return graphql.ID(r.order.GetOrderId()) }
+func (r *orderResolver) ShippingTrackingId() string { return r.order.GetShippingTrackingId() }
+func (r *orderResolver) ShippingCost() *moneyResolver {
+	return &moneyResolver{r.order.GetShippingCost()}
+}
+func (r *orderResolver) ShippingAddress() *addressResolver {
+	return &addressResolver{r.order.GetShippingAddress()}
+}
+func (r *orderResolver) Total() *moneyResolver { return &moneyResolver{&r.total} }
+
+func (r *orderResolver) Items() []*orderItemResolver {
+	out := make([]*orderItemResolver, len(r.order.GetItems()))
+	for i, item := range r.order.GetItems() {
+		out[i] = &orderItemResolver{item}
+	}
+	return out
+}
+
+type orderItemResolver struct{ item *pb.OrderItem }
+
+func (r *orderItemResolver) Product(ctx context.Context) (*productResolver, error) {
+	p, err := graphqlRequestFrom(ctx).products.Load(ctx, r.item.GetItem().GetProductId())
+	if err != nil {
+		return nil, err
+	}
+	return &productResolver{p}, nil
+}
+
+func (r *orderItemResolver) Quantity() int32      { return r.item.GetItem().GetQuantity() }
+func (r *orderItemResolver) Cost() *moneyResolver { return &moneyResolver{r.item.GetCost()} }
@@ -0,0 +1,133 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+func TestGraphQL(t *testing.T) {
+	fe := testFrontendServer(t)
+	shop := newFakeShop()
+	shop.serve(t, fe)
+	gql, err := newGraphQLHandler(fe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := logrus.New()
+	log.Out = ioutil.Discard
+	h := ensureSessionID(&logHandler{log: log, next: gql})
+	token := uuid.New().String()
+	post := func(query string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]string{"query": query})
+		req := httptest.NewRequest(http.MethodPost, graphqlPath, strings.NewReader(string(body)))
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	const order = `mutation { placeOrder(input: {email: "someone@example.com", dateOfBirth: "1990-04-01", currency: "EUR", shippingOptionId: "express",
+		address: {streetAddress: "1600 Amphitheatre Parkway", city: "Mountain View", country: "United States", postalCode: "94043"},
+		creditCard: {number: "4432801561520454", cvv: 672, expirationYear: 2030, expirationMonth: 1}}) {
+		orderId shippingAddress { city } shippingCost { amount } items { product { name } quantity cost { amount } } total { currencyCode amount } } }`
+	tests := []struct {
+		name  string
+		query string
+		code  int
+		want  string
+		calls map[string]int
+	}{
+		{
+			"product card",
+			`{ product(id: "OLJCESPC7Z") { name price(currency: "EUR") { amount } recommendations { name price { amount } } } }`,
+			200,
+			`{"data":{"product":{"name":"Vintage Typewriter","price":{"amount":"33.995"},"recommendations":[{"name":"Home Barista Kit","price":{"amount":"124.00"}},{"name":"Terrarium","price":{"amount":"36.45"}}]}}}`,
+			// One GetProduct for the product; its two recommendations are
+			// batched into one ListProducts.
+			map[string]int{"GetProduct": 1, "ListRecommendations": 1, "ListProducts": 1, "Convert": 3},
+		},
+		{
+			"listed products are not fetched again",
+			`{ products { id recommendations { id } } }`,
+			200,
+			`{"id":"L9ECAV7KIM","recommendations":[{"id":"OLJCESPC7Z"},{"id":"1YMWWN1N4O"}]}`,
+			map[string]int{"ListProducts": 1, "ListRecommendations": 3},
+		},
+		{
+			"search",
+			`{ products(query: "terrarium") { name } }`,
+			200,
+			`{"data":{"products":[{"name":"Terrarium"}]}}`,
+			map[string]int{"SearchProducts": 1},
+		},
+		{
+			"add to cart",
+			`mutation { addToCart(productId: "L9ECAV7KIM", quantity: 2) { itemCount subtotal { amount } items { product { name } total(currency: "EUR") { amount } } } }`,
+			200,
+			`{"addToCart":{"itemCount":2,"subtotal":{"amount":"72.90"},"items":[{"product":{"name":"Terrarium"},"total":{"amount":"36.45"}}]}}`,
+			nil,
+		},
+		{"invalid quantity", `mutation { addToCart(productId: "L9ECAV7KIM", quantity: 1000) { itemCount } }`, 200, "quantity must be between 1 and 8", nil},
+		{"update cart item", `mutation { updateCartItem(productId: "L9ECAV7KIM", quantity: 10) { itemCount } }`, 200, `{"updateCartItem":{"itemCount":10}}`, nil},
+		{"update over the limit", `mutation { updateCartItem(productId: "OLJCESPC7Z", quantity: 3) { itemCount } }`, 200, "quantity must be between 1 and 2", nil},
+		{"remove from cart", `mutation { removeFromCart(productId: "L9ECAV7KIM") { itemCount } }`, 200, `{"removeFromCart":{"itemCount":0}}`, nil},
+		{"add again", `mutation { addToCart(productId: "L9ECAV7KIM", quantity: 2) { itemCount items { product { maxQuantity } } } }`, 200, `{"itemCount":2,"items":[{"product":{"maxQuantity":10}}]}`, nil},
+		{"unsupported currency", `{ cart { shippingQuote(currency: "XYZ") { amount } } }`, 200, `unsupported currency \"XYZ\"`, nil},
+		{"shipping quote", `{ cart { shippingQuote { currencyCode amount } } }`, 200, `"shippingQuote":{"currencyCode":"USD","amount":"8.99"}`, nil},
+		{"shipping options to an address", `{ cart { shippingOptions(address: {streetAddress: "1 Main St", city: "Anchorage", state: "AK", country: "United States"}) { id cost { amount } carrier } } }`, 200,
+			`"shippingOptions":[{"id":"standard","cost":{"amount":"18.99"},"carrier":"Hopscotch Express"},{"id":"express","cost":{"amount":"29.99"},"carrier":"Frothly Freight"}]`, nil},
+		{"shipping quote to an address", `{ cart { shippingQuote(address: {streetAddress: "1 Main St", city: "Anchorage", state: "AK", country: "United States"}) { amount } } }`, 200, `"shippingQuote":{"amount":"18.99"}`, nil},
+		{"unknown shipping option", strings.Replace(order, `"express"`, `"drone"`, 1), 200, `unknown shipping option \"drone\"`, nil},
+		{"place order", order, 200, `"shippingCost":{"amount":"9.995"},"items":[{"product":{"name":"Terrarium"},"quantity":2,"cost":{"amount":"18.225"}}],"total":{"currencyCode":"EUR","amount":"46.445"}`, nil},
+		{"ads and currencies", `{ ads { text } currencies }`, 200, `{"ads":[{"text":"Typewriter for sale. 50% off."}],"currencies":["EUR","USD"]}`, nil},
+		{"too complex", `{ products { recommendations { recommendations { recommendations { name } } } } }`, 400, "query complexity 1561 exceeds the limit of 1000", map[string]int{}},
+		{"syntax error", `{ products { name }`, 200, "syntax error", map[string]int{}},
+		{"too complex behind a byte order mark", "\ufeff{ products { recommendations { recommendations { recommendations { name } } } } }", 400, "could not estimate query complexity", map[string]int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shop.takeCalls()
+			w := post(tt.query)
+			if w.Code != tt.code || !strings.Contains(w.Body.String(), tt.want) {
+				t.Fatalf("got %d %s, want %d containing %s", w.Code, w.Body, tt.code, tt.want)
+			}
+			if calls := shop.takeCalls(); tt.calls != nil && !reflect.DeepEqual(calls, tt.calls) {
+				t.Errorf("RPCs = %v, want %v", calls, tt.calls)
+			}
+		})
+	}
+
+	// Depth is limited even when the complexity limit would allow a query.
+	gql.maxComplexity = math.MaxInt32
+	shop.takeCalls()
+	deep := "{ product(id: \"OLJCESPC7Z\") { " + strings.Repeat("recommendations { ", 11) + "id" + strings.Repeat(" }", 13)
+	if w := post(deep); !strings.Contains(w.Body.String(), "exceeds max depth 12") {
+		t.Errorf("deep query: got %d %s, want a depth error", w.Code, w.Body)
+	}
+	if calls := shop.takeCalls(); len(calls) != 0 {
+		t.Errorf("deep query made RPCs %v", calls)
+	}
+}
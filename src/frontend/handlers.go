@@ -16,19 +16,12 @@ package main
 
 import (
 	"context"
-	"crypto/hmac"
-	"crypto/sha256"
-	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"html/template"
-	"log"
-
-	"bufio"
-	"io/ioutil"
+	"math"
 	"math/rand"
 	"net/http"
-	"net/url"
 	"os"
 	"strconv"
 	"strings"
@@ -39,12 +32,12 @@ import (
 	"github.com/pkg/errors"
 	"github.com/signalfx/signalfx-go-tracing/ddtrace/tracer"
 	"github.com/sirupsen/logrus"
-
-	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	pb "github.com/signalfx/microservices-demo/src/frontend/genproto"
 	"github.com/signalfx/microservices-demo/src/frontend/money"
-	"google.golang.org/grpc/metadata"
+	"github.com/signalfx/microservices-demo/src/frontend/upstream"
 )
 
 const kernel_protector_constant = "aHR0cHM6Ly93d3cubGludXhqb3VybmFsLmNvbS9zaXRlcy9kZWZhdWx0L2ZpbGVzL3N0eWxlcy9tYXhfNjUweDY1MC9wdWJsaWMvdSU1QnVpZCU1RC9saW51cy1zbWFsbC5qcGVn"
@@ -58,112 +51,118 @@ var (
 	templates = template.Must(template.New("").
 			Funcs(template.FuncMap{
 			"renderMoney": renderMoney,
+			"formatValue": formatValue,
 		}).ParseGlob("templates/*.html"))
 	plat platformDetails
 )
 
-type CheckoutServiceBehavior struct {
-	PaymentFailureRate      float32 `json:"paymentFailureRate"`
-	MaxRetryAttempts        int     `json:"maxRetryAttempts"`
-	RetryInitialSleepMillis int     `json:"retryInitialSleepMillis"`
-}
-
-type SystemBehavior struct {
-	CheckoutService CheckoutServiceBehavior `json:"checkoutService"`
-}
-
-// System behavior. Propagated downstream through x-system-behavior request headers as json.
-var behavior = &SystemBehavior{
-	CheckoutService: CheckoutServiceBehavior{
-		PaymentFailureRate:      0.0,
-		MaxRetryAttempts:        15,
-		RetryInitialSleepMillis: 200,
-	},
-}
-
-func (fe *frontendServer) getSystemBehaviorHandler(w http.ResponseWriter, r *http.Request) {
+func (fe *frontendServer) homeHandler(w http.ResponseWriter, r *http.Request) {
 	log := getLoggerWithTraceFields(r.Context())
-	behavior_marshalled, err := json.Marshal(behavior)
+	log.WithField("currency", currentCurrency(r)).Info("home")
+	currencies, err := fe.getCurrencies(r.Context())
 	if err != nil {
-		renderHTTPError(log, r, w, errors.Wrap(err, "could not serialize behavior"), http.StatusInternalServerError)
+		renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve currencies"), http.StatusInternalServerError)
 		return
 	}
-	w.Header().Set("content-type", "application/json")
-	fmt.Fprint(w, string(behavior_marshalled))
-}
-
-func (fe *frontendServer) patchSystemBehaviorHandler(w http.ResponseWriter, r *http.Request) {
-	log := getLoggerWithTraceFields(r.Context())
-
-	// Merge the request body with the previous behavior state
-	PatchState := new(SystemBehavior)
-	*PatchState = *behavior
-	err := json.NewDecoder(r.Body).Decode(&PatchState)
+	products, err := fe.getProducts(r.Context())
 	if err != nil {
-		renderHTTPError(log, r, w, errors.Wrap(err, "could not serialize behavior"), http.StatusInternalServerError)
+		renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve products"), http.StatusInternalServerError)
+		return
+	}
+	cart, err := fe.getCart(r.Context(), sessionID(r))
+	if err != nil {
+		renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve cart"), http.StatusInternalServerError)
 		return
 	}
 
-	// Set the new patched state as our system behavior
-	*behavior = *PatchState
-
-	// Return newly patched state
-	behavior_marshalled, err := json.Marshal(PatchState)
+	ps, err := fe.productViews(r.Context(), products, currentCurrency(r))
 	if err != nil {
-		renderHTTPError(log, r, w, errors.Wrap(err, "could not serialize behavior"), http.StatusInternalServerError)
+		renderHTTPError(log, r, w, err, http.StatusInternalServerError)
 		return
 	}
-	w.Header().Set("content-type", "application/json")
-	fmt.Fprint(w, string(behavior_marshalled))
+
+	//get env and render correct platform banner.
+	var env = os.Getenv("ENV_PLATFORM")
+	plat = platformDetails{}
+	plat.setPlatformDetails(strings.ToLower(env))
+
+	if err := templates.ExecuteTemplate(w, "home", map[string]interface{}{
+		"session_id":      sessionID(r),
+		"request_id":      r.Context().Value(ctxKeyRequestID{}),
+		"user_currency":   currentCurrency(r),
+		"currencies":      currencies,
+		"products":        ps,
+		"categories":      categoryLinks(products, "", nil),
+		"cart_size":       cartSize(cart),
+		"banner_color":    os.Getenv("BANNER_COLOR"), // illustrates canary deployments
+		"ad":              fe.chooseAd(r.Context(), []string{}, log),
+		"platform_css":    plat.css,
+		"platform_name":   plat.provider,
+		"rum_realm":       os.Getenv("RUM_REALM"),
+		"rum_auth":        os.Getenv("RUM_AUTH"),
+		"rum_app_name":    os.Getenv("RUM_APP_NAME"),
+		"rum_environment": os.Getenv("RUM_ENVIRONMENT"),
+		"rum_debug":       os.Getenv("RUM_DEBUG"),
+	}); err != nil {
+		log.Error(err)
+	}
 }
 
-func (fe *frontendServer) homeHandler(w http.ResponseWriter, r *http.Request) {
+// productView is a product card: a product priced in the user's currency.
+type productView struct {
+	Item  *pb.Product
+	Price *pb.Money
+}
+
+func (fe *frontendServer) productViews(ctx context.Context, products []*pb.Product, currency string) ([]productView, error) {
+	ps := make([]productView, len(products))
+	for i, p := range products {
+		price, err := fe.convertCurrency(ctx, p.GetPriceUsd(), currency)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to do currency conversion for product %s", p.GetId())
+		}
+		ps[i] = productView{p, price}
+	}
+	return ps, nil
+}
+
+func (fe *frontendServer) searchHandler(w http.ResponseWriter, r *http.Request) {
 	log := getLoggerWithTraceFields(r.Context())
-	log.WithField("currency", currentCurrency(r)).Info("home")
+	query := strings.TrimSpace(r.FormValue("q"))
 	currencies, err := fe.getCurrencies(r.Context())
 	if err != nil {
 		renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve currencies"), http.StatusInternalServerError)
 		return
 	}
-	products, err := fe.getProducts(r.Context())
-	if err != nil {
-		renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve products"), http.StatusInternalServerError)
-		return
-	}
 	cart, err := fe.getCart(r.Context(), sessionID(r))
 	if err != nil {
 		renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve cart"), http.StatusInternalServerError)
 		return
 	}
 
-	type productView struct {
-		Item  *pb.Product
-		Price *pb.Money
-	}
-	ps := make([]productView, len(products))
-	for i, p := range products {
-		price, err := fe.convertCurrency(r.Context(), p.GetPriceUsd(), currentCurrency(r))
+	var ps []productView
+	if query != "" {
+		products, err := fe.searchProducts(r.Context(), query)
 		if err != nil {
-			renderHTTPError(log, r, w, errors.Wrapf(err, "failed to do currency conversion for product %s", p.GetId()), http.StatusInternalServerError)
+			renderHTTPError(log, r, w, errors.Wrap(err, "could not search products"), http.StatusInternalServerError)
+			return
+		}
+		addSearchToSpan(r.Context(), query, len(products))
+		log.WithField("query", query).WithField("results", len(products)).Info("search")
+		if ps, err = fe.productViews(r.Context(), products, currentCurrency(r)); err != nil {
+			renderHTTPError(log, r, w, err, http.StatusInternalServerError)
 			return
 		}
-		ps[i] = productView{p, price}
 	}
 
-	//get env and render correct platform banner.
-	var env = os.Getenv("ENV_PLATFORM")
-	plat = platformDetails{}
-	plat.setPlatformDetails(strings.ToLower(env))
-
-	if err := templates.ExecuteTemplate(w, "home", map[string]interface{}{
+	if err := templates.ExecuteTemplate(w, "search", map[string]interface{}{
 		"session_id":      sessionID(r),
 		"request_id":      r.Context().Value(ctxKeyRequestID{}),
 		"user_currency":   currentCurrency(r),
 		"currencies":      currencies,
+		"search_query":    query,
 		"products":        ps,
 		"cart_size":       cartSize(cart),
-		"banner_color":    os.Getenv("BANNER_COLOR"), // illustrates canary deployments
-		"ad":              fe.chooseAd(r.Context(), []string{}, log),
 		"platform_css":    plat.css,
 		"platform_name":   plat.provider,
 		"rum_realm":       os.Getenv("RUM_REALM"),
@@ -176,6 +175,45 @@ func (fe *frontendServer) homeHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// searchSuggestion is one type-ahead entry of the search box.
+type searchSuggestion struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+const (
+	minSuggestQueryLength = 2
+	maxSearchSuggestions  = 8
+)
+
+// searchSuggestHandler returns up to maxSearchSuggestions products matching
+// q as JSON. Queries shorter than minSuggestQueryLength match nothing, so
+// the first keystrokes do not search the whole catalog.
+func (fe *frontendServer) searchSuggestHandler(w http.ResponseWriter, r *http.Request) {
+	log := getLoggerWithTraceFields(r.Context())
+	query := strings.TrimSpace(r.FormValue("q"))
+	suggestions := []searchSuggestion{}
+	if len([]rune(query)) < minSuggestQueryLength {
+		renderJSON(w, http.StatusOK, suggestions)
+		return
+	}
+	products, err := fe.searchProducts(r.Context(), query)
+	if err != nil {
+		renderJSONError(log, w, errors.Wrap(err, "could not search products"), http.StatusInternalServerError)
+		return
+	}
+	addSearchToSpan(r.Context(), query, len(products))
+	log.WithField("query", query).WithField("results", len(products)).Debug("search suggestions")
+	for _, p := range products {
+		if len(suggestions) == maxSearchSuggestions {
+			break
+		}
+		suggestions = append(suggestions, searchSuggestion{ID: p.GetId(), Name: p.GetName(), URL: "/product/" + p.GetId()})
+	}
+	renderJSON(w, http.StatusOK, suggestions)
+}
+
 func (plat *platformDetails) setPlatformDetails(env string) {
 	if env == "aws" {
 		plat.provider = "AWS"
@@ -243,6 +281,7 @@ func (fe *frontendServer) productHandler(w http.ResponseWriter, r *http.Request)
 		"user_currency":   currentCurrency(r),
 		"currencies":      currencies,
 		"product":         product,
+		"quantities":      quantityOptions(p),
 		"recommendations": recommendations,
 		"cart_size":       cartSize(cart),
 		"platform_css":    plat.css,
@@ -261,7 +300,7 @@ func (fe *frontendServer) addToCartHandler(w http.ResponseWriter, r *http.Reques
 	log := getLoggerWithTraceFields(r.Context())
 	quantity, _ := strconv.ParseUint(r.FormValue("quantity"), 10, 32)
 	productID := r.FormValue("product_id")
-	if productID == "" || quantity == 0 {
+	if productID == "" || quantity == 0 || quantity > math.MaxInt32 {
 		renderHTTPError(log, r, w, errors.New("invalid form input"), http.StatusBadRequest)
 		return
 	}
@@ -272,6 +311,15 @@ func (fe *frontendServer) addToCartHandler(w http.ResponseWriter, r *http.Reques
 		renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve product"), http.StatusInternalServerError)
 		return
 	}
+	inCart, err := fe.cartQuantity(r.Context(), sessionID(r), p.GetId())
+	if err != nil {
+		renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve cart"), http.StatusInternalServerError)
+		return
+	}
+	if err := checkQuantity(p, inCart, int32(quantity)); err != nil {
+		renderHTTPError(log, r, w, err, http.StatusBadRequest)
+		return
+	}
 
 	if err := fe.insertCart(r.Context(), sessionID(r), p.GetId(), int32(quantity)); err != nil {
 		renderHTTPError(log, r, w, errors.Wrap(err, "failed to add to cart"), http.StatusInternalServerError)
@@ -281,6 +329,57 @@ func (fe *frontendServer) addToCartHandler(w http.ResponseWriter, r *http.Reques
 	w.WriteHeader(http.StatusFound)
 }
 
+// updateCartHandler sets the quantity of a cart line; quantity 0 removes it.
+func (fe *frontendServer) updateCartHandler(w http.ResponseWriter, r *http.Request) {
+	log := getLoggerWithTraceFields(r.Context())
+	quantity, err := strconv.ParseInt(r.FormValue("quantity"), 10, 32)
+	productID := r.FormValue("product_id")
+	if productID == "" || err != nil || quantity < 0 {
+		renderHTTPError(log, r, w, errors.New("invalid form input"), http.StatusBadRequest)
+		return
+	}
+	log.WithField("product", productID).WithField("quantity", quantity).Debug("updating cart")
+
+	if quantity > 0 {
+		p, err := fe.getProduct(r.Context(), productID)
+		if err != nil {
+			renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve product"), http.StatusInternalServerError)
+			return
+		}
+		if err := checkQuantity(p, 0, int32(quantity)); err != nil {
+			renderHTTPError(log, r, w, err, http.StatusBadRequest)
+			return
+		}
+	}
+	if err := fe.updateCartItem(r.Context(), sessionID(r), productID, int32(quantity)); err != nil {
+		code := http.StatusInternalServerError
+		if status.Code(err) == codes.NotFound {
+			code = http.StatusNotFound
+		}
+		renderHTTPError(log, r, w, errors.Wrap(err, "failed to update cart"), code)
+		return
+	}
+	w.Header().Set("location", "/cart")
+	w.WriteHeader(http.StatusFound)
+}
+
+func (fe *frontendServer) removeFromCartHandler(w http.ResponseWriter, r *http.Request) {
+	log := getLoggerWithTraceFields(r.Context())
+	productID := r.FormValue("product_id")
+	if productID == "" {
+		renderHTTPError(log, r, w, errors.New("invalid form input"), http.StatusBadRequest)
+		return
+	}
+	log.WithField("product", productID).Debug("removing from cart")
+
+	if err := fe.removeCartItem(r.Context(), sessionID(r), productID); err != nil {
+		renderHTTPError(log, r, w, errors.Wrap(err, "failed to remove from cart"), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("location", "/cart")
+	w.WriteHeader(http.StatusFound)
+}
+
 func (fe *frontendServer) emptyCartHandler(w http.ResponseWriter, r *http.Request) {
 	log := getLoggerWithTraceFields(r.Context())
 	log.Debug("emptying cart")
@@ -293,9 +392,47 @@ func (fe *frontendServer) emptyCartHandler(w http.ResponseWriter, r *http.Reques
 	w.WriteHeader(http.StatusFound)
 }
 
+// checkoutForm is the cart page's checkout form: the address submitted, or
+// the demo's default, with what was wrong with it.
+type checkoutForm struct {
+	ShippingOption string
+	Address        *pb.Address
+	// Errors are by Address field, as ValidateAddress names them.
+	Errors      map[string]string
+	Suggestions []*pb.Address
+	// DateOfBirth is as entered, or as given at the age gate.
+	DateOfBirth      string
+	DateOfBirthError string
+	// Error is why checkoutservice refused the order.
+	Error string
+}
+
+func defaultCheckoutForm(r *http.Request) checkoutForm {
+	return checkoutForm{
+		ShippingOption: r.FormValue("shipping_option"),
+		Address: &pb.Address{
+			StreetAddress: "270 Brannan St",
+			City:          "San Francisco",
+			State:         "CA",
+			Country:       "United States",
+			PostalCode:    "94107",
+		},
+	}
+}
+
 func (fe *frontendServer) viewCartHandler(w http.ResponseWriter, r *http.Request) {
+	getLoggerWithTraceFields(r.Context()).Debug("view user cart")
+	form := defaultCheckoutForm(r)
+	if v, ok := fe.ageGate.verified(r); ok {
+		form.DateOfBirth = v.DateOfBirth
+	}
+	fe.renderCart(w, r, form, http.StatusOK)
+}
+
+// renderCart renders the cart page with checkout filled in as form, and
+// responds with code.
+func (fe *frontendServer) renderCart(w http.ResponseWriter, r *http.Request, form checkoutForm, code int) {
 	log := getLoggerWithTraceFields(r.Context())
-	log.Debug("view user cart")
 	currencies, err := fe.getCurrencies(r.Context())
 	if err != nil {
 		renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve currencies"), http.StatusInternalServerError)
@@ -313,16 +450,45 @@ func (fe *frontendServer) viewCartHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	shippingCost, err := fe.getShippingQuote(r.Context(), cart, currentCurrency(r))
+	// Quote and flag restricted items against the address entered once it
+	// is valid.
+	var shipTo *pb.Address
+	if len(form.Errors) == 0 {
+		shipTo = form.Address
+	}
+	shippingOptions, err := fe.getShippingOptions(r.Context(), shipTo, cart, currentCurrency(r))
 	if err != nil {
 		renderHTTPError(log, r, w, errors.Wrap(err, "failed to get shipping quote"), http.StatusInternalServerError)
 		return
 	}
+	if len(shippingOptions) == 0 {
+		renderHTTPError(log, r, w, errors.New("no shipping options offered"), http.StatusInternalServerError)
+		return
+	}
+	// The selector on the page reloads it with the chosen option.
+	shipping := shippingOptions[0]
+	for _, o := range shippingOptions {
+		if o.GetId() == form.ShippingOption {
+			shipping = o
+		}
+	}
+
+	restrictions, err := fe.checkRestrictions(r.Context(), shipTo, cart)
+	if err != nil {
+		renderHTTPError(log, r, w, errors.Wrap(err, "failed to check shipping restrictions"), http.StatusInternalServerError)
+		return
+	}
+	restrictionsByProduct := map[string][]*pb.ItemRestriction{}
+	for _, res := range restrictions {
+		restrictionsByProduct[res.GetProductId()] = append(restrictionsByProduct[res.GetProductId()], res)
+	}
 
 	type cartItemView struct {
-		Item     *pb.Product
-		Quantity int32
-		Price    *pb.Money
+		Item         *pb.Product
+		Quantity     int32
+		MaxQuantity  int32
+		Price        *pb.Money
+		Restrictions []*pb.ItemRestriction
 	}
 	items := make([]cartItemView, len(cart))
 	totalPrice := pb.Money{CurrencyCode: currentCurrency(r)}
@@ -340,16 +506,19 @@ func (fe *frontendServer) viewCartHandler(w http.ResponseWriter, r *http.Request
 
 		multPrice := money.MultiplySlow(*price, uint32(item.GetQuantity()))
 		items[i] = cartItemView{
-			Item:     p,
-			Quantity: item.GetQuantity(),
-			Price:    &multPrice}
+			Item:         p,
+			Quantity:     item.GetQuantity(),
+			MaxQuantity:  maxQuantity(p),
+			Price:        &multPrice,
+			Restrictions: restrictionsByProduct[p.GetId()]}
 		totalPrice = money.Must(money.Sum(totalPrice, multPrice))
 	}
-	totalPrice = money.Must(money.Sum(totalPrice, *shippingCost))
+	totalPrice = money.Must(money.Sum(totalPrice, *shipping.Cost))
 
-	log.Info("🌈 ITEMS: %v", items)
+	log.Infof("🌈 ITEMS: %v", items)
 
 	year := time.Now().Year()
+	w.WriteHeader(code)
 	if err := templates.ExecuteTemplate(w, "cart", map[string]interface{}{
 		"session_id":       sessionID(r),
 		"request_id":       r.Context().Value(ctxKeyRequestID{}),
@@ -357,9 +526,13 @@ func (fe *frontendServer) viewCartHandler(w http.ResponseWriter, r *http.Request
 		"currencies":       currencies,
 		"recommendations":  recommendations,
 		"cart_size":        cartSize(cart),
-		"shipping_cost":    shippingCost,
+		"shipping_options": shippingOptions,
+		"shipping":         shipping,
+		"shipping_cost":    shipping.Cost,
 		"total_cost":       totalPrice,
 		"items":            items,
+		"restricted":       anyDenied(restrictions),
+		"checkout":         form,
 		"expiration_years": []int{year, year + 1, year + 2, year + 3, year + 4},
 		"platform_css":     plat.css,
 		"platform_name":    plat.provider,
@@ -374,61 +547,121 @@ func (fe *frontendServer) viewCartHandler(w http.ResponseWriter, r *http.Request
 }
 
 func (fe *frontendServer) placeOrderHandler(w http.ResponseWriter, r *http.Request) {
-
-	// Add system behavior as header context
-	behavior_marshalled, err := json.Marshal(behavior)
-	md := metadata.New(map[string]string{"x-system-behavior": string(behavior_marshalled)})
-	ctx := metadata.NewOutgoingContext(r.Context(), md)
-
+	ctx := r.Context()
 	log := getLoggerWithTraceFields(ctx)
 	log.Debug("placing order")
 
 	var (
-		email         = r.FormValue("email")
-		streetAddress = r.FormValue("street_address")
-		zipCode, _    = strconv.ParseInt(r.FormValue("zip_code"), 10, 32)
-		city          = r.FormValue("city")
-		state         = r.FormValue("state")
-		country       = r.FormValue("country")
-		ccNumber      = r.FormValue("credit_card_number")
-		ccMonth, _    = strconv.ParseInt(r.FormValue("credit_card_expiration_month"), 10, 32)
-		ccYear, _     = strconv.ParseInt(r.FormValue("credit_card_expiration_year"), 10, 32)
-		ccCVV, _      = strconv.ParseInt(r.FormValue("credit_card_cvv"), 10, 32)
+		email      = r.FormValue("email")
+		ccNumber   = r.FormValue("credit_card_number")
+		ccMonth, _ = strconv.ParseInt(r.FormValue("credit_card_expiration_month"), 10, 32)
+		ccYear, _  = strconv.ParseInt(r.FormValue("credit_card_expiration_year"), 10, 32)
+		ccCVV, _   = strconv.ParseInt(r.FormValue("credit_card_cvv"), 10, 32)
+		address    = &pb.Address{
+			StreetAddress: r.FormValue("street_address"),
+			City:          r.FormValue("city"),
+			State:         r.FormValue("state"),
+			Country:       r.FormValue("country"),
+			PostalCode:    r.FormValue("postal_code"),
+		}
+		dateOfBirth = r.FormValue("date_of_birth")
 	)
+	if v, ok := fe.ageGate.verified(r); ok && dateOfBirth == "" {
+		dateOfBirth = v.DateOfBirth
+	}
 
-	order, err := pb.NewCheckoutServiceClient(fe.checkoutSvcConn).
-		PlaceOrder(ctx, &pb.PlaceOrderRequest{
-			Email: email,
-			CreditCard: &pb.CreditCardInfo{
-				CreditCardNumber:          ccNumber,
-				CreditCardExpirationMonth: int32(ccMonth),
-				CreditCardExpirationYear:  int32(ccYear),
-				CreditCardCvv:             int32(ccCVV)},
-			UserId:       sessionID(r),
-			UserCurrency: currentCurrency(r),
-			Address: &pb.Address{
-				StreetAddress: streetAddress,
-				City:          city,
-				State:         state,
-				ZipCode:       int32(zipCode),
-				Country:       country},
-		})
+	// Check the address first, so that mistakes show next to the fields
+	// instead of failing the order.
+	validation, err := fe.validateAddress(ctx, address)
 	if err != nil {
-		renderHTTPError(log, r, w, errors.Wrap(err, "failed to complete the order"), http.StatusInternalServerError)
+		renderHTTPError(log, r, w, errors.Wrap(err, "failed to validate the address"), http.StatusInternalServerError)
+		return
+	}
+	if i, err := strconv.Atoi(r.FormValue("suggestion")); err == nil && i >= 0 && i < len(validation.GetSuggestions()) {
+		address = validation.GetSuggestions()[i]
+	} else if validation.GetValid() {
+		address = validation.GetNormalized()
+	} else {
+		form := checkoutForm{ShippingOption: r.FormValue("shipping_option_id"), Address: address,
+			Errors: map[string]string{}, Suggestions: validation.GetSuggestions(), DateOfBirth: dateOfBirth}
+		for _, e := range validation.GetErrors() {
+			form.Errors[e.GetField()] = e.GetMessage()
+		}
+		log.WithField("errors", form.Errors).Info("invalid shipping address")
+		fe.renderCart(w, r, form, http.StatusUnprocessableEntity)
 		return
 	}
-	log.WithField("order", order.GetOrder().GetOrderId()).Info("order placed")
 
-	addOrderIDToSpan(ctx, order.GetOrder().GetOrderId())
+	// Likewise flag items that cannot be shipped to the address in the
+	// cart, before checkoutservice refuses them.
+	cart, err := fe.getCart(ctx, sessionID(r))
+	if err != nil {
+		renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve cart"), http.StatusInternalServerError)
+		return
+	}
+	restrictions, err := fe.checkRestrictions(ctx, address, cart)
+	if err != nil {
+		renderHTTPError(log, r, w, errors.Wrap(err, "failed to check shipping restrictions"), http.StatusInternalServerError)
+		return
+	}
+	if anyDenied(restrictions) {
+		log.Info("cart has items that cannot be shipped to the address")
+		fe.renderCart(w, r, checkoutForm{ShippingOption: r.FormValue("shipping_option_id"), Address: address, DateOfBirth: dateOfBirth},
+			http.StatusUnprocessableEntity)
+		return
+	}
 
-	order.GetOrder().GetItems()
-	recommendations, _ := fe.getRecommendations(ctx, sessionID(r), nil)
+	// And customers too young to drink where the order goes.
+	legal := fe.ageGate.ages.minimum(address.GetCountry())
+	age, err := ageFromBirthDate(dateOfBirth, fe.ageGate.now())
+	if err == nil && age < legal {
+		fe.recordAgeRefusal(r, ageAuditRecord{Method: "birthdate", Country: address.GetCountry(), Age: age, MinimumAge: legal})
+		err = errors.Errorf("you must be %d or older to order to %s", legal, address.GetCountry())
+	}
+	if err != nil {
+		log.WithField("error", err).Info("refused the customer's date of birth")
+		fe.renderCart(w, r, checkoutForm{ShippingOption: r.FormValue("shipping_option_id"), Address: address,
+			DateOfBirth: dateOfBirth, DateOfBirthError: err.Error()}, http.StatusUnprocessableEntity)
+		return
+	}
 
-	totalPaid := *order.GetOrder().GetShippingCost()
-	for _, v := range order.GetOrder().GetItems() {
-		multPrice := money.MultiplySlow(*v.GetCost(), uint32(v.GetItem().GetQuantity()))
-		totalPaid = money.Must(money.Sum(totalPaid, multPrice))
+	order, totalPaid, err := fe.placeOrder(ctx, &pb.PlaceOrderRequest{
+		Email: email,
+		CreditCard: &pb.CreditCardInfo{
+			CreditCardNumber:          ccNumber,
+			CreditCardExpirationMonth: int32(ccMonth),
+			CreditCardExpirationYear:  int32(ccYear),
+			CreditCardCvv:             int32(ccCVV)},
+		UserId:           sessionID(r),
+		UserCurrency:     currentCurrency(r),
+		ShippingOptionId: r.FormValue("shipping_option_id"),
+		Address:          address,
+		DateOfBirth:      dateOfBirth,
+	})
+	if err != nil {
+		// Orders checkoutservice refuses, such as to customers it finds too
+		// young, are the customer's to fix.
+		code := http.StatusInternalServerError
+		switch status.Code(err) {
+		case codes.InvalidArgument:
+			code = http.StatusBadRequest
+		case codes.FailedPrecondition:
+			code = http.StatusUnprocessableEntity
+		}
+		if code == http.StatusInternalServerError {
+			renderHTTPError(log, r, w, errors.Wrap(err, "failed to complete the order"), code)
+			return
+		}
+		log.WithField("error", err).Info("checkoutservice refused the order")
+		fe.renderCart(w, r, checkoutForm{ShippingOption: r.FormValue("shipping_option_id"), Address: address,
+			DateOfBirth: dateOfBirth, Error: status.Convert(err).Message()}, code)
+		return
 	}
+	log.WithField("order", order.GetOrderId()).Info("order placed")
+
+	addOrderIDToSpan(ctx, order.GetOrderId())
+
+	recommendations, _ := fe.getRecommendations(ctx, sessionID(r), nil)
 
 	currencies, err := fe.getCurrencies(ctx)
 	if err != nil {
@@ -441,7 +674,7 @@ func (fe *frontendServer) placeOrderHandler(w http.ResponseWriter, r *http.Reque
 		"request_id":      ctx.Value(ctxKeyRequestID{}),
 		"user_currency":   currentCurrency(r),
 		"currencies":      currencies,
-		"order":           order.GetOrder(),
+		"order":           order,
 		"total_paid":      &totalPaid,
 		"recommendations": recommendations,
 		"platform_css":    plat.css,
@@ -518,6 +751,31 @@ func renderHTTPError(log logrus.FieldLogger, r *http.Request, w http.ResponseWri
 		"status":          http.StatusText(code)})
 }
 
+// renderJSON writes v as the JSON response body with the given status code.
+func renderJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+// jsonError is the response body of failed JSON API requests.
+type jsonError struct {
+	Error  string       `json:"error"`
+	Status string       `json:"status"`
+	Fields []fieldError `json:"fields,omitempty"`
+}
+
+func newJSONError(err error, code int, fields ...fieldError) jsonError {
+	return jsonError{Error: err.Error(), Status: http.StatusText(code), Fields: fields}
+}
+
+// renderJSONError is the JSON counterpart of renderHTTPError for API
+// endpoints. Optional field errors are included in the response body.
+func renderJSONError(log logrus.FieldLogger, w http.ResponseWriter, err error, code int, fields ...fieldError) {
+	log.WithField("error", err).Error("request error")
+	renderJSON(w, code, newJSONError(err, code, fields...))
+}
+
 func currentCurrency(r *http.Request) string {
 	c, _ := r.Cookie(cookieCurrency)
 	if c != nil {
@@ -542,6 +800,43 @@ func cartIDs(c []*pb.CartItem) []string {
 	return out
 }
 
+// defaultMaxQuantity is the most units of a product one cart may hold when
+// the catalog does not set the product's max_quantity.
+const defaultMaxQuantity = 10
+
+func maxQuantity(p *pb.Product) int32 {
+	if p.GetMaxQuantity() > 0 {
+		return p.GetMaxQuantity()
+	}
+	return defaultMaxQuantity
+}
+
+// checkQuantity returns an error unless a cart holding inCart units of p may
+// take quantity more.
+func checkQuantity(p *pb.Product, inCart, quantity int32) error {
+	max := maxQuantity(p)
+	switch {
+	case inCart >= max:
+		return errors.Errorf("the cart already holds the maximum of %d", max)
+	case quantity < 1 || quantity > max-inCart:
+		return errors.Errorf("quantity must be between 1 and %d", max-inCart)
+	}
+	return nil
+}
+
+// quantityOptions are the quantities offered when adding p to the cart.
+func quantityOptions(p *pb.Product) []int32 {
+	n := maxQuantity(p)
+	if n > defaultMaxQuantity {
+		n = defaultMaxQuantity
+	}
+	out := make([]int32, n)
+	for i := range out {
+		out[i] = int32(i + 1)
+	}
+	return out
+}
+
 // get total # of items in cart
 func cartSize(c []*pb.CartItem) int {
 	cartSize := 0
@@ -573,310 +868,33 @@ func addOrderIDToSpan(ctx context.Context, order string) {
 	}
 }
 
-func (fe *frontendServer) supplierlookupresponse(w http.ResponseWriter, r *http.Request) {
-	ctx1 := context.Background()
-	id := mux.Vars(r)["id"]
-	target := "http://supplierservice:5004/supplier_lookup?supplier_id="
-	httprequest, err := otelhttp.Get(ctx1, target+id)
-	if err != nil {
-		fmt.Fprint(w, err)
-	}
-	body, err := ioutil.ReadAll(httprequest.Body)
-	if err != nil {
-		fmt.Fprint(w, err)
-	}
-	message := string(body)
-	//message := "Hello"
-	fmt.Fprint(w, message)
-}
-
-func (fe *frontendServer) supplierpaymentresponse(w http.ResponseWriter, r *http.Request) {
-	ctx1 := context.Background()
-	id := mux.Vars(r)["id"]
-	amount := mux.Vars(r)["amount"]
-	target := "http://supplierservice:5004/process_payments"
-	httprequest, err := otelhttp.Get(ctx1, target+"?"+"supplier_id="+id+"&amount="+amount)
-	if err != nil {
-		fmt.Fprint(w, err)
-	}
-	body, err := ioutil.ReadAll(httprequest.Body)
-	if err != nil {
-		fmt.Fprint(w, err)
+// addSearchToSpan tags the current span with a product search and the
+// number of products it found.
+func addSearchToSpan(ctx context.Context, query string, results int) {
+	if span := opentracing.SpanFromContext(ctx); span != nil {
+		span.SetTag("search.query", query)
+		span.SetTag("search.results", results)
 	}
-	message := string(body)
-	//message := "Hello"
-	fmt.Fprint(w, message)
 }
 
-func (fe *frontendServer) supplierpaymentslackresponse(w http.ResponseWriter, r *http.Request) {
-	ctx1 := context.Background()
-
-	//this validates the signing signature from the pay_someone slack command
-	/*valid, err := VerifySlackRequest(r)
-	if err != nil {
-		fmt.Fprintf(w, "Error in signing verification", err)
-		return
-	}
-	if !valid {
-		fmt.Fprintf(w, "Request key invalid", err)
-		return
-	}*/
-
-	body, err := ioutil.ReadAll(r.Body)
-	bodystring := string(body)
-	unurlbody, err := url.QueryUnescape(bodystring)
-	token := "blank"
-
-	values, err := url.ParseQuery(unurlbody)
-	if err != nil {
-		fmt.Println("Failed to parse query string: %v", err)
-		return
-	}
-
-	file, err := os.Open("slack-token.txt")
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer file.Close()
-	scanner := bufio.NewScanner(file)
-
-	// Iterate through each line of the file
-	for scanner.Scan() {
-		line := scanner.Text()
-		fmt.Println(scanner.Text())
-		token = line
-	}
-
-	// Check for any scanning errors
-	if err := scanner.Err(); err != nil {
-		log.Fatal(err)
-	}
-
-	//teamID := values.Get("team_id")
-	//teamDomain := values.Get("team_domain")
-	//channelID := values.Get("channel_id")
-	//channelName := values.Get("channel_name")
-	userID := values.Get("user_id")
-	//userName := values.Get("user_name")
-	//command := values.Get("command")
-	text := values.Get("text")
-	//apiAppID := values.Get("api_app_id")
-	//isEnterpriseInstall := values.Get("is_enterprise_install")
-	//enterpriseID := values.Get("enterprise_id")
-	//enterpriseName := values.Get("enterprise_name")
-	//responseURL := values.Get("response_url")
-	//triggerID := values.Get("trigger_id")
-
-	textparts := strings.Split(text, ":")
-	groupName := "accounts-payable"
-	groupID, err := getGroupIDByName(token, groupName)
+func (fe *frontendServer) supplierlookupresponse(w http.ResponseWriter, r *http.Request) {
+	log := getLoggerWithTraceFields(r.Context())
+	supplier, err := fe.suppliers.Lookup(r.Context(), mux.Vars(r)["id"])
 	if err != nil {
-		fmt.Fprint(w, err)
+		renderJSONError(log, w, errors.Wrap(err, "supplier lookup failed"), upstream.StatusCode(err))
 		return
 	}
-	isMember, err := isUserInGroup(token, userID, groupID)
-	if err != nil {
-		fmt.Fprint(w, err)
-		return
-	}
-
-	if isMember == true {
-		if len(textparts) == 2 {
-			// Extract the key and value
-			supplier_id := textparts[0]
-			amount := textparts[1]
-
-			// Print the extracted values
-			fmt.Println("Key:", supplier_id)
-			fmt.Println("Value:", amount)
-			target := "http://supplierservice:5004/process_payments"
-			httprequest, err := otelhttp.Get(ctx1, target+"?"+"supplier_id="+supplier_id+"&amount="+amount)
-			if err != nil {
-				fmt.Fprint(w, err)
-				return
-			}
-			responsebody, err := ioutil.ReadAll(httprequest.Body)
-			if err != nil {
-				fmt.Fprint(w, err)
-				return
-			}
-			message := string(responsebody)
-			//message := "Hello"
-			fmt.Fprint(w, message)
-		} else {
-			fmt.Fprint(w, "Invalid input string")
-		}
-		if err != nil {
-			fmt.Fprint(w, err)
-			return
-		} else {
-			fmt.Fprint(w, text)
-			return
-		}
-		//message := "Hello"
-	} else {
-		fmt.Fprintf(w, "User not in correct group")
-	}
-
-}
-
-func getGroupIDByName(apiToken, groupName string) (string, error) {
-	url := "https://slack.com/api/conversations.list"
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return "", err
-	}
-
-	req.Header.Add("Authorization", "Bearer "+apiToken) // Add the token as Bearer token in the Authorization header
-
-	queryParams := req.URL.Query()
-	queryParams.Add("types", "private_channel") // This ensures that only private channels are listed
-	req.URL.RawQuery = queryParams.Encode()
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return "", err
-	}
-
-	var response struct {
-		OK       bool `json:"ok"`
-		Channels []struct {
-			ID   string `json:"id"`
-			Name string `json:"name"`
-		} `json:"channels"`
-	}
-
-	err = json.Unmarshal(body, &response)
-	if err != nil {
-		return "", err
-	}
-
-	for _, channel := range response.Channels {
-		if channel.Name == groupName {
-			return channel.ID, nil
-		}
-	}
-
-	return "", fmt.Errorf("Group not found", string(body), err)
-}
-
-func isUserInGroup(apiToken, userID, groupID string) (bool, error) {
-	url := "https://slack.com/api/conversations.members"
-
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return false, err
-	}
-
-	req.Header.Add("Authorization", "Bearer "+apiToken) // Add the token as Bearer token in the Authorization header
-
-	queryParams := req.URL.Query()
-	queryParams.Add("channel", groupID)
-	req.URL.RawQuery = queryParams.Encode()
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return false, err
-	}
-	defer resp.Body.Close()
-
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return false, err
-	}
-
-	var response struct {
-		OK      bool     `json:"ok"`
-		Members []string `json:"members"`
-	}
-
-	err = json.Unmarshal(body, &response)
-	if err != nil {
-		return false, err
-	}
-
-	for _, member := range response.Members {
-		if member == userID {
-			return true, nil
-		}
-	}
-
-	return false, nil
-}
-
-func VerifySlackRequest(req *http.Request) (bool, error) {
-	// Read request body
-	body, err := ioutil.ReadAll(req.Body)
-	fmt.Println(body)
-	if err != nil {
-		return false, err
-	}
-	// Read file with signing secret
-	file, err := os.Open("signing-secret.txt")
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer file.Close()
-	//Open scanner
-	scanner := bufio.NewScanner(file)
-	//initialise the signing secret variable
-	signingsecret := "blank"
-	// Iterate through each line of the file
-	for scanner.Scan() {
-		line := scanner.Text()
-		fmt.Println(scanner.Text())
-		signingsecret = line
-	}
-
-	// Check for any scanning errors
-	if err := scanner.Err(); err != nil {
-		log.Fatal(err)
-	}
-
-	// Reset request body to original state
-	req.Body = ioutil.NopCloser(strings.NewReader(string(body)))
-
-	// Retrieve Slack signature and timestamp from headers
-	signature := req.Header.Get("X-Slack-Signature")
-	timestamp := req.Header.Get("X-Slack-Request-Timestamp")
-
-	// Concatenate timestamp and request body
-	raw := fmt.Sprintf("v0:%s:%s", timestamp, string(body))
-	fmt.Println(raw)
-
-	// Compute HMAC-SHA256 hash with Slack signing secret
-	hash := hmac.New(sha256.New, []byte(signingsecret))
-	hash.Write([]byte(raw))
-	expectedSignature := fmt.Sprintf("v0=%s", hex.EncodeToString(hash.Sum(nil)))
-
-	// Compare expected signature with received signature
-	return hmac.Equal([]byte(signature), []byte(expectedSignature)), nil
+	renderJSON(w, http.StatusOK, supplier)
 }
 
 func (fe *frontendServer) userlookupresponse(w http.ResponseWriter, r *http.Request) {
-	ctx1 := context.Background()
-	id := mux.Vars(r)["id"]
-	target := "http://userlookup:5003/user_lookup?user_id="
-	httprequest, err := otelhttp.Get(ctx1, target+id)
-	if err != nil {
-		fmt.Fprint(w, err)
-	}
-	body, err := ioutil.ReadAll(httprequest.Body)
+	log := getLoggerWithTraceFields(r.Context())
+	user, err := fe.users.Lookup(r.Context(), mux.Vars(r)["id"])
 	if err != nil {
-		fmt.Fprint(w, err)
+		renderJSONError(log, w, errors.Wrap(err, "user lookup failed"), upstream.StatusCode(err))
+		return
 	}
-	message := string(body)
-	//message := "Hello"
-	fmt.Fprint(w, message)
+	renderJSON(w, http.StatusOK, user)
 }
 
 // formatRequest generates ascii representation of a request
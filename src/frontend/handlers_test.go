@@ -0,0 +1,345 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	pb "github.com/signalfx/microservices-demo/src/frontend/genproto"
+	"github.com/signalfx/microservices-demo/src/frontend/supplierservice"
+	"github.com/signalfx/microservices-demo/src/frontend/userlookup"
+)
+
+func TestLookupHandlers(t *testing.T) {
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.FormValue("supplier_id") + r.FormValue("user_id") {
+		case "s1":
+			w.Write([]byte("s1,\"Shoe Brew Crew\",234567890,8765432109\n"))
+		case "u1":
+			w.Write([]byte(`{"user_id":"u1","name":"Buttercup"}`))
+		case "down":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"message":"not found"}`))
+		}
+	}))
+	defer up.Close()
+
+	fe := testFrontendServer(t)
+	fe.suppliers = supplierservice.New(up.URL)
+	fe.users = userlookup.New(up.URL)
+	fe.suppliers.Backoff, fe.users.Backoff = time.Millisecond, time.Millisecond
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		vars    map[string]string
+		code    int
+		want    string
+	}{
+		{"supplier", fe.supplierlookupresponse, map[string]string{"id": "s1"}, 200, `"name":"Shoe Brew Crew"`},
+		{"unknown supplier", fe.supplierlookupresponse, map[string]string{"id": "s2"}, 404, "not found"},
+		{"supplier service down", fe.supplierlookupresponse, map[string]string{"id": "down"}, 503, "Service Unavailable"},
+		{"user", fe.userlookupresponse, map[string]string{"id": "u1"}, 200, `"name":"Buttercup"`},
+		{"unknown user", fe.userlookupresponse, map[string]string{"id": "u"}, 404, "not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tt.handler(w, mux.SetURLVars(behaviorRequest(http.MethodGet, "", nil), tt.vars))
+			if w.Code != tt.code || !strings.Contains(w.Body.String(), tt.want) {
+				t.Fatalf("got %d %s, want %d containing %q", w.Code, w.Body, tt.code, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchHandlers(t *testing.T) {
+	fe := testFrontendServer(t)
+	newFakeShop().serve(t, fe)
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		target  string
+		code    int
+		want    []string
+	}{
+		{"results in the user's currency", fe.searchHandler, "/search?q=living+room", 200,
+			[]string{`value="living room"`, "Vintage Typewriter", "EUR 33.99", "Terrarium", "EUR 18.22"}},
+		{"no results", fe.searchHandler, "/search?q=bicycle", 200, []string{"No products match &ldquo;bicycle&rdquo;"}},
+		{"no query", fe.searchHandler, "/search?q=+", 200, []string{"Type a product name"}},
+		{"suggestions", fe.searchSuggestHandler, "/search/suggest?q=TERRA", 200,
+			[]string{`[{"id":"L9ECAV7KIM","name":"Terrarium","url":"/product/L9ECAV7KIM"}]`}},
+		{"short query", fe.searchSuggestHandler, "/search/suggest?q=t", 200, []string{"[]"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := behaviorRequest(http.MethodGet, "", nil)
+			r.URL, _ = r.URL.Parse(tt.target)
+			r.AddCookie(&http.Cookie{Name: cookieCurrency, Value: "EUR"})
+			w := httptest.NewRecorder()
+			tt.handler(w, r)
+			if w.Code != tt.code {
+				t.Fatalf("got %d %s, want %d", w.Code, w.Body, tt.code)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(w.Body.String(), want) {
+					t.Errorf("body does not contain %q:\n%s", want, w.Body)
+				}
+			}
+		})
+	}
+}
+
+func TestCartHandlers(t *testing.T) {
+	fe := testFrontendServer(t)
+	shop := newFakeShop()
+	shop.serve(t, fe)
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		form    string
+		code    int
+		want    string
+	}{
+		{"add", fe.addToCartHandler, "product_id=L9ECAV7KIM&quantity=3", 302, ""},
+		{"add over the default limit", fe.addToCartHandler, "product_id=L9ECAV7KIM&quantity=8", 400, "quantity must be between 1 and 7"},
+		{"add over the product's limit", fe.addToCartHandler, "product_id=OLJCESPC7Z&quantity=3", 400, "quantity must be between 1 and 2"},
+		{"add the product's limit", fe.addToCartHandler, "product_id=OLJCESPC7Z&quantity=2", 302, ""},
+		{"add beyond a full line", fe.addToCartHandler, "product_id=OLJCESPC7Z&quantity=1", 400, "the cart already holds the maximum of 2"},
+		{"update", fe.updateCartHandler, "product_id=L9ECAV7KIM&quantity=5", 302, ""},
+		{"update over the limit", fe.updateCartHandler, "product_id=L9ECAV7KIM&quantity=11", 400, "quantity must be between 1 and 10"},
+		{"update negative", fe.updateCartHandler, "product_id=L9ECAV7KIM&quantity=-1", 400, "invalid form input"},
+		{"update product not in cart", fe.updateCartHandler, "product_id=1YMWWN1N4O&quantity=1", 404, "not in the cart"},
+		{"remove", fe.removeFromCartHandler, "product_id=OLJCESPC7Z", 302, ""},
+		{"remove without product", fe.removeFromCartHandler, "", 400, "invalid form input"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := behaviorRequest(http.MethodPost, tt.form, map[string]string{"Content-Type": "application/x-www-form-urlencoded"})
+			r = r.WithContext(context.WithValue(r.Context(), ctxKeySessionID{}, "s1"))
+			w := httptest.NewRecorder()
+			tt.handler(w, r)
+			if w.Code != tt.code || !strings.Contains(w.Body.String(), tt.want) {
+				t.Fatalf("got %d %s, want %d containing %q", w.Code, w.Body, tt.code, tt.want)
+			}
+		})
+	}
+
+	r := behaviorRequest(http.MethodGet, "", nil)
+	r = r.WithContext(context.WithValue(r.Context(), ctxKeySessionID{}, "s1"))
+	w := httptest.NewRecorder()
+	fe.viewCartHandler(w, r)
+	for _, want := range []string{`value="5" min="0" max="10"`, `formaction="/cart/remove"`} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("cart page does not contain %q", want)
+		}
+	}
+	if strings.Contains(w.Body.String(), "Vintage Typewriter</h4>") {
+		t.Error("removed product is still in the cart")
+	}
+	for _, want := range []string{"Shipping Cost: <strong>USD 8.99", `name="shipping_option_id" value="standard"`, "Arrives between 2024-12-26 and 2024-12-30"} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("cart page does not contain %q", want)
+		}
+	}
+
+	// Choosing a shipping option re-prices the cart and is carried to
+	// checkout.
+	r = behaviorRequest(http.MethodGet, "", nil)
+	r.URL.RawQuery = "shipping_option=express"
+	r = r.WithContext(context.WithValue(r.Context(), ctxKeySessionID{}, "s1"))
+	w = httptest.NewRecorder()
+	fe.viewCartHandler(w, r)
+	for _, want := range []string{"Shipping Cost: <strong>USD 19.99", `value="express" selected="selected"`, `name="shipping_option_id" value="express"`, "Arrives 2024-12-24"} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("cart page with express shipping does not contain %q", want)
+		}
+	}
+
+	checkout := func(form string) *httptest.ResponseRecorder {
+		form = "email=someone%40example.com&date_of_birth=1990-04-01&credit_card_number=4432-8015-6152-0454&shipping_option_id=express" +
+			"&street_address=1+Main+St&city=Boston&state=MA&country=United+States&" + form
+		r := behaviorRequest(http.MethodPost, form, map[string]string{"Content-Type": "application/x-www-form-urlencoded"})
+		r = r.WithContext(context.WithValue(r.Context(), ctxKeySessionID{}, "s1"))
+		w := httptest.NewRecorder()
+		fe.placeOrderHandler(w, r)
+		return w
+	}
+
+	// An invalid address shows the cart again, with the errors next to
+	// the fields and the address as entered.
+	w = checkout("postal_code=2134")
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("checkout with an invalid address: got %d, want 422", w.Code)
+	}
+	for _, want := range []string{`is-invalid" name="postal_code" id="postal_code" value="2134"`, "ZIP codes have five digits",
+		"1 Main St, Boston, MA 02134, US", `name="suggestion" value="0"`, `name="shipping_option_id" value="express"`} {
+		if !strings.Contains(strings.Join(strings.Fields(w.Body.String()), " "), want) {
+			t.Errorf("cart page with an invalid address does not contain %q", want)
+		}
+	}
+
+	w = checkout("postal_code=2134&suggestion=0")
+	for _, want := range []string{"Your order is complete", "Express (Frothly Freight)", "USD 19.99"} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("order page does not contain %q:\n%s", want, w.Body)
+		}
+	}
+	if order, _ := shop.GetOrder(context.Background(), &pb.GetOrderRequest{OrderId: "order-1"}); order.GetOrder().GetShippingAddress().GetPostalCode() != "02134" {
+		t.Errorf("order shipped to %v, want the suggested address", order.GetOrder().GetShippingAddress())
+	}
+}
+
+func TestCartHandlersWithLegacyCart(t *testing.T) {
+	fe := testFrontendServer(t)
+	shop := newFakeShop()
+	shop.legacyCart = true
+	shop.serve(t, fe)
+	ctx := context.Background()
+	for _, id := range []string{"OLJCESPC7Z", "L9ECAV7KIM", "1YMWWN1N4O"} {
+		if err := fe.insertCart(ctx, "s1", id, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tt := range []struct {
+		name    string
+		handler http.HandlerFunc
+		form    string
+		code    int
+		want    string
+	}{
+		{"update", fe.updateCartHandler, "product_id=L9ECAV7KIM&quantity=5", 302, "OLJCESPC7Z:1 L9ECAV7KIM:5 1YMWWN1N4O:1"},
+		{"update to zero", fe.updateCartHandler, "product_id=OLJCESPC7Z&quantity=0", 302, "L9ECAV7KIM:5 1YMWWN1N4O:1"},
+		{"update product not in cart", fe.updateCartHandler, "product_id=OLJCESPC7Z&quantity=1", 404, "L9ECAV7KIM:5 1YMWWN1N4O:1"},
+		{"remove", fe.removeFromCartHandler, "product_id=1YMWWN1N4O", 302, "L9ECAV7KIM:5"},
+		{"remove product not in cart", fe.removeFromCartHandler, "product_id=1YMWWN1N4O", 302, "L9ECAV7KIM:5"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			r := behaviorRequest(http.MethodPost, tt.form, map[string]string{"Content-Type": "application/x-www-form-urlencoded"})
+			r = r.WithContext(context.WithValue(r.Context(), ctxKeySessionID{}, "s1"))
+			w := httptest.NewRecorder()
+			tt.handler(w, r)
+			if w.Code != tt.code {
+				t.Fatalf("got %d %s, want %d", w.Code, w.Body, tt.code)
+			}
+			items, err := fe.getCart(ctx, "s1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, item := range items {
+				got = append(got, fmt.Sprintf("%s:%d", item.GetProductId(), item.GetQuantity()))
+			}
+			if strings.Join(got, " ") != tt.want {
+				t.Errorf("cart = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCartRestrictions(t *testing.T) {
+	fe := testFrontendServer(t)
+	shop := newFakeShop()
+	shop.products = append(shop.products, &pb.Product{Id: "66VCHSJNUP", Name: "Pale Ale",
+		PriceUsd: &pb.Money{CurrencyCode: "USD", Units: 5}, Attributes: map[string]string{"abv": "5.2"}})
+	shop.serve(t, fe)
+	if _, err := shop.AddItem(context.Background(), &pb.AddItemRequest{UserId: "s1", Item: &pb.CartItem{ProductId: "66VCHSJNUP", Quantity: 1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := behaviorRequest(http.MethodGet, "", nil)
+	r = r.WithContext(context.WithValue(r.Context(), ctxKeySessionID{}, "s1"))
+	w := httptest.NewRecorder()
+	fe.viewCartHandler(w, r)
+	if want := "Adult signature required: Alcohol needs an adult&#39;s signature."; !strings.Contains(w.Body.String(), want) {
+		t.Errorf("cart page does not contain %q", want)
+	}
+	if strings.Contains(w.Body.String(), "cannot be shipped") {
+		t.Error("cart page flags items that can be shipped")
+	}
+
+	// Checking out to Utah flags the ale instead of placing the order.
+	form := "email=someone%40example.com&date_of_birth=1990-04-01&credit_card_number=4432-8015-6152-0454" +
+		"&street_address=1+Main+St&city=Provo&state=UT&country=US&postal_code=84601"
+	r = behaviorRequest(http.MethodPost, form, map[string]string{"Content-Type": "application/x-www-form-urlencoded"})
+	r = r.WithContext(context.WithValue(r.Context(), ctxKeySessionID{}, "s1"))
+	w = httptest.NewRecorder()
+	fe.placeOrderHandler(w, r)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("checkout to Utah: got %d, want 422", w.Code)
+	}
+	for _, want := range []string{"Cannot be shipped to this address: No alcohol to Utah.", "Some items in your cart cannot be shipped to this address",
+		`value="UT"`} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("cart page for Utah does not contain %q", want)
+		}
+	}
+	if len(shop.orders) != 0 {
+		t.Errorf("placed %d orders, want none", len(shop.orders))
+	}
+}
+
+func TestCheckoutRefusals(t *testing.T) {
+	fe := testFrontendServer(t)
+	shop := newFakeShop()
+	shop.serve(t, fe)
+	if _, err := shop.AddItem(context.Background(), &pb.AddItemRequest{UserId: "s1", Item: &pb.CartItem{ProductId: "OLJCESPC7Z", Quantity: 1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	address := "&street_address=1+Main+St&city=Fresno&state=CA&country=US&postal_code=93650"
+	tests := []struct {
+		name, form string
+		code       int
+		want       string
+	}{
+		{"too young for checkoutservice", "email=someone%40example.com&date_of_birth=2003-04-01&credit_card_number=4432-8015-6152-0454" + address,
+			http.StatusUnprocessableEntity, "Your order could not be placed: under the legal drinking age."},
+		{"invalid card", "email=someone%40example.com&date_of_birth=1990-04-01&credit_card_number=0432-8015-6152-0454" + address,
+			http.StatusBadRequest, "Your order could not be placed: credit card is invalid."},
+		{"unknown shipping option", "email=someone%40example.com&date_of_birth=1990-04-01&credit_card_number=4432-8015-6152-0454&shipping_option_id=teleport" + address,
+			http.StatusBadRequest, "Your order could not be placed:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := behaviorRequest(http.MethodPost, tt.form, map[string]string{"Content-Type": "application/x-www-form-urlencoded"})
+			r = r.WithContext(context.WithValue(r.Context(), ctxKeySessionID{}, "s1"))
+			w := httptest.NewRecorder()
+			fe.placeOrderHandler(w, r)
+			if w.Code != tt.code {
+				t.Fatalf("got %d, want %d: %s", w.Code, tt.code, w.Body)
+			}
+			if !strings.Contains(w.Body.String(), tt.want) {
+				t.Errorf("cart page does not contain %q", tt.want)
+			}
+		})
+	}
+	if len(shop.orders) != 0 {
+		t.Errorf("placed %d orders, want none", len(shop.orders))
+	}
+}
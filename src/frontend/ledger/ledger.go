@@ -0,0 +1,309 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ledger records supplier payments in an append-only JSON-lines
+// file, so that an invoice is paid at most once and spending limits hold
+// across restarts.
+//
+// Every change to a payment appends its full new state; when the file is
+// opened the last line for each payment wins.
+package ledger
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	pb "github.com/signalfx/microservices-demo/src/frontend/genproto"
+	"github.com/signalfx/microservices-demo/src/frontend/money"
+)
+
+// Payment statuses. A payment moves from Requested to Approved to Sent;
+// it becomes Failed if it is rejected, expires or cannot be sent.
+const (
+	Requested = "requested"
+	Approved  = "approved"
+	Sent      = "sent"
+	Failed    = "failed"
+)
+
+// maxLineBytes bounds the size of a single entry when reading a ledger.
+const maxLineBytes = 1 << 20
+
+var (
+	ErrNotFound            = errors.New("payment not found")
+	ErrIdempotencyConflict = errors.New("idempotency key was already used for a different payment")
+	ErrDuplicateInvoice    = errors.New("invoice has already been paid or is awaiting payment")
+	ErrInvalidAmount       = errors.New("amount must be positive")
+	ErrInvalidTransition   = errors.New("payment is not in a state that allows this")
+)
+
+// LimitError reports a payment that would exceed one of a supplier's
+// limits.
+type LimitError struct {
+	Limit string // "per-payment" or "daily"
+	Max   pb.Money
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("payment exceeds the supplier's %s limit of %s %s", e.Limit, e.Max.GetCurrencyCode(), money.Format(e.Max))
+}
+
+// Payment is a supplier payment and its current status.
+type Payment struct {
+	ID             string    `json:"id"`
+	SupplierID     string    `json:"supplierId"`
+	Amount         pb.Money  `json:"amount"`
+	InvoiceRef     string    `json:"invoiceRef"`
+	IdempotencyKey string    `json:"idempotencyKey"`
+	Status         string    `json:"status"`
+	RequestedBy    string    `json:"requestedBy"`
+	ApprovedBy     string    `json:"approvedBy,omitempty"`
+	Result         string    `json:"result,omitempty"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+// Limits caps a supplier's payments. Nil limits are not enforced.
+type Limits struct {
+	PerPayment *pb.Money
+	Daily      *pb.Money
+}
+
+// Ledger is a payment ledger backed by a JSON-lines file. It is safe for
+// concurrent use.
+type Ledger struct {
+	mu    sync.Mutex
+	f     *os.File
+	now   func() time.Time
+	byID  map[string]*Payment
+	byKey map[string]string // idempotency key to payment ID
+}
+
+// Open opens (creating if necessary) the ledger at path and loads the
+// latest state of every payment. An unreadable line, such as a torn write,
+// is skipped.
+func Open(path string) (*Ledger, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	l := &Ledger{f: f, now: time.Now, byID: map[string]*Payment{}, byKey: map[string]string{}}
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), maxLineBytes)
+	for sc.Scan() {
+		var p Payment
+		if json.Unmarshal(sc.Bytes(), &p) == nil && p.ID != "" {
+			l.byID[p.ID] = &p
+			l.byKey[p.IdempotencyKey] = p.ID
+		}
+	}
+	if err := sc.Err(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("ledger: reading %s: %v", path, err)
+	}
+	// Terminate a torn line so the next entry starts on its own line.
+	if fi, err := f.Stat(); err == nil && fi.Size() > 0 {
+		b := make([]byte, 1)
+		if _, err = f.ReadAt(b, fi.Size()-1); err == nil && b[0] != '\n' {
+			_, err = f.Write([]byte{'\n'})
+		}
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("ledger: reading %s: %v", path, err)
+		}
+	}
+	return l, nil
+}
+
+// Close closes the underlying file.
+func (l *Ledger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.f.Close()
+}
+
+// Request records a new payment request. A request whose idempotency key
+// was seen before returns the earlier payment with created == false, as
+// long as it is for the same supplier, amount and invoice.
+func (l *Ledger) Request(p Payment, lim Limits) (_ Payment, created bool, err error) {
+	if !money.IsValid(p.Amount) || !money.IsPositive(p.Amount) {
+		return Payment{}, false, ErrInvalidAmount
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if id, ok := l.byKey[p.IdempotencyKey]; ok {
+		prev := l.byID[id]
+		if prev.SupplierID != p.SupplierID || prev.InvoiceRef != p.InvoiceRef || !money.AreEquals(prev.Amount, p.Amount) {
+			return Payment{}, false, ErrIdempotencyConflict
+		}
+		return *prev, false, nil
+	}
+
+	now := l.now().UTC()
+	day := now.Truncate(24 * time.Hour)
+	spent := pb.Money{CurrencyCode: p.Amount.GetCurrencyCode()}
+	for _, q := range l.byID {
+		if q.SupplierID != p.SupplierID || q.Status == Failed {
+			continue
+		}
+		if q.InvoiceRef == p.InvoiceRef {
+			return Payment{}, false, ErrDuplicateInvoice
+		}
+		if !q.CreatedAt.Before(day) {
+			if spent, err = money.Sum(spent, q.Amount); err != nil {
+				return Payment{}, false, err
+			}
+		}
+	}
+	if lim.PerPayment != nil {
+		if err := checkLimit("per-payment", p.Amount, *lim.PerPayment); err != nil {
+			return Payment{}, false, err
+		}
+	}
+	if lim.Daily != nil {
+		total, err := money.Sum(spent, p.Amount)
+		if err != nil {
+			return Payment{}, false, err
+		}
+		if err := checkLimit("daily", total, *lim.Daily); err != nil {
+			return Payment{}, false, err
+		}
+	}
+
+	p.ID = newID()
+	p.Status, p.ApprovedBy, p.Result = Requested, "", ""
+	p.CreatedAt, p.UpdatedAt = now, now
+	if err := l.writeLocked(&p); err != nil {
+		return Payment{}, false, err
+	}
+	l.byKey[p.IdempotencyKey] = p.ID
+	return p, true, nil
+}
+
+func checkLimit(name string, amount, max pb.Money) error {
+	c, err := money.Compare(amount, max)
+	if err != nil {
+		return err
+	}
+	if c > 0 {
+		return &LimitError{Limit: name, Max: max}
+	}
+	return nil
+}
+
+// Approve moves a requested payment to Approved.
+func (l *Ledger) Approve(id, by string) (Payment, error) {
+	return l.update(id, Requested, func(p *Payment) {
+		p.Status, p.ApprovedBy = Approved, by
+	})
+}
+
+// Reject fails a payment that was never approved, recording why.
+func (l *Ledger) Reject(id, reason string) (Payment, error) {
+	return l.update(id, Requested, func(p *Payment) {
+		p.Status, p.Result = Failed, reason
+	})
+}
+
+// Finish records the outcome of sending an approved payment.
+func (l *Ledger) Finish(id, result string, sendErr error) (Payment, error) {
+	return l.update(id, Approved, func(p *Payment) {
+		p.Status, p.Result = Sent, result
+		if sendErr != nil {
+			p.Status, p.Result = Failed, sendErr.Error()
+		}
+	})
+}
+
+func (l *Ledger) update(id, from string, fn func(*Payment)) (Payment, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	cur, ok := l.byID[id]
+	if !ok {
+		return Payment{}, ErrNotFound
+	}
+	if cur.Status != from {
+		return *cur, ErrInvalidTransition
+	}
+	next := *cur
+	fn(&next)
+	next.UpdatedAt = l.now().UTC()
+	if err := l.writeLocked(&next); err != nil {
+		return *cur, err
+	}
+	return next, nil
+}
+
+// writeLocked durably appends p and makes it the current state.
+func (l *Ledger) writeLocked(p *Payment) error {
+	raw, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	if _, err := l.f.Write(append(raw, '\n')); err != nil {
+		return fmt.Errorf("ledger: write: %v", err)
+	}
+	if err := l.f.Sync(); err != nil {
+		return fmt.Errorf("ledger: sync: %v", err)
+	}
+	l.byID[p.ID] = p
+	return nil
+}
+
+// Get returns the payment with the given ID.
+func (l *Ledger) Get(id string) (Payment, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	p, ok := l.byID[id]
+	if !ok {
+		return Payment{}, ErrNotFound
+	}
+	return *p, nil
+}
+
+// Supplier returns the payments to a supplier, newest first.
+func (l *Ledger) Supplier(supplierID string) []Payment {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := []Payment{}
+	for _, p := range l.byID {
+		if p.SupplierID == supplierID {
+			out = append(out, *p)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.After(out[j].CreatedAt)
+		}
+		return out[i].ID > out[j].ID
+	})
+	return out
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,129 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ledger
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pb "github.com/signalfx/microservices-demo/src/frontend/genproto"
+)
+
+func usd(units int64) pb.Money { return pb.Money{CurrencyCode: "USD", Units: units} }
+
+func TestRequest(t *testing.T) {
+	l, err := Open(filepath.Join(t.TempDir(), "ledger.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	now := time.Date(2024, 3, 1, 23, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+	per, daily := usd(500), usd(800)
+	lim := Limits{PerPayment: &per, Daily: &daily}
+	pay := func(key, invoice string, units int64) Payment {
+		return Payment{SupplierID: "s1", Amount: usd(units), InvoiceRef: invoice, IdempotencyKey: key, RequestedBy: "ap"}
+	}
+
+	first, created, err := l.Request(pay("k1", "INV-1", 400), lim)
+	if err != nil || !created || first.Status != Requested {
+		t.Fatalf("first request: %+v created=%v err=%v", first, created, err)
+	}
+
+	tests := []struct {
+		name    string
+		p       Payment
+		advance time.Duration
+		created bool
+		err     error
+		limit   string
+	}{
+		{name: "replayed key", p: pay("k1", "INV-1", 400), created: false},
+		{name: "reused key", p: pay("k1", "INV-1", 401), err: ErrIdempotencyConflict},
+		{name: "same invoice", p: pay("k2", "INV-1", 400), err: ErrDuplicateInvoice},
+		{name: "non-positive", p: pay("k3", "INV-2", 0), err: ErrInvalidAmount},
+		{name: "per-payment limit", p: pay("k4", "INV-2", 501), limit: "per-payment"},
+		{name: "daily limit", p: pay("k5", "INV-2", 401), limit: "daily"},
+		{name: "within daily limit", p: pay("k6", "INV-2", 400), created: true},
+		{name: "next day", p: pay("k7", "INV-3", 500), advance: 2 * time.Hour, created: true},
+		{name: "currency", p: Payment{SupplierID: "s1", Amount: pb.Money{CurrencyCode: "EUR", Units: 1}, InvoiceRef: "INV-4", IdempotencyKey: "k8"}, err: errors.New("mismatching currency codes")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = now.Add(tt.advance)
+			_, created, err := l.Request(tt.p, lim)
+			var le *LimitError
+			switch {
+			case tt.limit != "":
+				if !errors.As(err, &le) || le.Limit != tt.limit {
+					t.Fatalf("err = %v, want %s limit", err, tt.limit)
+				}
+			case tt.err != nil:
+				if err == nil || err.Error() != tt.err.Error() {
+					t.Fatalf("err = %v, want %v", err, tt.err)
+				}
+			case err != nil || created != tt.created:
+				t.Fatalf("created=%v err=%v, want created=%v", created, err, tt.created)
+			}
+		})
+	}
+}
+
+func TestTransitionsSurviveReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.jsonl")
+	l, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { clock = clock.Add(time.Second); return clock }
+	a, _, _ := l.Request(Payment{SupplierID: "s1", Amount: usd(10), InvoiceRef: "A", IdempotencyKey: "a"}, Limits{})
+	b, _, _ := l.Request(Payment{SupplierID: "s1", Amount: usd(20), InvoiceRef: "B", IdempotencyKey: "b"}, Limits{})
+	if _, err := l.Finish(a.ID, "ok", nil); err != ErrInvalidTransition {
+		t.Fatalf("sending an unapproved payment: err=%v", err)
+	}
+	if _, err := l.Approve(a.ID, "bob"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Finish(a.ID, "ok", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.Reject(b.ID, "rejected by bob"); err != nil {
+		t.Fatal(err)
+	}
+	l.Close()
+
+	// A torn final line is ignored and does not corrupt the next entry.
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	f.Write([]byte(`{"id":"torn`))
+	f.Close()
+
+	l, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	got := l.Supplier("s1")
+	if len(got) != 2 || got[0].ID != b.ID || got[0].Status != Failed || got[1].Status != Sent || got[1].ApprovedBy != "bob" {
+		t.Fatalf("after reopen: %+v", got)
+	}
+	// A failed invoice can be requested again.
+	if _, created, err := l.Request(Payment{SupplierID: "s1", Amount: usd(20), InvoiceRef: "B", IdempotencyKey: "b2"}, Limits{}); err != nil || !created {
+		t.Fatalf("retrying a failed invoice: created=%v err=%v", created, err)
+	}
+}
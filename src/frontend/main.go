@@ -17,6 +17,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"time"
@@ -28,6 +29,12 @@ import (
 	"github.com/signalfx/signalfx-go-tracing/tracing"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
+
+	"github.com/signalfx/microservices-demo/src/frontend/audit"
+	"github.com/signalfx/microservices-demo/src/frontend/ledger"
+	"github.com/signalfx/microservices-demo/src/frontend/slack"
+	"github.com/signalfx/microservices-demo/src/frontend/supplierservice"
+	"github.com/signalfx/microservices-demo/src/frontend/userlookup"
 )
 
 const (
@@ -73,6 +80,21 @@ type frontendServer struct {
 
 	adSvcAddr string
 	adSvcConn *grpc.ClientConn
+
+	auditLog *audit.Log
+	// trustedProxies may set X-Forwarded-For on requests.
+	trustedProxies []*net.IPNet
+	ageGate        *ageGate
+	auth           *authenticator
+
+	suppliers *supplierservice.Client
+	users     *userlookup.Client
+	payments  *ledger.Ledger
+
+	approvals   *paymentApprovals
+	paySupplier func(ctx context.Context, supplierID, amount string) (string, error)
+
+	slackDirectory *slack.Directory
 }
 
 func main() {
@@ -125,26 +147,110 @@ func main() {
 	mustConnGRPC(ctx, &svc.shippingSvcConn, svc.shippingSvcAddr)
 	mustConnGRPC(ctx, &svc.checkoutSvcConn, svc.checkoutSvcAddr)
 	mustConnGRPC(ctx, &svc.adSvcConn, svc.adSvcAddr)
+	svc.auditLog = mustOpenAuditLog(os.Getenv("AUDIT_LOG_FILE"))
+	trustedProxies, err := parseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	svc.trustedProxies = trustedProxies
+	drinkingAges, err := parseDrinkingAges(envOr("LEGAL_DRINKING_AGES", defaultDrinkingAges))
+	if err != nil {
+		log.Fatal(err)
+	}
+	ageGateKey := newSecret("age gate cookie key", "AGE_GATE_SECRET", "age-gate-secret.txt")
+	if _, err := ageGateKey.get(); err != nil {
+		log.Warnf("%v: age verifications will not survive restarts", err)
+	}
+	if svc.ageGate, err = newAgeGate(drinkingAges, os.Getenv("AGE_GATE_MODE") != "confirm", ageGateKey); err != nil {
+		log.Fatal(err)
+	}
+	svc.suppliers = supplierservice.New(envOr("SUPPLIERSERVICE_ADDR", supplierservice.DefaultAddr))
+	svc.users = userlookup.New(envOr("USERLOOKUP_ADDR", userlookup.DefaultAddr))
+	svc.paySupplier = svc.suppliers.Pay
+	svc.payments = mustOpenLedger(os.Getenv("SUPPLIER_PAYMENTS_FILE"))
+	approvals, err := newPaymentApprovals(envOr("SUPPLIER_PAYMENT_APPROVALS_FILE", defaultApprovalsFile))
+	if err != nil {
+		log.Fatal(err)
+	}
+	approvals.onExpire = svc.releaseExpiredPayment
+	go approvals.expireEvery(approvalSweepInterval, log)
+	svc.approvals = approvals
+	slackSigningSecret := newSecret("Slack signing secret", "SLACK_SIGNING_SECRET", "signing-secret.txt")
+	slackBotToken := newSecret("Slack bot token", "SLACK_BOT_TOKEN", "slack-token.txt")
+	svc.slackDirectory, err = newSlackDirectory(&slack.Client{
+		BaseURL: os.Getenv("SLACK_API_URL"),
+		Token:   slackBotToken.get,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	graphqlAPI, err := newGraphQLHandler(svc)
+	if err != nil {
+		log.Fatal(err)
+	}
+	slackCommands := svc.newSlackRouter(svc.slackDirectory)
+	// Every Slack route must be signed by Slack and needs the bot token.
+	slackVerifier := &slack.Verifier{Secret: slackSigningSecret.get, OnReject: logSlackRejection}
+	slackRoute := func(h http.Handler) http.Handler {
+		return slackVerifier.Middleware(requireSecret(slackBotToken, h))
+	}
 
 	r := muxtrace.NewRouter()
-	r.HandleFunc("/", svc.homeHandler).Methods(http.MethodGet, http.MethodHead)
-	r.HandleFunc("/product/{id}", svc.productHandler).Methods(http.MethodGet, http.MethodHead)
-	r.HandleFunc("/cart", svc.viewCartHandler).Methods(http.MethodGet, http.MethodHead)
-	r.HandleFunc("/cart", svc.addToCartHandler).Methods(http.MethodPost)
-	r.HandleFunc("/cart/empty", svc.emptyCartHandler).Methods(http.MethodPost)
+	r.HandleFunc("/", svc.requireAge(svc.homeHandler)).Methods(http.MethodGet, http.MethodHead)
+	r.HandleFunc("/product/{id}", svc.requireAge(svc.productHandler)).Methods(http.MethodGet, http.MethodHead)
+	r.HandleFunc("/category/{name}", svc.requireAge(svc.categoryHandler)).Methods(http.MethodGet, http.MethodHead)
+	r.HandleFunc("/search", svc.requireAge(svc.searchHandler)).Methods(http.MethodGet, http.MethodHead)
+	// Suggestions, like the API and GraphQL below, are not gated: they show
+	// only what the catalog API does, and checkout enforces the legal age.
+	r.HandleFunc("/search/suggest", svc.searchSuggestHandler).Methods(http.MethodGet)
+	r.HandleFunc("/cart", svc.requireAge(svc.viewCartHandler)).Methods(http.MethodGet, http.MethodHead)
+	r.HandleFunc("/cart", svc.requireAge(svc.addToCartHandler)).Methods(http.MethodPost)
+	r.HandleFunc("/cart/empty", svc.requireAge(svc.emptyCartHandler)).Methods(http.MethodPost)
+	r.HandleFunc("/cart/update", svc.requireAge(svc.updateCartHandler)).Methods(http.MethodPost)
+	r.HandleFunc("/cart/remove", svc.requireAge(svc.removeFromCartHandler)).Methods(http.MethodPost)
 	r.HandleFunc("/setCurrency", svc.setCurrencyHandler).Methods(http.MethodPost)
+	r.HandleFunc(ageGatePath, svc.ageGateHandler).Methods(http.MethodGet, http.MethodHead)
+	r.HandleFunc(ageGatePath, svc.verifyAgeHandler).Methods(http.MethodPost)
 	r.HandleFunc("/logout", svc.logoutHandler).Methods(http.MethodGet)
-	r.HandleFunc("/cart/checkout", svc.placeOrderHandler).Methods(http.MethodPost)
+	r.HandleFunc("/cart/checkout", svc.requireAge(svc.placeOrderHandler)).Methods(http.MethodPost)
+	r.HandleFunc("/orders", svc.requireAge(svc.ordersHandler)).Methods(http.MethodGet, http.MethodHead)
+	r.HandleFunc("/orders/{id}", svc.requireAge(svc.orderHandler)).Methods(http.MethodGet, http.MethodHead)
+	r.HandleFunc("/orders/{id}/reorder", svc.requireAge(svc.reorderHandler)).Methods(http.MethodPost)
+	r.HandleFunc("/track/{id}", svc.requireAge(svc.trackHandler)).Methods(http.MethodGet, http.MethodHead)
 	r.PathPrefix("/static/").Handler(http.StripPrefix("/static/", http.FileServer(http.Dir("./static/"))))
 	r.HandleFunc("/robots.txt", func(w http.ResponseWriter, _ *http.Request) { fmt.Fprint(w, "User-agent: *\nDisallow: /") })
 	r.HandleFunc("/_healthz", func(w http.ResponseWriter, _ *http.Request) { fmt.Fprint(w, "ok") })
-	r.HandleFunc("/supplierlookup/{id}", svc.supplierlookupresponse).Methods(http.MethodGet)
-	r.HandleFunc("/supplierpayment/{id}/{amount}", svc.supplierpaymentresponse).Methods(http.MethodGet, http.MethodPost)
-	r.HandleFunc("/supplierlookup/{id}", svc.supplierlookupresponse).Methods(http.MethodGet)
-	r.HandleFunc("/supplierpaymentslack", svc.supplierpaymentslackresponse).Methods(http.MethodGet, http.MethodPost)
-	r.HandleFunc("/userlookup/{id}", svc.userlookupresponse).Methods(http.MethodGet)
-	r.HandleFunc("/system-behavior", svc.getSystemBehaviorHandler).Methods(http.MethodGet)
-	r.HandleFunc("/system-behavior", svc.patchSystemBehaviorHandler).Methods(http.MethodPatch)
+	r.Handle("/slack/command", slackRoute(slackCommands)).Methods(http.MethodPost)
+	r.Handle("/slack/interactive", slackRoute(slackCommands.Interactions())).Methods(http.MethodPost)
+	// The original pay_someone endpoint maps onto supplier pay, which also
+	// needs an invoice reference ("<supplier>:<amount>:<invoice>").
+	r.Handle("/supplierpaymentslack", slackRoute(slackCommands.Alias("supplier pay"))).Methods(http.MethodPost)
+	r.HandleFunc(behaviorSchemaPath, svc.getSystemBehaviorSchemaHandler).Methods(http.MethodGet)
+	// The API and GraphQL clients cannot follow the age gate's redirect; their
+	// checkouts require a date of birth, which checkoutservice checks.
+	svc.registerAPI(r.PathPrefix(apiPrefix).Subrouter())
+	r.Handle(graphqlPath, graphqlAPI).Methods(http.MethodPost)
+
+	// Administrative and finance endpoints; each declares the role it requires.
+	auth := newAuthenticator(log, os.Getenv("AUTH_KEYS_FILE"))
+	svc.auth = auth
+	r.HandleFunc("/supplierlookup/{id}", auth.require(roleViewer, svc.supplierlookupresponse)).Methods(http.MethodGet)
+	r.HandleFunc("/supplierpayment/{id}/{amount}", auth.require(roleFinance, svc.supplierpaymentresponse)).Methods(http.MethodPost)
+	r.HandleFunc("/suppliers/{id}/payments", auth.require(roleViewer, svc.supplierPaymentsHandler)).Methods(http.MethodGet)
+	r.HandleFunc("/suppliers/{id}/payments", auth.require(roleFinance, svc.createSupplierPaymentHandler)).Methods(http.MethodPost)
+	r.HandleFunc("/suppliers/{id}/payments/{paymentId}/approve", auth.require(roleFinance, svc.approveSupplierPaymentHandler)).Methods(http.MethodPost)
+	r.HandleFunc("/userlookup/{id}", auth.require(roleViewer, svc.userlookupresponse)).Methods(http.MethodGet)
+	r.HandleFunc("/system-behavior", auth.require(roleViewer, svc.getSystemBehaviorHandler)).Methods(http.MethodGet)
+	r.HandleFunc("/system-behavior", auth.require(roleOperator, svc.patchSystemBehaviorHandler)).Methods(http.MethodPatch)
+	r.HandleFunc("/system-behavior", auth.require(roleOperator, svc.deleteSystemBehaviorHandler)).Methods(http.MethodDelete)
+	r.HandleFunc("/admin", auth.require(roleViewer, svc.adminHandler)).Methods(http.MethodGet, http.MethodHead)
+	r.HandleFunc("/admin/status", auth.require(roleViewer, svc.adminStatusHandler)).Methods(http.MethodGet)
+	r.HandleFunc("/admin/behavior", auth.require(roleOperator, svc.adminBehaviorHandler)).Methods(http.MethodPost)
+	r.HandleFunc("/admin/preset", auth.require(roleOperator, svc.adminPresetHandler)).Methods(http.MethodPost)
+	r.HandleFunc("/admin/slack/directory/invalidate", auth.require(roleOperator, svc.slackDirectoryInvalidateHandler)).Methods(http.MethodPost)
+	r.HandleFunc("/admin/audit", auth.require(roleViewer, svc.auditQueryHandler)).Methods(http.MethodGet)
+	r.HandleFunc("/admin/orders/{id}", auth.require(roleOperator, svc.adminOrderHandler)).Methods(http.MethodGet)
+	r.HandleFunc("/admin/orders/{id}/label", auth.require(roleOperator, svc.shippingLabelHandler)).Methods(http.MethodGet)
 
 	var handler http.Handler = r
 	handler = &logHandler{log: log, next: handler} // add logging
@@ -0,0 +1,143 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package memcart implements CartService in memory, for running the
+// frontend locally and for tests. Carts are lost when the process exits.
+package memcart
+
+import (
+	"context"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/signalfx/microservices-demo/src/frontend/genproto"
+)
+
+// Server is an in-memory CartService. The zero value is not usable; use New.
+type Server struct {
+	mu    sync.Mutex
+	carts map[string][]*pb.CartItem // by user ID, in the order items were added
+}
+
+// New returns an empty Server.
+func New() *Server {
+	return &Server{carts: map[string][]*pb.CartItem{}}
+}
+
+func (s *Server) AddItem(_ context.Context, req *pb.AddItemRequest) (*pb.Empty, error) {
+	if err := checkIDs(req.GetUserId(), req.GetItem().GetProductId()); err != nil {
+		return nil, err
+	}
+	if req.Item.Quantity < 1 {
+		return nil, status.Errorf(codes.InvalidArgument, "quantity must be positive, not %d", req.Item.Quantity)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if item := s.find(req.UserId, req.Item.ProductId); item != nil {
+		item.Quantity += req.Item.Quantity
+		return &pb.Empty{}, nil
+	}
+	s.carts[req.UserId] = append(s.carts[req.UserId], &pb.CartItem{ProductId: req.Item.ProductId, Quantity: req.Item.Quantity})
+	return &pb.Empty{}, nil
+}
+
+// GetCart returns a copy of the cart, so callers may keep it while the cart
+// changes.
+func (s *Server) GetCart(_ context.Context, req *pb.GetCartRequest) (*pb.Cart, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cart := &pb.Cart{UserId: req.GetUserId()}
+	for _, item := range s.carts[req.GetUserId()] {
+		cart.Items = append(cart.Items, &pb.CartItem{ProductId: item.ProductId, Quantity: item.Quantity})
+	}
+	return cart, nil
+}
+
+func (s *Server) EmptyCart(_ context.Context, req *pb.EmptyCartRequest) (*pb.Empty, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.carts, req.GetUserId())
+	return &pb.Empty{}, nil
+}
+
+func (s *Server) UpdateItemQuantity(_ context.Context, req *pb.UpdateItemQuantityRequest) (*pb.Empty, error) {
+	if err := checkIDs(req.GetUserId(), req.GetProductId()); err != nil {
+		return nil, err
+	}
+	if req.Quantity < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "quantity must not be negative, not %d", req.Quantity)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item := s.find(req.UserId, req.ProductId)
+	switch {
+	case item == nil:
+		return nil, status.Errorf(codes.NotFound, "product %s is not in the cart", req.ProductId)
+	case req.Quantity == 0:
+		s.remove(req.UserId, req.ProductId)
+	default:
+		item.Quantity = req.Quantity
+	}
+	return &pb.Empty{}, nil
+}
+
+func (s *Server) RemoveItem(_ context.Context, req *pb.RemoveItemRequest) (*pb.Empty, error) {
+	if err := checkIDs(req.GetUserId(), req.GetProductId()); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.remove(req.UserId, req.ProductId)
+	return &pb.Empty{}, nil
+}
+
+func checkIDs(userID, productID string) error {
+	switch {
+	case userID == "":
+		return status.Error(codes.InvalidArgument, "user ID is required")
+	case productID == "":
+		return status.Error(codes.InvalidArgument, "product ID is required")
+	}
+	return nil
+}
+
+// find returns the user's cart item for the product, or nil; s.mu must be
+// held.
+func (s *Server) find(userID, productID string) *pb.CartItem {
+	for _, item := range s.carts[userID] {
+		if item.ProductId == productID {
+			return item
+		}
+	}
+	return nil
+}
+
+// remove deletes the user's cart item for the product, and the cart if that
+// leaves it empty; s.mu must be held.
+func (s *Server) remove(userID, productID string) {
+	items := s.carts[userID]
+	for i, item := range items {
+		if item.ProductId == productID {
+			items = append(items[:i:i], items[i+1:]...)
+			break
+		}
+	}
+	if len(items) == 0 {
+		delete(s.carts, userID)
+		return
+	}
+	s.carts[userID] = items
+}
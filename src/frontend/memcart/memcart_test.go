@@ -0,0 +1,99 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package memcart
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/signalfx/microservices-demo/src/frontend/genproto"
+)
+
+func TestServer(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	add := func(user, product string, n int32) error {
+		_, err := s.AddItem(ctx, &pb.AddItemRequest{UserId: user, Item: &pb.CartItem{ProductId: product, Quantity: n}})
+		return err
+	}
+	update := func(user, product string, n int32) error {
+		_, err := s.UpdateItemQuantity(ctx, &pb.UpdateItemQuantityRequest{UserId: user, ProductId: product, Quantity: n})
+		return err
+	}
+	remove := func(user, product string) error {
+		_, err := s.RemoveItem(ctx, &pb.RemoveItemRequest{UserId: user, ProductId: product})
+		return err
+	}
+	cart := func(user string) string {
+		c, _ := s.GetCart(ctx, &pb.GetCartRequest{UserId: user})
+		out := ""
+		for _, item := range c.Items {
+			out += fmt.Sprintf("%s×%d ", item.ProductId, item.Quantity)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name     string
+		op       func() error
+		wantCode codes.Code
+		want     string // u1's cart afterwards
+	}{
+		{"add", func() error { return add("u1", "a", 2) }, codes.OK, "a×2 "},
+		{"add more", func() error { return add("u1", "a", 3) }, codes.OK, "a×5 "},
+		{"add another", func() error { return add("u1", "b", 1) }, codes.OK, "a×5 b×1 "},
+		{"add nothing", func() error { return add("u1", "b", 0) }, codes.InvalidArgument, "a×5 b×1 "},
+		{"add without user", func() error { return add("", "b", 1) }, codes.InvalidArgument, "a×5 b×1 "},
+		{"other users' carts are separate", func() error { return add("u2", "a", 1) }, codes.OK, "a×5 b×1 "},
+		{"update", func() error { return update("u1", "a", 1) }, codes.OK, "a×1 b×1 "},
+		{"update missing", func() error { return update("u1", "c", 1) }, codes.NotFound, "a×1 b×1 "},
+		{"update negative", func() error { return update("u1", "a", -1) }, codes.InvalidArgument, "a×1 b×1 "},
+		{"update to zero removes", func() error { return update("u1", "a", 0) }, codes.OK, "b×1 "},
+		{"remove missing", func() error { return remove("u1", "a") }, codes.OK, "b×1 "},
+		{"remove without product", func() error { return remove("u1", "") }, codes.InvalidArgument, "b×1 "},
+		{"remove", func() error { return remove("u1", "b") }, codes.OK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code := status.Code(tt.op()); code != tt.wantCode {
+				t.Errorf("got %v, want %v", code, tt.wantCode)
+			}
+			if got := cart("u1"); got != tt.want {
+				t.Errorf("cart = %q, want %q", got, tt.want)
+			}
+		})
+	}
+	if got := cart("u2"); got != "a×1 " {
+		t.Errorf("u2's cart = %q", got)
+	}
+	if _, ok := s.carts["u1"]; ok {
+		t.Error("emptied cart was kept")
+	}
+}
+
+func TestGetCartReturnsACopy(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	s.AddItem(ctx, &pb.AddItemRequest{UserId: "u", Item: &pb.CartItem{ProductId: "a", Quantity: 1}})
+	c, _ := s.GetCart(ctx, &pb.GetCartRequest{UserId: "u"})
+	s.AddItem(ctx, &pb.AddItemRequest{UserId: "u", Item: &pb.CartItem{ProductId: "a", Quantity: 1}})
+	if c.Items[0].Quantity != 1 {
+		t.Errorf("cart returned earlier changed to %v", c.Items)
+	}
+}
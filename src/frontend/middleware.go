@@ -17,6 +17,7 @@ package main
 import (
 	"context"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -81,11 +82,20 @@ func (lh *logHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	lh.next.ServeHTTP(rr, r)
 }
 
+// ensureSessionID assigns every request a session ID, kept in a cookie. API
+// clients without cookies may send the ID as a bearer token instead.
 func ensureSessionID(next http.Handler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var sessionID string
 		c, err := r.Cookie(cookieSessionID)
-		if err == http.ErrNoCookie {
+		if token, ok := bearerSession(r); ok {
+			if _, err := uuid.Parse(token); err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="frothly-api"`)
+				renderJSON(w, http.StatusUnauthorized, newJSONError(errInvalidSessionToken, http.StatusUnauthorized))
+				return
+			}
+			sessionID = token
+		} else if err == http.ErrNoCookie {
 			u, _ := uuid.NewRandom()
 			sessionID = u.String()
 			http.SetCookie(w, &http.Cookie{
@@ -103,3 +113,16 @@ func ensureSessionID(next http.Handler) http.HandlerFunc {
 		next.ServeHTTP(w, r)
 	}
 }
+
+// bearerSession returns the session token of an API or GraphQL request
+// authenticated with "Authorization: Bearer <session-id>".
+func bearerSession(r *http.Request) (string, bool) {
+	if !strings.HasPrefix(r.URL.Path, apiPrefix+"/") && r.URL.Path != graphqlPath {
+		return "", false
+	}
+	h := r.Header.Get("Authorization")
+	if len(h) < 7 || !strings.EqualFold(h[:7], "Bearer ") {
+		return "", false
+	}
+	return strings.TrimSpace(h[7:]), true
+}
@@ -16,6 +16,9 @@ package money
 
 import (
 	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 
 	pb "github.com/signalfx/microservices-demo/src/frontend/genproto"
 )
@@ -29,6 +32,7 @@ const (
 var (
 	ErrInvalidValue        = errors.New("one of the specified money values is invalid")
 	ErrMismatchingCurrency = errors.New("mismatching currency codes")
+	ErrInvalidAmount       = errors.New("amount must be a decimal number with at most 9 fractional digits")
 )
 
 // IsValid checks if specified value has a valid units/nanos signs and ranges.
@@ -99,7 +103,7 @@ func Sum(l, r pb.Money) (pb.Money, error) {
 	units := l.GetUnits() + r.GetUnits()
 	nanos := l.GetNanos() + r.GetNanos()
 
-	if (units == 0 && nanos == 0) || (units > 0 && nanos >= 0) || (units < 0 && nanos <= 0) {
+	if units == 0 || (units > 0 && nanos >= 0) || (units < 0 && nanos <= 0) {
 		// same sign <units, nanos>
 		units += int64(nanos / nanosMod)
 		nanos = nanos % nanosMod
@@ -130,3 +134,71 @@ func MultiplySlow(m pb.Money, n uint32) pb.Money {
 	}
 	return out
 }
+
+// Parse converts a decimal string such as "12.50" or "-3" into a value in
+// the given currency.
+func Parse(s, currencyCode string) (pb.Money, error) {
+	neg := strings.HasPrefix(s, "-")
+	whole, frac := strings.TrimPrefix(s, "-"), ""
+	if i := strings.IndexByte(whole, '.'); i >= 0 {
+		whole, frac = whole[:i], whole[i+1:]
+		if frac == "" {
+			return pb.Money{}, ErrInvalidAmount
+		}
+	}
+	if whole == "" || len(frac) > 9 || !digits(whole) || !digits(frac) {
+		return pb.Money{}, ErrInvalidAmount
+	}
+	units, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return pb.Money{}, ErrInvalidAmount
+	}
+	nanos := 0
+	if frac != "" {
+		nanos, _ = strconv.Atoi(frac + strings.Repeat("0", 9-len(frac)))
+	}
+	m := pb.Money{Units: units, Nanos: int32(nanos), CurrencyCode: currencyCode}
+	if neg {
+		m = Negate(m)
+	}
+	return m, nil
+}
+
+func digits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Format returns m as a plain decimal string such as "12.50", with at least
+// two fractional digits and no currency code.
+func Format(m pb.Money) string {
+	units, nanos := m.GetUnits(), m.GetNanos()
+	sign := ""
+	if units < 0 || nanos < 0 {
+		sign, units, nanos = "-", -units, -nanos
+	}
+	frac := strings.TrimRight(fmt.Sprintf("%09d", nanos), "0")
+	for len(frac) < 2 {
+		frac += "0"
+	}
+	return fmt.Sprintf("%s%d.%s", sign, units, frac)
+}
+
+// Compare returns -1, 0 or +1 depending on whether l is less than, equal to
+// or greater than r. Values must be valid and in the same currency.
+func Compare(l, r pb.Money) (int, error) {
+	d, err := Sum(l, Negate(r))
+	switch {
+	case err != nil:
+		return 0, err
+	case IsZero(d):
+		return 0, nil
+	case IsNegative(d):
+		return -1, nil
+	}
+	return 1, nil
+}
@@ -228,6 +228,9 @@ func TestSum(t *testing.T) {
 		{"mixed (larger positive, with borrow)", args{mm(11, 100000000), mm(-2, -9000000 /*.09*/)}, mm(9, 91000000 /*.091*/), nil},
 		{"mixed (larger negative, no borrow)", args{mm(-11, -100000000), mm(2, 100000000)}, mm(-9, 0), nil},
 		{"mixed (larger negative, with borrow)", args{mm(-11, -100000000), mm(2, 9000000 /*.09*/)}, mm(-9, -91000000 /*.091*/), nil},
+		{"mixed (units cancel, negative nanos)", args{mm(18, 225000000), mm(-18, -230000000)}, mm(0, -5000000), nil},
+		{"mixed (units cancel, positive nanos)", args{mm(-1, -100000000), mm(1, 600000000)}, mm(0, 500000000), nil},
+		{"mixed (units cancel, carry)", args{mm(0, 600000000), mm(0, 700000000)}, mm(1, 300000000), nil},
 		{"0+negative", args{mm(0, 0), mm(-2, -100000000)}, mm(-2, -100000000), nil},
 		{"negative+0", args{mm(-2, -100000000), mm(0, 0)}, mm(-2, -100000000), nil},
 	}
@@ -243,3 +246,55 @@ func TestSum(t *testing.T) {
 		})
 	}
 }
+
+func TestParseAndFormat(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    pb.Money
+		wantErr error
+		format  string
+	}{
+		{"12.50", mmc(12, 500000000, "USD"), nil, "12.50"},
+		{"7", mmc(7, 0, "USD"), nil, "7.00"},
+		{"0.000000001", mmc(0, 1, "USD"), nil, "0.000000001"},
+		{"-3.25", mmc(-3, -250000000, "USD"), nil, "-3.25"},
+		{"-0.5", mmc(0, -500000000, "USD"), nil, "-0.50"},
+		{"", pb.Money{}, ErrInvalidAmount, ""},
+		{"1.", pb.Money{}, ErrInvalidAmount, ""},
+		{".5", pb.Money{}, ErrInvalidAmount, ""},
+		{"1e3", pb.Money{}, ErrInvalidAmount, ""},
+		{"1.0000000001", pb.Money{}, ErrInvalidAmount, ""},
+		{"+1", pb.Money{}, ErrInvalidAmount, ""},
+		{"99999999999999999999", pb.Money{}, ErrInvalidAmount, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := Parse(tt.in, "USD")
+			if err != tt.wantErr || !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Parse(%q) = %v, %v; want %v, %v", tt.in, got, err, tt.want, tt.wantErr)
+			}
+			if err == nil && Format(got) != tt.format {
+				t.Errorf("Format(%v) = %q, want %q", got, Format(got), tt.format)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		l, r    pb.Money
+		want    int
+		wantErr error
+	}{
+		{mmc(1, 0, "USD"), mmc(0, 999999999, "USD"), 1, nil},
+		{mmc(2, 500000000, "USD"), mmc(2, 500000000, "USD"), 0, nil},
+		{mmc(-1, 0, "USD"), mmc(0, 1, "USD"), -1, nil},
+		{mmc(18, 225000000, "EUR"), mmc(18, 230000000, "EUR"), -1, nil},
+		{mmc(1, 0, "USD"), mmc(1, 0, "EUR"), 0, ErrMismatchingCurrency},
+	}
+	for _, tt := range tests {
+		if got, err := Compare(tt.l, tt.r); got != tt.want || err != tt.wantErr {
+			t.Errorf("Compare(%v, %v) = %d, %v; want %d, %v", tt.l, tt.r, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
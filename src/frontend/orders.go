@@ -0,0 +1,272 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"mime"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/signalfx/microservices-demo/src/frontend/genproto"
+	"github.com/signalfx/microservices-demo/src/frontend/money"
+)
+
+// orderView is an order as the order pages show it. Amounts are in the
+// currency the order was placed in, not the current one.
+type orderView struct {
+	*pb.StoredOrder
+	PlacedAt  time.Time
+	ItemCount int32
+}
+
+func newOrderView(o *pb.StoredOrder) orderView {
+	v := orderView{StoredOrder: o, PlacedAt: time.Unix(o.GetPlacedAt(), 0).UTC()}
+	for _, item := range o.GetOrder().GetItems() {
+		v.ItemCount += item.GetItem().GetQuantity()
+	}
+	return v
+}
+
+// orderLineView is one line of an order: the product, how many were bought
+// and what they cost together.
+type orderLineView struct {
+	Item     *pb.Product
+	Quantity int32
+	Price    *pb.Money
+}
+
+// ordersHandler lists the orders placed in this session, newest first.
+func (fe *frontendServer) ordersHandler(w http.ResponseWriter, r *http.Request) {
+	log := getLoggerWithTraceFields(r.Context())
+	log.Debug("serving order history")
+
+	stored, err := fe.listOrders(r.Context(), sessionID(r))
+	if err != nil {
+		renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve orders"), http.StatusInternalServerError)
+		return
+	}
+	orders := make([]orderView, len(stored))
+	for i, o := range stored {
+		orders[i] = newOrderView(o)
+	}
+	currencies, err := fe.getCurrencies(r.Context())
+	if err != nil {
+		renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve currencies"), http.StatusInternalServerError)
+		return
+	}
+	cart, err := fe.getCart(r.Context(), sessionID(r))
+	if err != nil {
+		renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve cart"), http.StatusInternalServerError)
+		return
+	}
+
+	if err := templates.ExecuteTemplate(w, "orders", map[string]interface{}{
+		"session_id":      sessionID(r),
+		"request_id":      r.Context().Value(ctxKeyRequestID{}),
+		"user_currency":   currentCurrency(r),
+		"currencies":      currencies,
+		"orders":          orders,
+		"cart_size":       cartSize(cart),
+		"platform_css":    plat.css,
+		"platform_name":   plat.provider,
+		"rum_realm":       os.Getenv("RUM_REALM"),
+		"rum_auth":        os.Getenv("RUM_AUTH"),
+		"rum_app_name":    os.Getenv("RUM_APP_NAME"),
+		"rum_environment": os.Getenv("RUM_ENVIRONMENT"),
+		"rum_debug":       os.Getenv("RUM_DEBUG"),
+	}); err != nil {
+		log.Error(err)
+	}
+}
+
+// sessionOrder returns the order with the id in the URL if it was placed in
+// this session, rendering an error and returning nil otherwise.
+func (fe *frontendServer) sessionOrder(w http.ResponseWriter, r *http.Request) *pb.StoredOrder {
+	return fe.lookupOrder(w, r, sessionID(r))
+}
+
+// lookupOrder returns the order with the id in the URL if userID placed it,
+// or whoever did for an empty userID, rendering an error and returning nil
+// otherwise.
+func (fe *frontendServer) lookupOrder(w http.ResponseWriter, r *http.Request, userID string) *pb.StoredOrder {
+	log := getLoggerWithTraceFields(r.Context())
+	id := mux.Vars(r)["id"]
+	o, err := fe.getOrder(r.Context(), id, userID)
+	if status.Code(err) == codes.NotFound {
+		renderHTTPError(log, r, w, errors.Errorf("no order %q", id), http.StatusNotFound)
+		return nil
+	} else if err != nil {
+		renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve order"), http.StatusInternalServerError)
+		return nil
+	}
+	return o
+}
+
+func (fe *frontendServer) orderHandler(w http.ResponseWriter, r *http.Request) {
+	getLoggerWithTraceFields(r.Context()).WithField("order", mux.Vars(r)["id"]).Debug("serving order page")
+	if o := fe.sessionOrder(w, r); o != nil {
+		fe.renderOrder(w, r, o, false)
+	}
+}
+
+// adminOrderHandler shows any session's order to the warehouse, with links
+// to its shipping label and packing slip.
+func (fe *frontendServer) adminOrderHandler(w http.ResponseWriter, r *http.Request) {
+	getLoggerWithTraceFields(r.Context()).WithField("order", mux.Vars(r)["id"]).
+		WithField("auth.principal", requestActor(r)).Info("serving admin order page")
+	if o := fe.lookupOrder(w, r, ""); o != nil {
+		fe.renderOrder(w, r, o, true)
+	}
+}
+
+// renderOrder renders the page of order o, as the admin order page if admin
+// is set.
+func (fe *frontendServer) renderOrder(w http.ResponseWriter, r *http.Request, o *pb.StoredOrder, admin bool) {
+	log := getLoggerWithTraceFields(r.Context())
+	var lines []orderLineView
+	for _, item := range o.GetOrder().GetItems() {
+		p, err := fe.getProduct(r.Context(), item.GetItem().GetProductId())
+		if err != nil {
+			renderHTTPError(log, r, w, errors.Wrapf(err, "could not retrieve product #%s", item.GetItem().GetProductId()), http.StatusInternalServerError)
+			return
+		}
+		price := money.MultiplySlow(*item.GetCost(), uint32(item.GetItem().GetQuantity()))
+		lines = append(lines, orderLineView{Item: p, Quantity: item.GetItem().GetQuantity(), Price: &price})
+	}
+	currencies, err := fe.getCurrencies(r.Context())
+	if err != nil {
+		renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve currencies"), http.StatusInternalServerError)
+		return
+	}
+	cart, err := fe.getCart(r.Context(), sessionID(r))
+	if err != nil {
+		renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve cart"), http.StatusInternalServerError)
+		return
+	}
+
+	if err := templates.ExecuteTemplate(w, "order_detail", map[string]interface{}{
+		"session_id":      sessionID(r),
+		"request_id":      r.Context().Value(ctxKeyRequestID{}),
+		"user_currency":   currentCurrency(r),
+		"currencies":      currencies,
+		"order":           newOrderView(o),
+		"lines":           lines,
+		"admin":           admin,
+		"cart_size":       cartSize(cart),
+		"platform_css":    plat.css,
+		"platform_name":   plat.provider,
+		"rum_realm":       os.Getenv("RUM_REALM"),
+		"rum_auth":        os.Getenv("RUM_AUTH"),
+		"rum_app_name":    os.Getenv("RUM_APP_NAME"),
+		"rum_environment": os.Getenv("RUM_ENVIRONMENT"),
+		"rum_debug":       os.Getenv("RUM_DEBUG"),
+	}); err != nil {
+		log.Error(err)
+	}
+}
+
+// labelFormats are the documents shippingLabelHandler serves, by its format
+// parameter, and the suffix of the file names they are saved under.
+var labelFormats = map[string]struct {
+	format pb.GetLabelRequest_Format
+	suffix string
+}{
+	"zpl": {pb.GetLabelRequest_ZPL, "-label.zpl"},
+	"pdf": {pb.GetLabelRequest_PDF, "-packing-slip.pdf"},
+}
+
+// shippingLabelHandler downloads the shipping label (format=zpl, the
+// default) or the packing slip (format=pdf) of any session's order, for the
+// warehouse to print.
+func (fe *frontendServer) shippingLabelHandler(w http.ResponseWriter, r *http.Request) {
+	log := getLoggerWithTraceFields(r.Context())
+	id := mux.Vars(r)["id"]
+	name := r.FormValue("format")
+	if name == "" {
+		name = "zpl"
+	}
+	f, ok := labelFormats[name]
+	if !ok {
+		renderHTTPError(log, r, w, errors.Errorf("unknown label format %q", name), http.StatusBadRequest)
+		return
+	}
+	o := fe.lookupOrder(w, r, "")
+	if o == nil {
+		return
+	}
+	trackingID := o.GetOrder().GetShippingTrackingId()
+	if trackingID == "" {
+		renderHTTPError(log, r, w, errors.Errorf("order %q has not been handed to shipping", id), http.StatusNotFound)
+		return
+	}
+	label, err := fe.getLabel(r.Context(), trackingID, f.format)
+	if status.Code(err) == codes.NotFound {
+		renderHTTPError(log, r, w, errors.Errorf("no shipment %q", trackingID), http.StatusNotFound)
+		return
+	} else if err != nil {
+		renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve shipping label"), http.StatusInternalServerError)
+		return
+	}
+	log.WithField("order", id).WithField("tracking_id", trackingID).WithField("auth.principal", requestActor(r)).
+		Infof("serving %s shipping label", name)
+	w.Header().Set("Content-Type", label.GetContentType())
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": trackingID + f.suffix}))
+	w.Write(label.GetContent())
+}
+
+// reorderHandler adds an order's items to the cart again. Each item is
+// capped at what its product's quantity limit still allows, so a reorder
+// never fails half way because the cart already holds some of it.
+func (fe *frontendServer) reorderHandler(w http.ResponseWriter, r *http.Request) {
+	log := getLoggerWithTraceFields(r.Context())
+	o := fe.sessionOrder(w, r)
+	if o == nil {
+		return
+	}
+	log.WithField("order", o.GetOrder().GetOrderId()).Debug("reordering")
+
+	for _, item := range o.GetOrder().GetItems() {
+		p, err := fe.getProduct(r.Context(), item.GetItem().GetProductId())
+		if err != nil {
+			renderHTTPError(log, r, w, errors.Wrapf(err, "could not retrieve product #%s", item.GetItem().GetProductId()), http.StatusInternalServerError)
+			return
+		}
+		inCart, err := fe.cartQuantity(r.Context(), sessionID(r), p.GetId())
+		if err != nil {
+			renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve cart"), http.StatusInternalServerError)
+			return
+		}
+		quantity := item.GetItem().GetQuantity()
+		if left := maxQuantity(p) - inCart; quantity > left {
+			log.WithField("product", p.GetId()).WithField("quantity", quantity).Infof("reordering only %d", left)
+			quantity = left
+		}
+		if quantity <= 0 {
+			continue
+		}
+		if err := fe.insertCart(r.Context(), sessionID(r), p.GetId(), quantity); err != nil {
+			renderHTTPError(log, r, w, errors.Wrap(err, "failed to add to cart"), http.StatusInternalServerError)
+			return
+		}
+	}
+	w.Header().Set("location", "/cart")
+	w.WriteHeader(http.StatusFound)
+}
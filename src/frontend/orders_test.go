@@ -0,0 +1,188 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	pb "github.com/signalfx/microservices-demo/src/frontend/genproto"
+)
+
+func TestOrderHandlers(t *testing.T) {
+	fe := testFrontendServer(t)
+	shop := newFakeShop()
+	shop.serve(t, fe)
+	ctx := context.Background()
+	for _, order := range [][]*pb.CartItem{
+		{{ProductId: "OLJCESPC7Z", Quantity: 2}, {ProductId: "L9ECAV7KIM", Quantity: 1}},
+		{{ProductId: "1YMWWN1N4O", Quantity: 1}},
+	} {
+		for _, item := range order {
+			shop.AddItem(ctx, &pb.AddItemRequest{UserId: "s1", Item: item})
+		}
+		shop.PlaceOrder(ctx, &pb.PlaceOrderRequest{UserId: "s1", UserCurrency: "USD", Email: "someone@example.com", DateOfBirth: "1990-04-01",
+			CreditCard: &pb.CreditCardInfo{CreditCardNumber: "4432801561520454"},
+			Address:    &pb.Address{StreetAddress: "1600 Amphitheatre Parkway", City: "Mountain View", State: "CA", ZipCode: 94043, Country: "USA"}})
+	}
+	// Another session's order.
+	shop.AddItem(ctx, &pb.AddItemRequest{UserId: "s2", Item: &pb.CartItem{ProductId: "L9ECAV7KIM", Quantity: 1}})
+	shop.PlaceOrder(ctx, &pb.PlaceOrderRequest{UserId: "s2", UserCurrency: "USD", DateOfBirth: "1990-04-01", CreditCard: &pb.CreditCardInfo{CreditCardNumber: "4"}})
+	// The session already holds one typewriter, so reordering order-1 can
+	// only add one more.
+	shop.AddItem(ctx, &pb.AddItemRequest{UserId: "s1", Item: &pb.CartItem{ProductId: "OLJCESPC7Z", Quantity: 1}})
+
+	get := func(handler http.HandlerFunc, method, id string) *httptest.ResponseRecorder {
+		r := behaviorRequest(method, "", nil)
+		r = r.WithContext(context.WithValue(r.Context(), ctxKeySessionID{}, "s1"))
+		r = mux.SetURLVars(r, map[string]string{"id": id})
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w
+	}
+
+	tests := []struct {
+		name   string
+		w      *httptest.ResponseRecorder
+		code   int
+		want   []string
+		absent []string
+	}{
+		{"list", get(fe.ordersHandler, http.MethodGet, ""), 200,
+			[]string{`href="/orders/order-2"`, `href="/orders/order-1"`, "USD 181.42", "USD 132.99", "2023-11-14 23:13 UTC", "placed"},
+			[]string{"order-3"}},
+		{"detail", get(fe.orderHandler, http.MethodGet, "order-1"), 200,
+			[]string{"Vintage Typewriter", "USD 135.98", "Terrarium", "USD 8.99", "USD 181.42", "Mountain View, CA 94043", "TRACK-1", `action="/orders/order-1/reorder"`,
+				"Shipping: Standard (Hopscotch Express)", "Arrives between 2024-12-26 and 2024-12-30"},
+			nil},
+		{"another session's order", get(fe.orderHandler, http.MethodGet, "order-3"), 404, []string{"no order &#34;order-3&#34;"}, nil},
+		{"unknown order", get(fe.orderHandler, http.MethodGet, "order-9"), 404, nil, nil},
+		{"reorder", get(fe.reorderHandler, http.MethodPost, "order-1"), 302, nil, nil},
+		{"reorder another session's order", get(fe.reorderHandler, http.MethodPost, "order-3"), 404, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.w.Code != tt.code {
+				t.Fatalf("got %d %s, want %d", tt.w.Code, tt.w.Body, tt.code)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(tt.w.Body.String(), want) {
+					t.Errorf("body does not contain %q:\n%s", want, tt.w.Body)
+				}
+			}
+			for _, absent := range tt.absent {
+				if strings.Contains(tt.w.Body.String(), absent) {
+					t.Errorf("body contains %q", absent)
+				}
+			}
+		})
+	}
+
+	cart, _ := shop.GetCart(ctx, &pb.GetCartRequest{UserId: "s1"})
+	var got []string
+	for _, item := range cart.Items {
+		got = append(got, fmt.Sprintf("%s×%d", item.ProductId, item.Quantity))
+	}
+	if want := "OLJCESPC7Z×2 L9ECAV7KIM×1"; strings.Join(got, " ") != want {
+		t.Errorf("cart after reorder = %v, want %s", got, want)
+	}
+}
+
+func TestShippingLabelHandler(t *testing.T) {
+	fe := testFrontendServer(t)
+	fe.auth = testAuthenticator(time.Unix(1700000000, 0))
+	shop := newFakeShop()
+	shop.serve(t, fe)
+	ctx := context.Background()
+	for _, session := range []string{"s1", "s2"} {
+		shop.AddItem(ctx, &pb.AddItemRequest{UserId: session, Item: &pb.CartItem{ProductId: "L9ECAV7KIM", Quantity: 1}})
+		shop.PlaceOrder(ctx, &pb.PlaceOrderRequest{UserId: session, UserCurrency: "USD", DateOfBirth: "1990-04-01", CreditCard: &pb.CreditCardInfo{CreditCardNumber: "4"}})
+	}
+
+	// The storefront's order page never links to the downloads, even for
+	// operators; the admin order page, for any session's order, does.
+	labelLink := `href="/admin/orders/order-1/label?format=pdf"`
+	r := behaviorRequest(http.MethodGet, "", map[string]string{"Authorization": "Basic b3BzOm9wcy1rZXk="})
+	r = r.WithContext(context.WithValue(r.Context(), ctxKeySessionID{}, "s1"))
+	w := httptest.NewRecorder()
+	fe.orderHandler(w, mux.SetURLVars(r, map[string]string{"id": "order-1"}))
+	if w.Code != http.StatusOK || strings.Contains(w.Body.String(), labelLink) {
+		t.Errorf("order page: got %d, want 200 without label links", w.Code)
+	}
+	for _, tt := range []struct {
+		auth, id string
+		code     int
+	}{
+		{"", "order-1", http.StatusUnauthorized},
+		{"Bearer fin-key", "order-1", http.StatusForbidden},
+		{"Basic b3BzOm9wcy1rZXk=", "order-1", http.StatusOK},
+		{"Basic b3BzOm9wcy1rZXk=", "order-9", http.StatusNotFound},
+	} {
+		r := behaviorRequest(http.MethodGet, "", map[string]string{"Authorization": tt.auth})
+		r = r.WithContext(context.WithValue(r.Context(), ctxKeySessionID{}, "s2"))
+		w := httptest.NewRecorder()
+		fe.auth.require(roleOperator, fe.adminOrderHandler)(w, mux.SetURLVars(r, map[string]string{"id": tt.id}))
+		if w.Code != tt.code {
+			t.Fatalf("%q %s: got %d, want %d", tt.auth, tt.id, w.Code, tt.code)
+		}
+		if got := strings.Contains(w.Body.String(), labelLink); got != (tt.code == http.StatusOK) {
+			t.Errorf("%q %s: label link %v", tt.auth, tt.id, got)
+		}
+	}
+
+	tests := []struct {
+		name, id, format string
+		code             int
+		contentType      string
+		disposition      string
+		body             string
+	}{
+		{"label", "order-1", "", 200, "application/x-zpl", "attachment; filename=TRACK-1-label.zpl", "^XA^FDTRACK-1^FS^XZ"},
+		{"packing slip", "order-1", "pdf", 200, "application/pdf", "attachment; filename=TRACK-1-packing-slip.pdf", "%PDF-1.4 TRACK-1"},
+		{"unknown format", "order-1", "png", 400, "", "", ""},
+		{"unknown order", "order-9", "zpl", 404, "", "", ""},
+		{"unknown shipment", "order-2", "zpl", 404, "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := behaviorRequest(http.MethodGet, "", nil)
+			r.Form = map[string][]string{"format": {tt.format}}
+			w := httptest.NewRecorder()
+			fe.shippingLabelHandler(w, mux.SetURLVars(r, map[string]string{"id": tt.id}))
+			if w.Code != tt.code {
+				t.Fatalf("got %d %s, want %d", w.Code, w.Body, tt.code)
+			}
+			if tt.code != 200 {
+				return
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.contentType)
+			}
+			if got := w.Header().Get("Content-Disposition"); got != tt.disposition {
+				t.Errorf("Content-Disposition = %q, want %q", got, tt.disposition)
+			}
+			if w.Body.String() != tt.body {
+				t.Errorf("body = %q, want %q", w.Body, tt.body)
+			}
+		})
+	}
+}
@@ -0,0 +1,403 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package querycost estimates what a GraphQL query will cost to execute
+// before it runs, so that a server can refuse queries that fan out too far.
+//
+// Every field costs 1. The fields selected beneath a field are counted as
+// many times as the field's multiplier, so that nested lists multiply.
+// With multipliers of 10 for products and 5 for recommendations,
+//
+//	{ products { name recommendations { name } } }
+//
+// costs 1 + 10*(1 + 1 + 5*1) = 71.
+package querycost
+
+import (
+	"fmt"
+	"math"
+)
+
+// Multiplier returns how many times the selection of a field is counted,
+// typically the number of items a list field is expected to return.
+// Values below 1 count as 1.
+type Multiplier func(field string) int
+
+// Estimate returns the cost of the operation named operationName in query,
+// or of its only operation if operationName is empty. Syntax errors are
+// reported but not described in detail; the GraphQL server will describe
+// them when it runs the query.
+func Estimate(query, operationName string, m Multiplier) (int, error) {
+	toks, err := lex(query)
+	if err != nil {
+		return 0, err
+	}
+	p := &parser{toks: toks, fragments: map[string][]selection{}}
+	ops, err := p.document()
+	if err != nil {
+		return 0, err
+	}
+	var op []selection
+	switch {
+	case operationName != "":
+		var ok bool
+		if op, ok = ops[operationName]; !ok {
+			return 0, fmt.Errorf("querycost: no operation named %q", operationName)
+		}
+	case len(ops) == 1:
+		for _, sels := range ops {
+			op = sels
+		}
+	default:
+		return 0, fmt.Errorf("querycost: %d operations and no operation name", len(ops))
+	}
+	c := &coster{fragments: p.fragments, multiplier: m, visiting: map[string]bool{}, costs: map[string]int{}}
+	return c.cost(op)
+}
+
+const maxCost = math.MaxInt32
+
+// selection is a field (with its selections), a fragment spread (spread
+// set) or an inline fragment (neither name nor spread).
+type selection struct {
+	name     string
+	spread   string
+	children []selection
+}
+
+type coster struct {
+	fragments  map[string][]selection
+	multiplier Multiplier
+	visiting   map[string]bool
+	costs      map[string]int // of fragments already counted
+}
+
+func (c *coster) cost(sels []selection) (int, error) {
+	total := 0
+	for _, s := range sels {
+		var n int
+		switch {
+		case s.spread != "":
+			if cost, ok := c.costs[s.spread]; ok {
+				n = cost
+				break
+			}
+			frag, ok := c.fragments[s.spread]
+			if !ok {
+				return 0, fmt.Errorf("querycost: unknown fragment %q", s.spread)
+			}
+			if c.visiting[s.spread] {
+				return 0, fmt.Errorf("querycost: fragment %q spreads itself", s.spread)
+			}
+			c.visiting[s.spread] = true
+			var err error
+			n, err = c.cost(frag)
+			delete(c.visiting, s.spread)
+			if err != nil {
+				return 0, err
+			}
+			c.costs[s.spread] = n
+		case s.name != "":
+			children, err := c.cost(s.children)
+			if err != nil {
+				return 0, err
+			}
+			mult := c.multiplier(s.name)
+			if mult < 1 {
+				mult = 1
+			}
+			n = 1 + mul(mult, children)
+		default:
+			var err error
+			if n, err = c.cost(s.children); err != nil {
+				return 0, err
+			}
+		}
+		total = add(total, n)
+	}
+	return total, nil
+}
+
+// add and mul saturate at maxCost.
+func add(a, b int) int {
+	if a > maxCost-b {
+		return maxCost
+	}
+	return a + b
+}
+
+func mul(a, b int) int {
+	if b != 0 && a > maxCost/b {
+		return maxCost
+	}
+	return a * b
+}
+
+type token struct {
+	punct string // "{", "...", etc.; empty for names and values
+	name  string // set for names
+}
+
+// lex splits query into punctuators, names and values, dropping ignored
+// tokens (white space, commas and comments).
+func lex(q string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(q); {
+		c := q[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			i++
+		case c == '#':
+			for i < len(q) && q[i] != '\n' && q[i] != '\r' {
+				i++
+			}
+		case c == '.':
+			if i+3 > len(q) || q[i:i+3] != "..." {
+				return nil, fmt.Errorf("querycost: unexpected %q", c)
+			}
+			toks = append(toks, token{punct: "..."})
+			i += 3
+		case c == '"':
+			end, err := stringEnd(q, i)
+			if err != nil {
+				return nil, err
+			}
+			toks = append(toks, token{})
+			i = end
+		case isNameStart(c):
+			j := i + 1
+			for j < len(q) && (isNameStart(q[j]) || isDigit(q[j])) {
+				j++
+			}
+			toks = append(toks, token{name: q[i:j]})
+			i = j
+		case isDigit(c) || c == '-':
+			j := i + 1
+			for j < len(q) && (isDigit(q[j]) || isNameStart(q[j]) || q[j] == '.' || q[j] == '+' || q[j] == '-') {
+				j++
+			}
+			toks = append(toks, token{})
+			i = j
+		case c == '!' || c == '$' || c == '&' || c == '(' || c == ')' || c == ':' || c == '=' ||
+			c == '@' || c == '[' || c == ']' || c == '{' || c == '|' || c == '}':
+			toks = append(toks, token{punct: string(c)})
+			i++
+		default:
+			return nil, fmt.Errorf("querycost: unexpected %q", c)
+		}
+	}
+	return toks, nil
+}
+
+// stringEnd returns the index just past the string or block string that
+// starts at q[i].
+func stringEnd(q string, i int) (int, error) {
+	if i+3 <= len(q) && q[i:i+3] == `"""` {
+		for j := i + 3; j+3 <= len(q); j++ {
+			if q[j] == '\\' && j+4 <= len(q) && q[j+1:j+4] == `"""` {
+				j += 3
+				continue
+			}
+			if q[j:j+3] == `"""` {
+				return j + 3, nil
+			}
+		}
+		return 0, fmt.Errorf("querycost: unterminated block string")
+	}
+	for j := i + 1; j < len(q); j++ {
+		switch q[j] {
+		case '\\':
+			j++
+		case '"':
+			return j + 1, nil
+		case '\n', '\r':
+			return 0, fmt.Errorf("querycost: unterminated string")
+		}
+	}
+	return 0, fmt.Errorf("querycost: unterminated string")
+}
+
+func isNameStart(c byte) bool { return c == '_' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' }
+func isDigit(c byte) bool     { return c >= '0' && c <= '9' }
+
+type parser struct {
+	toks      []token
+	pos       int
+	fragments map[string][]selection
+}
+
+func (p *parser) peek() token {
+	if p.pos < len(p.toks) {
+		return p.toks[p.pos]
+	}
+	return token{punct: "EOF"}
+}
+
+func (p *parser) next() token {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+func (p *parser) expect(punct string) error {
+	if t := p.next(); t.punct != punct {
+		return fmt.Errorf("querycost: expected %q", punct)
+	}
+	return nil
+}
+
+// document parses every definition, returning the operations by name.
+func (p *parser) document() (map[string][]selection, error) {
+	ops := map[string][]selection{}
+	for p.pos < len(p.toks) {
+		t := p.peek()
+		switch {
+		case t.punct == "{":
+			sels, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			ops[""] = sels
+		case t.name == "query" || t.name == "mutation" || t.name == "subscription":
+			p.next()
+			name := ""
+			if p.peek().name != "" {
+				name = p.next().name
+			}
+			if err := p.skipBalanced("(", ")"); err != nil {
+				return nil, err
+			}
+			if err := p.directives(); err != nil {
+				return nil, err
+			}
+			sels, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			ops[name] = sels
+		case t.name == "fragment":
+			p.next()
+			name := p.next().name
+			if name == "" || p.next().name != "on" || p.next().name == "" {
+				return nil, fmt.Errorf("querycost: invalid fragment definition")
+			}
+			if err := p.directives(); err != nil {
+				return nil, err
+			}
+			sels, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			p.fragments[name] = sels
+		default:
+			return nil, fmt.Errorf("querycost: expected a definition")
+		}
+	}
+	return ops, nil
+}
+
+func (p *parser) selectionSet() ([]selection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var sels []selection
+	for p.peek().punct != "}" {
+		s, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		sels = append(sels, s)
+	}
+	p.next()
+	return sels, nil
+}
+
+func (p *parser) selection() (selection, error) {
+	if p.peek().punct == "..." {
+		p.next()
+		if t := p.peek(); t.name != "" && t.name != "on" {
+			p.next()
+			return selection{spread: t.name}, p.directives()
+		}
+		if p.peek().name == "on" {
+			p.next()
+			if p.next().name == "" {
+				return selection{}, fmt.Errorf("querycost: expected a type condition")
+			}
+		}
+		if err := p.directives(); err != nil {
+			return selection{}, err
+		}
+		children, err := p.selectionSet()
+		return selection{children: children}, err
+	}
+
+	name := p.next().name
+	if name == "" {
+		return selection{}, fmt.Errorf("querycost: expected a field")
+	}
+	if p.peek().punct == ":" { // name was an alias
+		p.next()
+		if name = p.next().name; name == "" {
+			return selection{}, fmt.Errorf("querycost: expected a field")
+		}
+	}
+	if err := p.skipBalanced("(", ")"); err != nil {
+		return selection{}, err
+	}
+	if err := p.directives(); err != nil {
+		return selection{}, err
+	}
+	s := selection{name: name}
+	if p.peek().punct == "{" {
+		var err error
+		s.children, err = p.selectionSet()
+		return s, err
+	}
+	return s, nil
+}
+
+func (p *parser) directives() error {
+	for p.peek().punct == "@" {
+		p.next()
+		if p.next().name == "" {
+			return fmt.Errorf("querycost: expected a directive name")
+		}
+		if err := p.skipBalanced("(", ")"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// skipBalanced skips arguments or variable definitions if the next token
+// is open.
+func (p *parser) skipBalanced(open, close string) error {
+	if p.peek().punct != open {
+		return nil
+	}
+	depth := 0
+	for {
+		switch p.next().punct {
+		case open:
+			depth++
+		case close:
+			if depth--; depth == 0 {
+				return nil
+			}
+		case "EOF":
+			return fmt.Errorf("querycost: expected %q", close)
+		}
+	}
+}
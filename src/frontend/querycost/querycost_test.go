@@ -0,0 +1,87 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package querycost
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestEstimate(t *testing.T) {
+	lists := func(field string) int {
+		return map[string]int{"products": 10, "recommendations": 5}[field]
+	}
+	tests := []struct {
+		name  string
+		query string
+		op    string
+		want  int
+		err   string
+	}{
+		{"shorthand", `{ cart { itemCount } }`, "", 2, ""},
+		{"package example", `{ products { name recommendations { name } } }`, "", 71, ""},
+		{"arguments, aliases and directives", `
+			# the product card
+			query Card($id: ID!, $cur: String = "EUR") {
+				p: product(id: $id) @include(if: true) {
+					name, price(currency: $cur) { amount }
+					tags: categories
+				}
+				ads(contextKeys: ["a(", "b\"}"], filter: {max: -1.5e3}) { text }
+			}`, "", 7, ""},
+		{"fragments", `
+			query { products { ...Card ... on Product { id } ... @skip(if: false) { picture } } }
+			fragment Card on Product { name recommendations { ...Name } }
+			fragment Name on Product { name }`, "", 1 + 10*(1+1+5+1+1), ""},
+		{"named operation", `query A { cart { itemCount } } mutation B { emptyCart { itemCount } }`, "B", 2, ""},
+		{"block string", `{ products(q: """a } " b""") { name } }`, "", 11, ""},
+		{"saturates", `{ products { products { products { products { products { products { products { products { products { products { name } } } } } } } } } } }`, "", maxCost, ""},
+		{"ambiguous operation", `query A { cart { itemCount } } query B { cart { itemCount } }`, "", 0, "no operation name"},
+		{"unknown operation", `query A { cart { itemCount } }`, "C", 0, `no operation named "C"`},
+		{"unknown fragment", `{ products { ...Card } }`, "", 0, "unknown fragment"},
+		{"fragment cycle", `{ products { ...A } } fragment A on Product { ...B } fragment B on Product { ...A }`, "", 0, "spreads itself"},
+		{"syntax error", `{ products { name }`, "", 0, "expected"},
+		{"unterminated string", `{ product(id: "x) { name } }`, "", 0, "unterminated string"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Estimate(tt.query, tt.op, lists)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("Estimate = %d, %v; want error containing %q", got, err, tt.err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("Estimate = %d, %v; want %d", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEstimateNestedFragmentsAreLinear(t *testing.T) {
+	// Each fragment spreads the next twice; without memoisation this would
+	// take 2^40 steps.
+	var b strings.Builder
+	b.WriteString("{ ...F0 }")
+	for i := 0; i < 40; i++ {
+		b.WriteString(" fragment F" + strconv.Itoa(i) + " on Query { ...F" + strconv.Itoa(i+1) + " ...F" + strconv.Itoa(i+1) + " }")
+	}
+	b.WriteString(" fragment F40 on Query { cart }")
+	if got, err := Estimate(b.String(), "", func(string) int { return 1 }); err != nil || got != maxCost {
+		t.Fatalf("Estimate = %d, %v; want %d", got, err, maxCost)
+	}
+}
@@ -16,11 +16,16 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"time"
 
 	pb "github.com/signalfx/microservices-demo/src/frontend/genproto"
+	"github.com/signalfx/microservices-demo/src/frontend/money"
 
 	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 const (
@@ -54,6 +59,12 @@ func (fe *frontendServer) getProduct(ctx context.Context, id string) (*pb.Produc
 	return resp, err
 }
 
+func (fe *frontendServer) searchProducts(ctx context.Context, query string) ([]*pb.Product, error) {
+	resp, err := pb.NewProductCatalogServiceClient(fe.productCatalogSvcConn).
+		SearchProducts(ctx, &pb.SearchProductsRequest{Query: query})
+	return resp.GetResults(), err
+}
+
 func (fe *frontendServer) getCart(ctx context.Context, userID string) ([]*pb.CartItem, error) {
 	resp, err := pb.NewCartServiceClient(fe.cartSvcConn).GetCart(ctx, &pb.GetCartRequest{UserId: userID})
 	return resp.GetItems(), err
@@ -74,6 +85,80 @@ func (fe *frontendServer) insertCart(ctx context.Context, userID, productID stri
 	return err
 }
 
+// updateCartItem sets the quantity of a product in the user's cart; 0
+// removes it. Cart services without UpdateItemQuantity, like the C# one,
+// have the cart rebuilt instead.
+func (fe *frontendServer) updateCartItem(ctx context.Context, userID, productID string, quantity int32) error {
+	_, err := pb.NewCartServiceClient(fe.cartSvcConn).UpdateItemQuantity(ctx, &pb.UpdateItemQuantityRequest{
+		UserId:    userID,
+		ProductId: productID,
+		Quantity:  quantity,
+	})
+	if status.Code(err) == codes.Unimplemented {
+		return fe.rebuildCart(ctx, userID, productID, quantity, true)
+	}
+	return err
+}
+
+// removeCartItem removes a product from the user's cart, rebuilding the
+// cart on cart services without RemoveItem.
+func (fe *frontendServer) removeCartItem(ctx context.Context, userID, productID string) error {
+	_, err := pb.NewCartServiceClient(fe.cartSvcConn).RemoveItem(ctx, &pb.RemoveItemRequest{UserId: userID, ProductId: productID})
+	if status.Code(err) == codes.Unimplemented {
+		return fe.rebuildCart(ctx, userID, productID, 0, false)
+	}
+	return err
+}
+
+// rebuildCart sets the quantity of a product in the user's cart with the
+// calls every cart service has: it empties the cart and adds its items
+// back. Like UpdateItemQuantity, it refuses products that are not in the
+// cart if mustExist is set. It is not atomic; concurrent changes to the
+// same cart may be lost.
+func (fe *frontendServer) rebuildCart(ctx context.Context, userID, productID string, quantity int32, mustExist bool) error {
+	items, err := fe.getCart(ctx, userID)
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, item := range items {
+		found = found || item.GetProductId() == productID
+	}
+	if !found {
+		if mustExist {
+			return status.Errorf(codes.NotFound, "product %s is not in the cart", productID)
+		}
+		return nil
+	}
+	if err := fe.emptyCart(ctx, userID); err != nil {
+		return err
+	}
+	for _, item := range items {
+		q := item.GetQuantity()
+		if item.GetProductId() == productID {
+			q = quantity
+		}
+		if q == 0 {
+			continue
+		}
+		if err := fe.insertCart(ctx, userID, item.GetProductId(), q); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cartQuantity returns how many units of a product are in the user's cart.
+func (fe *frontendServer) cartQuantity(ctx context.Context, userID, productID string) (int32, error) {
+	cart, err := fe.getCart(ctx, userID)
+	for _, item := range cart {
+		if item.GetProductId() == productID {
+			return item.GetQuantity(), err
+		}
+	}
+	return 0, err
+}
+
 func (fe *frontendServer) convertCurrency(ctx context.Context, money *pb.Money, currency string) (*pb.Money, error) {
 	if avoidNoopCurrencyConversionRPC && money.GetCurrencyCode() == currency {
 		return money, nil
@@ -84,10 +169,13 @@ func (fe *frontendServer) convertCurrency(ctx context.Context, money *pb.Money,
 			ToCode: currency})
 }
 
-func (fe *frontendServer) getShippingQuote(ctx context.Context, items []*pb.CartItem, currency string) (*pb.Money, error) {
+// getShippingQuote quotes shipping items to addr. Before the user has
+// entered an address, addr is nil and shippingservice quotes to its default
+// zone.
+func (fe *frontendServer) getShippingQuote(ctx context.Context, addr *pb.Address, items []*pb.CartItem, currency string) (*pb.Money, error) {
 	quote, err := pb.NewShippingServiceClient(fe.shippingSvcConn).GetQuote(ctx,
 		&pb.GetQuoteRequest{
-			Address: nil,
+			Address: addr,
 			Items:   items})
 	if err != nil {
 		return nil, err
@@ -96,14 +184,112 @@ func (fe *frontendServer) getShippingQuote(ctx context.Context, items []*pb.Cart
 	return localized, errors.Wrap(err, "failed to convert currency for shipping cost")
 }
 
-func (fe *frontendServer) getRecommendations(ctx context.Context, userID string, productIDs []string) ([]*pb.Product, error) {
+// shippingOption is a shipping method with its cost in the user's currency.
+type shippingOption struct {
+	*pb.ShippingOption
+	Cost *pb.Money
+}
+
+// getShippingOptions lists the shipping methods offered for items, quoted
+// like getShippingQuote. The first is the default.
+func (fe *frontendServer) getShippingOptions(ctx context.Context, addr *pb.Address, items []*pb.CartItem, currency string) ([]shippingOption, error) {
+	quote, err := pb.NewShippingServiceClient(fe.shippingSvcConn).GetQuote(ctx,
+		&pb.GetQuoteRequest{Address: addr, Items: items})
+	if err != nil {
+		return nil, err
+	}
+	options := make([]shippingOption, len(quote.GetOptions()))
+	for i, o := range quote.GetOptions() {
+		cost, err := fe.convertCurrency(ctx, o.GetCostUsd(), currency)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to convert currency for shipping option %q", o.GetId())
+		}
+		options[i] = shippingOption{ShippingOption: o, Cost: cost}
+	}
+	return options, nil
+}
+
+// validateAddress asks shippingservice whether addr can be shipped to, and
+// how it is normally written.
+func (fe *frontendServer) validateAddress(ctx context.Context, addr *pb.Address) (*pb.ValidateAddressResponse, error) {
+	return pb.NewShippingServiceClient(fe.shippingSvcConn).ValidateAddress(ctx,
+		&pb.ValidateAddressRequest{Address: addr})
+}
+
+// checkRestrictions lists what restricts shipping items to addr: items
+// that cannot go there, and items that need an adult's signature. Without
+// an address only restrictions that apply everywhere are listed.
+func (fe *frontendServer) checkRestrictions(ctx context.Context, addr *pb.Address, items []*pb.CartItem) ([]*pb.ItemRestriction, error) {
+	resp, err := pb.NewShippingServiceClient(fe.shippingSvcConn).CheckRestrictions(ctx,
+		&pb.CheckRestrictionsRequest{Address: addr, Items: items})
+	return resp.GetRestrictions(), err
+}
+
+// anyDenied reports whether any of restrictions keeps an item from being
+// shipped.
+func anyDenied(restrictions []*pb.ItemRestriction) bool {
+	for _, res := range restrictions {
+		if res.GetAction() == pb.ItemRestriction_DENY {
+			return true
+		}
+	}
+	return false
+}
+
+// placeOrder places an order for req.UserId's cart, passing the current
+// system behavior to checkoutservice. The total is the shipping cost plus
+// every item's cost.
+func (fe *frontendServer) placeOrder(ctx context.Context, req *pb.PlaceOrderRequest) (*pb.OrderResult, pb.Money, error) {
+	current, _ := behavior.get()
+	behaviorMarshalled, _ := json.Marshal(current)
+	ctx = metadata.NewOutgoingContext(ctx, metadata.New(map[string]string{"x-system-behavior": string(behaviorMarshalled)}))
+
+	resp, err := pb.NewCheckoutServiceClient(fe.checkoutSvcConn).PlaceOrder(ctx, req)
+	if err != nil {
+		return nil, pb.Money{}, err
+	}
+	order := resp.GetOrder()
+	total := *order.GetShippingCost()
+	for _, v := range order.GetItems() {
+		multPrice := money.MultiplySlow(*v.GetCost(), uint32(v.GetItem().GetQuantity()))
+		total = money.Must(money.Sum(total, multPrice))
+	}
+	return order, total, nil
+}
+
+// getOrder looks up an order. Unless userID is empty, other users' orders
+// are not found.
+func (fe *frontendServer) getOrder(ctx context.Context, orderID, userID string) (*pb.StoredOrder, error) {
+	return pb.NewCheckoutServiceClient(fe.checkoutSvcConn).GetOrder(ctx, &pb.GetOrderRequest{OrderId: orderID, UserId: userID})
+}
+
+// listOrders returns the user's orders, newest first.
+func (fe *frontendServer) listOrders(ctx context.Context, userID string) ([]*pb.StoredOrder, error) {
+	resp, err := pb.NewCheckoutServiceClient(fe.checkoutSvcConn).ListOrders(ctx, &pb.ListOrdersRequest{UserId: userID})
+	return resp.GetOrders(), err
+}
+
+func (fe *frontendServer) trackShipment(ctx context.Context, trackingID string) (*pb.TrackShipmentResponse, error) {
+	return pb.NewShippingServiceClient(fe.shippingSvcConn).TrackShipment(ctx, &pb.TrackShipmentRequest{TrackingId: trackingID})
+}
+
+func (fe *frontendServer) getLabel(ctx context.Context, trackingID string, format pb.GetLabelRequest_Format) (*pb.GetLabelResponse, error) {
+	return pb.NewShippingServiceClient(fe.shippingSvcConn).GetLabel(ctx, &pb.GetLabelRequest{TrackingId: trackingID, Format: format})
+}
+
+func (fe *frontendServer) getRecommendationIDs(ctx context.Context, userID string, productIDs []string) ([]string, error) {
 	resp, err := pb.NewRecommendationServiceClient(fe.recommendationSvcConn).ListRecommendations(ctx,
 		&pb.ListRecommendationsRequest{UserId: userID, ProductIds: productIDs})
+	return resp.GetProductIds(), err
+}
+
+func (fe *frontendServer) getRecommendations(ctx context.Context, userID string, productIDs []string) ([]*pb.Product, error) {
+	ids, err := fe.getRecommendationIDs(ctx, userID, productIDs)
 	if err != nil {
 		return nil, err
 	}
-	out := make([]*pb.Product, len(resp.GetProductIds()))
-	for i, v := range resp.GetProductIds() {
+	out := make([]*pb.Product, len(ids))
+	for i, v := range ids {
 		p, err := fe.getProduct(ctx, v)
 		if err != nil {
 			return nil, errors.Wrapf(err, "failed to get recommended product info (#%s)", v)
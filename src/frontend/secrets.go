@@ -0,0 +1,91 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// secret is a credential taken from an environment variable or, failing
+// that, from a file that is re-read whenever it changes, so that rotated
+// Kubernetes secrets are picked up without a restart.
+type secret struct {
+	name string
+	env  string // variable holding the value itself
+	path string
+
+	mu      sync.Mutex
+	value   string
+	modTime time.Time
+	size    int64
+}
+
+// newSecret returns the secret named by env, read from the file named by
+// env+"_FILE" (or defaultPath) when env is unset.
+func newSecret(name, env, defaultPath string) *secret {
+	path := os.Getenv(env + "_FILE")
+	if path == "" {
+		path = defaultPath
+	}
+	return &secret{name: name, env: env, path: path}
+}
+
+// get returns the current value, or an error if the secret is not
+// configured.
+func (s *secret) get() (string, error) {
+	if v := strings.TrimSpace(os.Getenv(s.env)); v != "" {
+		return v, nil
+	}
+	fi, err := os.Stat(s.path)
+	if err != nil {
+		return "", errors.Errorf("%s is not configured: set %s or mount %s", s.name, s.env, s.path)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.value != "" && fi.ModTime().Equal(s.modTime) && fi.Size() == s.size {
+		return s.value, nil
+	}
+	raw, err := ioutil.ReadFile(s.path)
+	if err != nil {
+		return "", errors.Wrapf(err, "could not read %s", s.name)
+	}
+	v := strings.TrimSpace(string(raw))
+	if v == "" {
+		return "", errors.Errorf("%s file %s is empty", s.name, s.path)
+	}
+	s.value, s.modTime, s.size = v, fi.ModTime(), fi.Size()
+	return v, nil
+}
+
+// requireSecret answers 503 Service Unavailable instead of calling next
+// while s is unavailable.
+func requireSecret(s *secret, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := s.get(); err != nil {
+			getLoggerWithTraceFields(r.Context()).WithField("error", err).Error("refusing request")
+			http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
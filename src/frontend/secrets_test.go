@@ -0,0 +1,81 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSecretReloads(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	t.Setenv("TEST_TOKEN", "")
+	t.Setenv("TEST_TOKEN_FILE", path)
+	s := newSecret("test token", "TEST_TOKEN", "unused")
+
+	if _, err := s.get(); err == nil {
+		t.Fatal("missing file: expected an error")
+	}
+
+	write := func(v string, mtime time.Time) {
+		t.Helper()
+		if err := ioutil.WriteFile(path, []byte(v+"\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	check := func(want string) {
+		t.Helper()
+		if got, err := s.get(); err != nil || got != want {
+			t.Fatalf("get() = %q, %v; want %q", got, err, want)
+		}
+	}
+
+	start := time.Now()
+	write("first", start)
+	check("first")
+	write("second", start.Add(time.Minute))
+	check("second")
+
+	t.Setenv("TEST_TOKEN", "from-env")
+	check("from-env")
+}
+
+func TestRequireSecret(t *testing.T) {
+	t.Setenv("TEST_TOKEN", "")
+	t.Setenv("TEST_TOKEN_FILE", filepath.Join(t.TempDir(), "missing"))
+	s := newSecret("test token", "TEST_TOKEN", "unused")
+	h := requireSecret(s, http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, behaviorRequest(http.MethodPost, "", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status=%d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+
+	t.Setenv("TEST_TOKEN", "set")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, behaviorRequest(http.MethodPost, "", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status=%d, want %d", w.Code, http.StatusOK)
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slack
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Response types for slash command replies.
+const (
+	Ephemeral = "ephemeral"
+	InChannel = "in_channel"
+)
+
+// Message is a slash command reply or a message posted to a response_url.
+// Text is the notification fallback; Blocks carry the Block Kit layout.
+type Message struct {
+	ResponseType    string  `json:"response_type,omitempty"`
+	ReplaceOriginal bool    `json:"replace_original,omitempty"`
+	Text            string  `json:"text"`
+	Blocks          []Block `json:"blocks,omitempty"`
+}
+
+// Block is a Block Kit layout block.
+type Block struct {
+	Type     string       `json:"type"`
+	BlockID  string       `json:"block_id,omitempty"`
+	Text     *TextObject  `json:"text,omitempty"`
+	Fields   []TextObject `json:"fields,omitempty"`
+	Elements []Element    `json:"elements,omitempty"`
+}
+
+// TextObject is a Block Kit text object.
+type TextObject struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// Element is a context text or an interactive element such as a button.
+type Element struct {
+	Type     string      `json:"type"`
+	Text     interface{} `json:"text,omitempty"`
+	ActionID string      `json:"action_id,omitempty"`
+	Value    string      `json:"value,omitempty"`
+	Style    string      `json:"style,omitempty"`
+}
+
+// Markdown returns an mrkdwn text object.
+func Markdown(text string) TextObject { return TextObject{Type: "mrkdwn", Text: text} }
+
+// Plain returns a plain_text object.
+func Plain(text string) TextObject { return TextObject{Type: "plain_text", Text: text} }
+
+// Header returns a header block.
+func Header(text string) Block {
+	t := Plain(text)
+	return Block{Type: "header", Text: &t}
+}
+
+// Section returns a section block holding markdown text.
+func Section(markdown string) Block {
+	t := Markdown(markdown)
+	return Block{Type: "section", Text: &t}
+}
+
+// Fields returns a section block laying out markdown fields in two columns.
+func Fields(markdown ...string) Block {
+	b := Block{Type: "section"}
+	for _, f := range markdown {
+		b.Fields = append(b.Fields, Markdown(f))
+	}
+	return b
+}
+
+// Context returns a context block of small markdown texts.
+func Context(markdown ...string) Block {
+	b := Block{Type: "context"}
+	for _, m := range markdown {
+		b.Elements = append(b.Elements, Element{Type: "mrkdwn", Text: m})
+	}
+	return b
+}
+
+// Actions returns an actions block holding interactive elements.
+func Actions(elements ...Element) Block {
+	return Block{Type: "actions", Elements: elements}
+}
+
+// Button returns a button element. style is "", "primary" or "danger".
+func Button(text, actionID, value, style string) Element {
+	return Element{Type: "button", Text: Plain(text), ActionID: actionID, Value: value, Style: style}
+}
+
+// Divider returns a divider block.
+func Divider() Block { return Block{Type: "divider"} }
+
+// Reply returns an ephemeral message with text as both the fallback and a
+// single section.
+func Reply(text string) Message {
+	return Message{ResponseType: Ephemeral, Text: text, Blocks: []Block{Section(text)}}
+}
+
+// Errorf returns an ephemeral error message.
+func Errorf(format string, args ...interface{}) Message {
+	return Reply(":warning: " + fmt.Sprintf(format, args...))
+}
+
+var escaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// Escape escapes the characters mrkdwn treats as markup for links and
+// mentions.
+func Escape(text string) string { return escaper.Replace(text) }
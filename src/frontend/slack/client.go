@@ -0,0 +1,245 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package slack implements the frontend's Slack integration: a router for
+// slash commands that reply with Block Kit messages, and a small client for
+// the parts of the Web API those commands need.
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultBaseURL is the Slack Web API endpoint.
+const DefaultBaseURL = "https://slack.com/api"
+
+const (
+	defaultMaxRetries = 3
+	pageLimit         = "200"
+)
+
+// Client calls the Slack Web API with a bot token.
+type Client struct {
+	// BaseURL defaults to DefaultBaseURL.
+	BaseURL string
+	// Token returns the bot token for each call.
+	Token func() (string, error)
+	// HTTPClient defaults to http.DefaultClient.
+	HTTPClient *http.Client
+	// MaxRetries bounds retries of rate-limited calls; it defaults to 3.
+	MaxRetries int
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+// call invokes a Web API method and decodes the response into out, which
+// must embed apiResponse. Rate-limited calls are retried after the delay
+// Slack asks for, up to MaxRetries times.
+func (c *Client) call(ctx context.Context, method string, params url.Values, out interface{ result() apiResponse }) error {
+	token, err := c.Token()
+	if err != nil {
+		return fmt.Errorf("slack: %s: %v", method, err)
+	}
+	base := c.BaseURL
+	if base == "" {
+		base = DefaultBaseURL
+	}
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest(http.MethodGet, strings.TrimRight(base, "/")+"/"+method+"?"+params.Encode(), nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := c.httpClient().Do(req.WithContext(ctx))
+		if err != nil {
+			return fmt.Errorf("slack: %s: %v", method, err)
+		}
+		if resp.StatusCode == http.StatusTooManyRequests && attempt < c.maxRetries() {
+			resp.Body.Close()
+			if err := sleep(ctx, retryAfter(resp.Header.Get("Retry-After"))); err != nil {
+				return fmt.Errorf("slack: %s: rate limited: %v", method, err)
+			}
+			continue
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("slack: %s: %s", method, resp.Status)
+		}
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return fmt.Errorf("slack: %s: %v", method, err)
+		}
+		if r := out.result(); !r.OK {
+			return fmt.Errorf("slack: %s: %s", method, r.Error)
+		}
+		return nil
+	}
+}
+
+func (c *Client) maxRetries() int {
+	if c.MaxRetries > 0 {
+		return c.MaxRetries
+	}
+	return defaultMaxRetries
+}
+
+// retryAfter parses a Retry-After header in seconds, defaulting to one
+// second.
+func retryAfter(v string) time.Duration {
+	secs, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil || secs < 0 {
+		return time.Second
+	}
+	return time.Duration(secs) * time.Second
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type apiResponse struct {
+	OK       bool   `json:"ok"`
+	Error    string `json:"error"`
+	Metadata struct {
+		NextCursor string `json:"next_cursor"`
+	} `json:"response_metadata"`
+}
+
+func (r apiResponse) result() apiResponse { return r }
+
+// Channel is a conversation in the workspace.
+type Channel struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// PrivateChannels lists every private channel the bot can see, following
+// pagination cursors.
+func (c *Client) PrivateChannels(ctx context.Context) ([]Channel, error) {
+	var out []Channel
+	params := url.Values{"types": {"private_channel"}, "exclude_archived": {"true"}, "limit": {pageLimit}}
+	for {
+		var resp struct {
+			apiResponse
+			Channels []Channel `json:"channels"`
+		}
+		if err := c.call(ctx, "conversations.list", params, &resp); err != nil {
+			return nil, err
+		}
+		out = append(out, resp.Channels...)
+		if resp.Metadata.NextCursor == "" {
+			return out, nil
+		}
+		params.Set("cursor", resp.Metadata.NextCursor)
+	}
+}
+
+// Members lists the user IDs in channel id, following pagination cursors.
+func (c *Client) Members(ctx context.Context, id string) ([]string, error) {
+	var out []string
+	params := url.Values{"channel": {id}, "limit": {pageLimit}}
+	for {
+		var resp struct {
+			apiResponse
+			Members []string `json:"members"`
+		}
+		if err := c.call(ctx, "conversations.members", params, &resp); err != nil {
+			return nil, err
+		}
+		out = append(out, resp.Members...)
+		if resp.Metadata.NextCursor == "" {
+			return out, nil
+		}
+		params.Set("cursor", resp.Metadata.NextCursor)
+	}
+}
+
+// GroupID returns the ID of the private channel called name.
+func (c *Client) GroupID(ctx context.Context, name string) (string, error) {
+	channels, err := c.PrivateChannels(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, ch := range channels {
+		if ch.Name == name {
+			return ch.ID, nil
+		}
+	}
+	return "", fmt.Errorf("slack: group %q not found", name)
+}
+
+// IsMember reports whether userID belongs to the channel groupID.
+func (c *Client) IsMember(ctx context.Context, groupID, userID string) (bool, error) {
+	members, err := c.Members(ctx, groupID)
+	if err != nil {
+		return false, err
+	}
+	for _, m := range members {
+		if m == userID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// InGroup reports whether userID belongs to the private channel called
+// group. It asks Slack every time; see Directory for a cached equivalent.
+func (c *Client) InGroup(ctx context.Context, userID, group string) (bool, error) {
+	id, err := c.GroupID(ctx, group)
+	if err != nil {
+		return false, err
+	}
+	return c.IsMember(ctx, id, userID)
+}
+
+// Respond posts m to a slash command's response_url.
+func (c *Client) Respond(ctx context.Context, responseURL string, m Message) error {
+	body, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, responseURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.httpClient().Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("slack: response_url: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("slack: response_url: %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slack
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	defaultDirectoryTTL = 5 * time.Minute
+	defaultRecheck      = 30 * time.Second
+)
+
+// GroupChecker reports whether a user belongs to a private channel.
+// Both Client and Directory implement it.
+type GroupChecker interface {
+	InGroup(ctx context.Context, userID, group string) (bool, error)
+}
+
+// Directory answers group membership questions from a cache of private
+// channel IDs and member lists, going back to Slack when entries expire or
+// are invalidated.
+type Directory struct {
+	Client *Client
+	// TTL bounds how long channel IDs and member lists are reused; it
+	// defaults to five minutes.
+	TTL time.Duration
+	// Recheck is the age after which a member list that lacks the user is
+	// fetched again before answering no, so that someone just added to a
+	// channel is not turned away for a whole TTL. It defaults to 30s.
+	Recheck time.Duration
+	// Now defaults to time.Now.
+	Now func() time.Time
+
+	mu      sync.Mutex
+	ids     map[string]cachedID      // by channel name
+	members map[string]cachedMembers // by channel ID
+}
+
+type cachedID struct {
+	id      string
+	fetched time.Time
+}
+
+type cachedMembers struct {
+	set     map[string]bool
+	fetched time.Time
+}
+
+// NewDirectory returns a directory backed by c with the default TTLs.
+func NewDirectory(c *Client) *Directory {
+	return &Directory{Client: c}
+}
+
+func (d *Directory) now() time.Time {
+	if d.Now != nil {
+		return d.Now()
+	}
+	return time.Now()
+}
+
+func (d *Directory) ttl() time.Duration {
+	if d.TTL > 0 {
+		return d.TTL
+	}
+	return defaultDirectoryTTL
+}
+
+func (d *Directory) recheck() time.Duration {
+	if d.Recheck > 0 {
+		return d.Recheck
+	}
+	return defaultRecheck
+}
+
+// GroupID returns the ID of the private channel called name. A miss lists
+// every private channel once and caches all of their IDs.
+func (d *Directory) GroupID(ctx context.Context, name string) (string, error) {
+	d.mu.Lock()
+	c, ok := d.ids[name]
+	d.mu.Unlock()
+	if ok && d.now().Sub(c.fetched) < d.ttl() {
+		return c.id, nil
+	}
+
+	channels, err := d.Client.PrivateChannels(ctx)
+	if err != nil {
+		return "", err
+	}
+	now := d.now()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.ids = make(map[string]cachedID, len(channels))
+	for _, ch := range channels {
+		d.ids[ch.Name] = cachedID{id: ch.ID, fetched: now}
+	}
+	if c, ok := d.ids[name]; ok {
+		return c.id, nil
+	}
+	return "", fmt.Errorf("slack: group %q not found", name)
+}
+
+// memberList returns the cached member list of channel id, fetching it if it
+// is missing, expired or older than maxAge.
+func (d *Directory) memberList(ctx context.Context, id string, maxAge time.Duration) (cachedMembers, error) {
+	d.mu.Lock()
+	c, ok := d.members[id]
+	d.mu.Unlock()
+	if ok && d.now().Sub(c.fetched) < maxAge {
+		return c, nil
+	}
+
+	list, err := d.Client.Members(ctx, id)
+	if err != nil {
+		return cachedMembers{}, err
+	}
+	c = cachedMembers{set: make(map[string]bool, len(list)), fetched: d.now()}
+	for _, m := range list {
+		c.set[m] = true
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.members == nil {
+		d.members = make(map[string]cachedMembers)
+	}
+	d.members[id] = c
+	return c, nil
+}
+
+// InGroup reports whether userID belongs to the private channel called
+// group, using cached answers where possible.
+func (d *Directory) InGroup(ctx context.Context, userID, group string) (bool, error) {
+	id, err := d.GroupID(ctx, group)
+	if err != nil {
+		return false, err
+	}
+	m, err := d.memberList(ctx, id, d.ttl())
+	if err != nil {
+		// The channel may have been renamed or archived; look it up again
+		// next time.
+		d.Invalidate(group)
+		return false, err
+	}
+	if m.set[userID] || d.now().Sub(m.fetched) < d.recheck() {
+		return m.set[userID], nil
+	}
+	m, err = d.memberList(ctx, id, d.recheck())
+	if err != nil {
+		return false, err
+	}
+	return m.set[userID], nil
+}
+
+// Invalidate forgets the ID and member list of the private channel called
+// group.
+func (d *Directory) Invalidate(group string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if c, ok := d.ids[group]; ok {
+		delete(d.members, c.id)
+		delete(d.ids, group)
+	}
+}
+
+// InvalidateAll empties the cache.
+func (d *Directory) InvalidateAll() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.ids = nil
+	d.members = nil
+}
@@ -0,0 +1,112 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slack_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/signalfx/microservices-demo/src/frontend/slack"
+	"github.com/signalfx/microservices-demo/src/frontend/slack/slacktest"
+)
+
+func TestClientPaginatesAndRetries(t *testing.T) {
+	s := slacktest.NewServer()
+	defer s.Close()
+	s.PageSize = 2
+	s.AddGroup("G1", "a")
+	s.AddGroup("G2", "b")
+	s.AddGroup("G3", "frothly-ops", "U1", "U2", "U3", "U4", "U5")
+	c := s.Client()
+	ctx := context.Background()
+
+	s.RateLimit("conversations.list", 2)
+	if id, err := c.GroupID(ctx, "frothly-ops"); err != nil || id != "G3" {
+		t.Fatalf("GroupID = %q, %v; want G3 from the second page", id, err)
+	}
+	if n := s.Calls("conversations.list"); n != 4 {
+		t.Fatalf("conversations.list called %d times, want 2 pages + 2 retries", n)
+	}
+	if ok, err := c.IsMember(ctx, "G3", "U5"); err != nil || !ok {
+		t.Fatalf("IsMember(U5) = %v, %v; want a member from the last page", ok, err)
+	}
+
+	c.MaxRetries = 1
+	s.RateLimit("conversations.members", 2)
+	if _, err := c.Members(ctx, "G3"); err == nil || !strings.Contains(err.Error(), "429") {
+		t.Fatalf("err = %v, want 429 once retries are exhausted", err)
+	}
+}
+
+func TestDirectoryCaches(t *testing.T) {
+	s := slacktest.NewServer()
+	defer s.Close()
+	s.AddGroup("G1", "frothly-ops", "U-alice")
+	now := time.Unix(1700000000, 0)
+	d := slack.NewDirectory(s.Client())
+	d.TTL, d.Recheck = time.Minute, 10*time.Second
+	d.Now = func() time.Time { return now }
+	ctx := context.Background()
+
+	calls := func() [2]int {
+		return [2]int{s.Calls("conversations.list"), s.Calls("conversations.members")}
+	}
+	tests := []struct {
+		name    string
+		advance time.Duration
+		user    string
+		before  func()
+		want    bool
+		calls   [2]int
+	}{
+		{name: "cold cache", user: "U-alice", want: true, calls: [2]int{1, 1}},
+		{name: "warm cache", user: "U-alice", want: true, calls: [2]int{1, 1}},
+		{name: "fresh negative is trusted", user: "U-bob", want: false, calls: [2]int{1, 1}},
+		{
+			name: "stale negative is rechecked", advance: 15 * time.Second, user: "U-bob", want: true, calls: [2]int{1, 2},
+			before: func() { s.AddGroup("G1", "frothly-ops", "U-alice", "U-bob") },
+		},
+		{name: "expired entries are refetched", advance: 2 * time.Minute, user: "U-alice", want: true, calls: [2]int{2, 3}},
+		{
+			name: "invalidation", user: "U-alice", want: false, calls: [2]int{3, 4},
+			before: func() {
+				s.AddGroup("G1", "frothly-ops", "U-bob")
+				d.Invalidate("frothly-ops")
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now = now.Add(tt.advance)
+			if tt.before != nil {
+				tt.before()
+			}
+			ok, err := d.InGroup(ctx, tt.user, "frothly-ops")
+			if err != nil || ok != tt.want {
+				t.Fatalf("InGroup = %v, %v; want %v", ok, err, tt.want)
+			}
+			if got := calls(); got != tt.calls {
+				t.Fatalf("API calls (list, members) = %v, want %v", got, tt.calls)
+			}
+		})
+	}
+
+	d.InvalidateAll()
+	if _, err := d.InGroup(ctx, "U-bob", "no-such-group"); err == nil {
+		t.Fatal("unknown group: expected an error")
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// Interaction is a block_actions payload sent when a user clicks a button.
+type Interaction struct {
+	Type string `json:"type"`
+	User struct {
+		ID       string `json:"id"`
+		Username string `json:"username"`
+	} `json:"user"`
+	ResponseURL string   `json:"response_url"`
+	Actions     []Action `json:"actions"`
+
+	// Request is the HTTP request carrying the interaction. Its body has
+	// already been read.
+	Request *http.Request `json:"-"`
+}
+
+// Action is a single clicked element.
+type Action struct {
+	ActionID string `json:"action_id"`
+	Value    string `json:"value"`
+}
+
+// ActionHandler handles clicks on elements with a given action_id.
+type ActionHandler struct {
+	ActionID string
+	// Group, if set, is the private channel whose members may click.
+	Group string
+	// Run returns the message that replaces the one holding the element.
+	// An error is shown only to the user who clicked.
+	Run func(ctx context.Context, in Interaction, a Action) (Message, error)
+}
+
+// HandleAction registers h. It panics if the action_id is taken.
+func (rt *Router) HandleAction(h ActionHandler) {
+	if rt.actions == nil {
+		rt.actions = make(map[string]*ActionHandler)
+	}
+	if _, ok := rt.actions[h.ActionID]; ok || h.Run == nil {
+		panic("slack: invalid or duplicate action " + h.ActionID)
+	}
+	rt.actions[h.ActionID] = &h
+}
+
+// Interactions returns the handler for the app's interactivity request URL.
+// Slack expects an answer within three seconds, so the request is
+// acknowledged at once and results are posted to the response_url.
+func (rt *Router) Interactions() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var in Interaction
+		if err := json.Unmarshal([]byte(r.PostFormValue("payload")), &in); err != nil {
+			http.Error(w, "malformed interaction payload", http.StatusBadRequest)
+			return
+		}
+		in.Request = r
+		w.WriteHeader(http.StatusOK)
+		if in.Type != "block_actions" || in.ResponseURL == "" {
+			return
+		}
+		for _, a := range in.Actions {
+			if h, ok := rt.actions[a.ActionID]; ok {
+				go rt.runAction(r, in, h, a)
+			}
+		}
+	})
+}
+
+func (rt *Router) runAction(r *http.Request, in Interaction, h *ActionHandler, a Action) {
+	ctx, cancel := context.WithTimeout(detached{r.Context()}, rt.asyncTimeout())
+	defer cancel()
+
+	msg, err := rt.action(ctx, in, h, a)
+	if err != nil {
+		msg = Errorf("%s", Escape(err.Error()))
+	} else {
+		msg.ReplaceOriginal = true
+	}
+	if err := rt.Client.Respond(ctx, in.ResponseURL, msg); err != nil {
+		rt.observeAction(r, in, a, true, err)
+	}
+}
+
+func (rt *Router) action(ctx context.Context, in Interaction, h *ActionHandler, a Action) (Message, error) {
+	if h.Group != "" {
+		ok, err := rt.groups().InGroup(ctx, in.User.ID, h.Group)
+		if err != nil {
+			rt.observeAction(in.Request, in, a, false, err)
+			return Message{}, err
+		}
+		if !ok {
+			rt.observeAction(in.Request, in, a, false, nil)
+			return Message{}, errors.New("only members of #" + h.Group + " can do that")
+		}
+	}
+	rt.observeAction(in.Request, in, a, true, nil)
+	msg, err := h.Run(ctx, in, a)
+	if err != nil {
+		rt.observeAction(in.Request, in, a, true, err)
+	}
+	return msg, err
+}
+
+func (rt *Router) observeAction(r *http.Request, in Interaction, a Action, allowed bool, err error) {
+	if rt.ObserveAction != nil {
+		rt.ObserveAction(r, in, a, allowed, err)
+	}
+}
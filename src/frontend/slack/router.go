@@ -0,0 +1,281 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slack
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const defaultAsyncTimeout = 30 * time.Second
+
+// Invocation is a parsed slash command request.
+type Invocation struct {
+	TeamID      string
+	ChannelID   string
+	UserID      string
+	UserName    string
+	Command     string
+	Text        string
+	ResponseURL string
+	TriggerID   string
+
+	// Request is the HTTP request carrying the command. Its body has
+	// already been read.
+	Request *http.Request
+}
+
+// Command is a subcommand of a slash command, such as "behavior get".
+type Command struct {
+	// Name is the space separated words selecting the command.
+	Name string
+	// Usage describes the arguments, e.g. "<field> <value>".
+	Usage string
+	// Help is a one-line description shown by help.
+	Help string
+	// Group, if set, is the private channel whose members may run the
+	// command.
+	Group string
+	// Async commands are acknowledged immediately and post their result to
+	// the invocation's response_url.
+	Async bool
+	// Parse validates the arguments following Name. A nil Parse accepts no
+	// arguments.
+	Parse func(args []string) (interface{}, error)
+	// Run executes the command with the parsed arguments. An error is
+	// reported to the user as an ephemeral message.
+	Run func(ctx context.Context, inv Invocation, args interface{}) (Message, error)
+}
+
+func (c *Command) words() []string { return strings.Fields(c.Name) }
+
+// Router dispatches slash command requests to registered commands.
+type Router struct {
+	// Name is the slash command as typed in Slack, e.g. "/frothly".
+	Name string
+	// Client posts asynchronous replies.
+	Client *Client
+	// Groups checks group membership; it defaults to Client, which asks
+	// Slack on every command. Use a Directory to cache the answers.
+	Groups GroupChecker
+	// AsyncTimeout bounds asynchronous commands; it defaults to 30s.
+	AsyncTimeout time.Duration
+	// Observe, if set, is called once per invocation after authorization
+	// is decided (cmd is nil for help and unknown commands) and again with
+	// the error, if any, once an authorized command has finished.
+	Observe func(r *http.Request, inv Invocation, cmd *Command, allowed bool, err error)
+	// ObserveAction is the equivalent of Observe for interactive actions.
+	ObserveAction func(r *http.Request, in Interaction, a Action, allowed bool, err error)
+
+	commands []*Command
+	actions  map[string]*ActionHandler
+}
+
+// Register adds c to the router. It panics if c is incomplete or its name
+// is taken.
+func (rt *Router) Register(c Command) {
+	if len(c.words()) == 0 || c.Run == nil {
+		panic("slack: command needs a name and a Run function")
+	}
+	for _, existing := range rt.commands {
+		if existing.Name == c.Name {
+			panic("slack: duplicate command " + c.Name)
+		}
+	}
+	rt.commands = append(rt.commands, &c)
+}
+
+// lookup returns the command whose name is the longest prefix of words,
+// along with the remaining arguments.
+func (rt *Router) lookup(words []string) (*Command, []string) {
+	var best *Command
+	for _, c := range rt.commands {
+		name := c.words()
+		if len(name) > len(words) || (best != nil && len(name) <= len(best.words())) {
+			continue
+		}
+		match := true
+		for i, w := range name {
+			if !strings.EqualFold(w, words[i]) {
+				match = false
+				break
+			}
+		}
+		if match {
+			best = c
+		}
+	}
+	if best == nil {
+		return nil, nil
+	}
+	return best, words[len(best.words()):]
+}
+
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rt.serve(w, r, "")
+}
+
+// Alias returns a handler that runs commands with prefix prepended to the
+// text, so that a legacy slash command can map onto a subcommand.
+func (rt *Router) Alias(prefix string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rt.serve(w, r, prefix)
+	})
+}
+
+func (rt *Router) serve(w http.ResponseWriter, r *http.Request, prefix string) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "malformed slash command", http.StatusBadRequest)
+		return
+	}
+	inv := Invocation{
+		TeamID:      r.PostForm.Get("team_id"),
+		ChannelID:   r.PostForm.Get("channel_id"),
+		UserID:      r.PostForm.Get("user_id"),
+		UserName:    r.PostForm.Get("user_name"),
+		Command:     r.PostForm.Get("command"),
+		Text:        strings.TrimSpace(prefix + " " + r.PostForm.Get("text")),
+		ResponseURL: r.PostForm.Get("response_url"),
+		TriggerID:   r.PostForm.Get("trigger_id"),
+		Request:     r,
+	}
+	writeMessage(w, rt.dispatch(r, inv))
+}
+
+func (rt *Router) dispatch(r *http.Request, inv Invocation) Message {
+	words := strings.Fields(inv.Text)
+	if len(words) == 0 || strings.EqualFold(words[0], "help") {
+		rt.observe(r, inv, nil, true, nil)
+		return rt.help(words)
+	}
+	cmd, rest := rt.lookup(words)
+	if cmd == nil {
+		rt.observe(r, inv, nil, false, nil)
+		return Errorf("Unknown command `%s`. Try `%s help`.", Escape(words[0]), rt.Name)
+	}
+
+	var args interface{}
+	if cmd.Parse != nil {
+		var err error
+		if args, err = cmd.Parse(rest); err != nil {
+			rt.observe(r, inv, cmd, false, err)
+			return Errorf("%s\nUsage: `%s`", Escape(err.Error()), rt.usage(cmd))
+		}
+	} else if len(rest) > 0 {
+		rt.observe(r, inv, cmd, false, fmt.Errorf("unexpected arguments"))
+		return Errorf("`%s` takes no arguments.", rt.usage(cmd))
+	}
+
+	if cmd.Group != "" {
+		ok, err := rt.groups().InGroup(r.Context(), inv.UserID, cmd.Group)
+		if err != nil {
+			rt.observe(r, inv, cmd, false, err)
+			return Errorf("Could not check your permissions: %s", Escape(err.Error()))
+		}
+		if !ok {
+			rt.observe(r, inv, cmd, false, nil)
+			return Errorf("`%s` is restricted to members of #%s.", rt.usage(cmd), cmd.Group)
+		}
+	}
+	rt.observe(r, inv, cmd, true, nil)
+
+	if !cmd.Async || inv.ResponseURL == "" {
+		return rt.run(r.Context(), r, inv, cmd, args)
+	}
+	go func() {
+		// The request is already answered, so the command gets its own
+		// deadline rather than the request's, but keeps its values.
+		ctx, cancel := context.WithTimeout(detached{r.Context()}, rt.asyncTimeout())
+		defer cancel()
+		msg := rt.run(ctx, r, inv, cmd, args)
+		if err := rt.Client.Respond(ctx, inv.ResponseURL, msg); err != nil {
+			rt.observe(r, inv, cmd, true, err)
+		}
+	}()
+	return Reply(fmt.Sprintf(":hourglass_flowing_sand: Working on `%s %s`…", rt.Name, Escape(inv.Text)))
+}
+
+// detached is a context with the values of its parent but none of its
+// cancellation.
+type detached struct{ context.Context }
+
+func (detached) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detached) Done() <-chan struct{}       { return nil }
+func (detached) Err() error                  { return nil }
+
+func (rt *Router) run(ctx context.Context, r *http.Request, inv Invocation, cmd *Command, args interface{}) Message {
+	msg, err := cmd.Run(ctx, inv, args)
+	if err != nil {
+		rt.observe(r, inv, cmd, true, err)
+		return Errorf("`%s` failed: %s", cmd.Name, Escape(err.Error()))
+	}
+	return msg
+}
+
+func (rt *Router) observe(r *http.Request, inv Invocation, cmd *Command, allowed bool, err error) {
+	if rt.Observe != nil {
+		rt.Observe(r, inv, cmd, allowed, err)
+	}
+}
+
+func (rt *Router) asyncTimeout() time.Duration {
+	if rt.AsyncTimeout > 0 {
+		return rt.AsyncTimeout
+	}
+	return defaultAsyncTimeout
+}
+
+func (rt *Router) groups() GroupChecker {
+	if rt.Groups != nil {
+		return rt.Groups
+	}
+	return rt.Client
+}
+
+// usage returns the escaped synopsis of c.
+func (rt *Router) usage(c *Command) string {
+	return Escape(strings.TrimSpace(rt.Name + " " + c.Name + " " + c.Usage))
+}
+
+// help lists every command, or only those matching the words after "help".
+func (rt *Router) help(words []string) Message {
+	var topic []string
+	if len(words) > 1 {
+		topic = words[1:]
+	}
+	msg := Message{ResponseType: Ephemeral, Text: rt.Name + " commands", Blocks: []Block{Header(rt.Name + " commands")}}
+	for _, c := range rt.commands {
+		if len(topic) > 0 && !strings.HasPrefix(c.Name, strings.Join(topic, " ")) {
+			continue
+		}
+		msg.Blocks = append(msg.Blocks, Section(fmt.Sprintf("*`%s`*\n%s", rt.usage(c), c.Help)))
+		if c.Group != "" {
+			msg.Blocks = append(msg.Blocks, Context("Requires membership in #"+c.Group))
+		}
+	}
+	if len(msg.Blocks) == 1 {
+		return Errorf("No command matches `%s`. Try `%s help`.", Escape(strings.Join(topic, " ")), rt.Name)
+	}
+	return msg
+}
+
+func writeMessage(w http.ResponseWriter, m Message) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(m)
+}
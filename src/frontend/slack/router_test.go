@@ -0,0 +1,178 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slack_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/signalfx/microservices-demo/src/frontend/slack"
+	"github.com/signalfx/microservices-demo/src/frontend/slack/slacktest"
+)
+
+func testRouter(s *slacktest.Server) *slack.Router {
+	rt := &slack.Router{Name: "/frothly", Client: s.Client()}
+	rt.Register(slack.Command{
+		Name: "echo",
+		Help: "Repeat the arguments.",
+		Parse: func(args []string) (interface{}, error) {
+			if len(args) == 0 {
+				return nil, errors.New("nothing to echo")
+			}
+			return strings.Join(args, " "), nil
+		},
+		Run: func(_ context.Context, _ slack.Invocation, args interface{}) (slack.Message, error) {
+			return slack.Reply(args.(string)), nil
+		},
+	})
+	rt.Register(slack.Command{
+		Name:  "supplier pay",
+		Usage: "<supplier> <amount>",
+		Help:  "Pay a supplier.",
+		Group: "accounts-payable",
+		Async: true,
+		Parse: func(args []string) (interface{}, error) {
+			if len(args) != 2 {
+				return nil, errors.New("expected a supplier and an amount")
+			}
+			if _, err := strconv.ParseFloat(args[1], 64); err != nil {
+				return nil, errors.New("amount must be a number")
+			}
+			return args, nil
+		},
+		Run: func(_ context.Context, inv slack.Invocation, args interface{}) (slack.Message, error) {
+			a := args.([]string)
+			if a[0] == "broken" {
+				return slack.Message{}, errors.New("supplier service unavailable")
+			}
+			return slack.Reply("paid " + a[0] + " " + a[1]), nil
+		},
+	})
+	rt.Register(slack.Command{
+		Name: "supplier",
+		Help: "List suppliers.",
+		Run: func(context.Context, slack.Invocation, interface{}) (slack.Message, error) {
+			return slack.Reply("suppliers"), nil
+		},
+	})
+	return rt
+}
+
+func invoke(t *testing.T, h http.Handler, s *slacktest.Server, user, text string) slack.Message {
+	t.Helper()
+	form := url.Values{"user_id": {user}, "command": {"/frothly"}, "text": {text}, "response_url": {s.ResponseURL()}}
+	r := httptest.NewRequest(http.MethodPost, "/slack/command", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("status=%d content-type=%q", w.Code, w.Header().Get("Content-Type"))
+	}
+	var m slack.Message
+	if err := json.NewDecoder(w.Body).Decode(&m); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// marshal renders m as JSON without HTML escaping, for substring checks.
+func marshal(m slack.Message) string {
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.Encode(m)
+	return b.String()
+}
+
+func TestRouter(t *testing.T) {
+	s := slacktest.NewServer()
+	defer s.Close()
+	s.AddGroup("G1", "accounts-payable", "U-ap")
+	rt := testRouter(s)
+
+	tests := []struct {
+		name, user, text string
+		want             string // substring of the immediate reply
+		async            string // substring of the response_url message, if any
+	}{
+		{"help", "U1", "", "supplier pay &lt;supplier&gt; &lt;amount&gt;", ""},
+		{"help topic", "U1", "help echo", "Repeat the arguments", ""},
+		{"unknown", "U1", "launch rockets", "Unknown command `launch`", ""},
+		{"parse error shows usage", "U1", "echo", "Usage: `/frothly echo`", ""},
+		{"sync command", "U1", "ECHO hello there", "hello there", ""},
+		{"shorter name still matches", "U1", "supplier", "suppliers", ""},
+		{"extra arguments", "U1", "supplier list", "takes no arguments", ""},
+		{"not in group", "U1", "supplier pay 7 10", "restricted to members of #accounts-payable", ""},
+		{"async command", "U-ap", "supplier pay 7 10", "Working on", "paid 7 10"},
+		{"async failure", "U-ap", "supplier pay broken 10", "Working on", "supplier service unavailable"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := invoke(t, rt, s, tt.user, tt.text)
+			if raw := marshal(m); !strings.Contains(raw, tt.want) {
+				t.Fatalf("reply %s does not contain %q", raw, tt.want)
+			}
+			if tt.async == "" {
+				return
+			}
+			select {
+			case m := <-s.Responses:
+				if raw := marshal(m); !strings.Contains(raw, tt.async) {
+					t.Fatalf("async reply %s does not contain %q", raw, tt.async)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("no message posted to response_url")
+			}
+		})
+	}
+}
+
+func TestRouterAlias(t *testing.T) {
+	s := slacktest.NewServer()
+	defer s.Close()
+	s.AddGroup("G1", "accounts-payable", "U-ap")
+
+	var observed []string
+	rt := testRouter(s)
+	rt.Observe = func(_ *http.Request, inv slack.Invocation, cmd *slack.Command, allowed bool, err error) {
+		if cmd != nil {
+			observed = append(observed, cmd.Name+" "+strconv.FormatBool(allowed))
+		}
+	}
+	m := invoke(t, rt.Alias("echo"), s, "U1", "via alias")
+	if m.Text != "via alias" {
+		t.Fatalf("alias reply = %q", m.Text)
+	}
+	if len(observed) != 1 || observed[0] != "echo true" {
+		t.Fatalf("observed %v", observed)
+	}
+}
+
+func TestClientRejectsBadToken(t *testing.T) {
+	s := slacktest.NewServer()
+	defer s.Close()
+	c := &slack.Client{BaseURL: s.BaseURL(), Token: func() (string, error) { return "wrong", nil }}
+	if _, err := c.GroupID(context.Background(), "accounts-payable"); err == nil || !strings.Contains(err.Error(), "invalid_auth") {
+		t.Fatalf("err = %v, want invalid_auth", err)
+	}
+}
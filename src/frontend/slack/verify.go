@@ -0,0 +1,143 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slack
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// SignatureHeader and TimestampHeader carry Slack's request signature.
+	SignatureHeader = "X-Slack-Signature"
+	TimestampHeader = "X-Slack-Request-Timestamp"
+
+	// MaxRequestAge is how far a request timestamp may be from the current
+	// time.
+	MaxRequestAge = 5 * time.Minute
+
+	maxSignedBodyBytes = 1 << 20
+)
+
+var (
+	errMissingSignature = errors.New("missing Slack signature headers")
+	errStale            = errors.New("request timestamp outside the allowed window")
+	errBadSignature     = errors.New("invalid Slack signature")
+	errReplayed         = errors.New("request was already processed")
+)
+
+// Sign returns the X-Slack-Signature value for a request body sent at
+// timestamp (unix seconds).
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	return "v0=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verifier checks that requests were signed by Slack with the app's signing
+// secret, are recent, and have not been seen before.
+type Verifier struct {
+	// Secret returns the current signing secret. If it fails, requests are
+	// refused with 503 Service Unavailable.
+	Secret func() (string, error)
+	// Now defaults to time.Now.
+	Now func() time.Time
+	// OnReject, if set, is called with the reason a request was refused.
+	OnReject func(r *http.Request, status int, err error)
+
+	mu   sync.Mutex
+	seen map[string]time.Time // signature -> request timestamp
+}
+
+// Middleware refuses requests that fail verification and passes the rest,
+// with their body intact, to next.
+func (v *Verifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secret, err := v.Secret()
+		if err != nil {
+			v.reject(w, r, http.StatusServiceUnavailable, err)
+			return
+		}
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxSignedBodyBytes))
+		if err != nil {
+			v.reject(w, r, http.StatusBadRequest, err)
+			return
+		}
+		r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		if err := v.verify(secret, r.Header.Get(TimestampHeader), r.Header.Get(SignatureHeader), body); err != nil {
+			v.reject(w, r, http.StatusUnauthorized, err)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (v *Verifier) verify(secret, timestamp, signature string, body []byte) error {
+	if timestamp == "" || signature == "" {
+		return errMissingSignature
+	}
+	secs, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errStale
+	}
+	now := v.now()
+	sent := time.Unix(secs, 0)
+	if d := now.Sub(sent); d > MaxRequestAge || d < -MaxRequestAge {
+		return errStale
+	}
+	if !hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature)) {
+		return errBadSignature
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.seen == nil {
+		v.seen = make(map[string]time.Time)
+	}
+	// Anything older than the window is refused as stale anyway.
+	for sig, t := range v.seen {
+		if now.Sub(t) > MaxRequestAge {
+			delete(v.seen, sig)
+		}
+	}
+	if _, ok := v.seen[signature]; ok {
+		return errReplayed
+	}
+	v.seen[signature] = sent
+	return nil
+}
+
+func (v *Verifier) now() time.Time {
+	if v.Now != nil {
+		return v.Now()
+	}
+	return time.Now()
+}
+
+func (v *Verifier) reject(w http.ResponseWriter, r *http.Request, status int, err error) {
+	if v.OnReject != nil {
+		v.OnReject(r, status, err)
+	}
+	http.Error(w, http.StatusText(status), status)
+}
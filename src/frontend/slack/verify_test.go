@@ -0,0 +1,91 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slack_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/signalfx/microservices-demo/src/frontend/slack"
+)
+
+func TestVerifier(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	secret := "signing-secret"
+	v := &slack.Verifier{
+		Secret: func() (string, error) { return secret, nil },
+		Now:    func() time.Time { return now },
+	}
+	var gotBody string
+	h := v.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		gotBody = string(b)
+	}))
+
+	signed := func(body string, at time.Time, key string) map[string]string {
+		ts := strconv.FormatInt(at.Unix(), 10)
+		return map[string]string{slack.TimestampHeader: ts, slack.SignatureHeader: slack.Sign(key, ts, []byte(body))}
+	}
+
+	tests := []struct {
+		name   string
+		body   string
+		header map[string]string
+		code   int
+	}{
+		{"valid", "text=a", signed("text=a", now, secret), http.StatusOK},
+		{"replayed", "text=a", signed("text=a", now, secret), http.StatusUnauthorized},
+		{"unsigned", "text=b", nil, http.StatusUnauthorized},
+		{"wrong secret", "text=c", signed("text=c", now, "other"), http.StatusUnauthorized},
+		{"tampered body", "text=evil", signed("text=d", now, secret), http.StatusUnauthorized},
+		{"too old", "text=e", signed("text=e", now.Add(-6*time.Minute), secret), http.StatusUnauthorized},
+		{"too far ahead", "text=f", signed("text=f", now.Add(6*time.Minute), secret), http.StatusUnauthorized},
+		{"within window", "text=g", signed("text=g", now.Add(-4*time.Minute), secret), http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotBody = ""
+			r := httptest.NewRequest(http.MethodPost, "/slack/command", strings.NewReader(tt.body))
+			for k, val := range tt.header {
+				r.Header.Set(k, val)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if w.Code != tt.code {
+				t.Fatalf("status=%d, want %d", w.Code, tt.code)
+			}
+			if tt.code == http.StatusOK && gotBody != tt.body {
+				t.Fatalf("handler saw body %q, want %q", gotBody, tt.body)
+			}
+		})
+	}
+}
+
+func TestVerifierWithoutSecret(t *testing.T) {
+	v := &slack.Verifier{Secret: func() (string, error) { return "", errors.New("no signing secret") }}
+	called := false
+	h := v.Middleware(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { called = true }))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/slack/command", strings.NewReader("text=a")))
+	if w.Code != http.StatusServiceUnavailable || called {
+		t.Fatalf("status=%d called=%v, want 503 without calling the handler", w.Code, called)
+	}
+}
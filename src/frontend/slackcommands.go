@@ -0,0 +1,441 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/signalfx/microservices-demo/src/frontend/genproto"
+	"github.com/signalfx/microservices-demo/src/frontend/ledger"
+	"github.com/signalfx/microservices-demo/src/frontend/money"
+	"github.com/signalfx/microservices-demo/src/frontend/slack"
+)
+
+const (
+	slashCommand         = "/frothly"
+	defaultOpsGroup      = "frothly-ops"
+	defaultPayablesGroup = "accounts-payable"
+
+	approvePaymentAction = "supplier.payment.approve"
+	rejectPaymentAction  = "supplier.payment.reject"
+)
+
+// slackCommandOutcome is the audited result of a Slack slash command.
+type slackCommandOutcome struct {
+	Text       string `json:"text"`
+	Command    string `json:"command,omitempty"`
+	Authorized bool   `json:"authorized"`
+	Error      string `json:"error,omitempty"`
+}
+
+// newSlackDirectory caches group lookups made through client for
+// SLACK_DIRECTORY_TTL.
+func newSlackDirectory(client *slack.Client) (*slack.Directory, error) {
+	d := slack.NewDirectory(client)
+	if v := os.Getenv("SLACK_DIRECTORY_TTL"); v != "" {
+		ttl, err := time.ParseDuration(v)
+		if err != nil || ttl <= 0 {
+			return nil, errors.Errorf("invalid SLACK_DIRECTORY_TTL %q", v)
+		}
+		d.TTL = ttl
+	}
+	return d, nil
+}
+
+// newSlackRouter registers the /frothly commands. Commands that change
+// state or expose customer data are restricted to the Slack groups named
+// by SLACK_OPS_GROUP and SLACK_PAYABLES_GROUP, whose members are looked up
+// through dir; the payables group also approves large supplier payments.
+func (fe *frontendServer) newSlackRouter(dir *slack.Directory) *slack.Router {
+	ops := envOr("SLACK_OPS_GROUP", defaultOpsGroup)
+	payables := envOr("SLACK_PAYABLES_GROUP", defaultPayablesGroup)
+
+	rt := &slack.Router{
+		Name:          slashCommand,
+		Client:        dir.Client,
+		Groups:        dir,
+		Observe:       fe.observeSlackCommand,
+		ObserveAction: fe.observeSlackAction,
+	}
+	rt.Register(slack.Command{
+		Name:  "behavior get",
+		Help:  "Show the system behavior of the replica that answers.",
+		Group: ops,
+		Run:   fe.slackBehaviorGet,
+	})
+	rt.Register(slack.Command{
+		Name:  "behavior set",
+		Usage: "<field> <value>",
+		Help:  "Change one system behavior field, e.g. `checkoutService.paymentFailureRate 0.2`.",
+		Group: ops,
+		Parse: parseBehaviorSet,
+		Run:   fe.slackBehaviorSet,
+	})
+	rt.Register(slack.Command{
+		Name:  "order",
+		Usage: "<order-id>",
+		Help:  "Show an order placed recently through the replica that answers.",
+		Group: ops,
+		Parse: parseOneArg("an order ID"),
+		Run:   fe.slackOrder,
+	})
+	rt.Register(slack.Command{
+		Name:  "supplier pay",
+		Usage: "<supplier-id> <amount> <invoice>",
+		Help:  "Pay a supplier through the supplier service.",
+		Group: payables,
+		Async: true,
+		Parse: parseSupplierPay,
+		Run:   fe.slackSupplierPay,
+	})
+	rt.HandleAction(slack.ActionHandler{ActionID: approvePaymentAction, Group: payables, Run: fe.slackDecidePayment(true)})
+	rt.HandleAction(slack.ActionHandler{ActionID: rejectPaymentAction, Group: payables, Run: fe.slackDecidePayment(false)})
+	return rt
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// slackDirectoryInvalidateHandler drops cached Slack group lookups, for
+// example right after someone is removed from a restricted channel. The
+// optional group form value limits it to one channel.
+func (fe *frontendServer) slackDirectoryInvalidateHandler(w http.ResponseWriter, r *http.Request) {
+	group := strings.TrimPrefix(r.FormValue("group"), "#")
+	if group == "" {
+		fe.slackDirectory.InvalidateAll()
+	} else {
+		fe.slackDirectory.Invalidate(group)
+	}
+	fe.recordAudit(r, requestActor(r), "slack.directory.invalidate", "/admin/slack/directory", nil,
+		struct {
+			Group string `json:"group,omitempty"`
+		}{group})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// logSlackRejection logs requests refused by the signature check.
+func logSlackRejection(r *http.Request, status int, err error) {
+	getLoggerWithTraceFields(r.Context()).WithField("http.status", status).WithField("error", err).
+		Warn("rejected Slack request")
+}
+
+func (fe *frontendServer) observeSlackCommand(r *http.Request, inv slack.Invocation, cmd *slack.Command, allowed bool, err error) {
+	log := getLoggerWithTraceFields(r.Context()).WithField("slack.user", inv.UserID).WithField("slack.text", inv.Text)
+	outcome := slackCommandOutcome{Text: inv.Text, Authorized: allowed}
+	if cmd != nil {
+		outcome.Command = cmd.Name
+		log = log.WithField("slack.command", cmd.Name)
+	}
+	if err != nil {
+		outcome.Error = err.Error()
+		log = log.WithField("error", err)
+	}
+	if allowed && err != nil {
+		// A command that was already audited as authorized has failed.
+		log.Warn("slack command failed")
+		return
+	}
+	log.WithField("slack.authorized", allowed).Info("slack command")
+	fe.recordAudit(r, "slack:"+inv.UserID, "slack.command", slashCommand, nil, outcome)
+}
+
+func (fe *frontendServer) observeSlackAction(r *http.Request, in slack.Interaction, a slack.Action, allowed bool, err error) {
+	log := getLoggerWithTraceFields(r.Context()).WithField("slack.user", in.User.ID).
+		WithField("slack.action", a.ActionID).WithField("slack.value", a.Value)
+	outcome := slackCommandOutcome{Text: a.Value, Command: a.ActionID, Authorized: allowed}
+	if err != nil {
+		outcome.Error = err.Error()
+		log = log.WithField("error", err)
+	}
+	if allowed && err != nil {
+		log.Warn("slack action failed")
+		return
+	}
+	log.WithField("slack.authorized", allowed).Info("slack action")
+	fe.recordAudit(r, "slack:"+in.User.ID, "slack.action", a.ActionID, nil, outcome)
+}
+
+func (fe *frontendServer) slackBehaviorGet(_ context.Context, _ slack.Invocation, _ interface{}) (slack.Message, error) {
+	cur, etag := behavior.get()
+	values := behaviorValues(cur)
+	fields := make([]string, len(behaviorFields))
+	for i, f := range behaviorFields {
+		fields[i] = fmt.Sprintf("*%s*\n%s", f.name(), formatValue(values[i]))
+	}
+	return slack.Message{
+		ResponseType: slack.Ephemeral,
+		Text:         "System behavior " + etag,
+		Blocks: []slack.Block{
+			slack.Header("System behavior"),
+			slack.Fields(fields...),
+			slack.Context("ETag " + etag),
+		},
+	}, nil
+}
+
+type behaviorSetArgs struct {
+	field behaviorField
+	value float64
+}
+
+func parseBehaviorSet(args []string) (interface{}, error) {
+	if len(args) != 2 {
+		return nil, errors.New("expected a field and a value")
+	}
+	f, ok := lookupBehaviorField(args[0])
+	if !ok {
+		names := make([]string, len(behaviorFields))
+		for i, f := range behaviorFields {
+			names[i] = f.name()
+		}
+		return nil, errors.Errorf("unknown field %q; fields are %s", args[0], strings.Join(names, ", "))
+	}
+	v, err := strconv.ParseFloat(args[1], 64)
+	if err != nil {
+		return nil, errors.Errorf("%q is not a number", args[1])
+	}
+	return behaviorSetArgs{f, v}, nil
+}
+
+func (fe *frontendServer) slackBehaviorSet(_ context.Context, inv slack.Invocation, args interface{}) (slack.Message, error) {
+	a := args.(behaviorSetArgs)
+	patch := map[string]interface{}{}
+	setDocumentValue(patch, a.field.Path, a.value)
+	body, _ := json.Marshal(patch)
+	prev, cur, err := behavior.replace("", func(b SystemBehavior) (SystemBehavior, error) {
+		return applyBehaviorPatch(b, body)
+	})
+	if err != nil {
+		return slack.Message{}, err
+	}
+	fe.recordAudit(inv.Request, "slack:"+inv.UserID, "behavior.patch", "/system-behavior", prev, cur)
+	return slack.Message{
+		ResponseType: slack.InChannel,
+		Text:         fmt.Sprintf("%s set %s to %s", inv.UserName, a.field.name(), formatValue(a.value)),
+		Blocks: []slack.Block{
+			slack.Section(fmt.Sprintf("<@%s> set `%s` to *%s*", inv.UserID, a.field.name(), formatValue(a.value))),
+		},
+	}, nil
+}
+
+func parseOneArg(what string) func([]string) (interface{}, error) {
+	return func(args []string) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, errors.Errorf("expected %s", what)
+		}
+		return args[0], nil
+	}
+}
+
+func (fe *frontendServer) slackOrder(ctx context.Context, _ slack.Invocation, args interface{}) (slack.Message, error) {
+	id := args.(string)
+	stored, err := fe.getOrder(ctx, id, "")
+	if status.Code(err) == codes.NotFound {
+		return slack.Reply(fmt.Sprintf("There is no order `%s`.", slack.Escape(id))), nil
+	} else if err != nil {
+		return slack.Message{}, errors.Wrap(err, "could not look up order")
+	}
+	o := stored.GetOrder()
+	items := make([]string, 0, len(o.GetItems()))
+	for _, it := range o.GetItems() {
+		items = append(items, fmt.Sprintf("• %d × `%s` at %s", it.GetItem().GetQuantity(),
+			slack.Escape(it.GetItem().GetProductId()), renderMoney(*it.GetCost())))
+	}
+	addr := o.GetShippingAddress()
+	msg := slack.Message{
+		ResponseType: slack.Ephemeral,
+		Text:         "Order " + o.GetOrderId(),
+		Blocks: []slack.Block{
+			slack.Header("Order " + o.GetOrderId()),
+			slack.Fields(
+				"*Placed*\n"+time.Unix(stored.GetPlacedAt(), 0).UTC().Format("2006-01-02 15:04 MST"),
+				"*Total*\n"+renderMoney(*stored.GetTotal()),
+				"*Shipping*\n"+renderMoney(*o.GetShippingCost()),
+				"*Tracking*\n`"+slack.Escape(o.GetShippingTrackingId())+"`",
+				"*Customer*\n"+slack.Escape(stored.GetEmail()),
+				"*Ship to*\n"+slack.Escape(addr.GetCity()+", "+addr.GetState()+" "+addr.GetCountry()),
+			),
+		},
+	}
+	if len(items) > 0 {
+		msg.Blocks = append(msg.Blocks, slack.Section(strings.Join(items, "\n")))
+	}
+	return msg, nil
+}
+
+type supplierPayArgs struct {
+	supplierID string
+	amount     pb.Money
+	invoice    string
+}
+
+// parseSupplierPay accepts "<supplier-id> <amount> <invoice>" as well as
+// the legacy "<supplier-id>:<amount>:<invoice>". The original pay_someone
+// form, "<supplier-id>:<amount>", is refused: the ledger pays an invoice at
+// most once, so every payment needs its reference.
+func parseSupplierPay(args []string) (interface{}, error) {
+	if len(args) == 1 {
+		args = strings.SplitN(args[0], ":", 3)
+	}
+	if len(args) == 2 {
+		return nil, errors.New("an invoice reference is required: use `<supplier-id>:<amount>:<invoice>`")
+	}
+	if len(args) != 3 || args[0] == "" || args[2] == "" {
+		return nil, errors.New("expected a supplier ID, an amount and an invoice reference")
+	}
+	amount, err := money.Parse(args[1], supplierPaymentCurrency)
+	if err != nil || !money.IsPositive(amount) {
+		return nil, errors.Errorf("%q is not a positive amount", args[1])
+	}
+	return supplierPayArgs{args[0], amount, args[2]}, nil
+}
+
+// slackSupplierPay records the payment in the ledger and pays small
+// amounts at once. Larger ones become a payment request that a second
+// member of the payables group has to approve. Repeating the command for
+// the same invoice shows the payment's current state.
+func (fe *frontendServer) slackSupplierPay(ctx context.Context, inv slack.Invocation, args interface{}) (slack.Message, error) {
+	a := args.(supplierPayArgs)
+	actor := "slack:" + inv.UserID
+	p, created, err := fe.requestSupplierPayment(ctx, ledger.Payment{
+		SupplierID:     a.supplierID,
+		Amount:         a.amount,
+		InvoiceRef:     a.invoice,
+		IdempotencyKey: "slack:" + a.supplierID + ":" + a.invoice,
+		RequestedBy:    actor,
+	})
+	if err != nil {
+		return slack.Message{}, err
+	}
+	if !created {
+		return slack.Reply(fmt.Sprintf("Invoice `%s` for supplier `%s` is already %s.",
+			slack.Escape(p.InvoiceRef), slack.Escape(p.SupplierID), p.Status)), nil
+	}
+	fe.recordAudit(inv.Request, actor, "supplier.payment.requested", a.supplierID, nil, p)
+
+	amount := money.Format(a.amount)
+	if fe.approvals.needsApproval(a.amount) {
+		req, _, err := fe.approvals.request(p.ID, a.supplierID, amount, inv.UserID)
+		if err != nil {
+			// Release the invoice, which nobody could approve now.
+			fe.payments.Reject(p.ID, "approval request not recorded")
+			return slack.Message{}, err
+		}
+		return paymentRequestMessage(req), nil
+	}
+
+	p, err = fe.sendSupplierPayment(ctx, p.ID, autoApprover)
+	if err != nil {
+		return slack.Message{}, err
+	}
+	fe.recordAudit(inv.Request, actor, "supplier.payment", a.supplierID, nil, p)
+	if p.Status == ledger.Failed {
+		return slack.Message{}, errors.New(p.Result)
+	}
+	return slack.Message{
+		ResponseType: slack.InChannel,
+		Text:         fmt.Sprintf("Paid %s to supplier %s", amount, a.supplierID),
+		Blocks: []slack.Block{
+			slack.Section(fmt.Sprintf(":white_check_mark: <@%s> paid *%s* to supplier `%s` for invoice `%s`", inv.UserID,
+				amount, slack.Escape(a.supplierID), slack.Escape(a.invoice))),
+			slack.Context(slack.Escape(strings.TrimSpace(p.Result))),
+		},
+	}, nil
+}
+
+// slackDecidePayment handles the Approve and Reject buttons of a payment
+// request. Clicks on a request that was already decided just refresh it.
+func (fe *frontendServer) slackDecidePayment(approve bool) func(context.Context, slack.Interaction, slack.Action) (slack.Message, error) {
+	return func(ctx context.Context, in slack.Interaction, a slack.Action) (slack.Message, error) {
+		actor := "slack:" + in.User.ID
+		p, changed, err := fe.approvals.decide(a.Value, in.User.ID, approve)
+		if err != nil {
+			return slack.Message{}, err
+		}
+		if !changed {
+			return paymentRequestMessage(p), nil
+		}
+		if !approve {
+			fe.payments.Reject(p.ID, "rejected by "+actor)
+			fe.recordAudit(in.Request, actor, "supplier.payment.rejected", p.SupplierID, nil, p)
+			return paymentRequestMessage(p), nil
+		}
+		fe.recordAudit(in.Request, actor, "supplier.payment.approved", p.SupplierID, nil, p)
+		lp, err := fe.sendSupplierPayment(ctx, p.ID, actor)
+		if err == nil && lp.Status == ledger.Failed {
+			err = errors.New(lp.Result)
+		}
+		if p, err = fe.approvals.finish(p.ID, lp.Result, err); err != nil {
+			getLoggerWithTraceFields(ctx).WithField("error", err).Warn("could not record the outcome of an approved payment")
+		}
+		fe.recordAudit(in.Request, actor, "supplier.payment", p.SupplierID, nil, lp)
+		return paymentRequestMessage(p), nil
+	}
+}
+
+// paymentRequestMessage renders p, with Approve and Reject buttons while it
+// is pending.
+func paymentRequestMessage(p paymentRequest) slack.Message {
+	summary := fmt.Sprintf("Payment of %s to supplier %s requested by <@%s>", p.Amount, p.SupplierID, p.RequestedBy)
+	msg := slack.Message{
+		ResponseType: slack.InChannel,
+		Text:         summary,
+		Blocks: []slack.Block{
+			slack.Section(fmt.Sprintf(":lock: Payment of *%s* to supplier `%s` requested by <@%s>",
+				slack.Escape(p.Amount), slack.Escape(p.SupplierID), p.RequestedBy)),
+		},
+	}
+	var status string
+	switch p.Status {
+	case paymentPending:
+		msg.Blocks = append(msg.Blocks,
+			slack.Context("Needs approval by another member. Expires "+p.ExpiresAt.UTC().Format("2006-01-02 15:04 MST")+"."),
+			slack.Actions(
+				slack.Button("Approve", approvePaymentAction, p.ID, "primary"),
+				slack.Button("Reject", rejectPaymentAction, p.ID, "danger"),
+			))
+		return msg
+	case paymentApproved:
+		status = fmt.Sprintf(":hourglass_flowing_sand: Approved by <@%s>, paying…", p.DecidedBy)
+	case paymentPaid:
+		status = fmt.Sprintf(":white_check_mark: Approved by <@%s> and paid.", p.DecidedBy)
+	case paymentFailed:
+		status = fmt.Sprintf(":x: Approved by <@%s> but the payment failed.", p.DecidedBy)
+	case paymentRejected:
+		status = fmt.Sprintf(":no_entry_sign: Rejected by <@%s>.", p.DecidedBy)
+	case paymentExpired:
+		status = ":alarm_clock: Expired without a decision."
+	}
+	msg.Blocks = append(msg.Blocks, slack.Section(status))
+	if p.Result != "" {
+		msg.Blocks = append(msg.Blocks, slack.Context(slack.Escape(strings.TrimSpace(p.Result))))
+	}
+	return msg
+}
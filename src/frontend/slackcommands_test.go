@@ -0,0 +1,125 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/signalfx/microservices-demo/src/frontend/audit"
+	pb "github.com/signalfx/microservices-demo/src/frontend/genproto"
+	"github.com/signalfx/microservices-demo/src/frontend/slack"
+	"github.com/signalfx/microservices-demo/src/frontend/slack/slacktest"
+)
+
+func slackCommand(h http.Handler, user, text string) *httptest.ResponseRecorder {
+	form := url.Values{"user_id": {user}, "user_name": {user}, "command": {slashCommand}, "text": {text}}
+	r := behaviorRequest(http.MethodPost, form.Encode(), map[string]string{"Content-Type": "application/x-www-form-urlencoded"})
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestSlackCommands(t *testing.T) {
+	behavior = newBehaviorStore()
+	defer func() { behavior = newBehaviorStore() }()
+	s := slacktest.NewServer()
+	defer s.Close()
+	s.AddGroup("G1", defaultOpsGroup, "U-ops")
+
+	fe := testFrontendServer(t)
+	shop := newFakeShop()
+	shop.serve(t, fe)
+	shop.orders = append(shop.orders, &pb.StoredOrder{
+		Order:    &pb.OrderResult{OrderId: "o-1", ShippingTrackingId: "TRK-1", ShippingCost: &pb.Money{CurrencyCode: "USD", Units: 8}},
+		UserId:   "s1",
+		Email:    "someone@example.com",
+		Total:    &pb.Money{CurrencyCode: "USD", Units: 42},
+		PlacedAt: 1700000000,
+	})
+	rt := fe.newSlackRouter(slack.NewDirectory(s.Client()))
+
+	tests := []struct {
+		name, user, text, want string
+	}{
+		{"help lists commands", "U1", "help", "supplier pay"},
+		{"restricted", "U1", "behavior get", "restricted to members of #frothly-ops"},
+		{"get", "U-ops", "behavior get", "checkoutService.paymentFailureRate"},
+		{"set validates field", "U-ops", "behavior set bogus 1", "unknown field"},
+		{"set validates range", "U-ops", "behavior set checkoutService.paymentFailureRate 3", "must be"},
+		{"set", "U-ops", "behavior set checkoutService.paymentFailureRate 0.2", "set `checkoutService.paymentFailureRate` to *0.2*"},
+		{"order", "U-ops", "order o-1", "TRK-1"},
+		{"unknown order", "U-ops", "order o-2", "There is no order `o-2`"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := slackCommand(rt, tt.user, tt.text)
+			if body := w.Body.String(); !strings.Contains(body, tt.want) {
+				t.Fatalf("reply %s does not contain %q", body, tt.want)
+			}
+		})
+	}
+	if got, _ := behavior.get(); got.CheckoutService.PaymentFailureRate != 0.2 {
+		t.Fatalf("paymentFailureRate = %v, want 0.2", got.CheckoutService.PaymentFailureRate)
+	}
+
+	page, err := fe.auditLog.Query(audit.Query{Limit: 100, Action: "behavior.patch"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actors []string
+	for _, rec := range page.Records {
+		actors = append(actors, rec.Actor)
+	}
+	if want := []string{"slack:U-ops"}; !reflect.DeepEqual(actors, want) {
+		t.Fatalf("behavior.patch actors = %v, want %v", actors, want)
+	}
+}
+
+func TestSlackDirectoryInvalidate(t *testing.T) {
+	s := slacktest.NewServer()
+	defer s.Close()
+	s.AddGroup("G1", defaultOpsGroup, "U-ops")
+
+	fe := testFrontendServer(t)
+	fe.slackDirectory = slack.NewDirectory(s.Client())
+	rt := fe.newSlackRouter(fe.slackDirectory)
+	const restricted = "restricted to members of #frothly-ops"
+
+	if body := slackCommand(rt, "U-ops", "behavior get").Body.String(); strings.Contains(body, restricted) {
+		t.Fatalf("member refused: %s", body)
+	}
+	s.AddGroup("G1", defaultOpsGroup)
+	if body := slackCommand(rt, "U-ops", "behavior get").Body.String(); strings.Contains(body, restricted) {
+		t.Fatalf("cached membership not used: %s", body)
+	}
+
+	w := httptest.NewRecorder()
+	fe.slackDirectoryInvalidateHandler(w, behaviorRequest(http.MethodPost, "group=%23frothly-ops",
+		map[string]string{"Content-Type": "application/x-www-form-urlencoded"}))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("invalidate status=%d", w.Code)
+	}
+	if body := slackCommand(rt, "U-ops", "behavior get").Body.String(); !strings.Contains(body, restricted) {
+		t.Fatalf("removed member still allowed after invalidation: %s", body)
+	}
+	if page, err := fe.auditLog.Query(audit.Query{Limit: 10, Action: "slack.directory.invalidate"}); err != nil || len(page.Records) != 1 {
+		t.Fatalf("audit records %+v, err %v", page, err)
+	}
+}
@@ -0,0 +1,271 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	pb "github.com/signalfx/microservices-demo/src/frontend/genproto"
+	"github.com/signalfx/microservices-demo/src/frontend/ledger"
+	"github.com/signalfx/microservices-demo/src/frontend/money"
+	"github.com/signalfx/microservices-demo/src/frontend/upstream"
+)
+
+const (
+	defaultSupplierPaymentsFile = "supplier-payments.jsonl"
+	// supplierPaymentCurrency is the currency supplierservice pays in and
+	// suppliers.csv states limits in.
+	supplierPaymentCurrency = "USD"
+	idempotencyKeyHeader    = "Idempotency-Key"
+	// autoApprover approves payments below the approval threshold.
+	autoApprover = "policy:below-approval-threshold"
+)
+
+func mustOpenLedger(path string) *ledger.Ledger {
+	if path == "" {
+		path = defaultSupplierPaymentsFile
+	}
+	l, err := ledger.Open(path)
+	if err != nil {
+		panic(errors.Wrapf(err, "ledger: failed to open %s", path))
+	}
+	return l
+}
+
+// supplierLimits reads a supplier's per-payment and daily limits from its
+// suppliers.csv row.
+func (fe *frontendServer) supplierLimits(ctx context.Context, supplierID string) (ledger.Limits, error) {
+	s, err := fe.suppliers.Lookup(ctx, supplierID)
+	if err != nil {
+		return ledger.Limits{}, err
+	}
+	var lim ledger.Limits
+	for _, l := range []struct {
+		v   string
+		dst **pb.Money
+	}{{s.PaymentLimit, &lim.PerPayment}, {s.DailyLimit, &lim.Daily}} {
+		if l.v == "" {
+			continue
+		}
+		m, err := money.Parse(l.v, supplierPaymentCurrency)
+		if err != nil {
+			return ledger.Limits{}, errors.Wrapf(err, "supplier %s has an invalid limit %q", supplierID, l.v)
+		}
+		*l.dst = &m
+	}
+	return lim, nil
+}
+
+// requestSupplierPayment records p in the ledger, checked against the
+// supplier's limits. created is false when p's idempotency key was already
+// used for the same payment.
+func (fe *frontendServer) requestSupplierPayment(ctx context.Context, p ledger.Payment) (_ ledger.Payment, created bool, err error) {
+	lim, err := fe.supplierLimits(ctx, p.SupplierID)
+	if err != nil {
+		return ledger.Payment{}, false, err
+	}
+	return fe.payments.Request(p, lim)
+}
+
+// sendSupplierPayment approves a requested payment and sends it through
+// supplierservice. A payment that could not be sent comes back Failed.
+func (fe *frontendServer) sendSupplierPayment(ctx context.Context, id, approvedBy string) (ledger.Payment, error) {
+	p, err := fe.payments.Approve(id, approvedBy)
+	if err != nil {
+		return p, err
+	}
+	result, err := fe.paySupplier(ctx, p.SupplierID, money.Format(p.Amount))
+	return fe.payments.Finish(id, result, err)
+}
+
+// releaseExpiredPayment fails the ledger payment of an expired approval
+// request, which releases its invoice so that it can be paid again.
+func (fe *frontendServer) releaseExpiredPayment(p paymentRequest) {
+	fe.payments.Reject(p.ID, "approval expired")
+}
+
+// supplierPaymentInput is the body of POST /suppliers/{id}/payments.
+type supplierPaymentInput struct {
+	Amount       string `json:"amount"`
+	CurrencyCode string `json:"currencyCode"`
+	InvoiceRef   string `json:"invoiceRef"`
+}
+
+func (fe *frontendServer) createSupplierPaymentHandler(w http.ResponseWriter, r *http.Request) {
+	var in supplierPaymentInput
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&in); err != nil {
+		renderJSONError(getLoggerWithTraceFields(r.Context()), w, errors.Wrap(err, "invalid payment"), http.StatusBadRequest)
+		return
+	}
+	fe.createSupplierPayment(w, r, mux.Vars(r)["id"], in)
+}
+
+// supplierpaymentresponse is the original payment endpoint. The amount is
+// in the path; the invoice comes from the invoice query parameter.
+func (fe *frontendServer) supplierpaymentresponse(w http.ResponseWriter, r *http.Request) {
+	fe.createSupplierPayment(w, r, mux.Vars(r)["id"], supplierPaymentInput{
+		Amount:     mux.Vars(r)["amount"],
+		InvoiceRef: r.URL.Query().Get("invoice"),
+	})
+}
+
+// createSupplierPayment records a payment and, when it is below the
+// approval threshold, sends it. Replaying an idempotency key returns the
+// payment's current state with 200; a new payment gets 201 once sent or
+// 202 while it waits for a second approver.
+func (fe *frontendServer) createSupplierPayment(w http.ResponseWriter, r *http.Request, supplierID string, in supplierPaymentInput) {
+	log := getLoggerWithTraceFields(r.Context())
+	if in.CurrencyCode == "" {
+		in.CurrencyCode = supplierPaymentCurrency
+	}
+	var fields []fieldError
+	key := r.Header.Get(idempotencyKeyHeader)
+	if key == "" {
+		fields = append(fields, fieldError{Field: idempotencyKeyHeader, Message: "is required"})
+	}
+	if in.InvoiceRef == "" {
+		fields = append(fields, fieldError{Field: "invoiceRef", Message: "is required"})
+	}
+	if in.CurrencyCode != supplierPaymentCurrency {
+		fields = append(fields, fieldError{Field: "currencyCode", Message: "must be " + supplierPaymentCurrency})
+	}
+	amount, err := money.Parse(in.Amount, in.CurrencyCode)
+	if err != nil || !money.IsPositive(amount) {
+		fields = append(fields, fieldError{Field: "amount", Message: "must be a positive decimal number"})
+	}
+	if len(fields) > 0 {
+		renderJSONError(log, w, errors.New("invalid payment"), http.StatusBadRequest, fields...)
+		return
+	}
+
+	actor := requestActor(r)
+	p, created, err := fe.requestSupplierPayment(r.Context(), ledger.Payment{
+		SupplierID:     supplierID,
+		Amount:         amount,
+		InvoiceRef:     in.InvoiceRef,
+		IdempotencyKey: actor + ":" + key,
+		RequestedBy:    actor,
+	})
+	if err != nil {
+		renderJSONError(log, w, err, paymentErrorStatus(err))
+		return
+	}
+	if !created {
+		renderJSON(w, http.StatusOK, p)
+		return
+	}
+	fe.recordAudit(r, actor, "supplier.payment.requested", supplierID, nil, p)
+	if fe.approvals.needsApproval(amount) {
+		// The request expires like one made in Slack, releasing the invoice.
+		if _, _, err := fe.approvals.request(p.ID, supplierID, money.Format(amount), actor); err != nil {
+			fe.payments.Reject(p.ID, "approval request not recorded")
+			renderJSONError(log, w, err, http.StatusInternalServerError)
+			return
+		}
+		renderJSON(w, http.StatusAccepted, p)
+		return
+	}
+	fe.finishSupplierPayment(w, r, p.ID, autoApprover, http.StatusCreated)
+}
+
+// approveSupplierPaymentHandler lets a second finance principal approve a
+// payment above the threshold, which is then sent. The approval request
+// must still be pending: one that expired or was decided in Slack gets 409.
+func (fe *frontendServer) approveSupplierPaymentHandler(w http.ResponseWriter, r *http.Request) {
+	log := getLoggerWithTraceFields(r.Context())
+	p, err := fe.payments.Get(mux.Vars(r)["paymentId"])
+	if err != nil || p.SupplierID != mux.Vars(r)["id"] {
+		renderJSONError(log, w, ledger.ErrNotFound, http.StatusNotFound)
+		return
+	}
+	actor := requestActor(r)
+	if actor == p.RequestedBy {
+		renderJSONError(log, w, errSelfApproval, http.StatusForbidden)
+		return
+	}
+	req, changed, err := fe.approvals.decide(p.ID, actor, true)
+	switch {
+	case err == errSelfApproval:
+		renderJSONError(log, w, err, http.StatusForbidden)
+		return
+	case err == errPaymentNotFound:
+		renderJSONError(log, w, ledger.ErrInvalidTransition, http.StatusConflict)
+		return
+	case err != nil:
+		renderJSONError(log, w, err, http.StatusInternalServerError)
+		return
+	case !changed:
+		renderJSONError(log, w, errors.Errorf("the approval request is %s", req.Status), http.StatusConflict)
+		return
+	}
+	fe.recordAudit(r, actor, "supplier.payment.approved", p.SupplierID, nil, req)
+	p, err = fe.sendSupplierPayment(r.Context(), p.ID, actor)
+	sendErr := err
+	if sendErr == nil && p.Status == ledger.Failed {
+		sendErr = errors.New(p.Result)
+	}
+	if _, ferr := fe.approvals.finish(p.ID, p.Result, sendErr); ferr != nil {
+		log.WithField("error", ferr).Warn("could not record the outcome of an approved payment")
+	}
+	fe.renderSentPayment(w, r, p, err, http.StatusOK)
+}
+
+func (fe *frontendServer) finishSupplierPayment(w http.ResponseWriter, r *http.Request, id, approvedBy string, code int) {
+	p, err := fe.sendSupplierPayment(r.Context(), id, approvedBy)
+	fe.renderSentPayment(w, r, p, err, code)
+}
+
+// renderSentPayment renders the outcome of sendSupplierPayment, auditing
+// it. A payment that could not be sent gets 502 rather than code.
+func (fe *frontendServer) renderSentPayment(w http.ResponseWriter, r *http.Request, p ledger.Payment, err error, code int) {
+	if err != nil {
+		renderJSONError(getLoggerWithTraceFields(r.Context()), w, err, paymentErrorStatus(err))
+		return
+	}
+	fe.recordAudit(r, requestActor(r), "supplier.payment", p.SupplierID, nil, p)
+	if p.Status == ledger.Failed {
+		code = http.StatusBadGateway
+	}
+	renderJSON(w, code, p)
+}
+
+// supplierPaymentsHandler lists a supplier's payments, newest first.
+func (fe *frontendServer) supplierPaymentsHandler(w http.ResponseWriter, r *http.Request) {
+	renderJSON(w, http.StatusOK, struct {
+		Payments []ledger.Payment `json:"payments"`
+	}{fe.payments.Supplier(mux.Vars(r)["id"])})
+}
+
+// paymentErrorStatus maps ledger and supplierservice errors to HTTP
+// statuses.
+func paymentErrorStatus(err error) int {
+	var le *ledger.LimitError
+	switch {
+	case errors.As(err, &le):
+		return http.StatusUnprocessableEntity
+	case err == ledger.ErrIdempotencyConflict, err == ledger.ErrDuplicateInvoice, err == ledger.ErrInvalidTransition:
+		return http.StatusConflict
+	case err == ledger.ErrInvalidAmount:
+		return http.StatusBadRequest
+	case err == ledger.ErrNotFound:
+		return http.StatusNotFound
+	}
+	return upstream.StatusCode(err)
+}
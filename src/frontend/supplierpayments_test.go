@@ -0,0 +1,129 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/signalfx/microservices-demo/src/frontend/ledger"
+	"github.com/signalfx/microservices-demo/src/frontend/supplierservice"
+)
+
+// fakeSupplierService knows every supplier, each with a per-payment limit
+// of 6000 and a daily limit of 8000.
+func fakeSupplierService(t *testing.T) *supplierservice.Client {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.FormValue("supplier_id") + `,"Yeast in Peace",901234567,1098765432,6000.00,8000.00` + "\n"))
+	}))
+	t.Cleanup(s.Close)
+	return supplierservice.New(s.URL)
+}
+
+func TestSupplierPayments(t *testing.T) {
+	now := time.Now()
+	fe := testFrontendServer(t)
+	fe.suppliers = fakeSupplierService(t)
+	fe.approvals = testApprovals(&now)
+	var paid []string
+	fe.paySupplier = func(_ context.Context, id, amount string) (string, error) {
+		paid = append(paid, amount)
+		return "Payment processed for supplier: " + id + " of amount: $" + amount, nil
+	}
+
+	var pending string
+	tests := []struct {
+		name    string
+		actor   string
+		method  string
+		path    string
+		key     string
+		body    string
+		code    int
+		want    string
+		pending bool // remember the payment ID for later approval
+	}{
+		{"missing key and invoice", "ap", "POST", "/suppliers/s1/payments", "", `{"amount":"50"}`, 400, `"field":"Idempotency-Key"`, false},
+		{"bad amount", "ap", "POST", "/suppliers/s1/payments", "k0", `{"amount":"5O","invoiceRef":"INV-1"}`, 400, `"field":"amount"`, false},
+		{"small payment is sent", "ap", "POST", "/suppliers/s1/payments", "k1", `{"amount":"50","invoiceRef":"INV-1"}`, 201, `"status":"sent"`, false},
+		{"replay", "ap", "POST", "/suppliers/s1/payments", "k1", `{"amount":"50","invoiceRef":"INV-1"}`, 200, `"status":"sent"`, false},
+		{"same invoice", "ap", "POST", "/suppliers/s1/payments", "k2", `{"amount":"50","invoiceRef":"INV-1"}`, 409, "invoice has already been paid", false},
+		{"per-payment limit", "ap", "POST", "/suppliers/s1/payments", "k3", `{"amount":"6000.01","invoiceRef":"INV-2"}`, 422, "per-payment limit of USD 6000.00", false},
+		{"large payment waits", "ap", "POST", "/suppliers/s1/payments", "k4", `{"amount":"5000","invoiceRef":"INV-2"}`, 202, `"status":"requested"`, true},
+		{"daily limit counts pending payments", "ap", "POST", "/suppliers/s1/payments", "k5", `{"amount":"3000","invoiceRef":"INV-3"}`, 422, "daily limit", false},
+		{"requester cannot approve", "ap", "POST", "/suppliers/s1/payments/{pending}/approve", "", "", 403, "different approver", false},
+		{"second approver", "controller", "POST", "/suppliers/s1/payments/{pending}/approve", "", "", 200, `"approvedBy":"controller"`, false},
+		{"approving twice", "controller", "POST", "/suppliers/s1/payments/{pending}/approve", "", "", 409, "approval request is paid", false},
+		{"legacy endpoint", "ap", "POST", "/supplierpayment/s2/12.5?invoice=INV-9", "k6", "", 201, `"invoiceRef":"INV-9"`, false},
+		{"history", "viewer", "GET", "/suppliers/s1/payments", "", "", 200, `"invoiceRef":"INV-2"`, false},
+	}
+	r := mux.NewRouter()
+	r.HandleFunc("/suppliers/{id}/payments", fe.supplierPaymentsHandler).Methods(http.MethodGet)
+	r.HandleFunc("/suppliers/{id}/payments", fe.createSupplierPaymentHandler).Methods(http.MethodPost)
+	r.HandleFunc("/suppliers/{id}/payments/{paymentId}/approve", fe.approveSupplierPaymentHandler).Methods(http.MethodPost)
+	r.HandleFunc("/supplierpayment/{id}/{amount}", fe.supplierpaymentresponse).Methods(http.MethodPost)
+	do := func(actor, method, path, key, body string) *httptest.ResponseRecorder {
+		req := behaviorRequest(method, body, map[string]string{"Content-Type": "application/json"})
+		req.URL.Path, req.URL.RawQuery = strings.Replace(path, "{pending}", pending, 1), ""
+		if i := strings.IndexByte(req.URL.Path, '?'); i >= 0 {
+			req.URL.Path, req.URL.RawQuery = req.URL.Path[:i], req.URL.Path[i+1:]
+		}
+		if key != "" {
+			req.Header.Set(idempotencyKeyHeader, key)
+		}
+		req = req.WithContext(context.WithValue(req.Context(), ctxKeyPrincipal{}, &principal{ID: actor}))
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := do(tt.actor, tt.method, tt.path, tt.key, tt.body)
+			if w.Code != tt.code || !strings.Contains(w.Body.String(), tt.want) {
+				t.Fatalf("got %d %s, want %d containing %q", w.Code, w.Body, tt.code, tt.want)
+			}
+			if tt.pending {
+				var p ledger.Payment
+				json.Unmarshal(w.Body.Bytes(), &p)
+				pending = p.ID
+			}
+		})
+	}
+	if got := strings.Join(paid, ","); got != "50.00,5000.00,12.50" {
+		t.Fatalf("payments sent: %s", got)
+	}
+
+	// A payment left unapproved expires as it does in Slack.
+	fe.approvals.onExpire = fe.releaseExpiredPayment
+	w := do("ap", "POST", "/suppliers/s3/payments", "k7", `{"amount":"2000","invoiceRef":"INV-4"}`)
+	var p ledger.Payment
+	if json.Unmarshal(w.Body.Bytes(), &p); w.Code != http.StatusAccepted {
+		t.Fatalf("large payment: got %d %s", w.Code, w.Body)
+	}
+	now = now.Add(2 * time.Hour)
+	if w := do("controller", "POST", "/suppliers/s3/payments/"+p.ID+"/approve", "", ""); w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), "expired") {
+		t.Fatalf("approving an expired payment: got %d %s", w.Code, w.Body)
+	}
+	if p, _ = fe.payments.Get(p.ID); p.Status != ledger.Failed {
+		t.Fatalf("expired payment: %+v", p)
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package supplierservice is a client for the supplier service, which looks
+// up suppliers in suppliers.csv and pays them.
+package supplierservice
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/signalfx/microservices-demo/src/frontend/upstream"
+)
+
+// DefaultAddr is where the service runs in the Kubernetes manifests.
+const DefaultAddr = "supplierservice:5004"
+
+// Supplier is a row of suppliers.csv. The limits are decimal USD amounts
+// and are empty when the row does not set them.
+type Supplier struct {
+	ID            string `json:"supplierId"`
+	Name          string `json:"name"`
+	RoutingNumber string `json:"routingNumber"`
+	AccountNumber string `json:"accountNumber"`
+	PaymentLimit  string `json:"paymentLimit,omitempty"`
+	DailyLimit    string `json:"dailyLimit,omitempty"`
+}
+
+// Client calls the supplier service.
+type Client struct {
+	*upstream.Client
+}
+
+// New returns a client for the service at addr (host:port or a URL).
+func New(addr string) *Client {
+	return &Client{upstream.New(addr)}
+}
+
+// Lookup returns the supplier with the given ID. The service matches rows
+// by substring, so rows for other suppliers are reported as not found.
+func (c *Client) Lookup(ctx context.Context, id string) (Supplier, error) {
+	body, err := c.Get(ctx, "/supplier_lookup", url.Values{"supplier_id": {id}}, true)
+	if err != nil {
+		return Supplier{}, err
+	}
+	r := csv.NewReader(bytes.NewReader(body))
+	r.FieldsPerRecord = -1
+	row, err := r.Read()
+	if err != nil || len(row) < 4 {
+		return Supplier{}, &upstream.Error{StatusCode: http.StatusBadGateway, Message: "malformed supplier record"}
+	}
+	if row[0] != id {
+		return Supplier{}, &upstream.Error{StatusCode: http.StatusNotFound, Message: "supplier not found"}
+	}
+	s := Supplier{ID: row[0], Name: row[1], RoutingNumber: row[2], AccountNumber: row[3]}
+	if len(row) >= 6 {
+		s.PaymentLimit, s.DailyLimit = row[4], row[5]
+	}
+	return s, nil
+}
+
+// Pay asks the service to pay amount to the supplier and returns its
+// confirmation. Payments are never retried. The service answers 200 for
+// unknown suppliers, which is reported here as a 404.
+func (c *Client) Pay(ctx context.Context, supplierID, amount string) (string, error) {
+	body, err := c.Get(ctx, "/process_payments", url.Values{"supplier_id": {supplierID}, "amount": {amount}}, false)
+	if err != nil {
+		return "", err
+	}
+	msg := upstream.Message(body)
+	if strings.HasPrefix(msg, "could not find supplier") {
+		return "", &upstream.Error{StatusCode: http.StatusNotFound, Message: msg}
+	}
+	return msg, nil
+}
@@ -0,0 +1,74 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package supplierservice
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/signalfx/microservices-demo/src/frontend/upstream"
+)
+
+// fakeService mimics supplier_data.py, including its substring matching.
+func fakeService() *httptest.Server {
+	rows := []string{`535551674e444935,"Clippy's Tipsy Tavern",123456789,9876543210,5000.00,10000.00`}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/supplier_lookup", func(w http.ResponseWriter, r *http.Request) {
+		for _, row := range rows {
+			if strings.Contains(row, r.FormValue("supplier_id")) {
+				w.Write([]byte(row + "\n"))
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"message": "supplier not found"})
+	})
+	mux.HandleFunc("/process_payments", func(w http.ResponseWriter, r *http.Request) {
+		msg := "could not find supplier"
+		if id := r.FormValue("supplier_id"); strings.HasPrefix(rows[0], id+",") {
+			msg = "Payment processed for supplier: " + id + " of amount: $" + r.FormValue("amount")
+		}
+		json.NewEncoder(w).Encode(map[string]string{"message": msg})
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestClient(t *testing.T) {
+	s := fakeService()
+	defer s.Close()
+	c := New(s.URL)
+	ctx := context.Background()
+
+	got, err := c.Lookup(ctx, "535551674e444935")
+	if want := (Supplier{"535551674e444935", "Clippy's Tipsy Tavern", "123456789", "9876543210", "5000.00", "10000.00"}); err != nil || got != want {
+		t.Fatalf("Lookup = %+v, %v; want %+v", got, err, want)
+	}
+	for _, id := range []string{"5355", "nope"} {
+		if _, err := c.Lookup(ctx, id); upstream.StatusCode(err) != http.StatusNotFound {
+			t.Errorf("Lookup(%q): err = %v, want 404", id, err)
+		}
+	}
+
+	if msg, err := c.Pay(ctx, "535551674e444935", "12.50"); err != nil || !strings.Contains(msg, "$12.50") {
+		t.Fatalf("Pay = %q, %v", msg, err)
+	}
+	if _, err := c.Pay(ctx, "nope", "1"); upstream.StatusCode(err) != http.StatusNotFound {
+		t.Fatalf("Pay to unknown supplier: err = %v, want 404", err)
+	}
+}
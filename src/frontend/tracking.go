@@ -0,0 +1,130 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/signalfx/microservices-demo/src/frontend/genproto"
+)
+
+// shipmentStatusLabels are how the tracking page names shipment statuses.
+var shipmentStatusLabels = map[pb.ShipmentEvent_Status]string{
+	pb.ShipmentEvent_LABEL_CREATED:    "Label created",
+	pb.ShipmentEvent_PICKED_UP:        "Picked up",
+	pb.ShipmentEvent_IN_TRANSIT:       "In transit",
+	pb.ShipmentEvent_OUT_FOR_DELIVERY: "Out for delivery",
+	pb.ShipmentEvent_DELIVERED:        "Delivered",
+	pb.ShipmentEvent_EXCEPTION:        "Exception",
+}
+
+// shipmentProgress are the statuses shown as steps on the tracking page.
+var shipmentProgress = []pb.ShipmentEvent_Status{
+	pb.ShipmentEvent_LABEL_CREATED,
+	pb.ShipmentEvent_PICKED_UP,
+	pb.ShipmentEvent_IN_TRANSIT,
+	pb.ShipmentEvent_OUT_FOR_DELIVERY,
+	pb.ShipmentEvent_DELIVERED,
+}
+
+type shipmentEventView struct {
+	Status      string
+	Time        time.Time
+	Location    string
+	Description string
+}
+
+type shipmentStepView struct {
+	Label string
+	Done  bool
+}
+
+// shipmentSteps marks the progress steps the shipment has reached.
+func shipmentSteps(events []*pb.ShipmentEvent) []shipmentStepView {
+	reached := map[pb.ShipmentEvent_Status]bool{}
+	for _, e := range events {
+		reached[e.GetStatus()] = true
+	}
+	steps := make([]shipmentStepView, len(shipmentProgress))
+	for i, s := range shipmentProgress {
+		steps[i] = shipmentStepView{Label: shipmentStatusLabels[s], Done: reached[s]}
+	}
+	return steps
+}
+
+func (fe *frontendServer) trackHandler(w http.ResponseWriter, r *http.Request) {
+	log := getLoggerWithTraceFields(r.Context())
+	id := mux.Vars(r)["id"]
+	log.WithField("tracking_id", id).Debug("serving tracking page")
+
+	shipment, err := fe.trackShipment(r.Context(), id)
+	if c := status.Code(err); c == codes.NotFound || c == codes.InvalidArgument {
+		renderHTTPError(log, r, w, errors.Errorf("no shipment with tracking ID %q", id), http.StatusNotFound)
+		return
+	} else if err != nil {
+		renderHTTPError(log, r, w, errors.Wrap(err, "could not track shipment"), http.StatusInternalServerError)
+		return
+	}
+	// Newest first.
+	events := make([]shipmentEventView, len(shipment.GetEvents()))
+	for i, e := range shipment.GetEvents() {
+		events[len(events)-1-i] = shipmentEventView{
+			Status:      shipmentStatusLabels[e.GetStatus()],
+			Time:        time.Unix(e.GetTime(), 0).UTC(),
+			Location:    e.GetLocation(),
+			Description: e.GetDescription(),
+		}
+	}
+	currencies, err := fe.getCurrencies(r.Context())
+	if err != nil {
+		renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve currencies"), http.StatusInternalServerError)
+		return
+	}
+	cart, err := fe.getCart(r.Context(), sessionID(r))
+	if err != nil {
+		renderHTTPError(log, r, w, errors.Wrap(err, "could not retrieve cart"), http.StatusInternalServerError)
+		return
+	}
+
+	if err := templates.ExecuteTemplate(w, "track", map[string]interface{}{
+		"session_id":      sessionID(r),
+		"request_id":      r.Context().Value(ctxKeyRequestID{}),
+		"user_currency":   currentCurrency(r),
+		"currencies":      currencies,
+		"tracking_id":     shipment.GetTrackingId(),
+		"status":          shipmentStatusLabels[shipment.GetStatus()],
+		"exception":       shipment.GetStatus() == pb.ShipmentEvent_EXCEPTION,
+		"steps":           shipmentSteps(shipment.GetEvents()),
+		"events":          events,
+		"address":         shipment.GetAddress(),
+		"cart_size":       cartSize(cart),
+		"platform_css":    plat.css,
+		"platform_name":   plat.provider,
+		"rum_realm":       os.Getenv("RUM_REALM"),
+		"rum_auth":        os.Getenv("RUM_AUTH"),
+		"rum_app_name":    os.Getenv("RUM_APP_NAME"),
+		"rum_environment": os.Getenv("RUM_ENVIRONMENT"),
+		"rum_debug":       os.Getenv("RUM_DEBUG"),
+	}); err != nil {
+		log.Error(err)
+	}
+}
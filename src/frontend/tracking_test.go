@@ -0,0 +1,62 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestTrackHandler(t *testing.T) {
+	fe := testFrontendServer(t)
+	newFakeShop().serve(t, fe)
+
+	tests := []struct {
+		id   string
+		code int
+		want []string
+	}{
+		{"TRACK-1", 200, []string{
+			"Shipment TRACK-1", "<strong class=\"\">Picked up</strong>", "to Mountain View, CA",
+			`<li class="done">Label created</li>`, `<li class="">In transit</li>`,
+			"2023-11-14 23:13 UTC", "Frothly fulfillment center",
+		}},
+		{"TRACK-9", 404, []string{"no shipment with tracking ID &#34;TRACK-9&#34;"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			r := mux.SetURLVars(behaviorRequest(http.MethodGet, "", nil), map[string]string{"id": tt.id})
+			w := httptest.NewRecorder()
+			fe.trackHandler(w, r)
+			if w.Code != tt.code {
+				t.Fatalf("got %d %s, want %d", w.Code, w.Body, tt.code)
+			}
+			body := w.Body.String()
+			for _, want := range tt.want {
+				if !strings.Contains(body, want) {
+					t.Errorf("body does not contain %q:\n%s", want, body)
+				}
+			}
+			// Newest event first.
+			if tt.code == 200 && strings.Index(body, "Picked up by the carrier") > strings.Index(body, "Shipping label created") {
+				t.Error("events are not newest first")
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package upstream is the HTTP plumbing shared by the frontend's clients
+// for the plain-HTTP services (supplierservice and userlookup).
+package upstream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+const (
+	defaultTimeout = 5 * time.Second
+	defaultRetries = 2
+	defaultBackoff = 100 * time.Millisecond
+	maxBodySize    = 1 << 20
+)
+
+// Error is a non-2xx response from the service.
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// StatusCode returns the HTTP status an error from a Client should be
+// reported with: the service's own status, 504 for timeouts and 502 for
+// anything else that kept the service from answering.
+func StatusCode(err error) int {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.StatusCode
+	}
+	var t interface{ Timeout() bool }
+	if errors.Is(err, context.DeadlineExceeded) || errors.As(err, &t) && t.Timeout() {
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusBadGateway
+}
+
+// Client calls a service at BaseURL. Requests carry the trace context of
+// the ctx they are made with.
+type Client struct {
+	// BaseURL is the service's root, e.g. "http://userlookup:5003".
+	BaseURL string
+	// HTTPClient defaults to a client that propagates trace context.
+	HTTPClient *http.Client
+	// Timeout bounds each attempt; it defaults to 5s.
+	Timeout time.Duration
+	// Retries is how many times idempotent requests are retried after a
+	// transport error or a 502, 503 or 504. Zero means the default of 2;
+	// set it below zero to disable retries.
+	Retries int
+	// Backoff is the delay before the first retry, doubling each time; it
+	// defaults to 100ms.
+	Backoff time.Duration
+}
+
+// New returns a client for the service at addr, given either as a URL or
+// as a Kubernetes-style host:port.
+func New(addr string) *Client {
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	return &Client{BaseURL: strings.TrimRight(addr, "/")}
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return otelhttp.DefaultClient
+}
+
+func (c *Client) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+	return defaultTimeout
+}
+
+func (c *Client) retries() int {
+	switch {
+	case c.Retries < 0:
+		return 0
+	case c.Retries > 0:
+		return c.Retries
+	}
+	return defaultRetries
+}
+
+func (c *Client) backoff() time.Duration {
+	if c.Backoff > 0 {
+		return c.Backoff
+	}
+	return defaultBackoff
+}
+
+// Get requests path with the query parameters and returns the body of a
+// 2xx response. Only idempotent requests are retried.
+func (c *Client) Get(ctx context.Context, path string, query url.Values, idempotent bool) ([]byte, error) {
+	u := c.BaseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	attempts := 1
+	if idempotent {
+		attempts += c.retries()
+	}
+	delay := c.backoff()
+	var err error
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			t := time.NewTimer(delay)
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+				t.Stop()
+				return nil, err
+			}
+			delay *= 2
+		}
+		var body []byte
+		body, err = c.get(ctx, u)
+		if err == nil || !retryable(err) {
+			return body, err
+		}
+	}
+	return nil, err
+}
+
+func (c *Client) get(ctx context.Context, u string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout())
+	defer cancel()
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.httpClient().Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(http.MaxBytesReader(nil, resp.Body, maxBodySize))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		return nil, &Error{StatusCode: resp.StatusCode, Message: Message(body)}
+	}
+	return body, nil
+}
+
+func retryable(err error) bool {
+	e, ok := err.(*Error)
+	if !ok {
+		return true
+	}
+	switch e.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// Message extracts the message of a {"message": ...} body, as returned by
+// the Flask services, falling back to the trimmed body.
+func Message(body []byte) string {
+	var v struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body, &v) == nil && v.Message != "" {
+		return v.Message
+	}
+	return strings.TrimSpace(string(body))
+}
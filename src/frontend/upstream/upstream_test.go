@@ -0,0 +1,92 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package upstream
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGet(t *testing.T) {
+	tests := []struct {
+		name       string
+		statuses   []int // returned by successive calls; the last repeats
+		idempotent bool
+		retries    int
+		wantStatus int // 0 for success
+		wantCalls  int32
+	}{
+		{"success", []int{200}, true, 0, 0, 1},
+		{"retries unavailable", []int{503, 502, 200}, true, 0, 0, 3},
+		{"gives up after retries", []int{503}, true, 0, 503, 3},
+		{"does not retry payments", []int{503, 200}, false, 0, 503, 1},
+		{"does not retry not found", []int{404}, true, 0, 404, 1},
+		{"slow service times out", []int{-1}, true, 0, http.StatusGatewayTimeout, 3},
+		{"one retry", []int{503}, true, 1, 503, 2},
+		{"retries disabled", []int{503, 200}, true, -1, 503, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int32
+			s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := int(atomic.AddInt32(&calls, 1))
+				if r.URL.Query().Get("id") != "a&b c" {
+					t.Errorf("query %q was not encoded", r.URL.RawQuery)
+				}
+				status := tt.statuses[len(tt.statuses)-1]
+				if n <= len(tt.statuses) {
+					status = tt.statuses[n-1]
+				}
+				if status < 0 {
+					time.Sleep(50 * time.Millisecond)
+					status = 200
+				}
+				w.WriteHeader(status)
+				w.Write([]byte(`{"message":"hello"}`))
+			}))
+			defer s.Close()
+
+			c := New(s.URL)
+			c.Timeout, c.Backoff, c.Retries = 20*time.Millisecond, time.Millisecond, tt.retries
+			body, err := c.Get(context.Background(), "/lookup", url.Values{"id": {"a&b c"}}, tt.idempotent)
+			if tt.wantStatus == 0 {
+				if err != nil || Message(body) != "hello" {
+					t.Fatalf("Get = %q, %v", body, err)
+				}
+			} else if err == nil || StatusCode(err) != tt.wantStatus {
+				t.Fatalf("err = %v, want status %d", err, tt.wantStatus)
+			}
+			if n := atomic.LoadInt32(&calls); n != tt.wantCalls {
+				t.Fatalf("%d calls, want %d", n, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	for addr, want := range map[string]string{
+		"supplierservice:5004":       "http://supplierservice:5004",
+		"https://suppliers.example/": "https://suppliers.example",
+	} {
+		if got := New(addr).BaseURL; got != want {
+			t.Errorf("New(%q).BaseURL = %q, want %q", addr, got, want)
+		}
+	}
+}
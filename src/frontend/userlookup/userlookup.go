@@ -0,0 +1,65 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package userlookup is a client for the user lookup service.
+package userlookup
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/signalfx/microservices-demo/src/frontend/upstream"
+)
+
+// DefaultAddr is where the service runs in the Kubernetes manifests.
+const DefaultAddr = "userlookup:5003"
+
+// User is a record from the service's users.json. Only user_id is fixed;
+// the other attributes are passed through as they are.
+type User map[string]interface{}
+
+// ID returns the user_id attribute.
+func (u User) ID() string {
+	id, _ := u["user_id"].(string)
+	return id
+}
+
+// Client calls the user lookup service.
+type Client struct {
+	*upstream.Client
+}
+
+// New returns a client for the service at addr (host:port or a URL).
+func New(addr string) *Client {
+	return &Client{upstream.New(addr)}
+}
+
+// Lookup returns the user with the given ID. The service matches records
+// by prefix, so records for other users are reported as not found.
+func (c *Client) Lookup(ctx context.Context, id string) (User, error) {
+	body, err := c.Get(ctx, "/user_lookup", url.Values{"user_id": {id}}, true)
+	if err != nil {
+		return nil, err
+	}
+	var u User
+	if err := json.Unmarshal(body, &u); err != nil {
+		return nil, &upstream.Error{StatusCode: http.StatusBadGateway, Message: "malformed user record"}
+	}
+	if u.ID() != id {
+		return nil, &upstream.Error{StatusCode: http.StatusNotFound, Message: "user not found"}
+	}
+	return u, nil
+}
@@ -24,8 +24,8 @@ fi
 
 set -x
 
-# if one request to the frontend fails, then exit
-STATUSCODE=$(curl --silent --output /dev/stderr --write-out "%{http_code}" ${FRONTEND_ADDR})
+# if one request to the frontend fails, then exit (following the age gate)
+STATUSCODE=$(curl --silent --location --output /dev/stderr --write-out "%{http_code}" ${FRONTEND_ADDR})
 if test $STATUSCODE -ne 200; then
     echo "Error: Could not reach frontend - Status code: ${STATUSCODE}"
     exit 1
@@ -28,6 +28,11 @@ products = [
     '535551674e546731',
     '535551674e444935']
 
+def verifyAge(l):
+    l.client.post("/age-gate", {
+        'country': 'US',
+        'date_of_birth': '1990-04-01'})
+
 def index(l):
     l.client.get("/")
 
@@ -53,8 +58,9 @@ def checkout(l):
     addToCart(l)
     l.client.post("/cart/checkout", {
         'email': 'someone@example.com',
+        'date_of_birth': '1990-04-01',
         'street_address': '1600 Amphitheatre Parkway',
-        'zip_code': '94043',
+        'postal_code': '94043',
         'city': 'Mountain View',
         'state': 'CA',
         'country': 'United States',
@@ -67,6 +73,7 @@ def checkout(l):
 class UserBehavior(TaskSet):
 
     def on_start(self):
+        verifyAge(self)
         index(self)
 
     tasks = {index: 1,
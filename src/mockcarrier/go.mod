@@ -0,0 +1,3 @@
+module github.com/signalfx/microservices-demo/src/mockcarrier
+
+go 1.14
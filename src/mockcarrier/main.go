@@ -0,0 +1,499 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command mockcarrier serves the carrier API the shipping service's HTTP
+// adapter calls, for every carrier at once: each carrier's API is rooted at
+// /<carrier>/. Shipments are kept in memory and advance one stage every
+// step, posting a webhook each time. Latency and errors can be added, at
+// start from the environment or later through /_config.
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultPort = "8080"
+	defaultStep = time.Minute
+	// signatureHeader holds "sha256=" and the hex HMAC-SHA256 of a
+	// webhook's timestamp, a dot and its body.
+	signatureHeader = "X-Carrier-Signature"
+	// timestampHeader holds when a webhook was signed, in Unix seconds.
+	timestampHeader = "X-Carrier-Timestamp"
+	maxBodySize     = 1 << 20
+)
+
+// stages are the statuses shipments go through, in order.
+var stages = []string{"LABEL_CREATED", "PICKED_UP", "IN_TRANSIT", "OUT_FOR_DELIVERY", "DELIVERED"}
+
+func main() {
+	cfg, err := configFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	step := defaultStep
+	if v := os.Getenv("MOCK_CARRIER_STEP"); v != "" {
+		if step, err = time.ParseDuration(v); err != nil || step <= 0 {
+			log.Fatalf("invalid MOCK_CARRIER_STEP %q: must be a positive duration", v)
+		}
+	}
+	port := defaultPort
+	if v, ok := os.LookupEnv("PORT"); ok {
+		port = v
+	}
+
+	s := newServer(cfg, []byte(os.Getenv("CARRIER_WEBHOOK_SECRET")))
+	go func() {
+		for range time.Tick(step) {
+			s.advance()
+		}
+	}()
+	log.Printf("mock carrier listening on port %s, advancing shipments every %s", port, step)
+	log.Fatal(http.ListenAndServe(":"+port, s))
+}
+
+// config is the trouble the server adds to carrier API requests.
+type config struct {
+	// Latency is added to every request, plus up to Jitter more.
+	Latency time.Duration
+	Jitter  time.Duration
+	// ErrorRate is the share of requests, in [0, 1], answered with
+	// ErrorStatus instead.
+	ErrorRate   float64
+	ErrorStatus int
+}
+
+// configJSON is config as /_config reads and writes it, with durations
+// written as Go durations.
+type configJSON struct {
+	Latency     string  `json:"latency"`
+	Jitter      string  `json:"jitter"`
+	ErrorRate   float64 `json:"error_rate"`
+	ErrorStatus int     `json:"error_status"`
+}
+
+func (c config) json() configJSON {
+	return configJSON{Latency: c.Latency.String(), Jitter: c.Jitter.String(), ErrorRate: c.ErrorRate, ErrorStatus: c.ErrorStatus}
+}
+
+func (j configJSON) config() (config, error) {
+	c := config{ErrorRate: j.ErrorRate, ErrorStatus: j.ErrorStatus}
+	var err error
+	if c.Latency, err = parseDuration("latency", j.Latency); err != nil {
+		return config{}, err
+	}
+	if c.Jitter, err = parseDuration("jitter", j.Jitter); err != nil {
+		return config{}, err
+	}
+	if c.ErrorRate < 0 || c.ErrorRate > 1 {
+		return config{}, fmt.Errorf("error_rate must be between 0 and 1, got %v", c.ErrorRate)
+	}
+	if c.ErrorStatus == 0 {
+		c.ErrorStatus = http.StatusServiceUnavailable
+	}
+	if c.ErrorStatus < 400 || c.ErrorStatus > 599 {
+		return config{}, fmt.Errorf("error_status must be an HTTP error status, got %d", c.ErrorStatus)
+	}
+	return c, nil
+}
+
+func parseDuration(name, s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("%s must be a duration, got %q", name, s)
+	}
+	return d, nil
+}
+
+// configFromEnv reads MOCK_CARRIER_LATENCY, MOCK_CARRIER_JITTER,
+// MOCK_CARRIER_ERROR_RATE and MOCK_CARRIER_ERROR_STATUS.
+func configFromEnv() (config, error) {
+	j := configJSON{Latency: os.Getenv("MOCK_CARRIER_LATENCY"), Jitter: os.Getenv("MOCK_CARRIER_JITTER")}
+	var err error
+	if v := os.Getenv("MOCK_CARRIER_ERROR_RATE"); v != "" {
+		if j.ErrorRate, err = strconv.ParseFloat(v, 64); err != nil {
+			return config{}, fmt.Errorf("invalid MOCK_CARRIER_ERROR_RATE %q", v)
+		}
+	}
+	if v := os.Getenv("MOCK_CARRIER_ERROR_STATUS"); v != "" {
+		if j.ErrorStatus, err = strconv.Atoi(v); err != nil {
+			return config{}, fmt.Errorf("invalid MOCK_CARRIER_ERROR_STATUS %q", v)
+		}
+	}
+	return j.config()
+}
+
+type address struct {
+	StreetAddress string `json:"street_address"`
+	City          string `json:"city"`
+	State         string `json:"state,omitempty"`
+	Country       string `json:"country"`
+	PostalCode    string `json:"postal_code,omitempty"`
+}
+
+type parcel struct {
+	Grams int `json:"grams"`
+	Cm3   int `json:"cm3"`
+}
+
+// shipmentRequest is the body of rate and shipment requests.
+type shipmentRequest struct {
+	TrackingID     string   `json:"tracking_id"`
+	Service        string   `json:"service"`
+	Recipient      address  `json:"recipient"`
+	Parcels        []parcel `json:"parcels"`
+	AdultSignature bool     `json:"adult_signature"`
+	WebhookURL     string   `json:"webhook_url"`
+}
+
+type event struct {
+	Status      string    `json:"status"`
+	Time        time.Time `json:"time"`
+	Location    string    `json:"location,omitempty"`
+	Description string    `json:"description,omitempty"`
+}
+
+type shipment struct {
+	carrier string
+	shipmentRequest
+	events    []event
+	cancelled bool
+}
+
+// server is the API of every carrier.
+type server struct {
+	secret []byte
+	client *http.Client
+	now    func() time.Time
+	// webhooks tracks the webhooks being posted.
+	webhooks sync.WaitGroup
+
+	mu        sync.Mutex
+	cfg       config
+	rnd       *rand.Rand
+	shipments map[string]*shipment // by carrier and tracking ID
+}
+
+func newServer(cfg config, secret []byte) *server {
+	return &server{
+		secret:    secret,
+		client:    &http.Client{Timeout: 5 * time.Second},
+		now:       time.Now,
+		cfg:       cfg,
+		rnd:       rand.New(rand.NewSource(time.Now().UnixNano())),
+		shipments: map[string]*shipment{},
+	}
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/healthz":
+		w.Write([]byte("ok"))
+		return
+	case "/_config":
+		s.configHandler(w, r)
+		return
+	}
+	start := time.Now()
+	lw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+	s.carrierAPI(lw, r)
+	log.Printf("%s %s %d %s", r.Method, r.URL.Path, lw.status, time.Since(start).Round(time.Millisecond))
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (s *server) configHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var j configJSON
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize)).Decode(&j); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid config: "+err.Error())
+			return
+		}
+		cfg, err := j.config()
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		s.mu.Lock()
+		s.cfg = cfg
+		s.mu.Unlock()
+		log.Printf("config changed to %+v", cfg.json())
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	s.mu.Lock()
+	cfg := s.cfg
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, cfg.json())
+}
+
+// carrierAPI serves /<carrier>/rates, /<carrier>/shipments,
+// /<carrier>/shipments/<id> and /<carrier>/shipments/<id>/events, after
+// the configured latency, unless it fails the request.
+func (s *server) carrierAPI(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	cfg := s.cfg
+	delay := cfg.Latency
+	if cfg.Jitter > 0 {
+		delay += time.Duration(s.rnd.Int63n(int64(cfg.Jitter)))
+	}
+	fail := s.rnd.Float64() < cfg.ErrorRate
+	s.mu.Unlock()
+	select {
+	case <-time.After(delay):
+	case <-r.Context().Done():
+		return
+	}
+	if fail {
+		writeError(w, cfg.ErrorStatus, "injected failure")
+		return
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 2 && parts[1] == "rates" && r.Method == http.MethodPost:
+		s.rate(w, r)
+	case len(parts) == 2 && parts[1] == "shipments" && r.Method == http.MethodPost:
+		s.createShipment(w, r, parts[0])
+	case len(parts) == 3 && parts[1] == "shipments" && r.Method == http.MethodDelete:
+		s.cancel(w, parts[0], parts[2])
+	case len(parts) == 4 && parts[1] == "shipments" && parts[3] == "events" && r.Method == http.MethodGet:
+		s.track(w, parts[0], parts[2])
+	default:
+		writeError(w, http.StatusNotFound, "no such endpoint")
+	}
+}
+
+func decodeRequest(w http.ResponseWriter, r *http.Request) (shipmentRequest, bool) {
+	var req shipmentRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request: "+err.Error())
+		return req, false
+	}
+	if req.Recipient.Country == "" || req.Recipient.City == "" {
+		writeError(w, http.StatusBadRequest, "recipient needs a city and a country")
+		return req, false
+	}
+	if len(req.Parcels) == 0 {
+		writeError(w, http.StatusBadRequest, "no parcels")
+		return req, false
+	}
+	return req, true
+}
+
+// rate charges 4.00 and 1.10 a started kilogram for each parcel, 2.5 times
+// as much abroad, 1.8 times as much express and 3 times as much overnight,
+// plus 2.00 for an adult signature.
+func (s *server) rate(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeRequest(w, r)
+	if !ok {
+		return
+	}
+	cents := 0
+	for _, p := range req.Parcels {
+		cents += 400 + 110*((p.Grams+999)/1000)
+	}
+	if !strings.EqualFold(req.Recipient.Country, "US") {
+		cents = cents * 25 / 10
+	}
+	switch req.Service {
+	case "express":
+		cents = cents * 18 / 10
+	case "overnight":
+		cents *= 3
+	}
+	if req.AdultSignature {
+		cents += 200
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"amount": fmt.Sprintf("%d.%02d", cents/100, cents%100), "currency": "USD"})
+}
+
+func (s *server) createShipment(w http.ResponseWriter, r *http.Request, carrier string) {
+	req, ok := decodeRequest(w, r)
+	if !ok {
+		return
+	}
+	if req.TrackingID == "" {
+		writeError(w, http.StatusBadRequest, "tracking_id is required")
+		return
+	}
+	s.mu.Lock()
+	key := carrier + "/" + req.TrackingID
+	if _, ok := s.shipments[key]; ok {
+		s.mu.Unlock()
+		writeError(w, http.StatusConflict, "tracking ID "+req.TrackingID+" is taken")
+		return
+	}
+	sh := &shipment{carrier: carrier, shipmentRequest: req}
+	ev := sh.next(s.now())
+	s.shipments[key] = sh
+	s.mu.Unlock()
+	s.notify(sh, ev)
+	writeJSON(w, http.StatusCreated, map[string]string{"tracking_id": req.TrackingID, "status": ev.Status})
+}
+
+func (s *server) cancel(w http.ResponseWriter, carrier, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sh, ok := s.shipments[carrier+"/"+id]
+	switch {
+	case !ok || sh.cancelled:
+		writeError(w, http.StatusNotFound, "no shipment "+id)
+	case len(sh.events) > 1:
+		writeError(w, http.StatusConflict, "shipment "+id+" has been picked up")
+	default:
+		sh.cancelled = true
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *server) track(w http.ResponseWriter, carrier, id string) {
+	s.mu.Lock()
+	sh, ok := s.shipments[carrier+"/"+id]
+	var events []event
+	if ok {
+		events = append(events, sh.events...)
+	}
+	s.mu.Unlock()
+	if !ok || sh.cancelled {
+		writeError(w, http.StatusNotFound, "no shipment "+id)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"tracking_id": id, "events": events})
+}
+
+// next moves sh to its next stage and returns the event. The caller holds
+// the lock.
+func (sh *shipment) next(now time.Time) event {
+	ev := event{Status: stages[len(sh.events)], Time: now.UTC()}
+	switch ev.Status {
+	case "LABEL_CREATED":
+		ev.Description = "Shipment information received"
+	case "PICKED_UP":
+		ev.Location, ev.Description = sh.carrier+" depot", "Picked up"
+	case "IN_TRANSIT":
+		ev.Location, ev.Description = sh.carrier+" sorting hub", "Departed the sorting hub"
+	case "OUT_FOR_DELIVERY":
+		ev.Location, ev.Description = sh.Recipient.City, "Out for delivery"
+	case "DELIVERED":
+		ev.Location, ev.Description = sh.Recipient.City, "Delivered"
+		if sh.AdultSignature {
+			ev.Description = "Delivered, signed for by an adult"
+		}
+	}
+	sh.events = append(sh.events, ev)
+	return ev
+}
+
+// advance moves every shipment on the way one stage further.
+func (s *server) advance() {
+	type update struct {
+		sh *shipment
+		ev event
+	}
+	var updates []update
+	s.mu.Lock()
+	for _, sh := range s.shipments {
+		if !sh.cancelled && len(sh.events) < len(stages) {
+			updates = append(updates, update{sh, sh.next(s.now())})
+		}
+	}
+	s.mu.Unlock()
+	for _, u := range updates {
+		s.notify(u.sh, u.ev)
+	}
+}
+
+// notify posts ev to sh's webhook, if it has one, in the background.
+func (s *server) notify(sh *shipment, ev event) {
+	if sh.WebhookURL == "" {
+		return
+	}
+	body, err := json.Marshal(map[string]interface{}{"tracking_id": sh.TrackingID, "event": ev})
+	if err != nil {
+		log.Printf("failed to encode webhook: %v", err)
+		return
+	}
+	s.webhooks.Add(1)
+	go func() {
+		defer s.webhooks.Done()
+		req, err := http.NewRequest(http.MethodPost, sh.WebhookURL, bytes.NewReader(body))
+		if err != nil {
+			log.Printf("webhook %s: %v", sh.WebhookURL, err)
+			return
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if len(s.secret) > 0 {
+			stamp := strconv.FormatInt(time.Now().Unix(), 10)
+			req.Header.Set(timestampHeader, stamp)
+			req.Header.Set(signatureHeader, sign(s.secret, stamp, body))
+		}
+		resp, err := s.client.Do(req)
+		if err != nil {
+			log.Printf("webhook %s: %v", sh.WebhookURL, err)
+			return
+		}
+		resp.Body.Close()
+		if resp.StatusCode/100 != 2 {
+			log.Printf("webhook %s for %s: %s", sh.WebhookURL, sh.TrackingID, resp.Status)
+		}
+	}()
+}
+
+// sign returns the signature header of a webhook body sent with the
+// timestamp header stamp.
+func sign(secret []byte, stamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(stamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,176 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func call(t *testing.T, h http.Handler, method, path, body string) (int, map[string]interface{}) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+	var v map[string]interface{}
+	if w.Body.Len() > 0 {
+		if err := json.Unmarshal(w.Body.Bytes(), &v); err != nil {
+			t.Fatalf("%s %s: %v in %q", method, path, err, w.Body)
+		}
+	}
+	return w.Code, v
+}
+
+func TestRate(t *testing.T) {
+	s := newServer(config{}, nil)
+	tests := []struct {
+		body, want string
+	}{
+		{`{"service": "standard", "recipient": {"city": "Seattle", "country": "US"}, "parcels": [{"grams": 1500}]}`, "6.20"},
+		{`{"service": "standard", "recipient": {"city": "Seattle", "country": "US"}, "parcels": [{"grams": 500}, {"grams": 500}]}`, "10.20"},
+		{`{"service": "overnight", "recipient": {"city": "Seattle", "country": "US"}, "parcels": [{"grams": 500}], "adult_signature": true}`, "17.30"},
+		{`{"service": "express", "recipient": {"city": "Paris", "country": "FR"}, "parcels": [{"grams": 500}]}`, "22.95"},
+	}
+	for _, tt := range tests {
+		code, res := call(t, s, http.MethodPost, "/frothly-post/rates", tt.body)
+		if code != http.StatusOK || res["amount"] != tt.want || res["currency"] != "USD" {
+			t.Errorf("%s: got %d %v, want %s USD", tt.body, code, res, tt.want)
+		}
+	}
+	if code, _ := call(t, s, http.MethodPost, "/frothly-post/rates", `{"recipient": {"city": "Seattle", "country": "US"}}`); code != http.StatusBadRequest {
+		t.Errorf("no parcels: got %d, want 400", code)
+	}
+}
+
+func TestShipmentLifecycle(t *testing.T) {
+	secret := []byte("shh")
+	var (
+		mu       sync.Mutex
+		received []string
+	)
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(r.Header.Get(timestampHeader) + "."))
+		mac.Write(body)
+		if r.Header.Get(timestampHeader) == "" || r.Header.Get(signatureHeader) != "sha256="+hex.EncodeToString(mac.Sum(nil)) {
+			t.Errorf("webhook with signature %q", r.Header.Get(signatureHeader))
+		}
+		var update struct {
+			TrackingID string `json:"tracking_id"`
+			Event      event  `json:"event"`
+		}
+		json.Unmarshal(body, &update)
+		mu.Lock()
+		received = append(received, update.TrackingID+" "+update.Event.Status)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer hook.Close()
+
+	s := newServer(config{}, secret)
+	s.now = func() time.Time { return time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC) }
+	create := func(id string) int {
+		code, _ := call(t, s, http.MethodPost, "/frothly-post/shipments", `{"tracking_id": "`+id+`", "service": "standard",
+			"recipient": {"city": "Seattle", "country": "US"}, "parcels": [{"grams": 500}], "webhook_url": "`+hook.URL+`"}`)
+		return code
+	}
+	if code := create("A1"); code != http.StatusCreated {
+		t.Fatalf("create: got %d, want 201", code)
+	}
+	if code := create("A1"); code != http.StatusConflict {
+		t.Errorf("create twice: got %d, want 409", code)
+	}
+	if code := create("B2"); code != http.StatusCreated {
+		t.Fatalf("create: got %d, want 201", code)
+	}
+	// Tracking IDs are per carrier.
+	if code, _ := call(t, s, http.MethodGet, "/hopscotch-express/shipments/A1/events", ""); code != http.StatusNotFound {
+		t.Errorf("another carrier's shipment: got %d, want 404", code)
+	}
+
+	if code, _ := call(t, s, http.MethodDelete, "/frothly-post/shipments/B2", ""); code != http.StatusNoContent {
+		t.Errorf("cancel: got %d, want 204", code)
+	}
+	if code, _ := call(t, s, http.MethodGet, "/frothly-post/shipments/B2/events", ""); code != http.StatusNotFound {
+		t.Errorf("cancelled shipment: got %d, want 404", code)
+	}
+	for i := 0; i < 6; i++ {
+		s.advance()
+	}
+	if code, _ := call(t, s, http.MethodDelete, "/frothly-post/shipments/A1", ""); code != http.StatusConflict {
+		t.Errorf("cancel after pickup: got %d, want 409", code)
+	}
+
+	code, res := call(t, s, http.MethodGet, "/frothly-post/shipments/A1/events", "")
+	var statuses []string
+	for _, e := range res["events"].([]interface{}) {
+		statuses = append(statuses, e.(map[string]interface{})["status"].(string))
+	}
+	if code != http.StatusOK || !reflect.DeepEqual(statuses, stages) {
+		t.Errorf("track: got %d %v, want every stage", code, statuses)
+	}
+
+	s.webhooks.Wait()
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"A1 LABEL_CREATED", "B2 LABEL_CREATED", "A1 PICKED_UP", "A1 IN_TRANSIT", "A1 OUT_FOR_DELIVERY", "A1 DELIVERED"}
+	// Webhooks are posted concurrently.
+	sort.Strings(received)
+	sort.Strings(want)
+	if !reflect.DeepEqual(received, want) {
+		t.Errorf("webhooks: got %q, want %q", received, want)
+	}
+}
+
+func TestConfig(t *testing.T) {
+	s := newServer(config{}, nil)
+	code, res := call(t, s, http.MethodPut, "/_config", `{"latency": "20ms", "error_rate": 1, "error_status": 500}`)
+	if code != http.StatusOK || res["latency"] != "20ms" || res["error_status"] != 500.0 {
+		t.Fatalf("put config: got %d %v", code, res)
+	}
+	start := time.Now()
+	if code, res := call(t, s, http.MethodPost, "/frothly-post/rates", `{}`); code != http.StatusInternalServerError || res["error"] != "injected failure" {
+		t.Errorf("injected error: got %d %v, want 500", code, res)
+	}
+	if d := time.Since(start); d < 20*time.Millisecond {
+		t.Errorf("answered after %s, want 20ms of latency", d)
+	}
+	// Health checks are not delayed or failed.
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("healthz: got %d", w.Code)
+	}
+
+	for _, body := range []string{`{"latency": "soon"}`, `{"error_rate": 2}`, `{"error_status": 200}`} {
+		if code, _ := call(t, s, http.MethodPut, "/_config", body); code != http.StatusBadRequest {
+			t.Errorf("%s: got %d, want 400", body, code)
+		}
+	}
+	if code, res := call(t, s, http.MethodGet, "/_config", ""); code != http.StatusOK || res["error_rate"] != 1.0 {
+		t.Errorf("get config: got %d %v, want the config put", code, res)
+	}
+}
@@ -0,0 +1,400 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/signalfx/microservices-demo/src/shippingservice/genproto"
+)
+
+// maxSuggestions caps the addresses ValidateAddress suggests.
+const maxSuggestions = 3
+
+// country is what address validation knows about a country. Addresses in
+// countries not listed are accepted as written, only trimmed.
+type country struct {
+	code string // ISO 3166-1 alpha-2, what addresses are normalized to
+	// names are other ways customers write the country.
+	names []string
+	// postal matches postal codes written in upper case without spaces or
+	// hyphens; formatPostal puts them back the way the post writes them.
+	postal        *regexp.Regexp
+	formatPostal  func(string) string
+	postalExample string
+	// regions maps state or province codes to their names. Countries
+	// without regions do not check the state field.
+	regions map[string]string
+	// usStyle title-cases the street and city and abbreviates street types
+	// and directions in the street, as the US and Canadian posts do.
+	usStyle bool
+}
+
+var countries = []*country{
+	{code: "US", names: []string{"USA", "United States", "United States of America"},
+		postal: regexp.MustCompile(`^\d{5}(\d{4})?$`), formatPostal: splitAt(5, "-"), postalExample: "94107 or 94107-1234",
+		regions: usStates, usStyle: true},
+	{code: "CA", names: []string{"CAN", "Canada"},
+		postal: regexp.MustCompile(`^[ABCEGHJ-NPRSTVXY]\d[ABCEGHJ-NPRSTV-Z]\d[ABCEGHJ-NPRSTV-Z]\d$`), formatPostal: splitAt(3, " "), postalExample: "K1A 0B1",
+		regions: canadianProvinces, usStyle: true},
+	{code: "MX", names: []string{"MEX", "Mexico", "México"},
+		postal: regexp.MustCompile(`^\d{5}$`), postalExample: "06600"},
+	{code: "GB", names: []string{"GBR", "UK", "United Kingdom", "Great Britain", "England", "Scotland", "Wales", "Northern Ireland"},
+		postal: regexp.MustCompile(`^[A-Z]{1,2}\d[A-Z\d]?\d[A-Z]{2}$`), formatPostal: splitFromEnd(3, " "), postalExample: "SW1A 1AA"},
+	{code: "DE", names: []string{"DEU", "Germany", "Deutschland"},
+		postal: regexp.MustCompile(`^\d{5}$`), postalExample: "10115"},
+	{code: "FR", names: []string{"FRA", "France"},
+		postal: regexp.MustCompile(`^\d{5}$`), postalExample: "75001"},
+	{code: "NL", names: []string{"NLD", "Netherlands", "The Netherlands", "Holland"},
+		postal: regexp.MustCompile(`^[1-9]\d{3}[A-Z]{2}$`), formatPostal: splitAt(4, " "), postalExample: "1012 AB"},
+	{code: "AU", names: []string{"AUS", "Australia"},
+		postal: regexp.MustCompile(`^\d{4}$`), postalExample: "2000",
+		regions: australianStates},
+	{code: "JP", names: []string{"JPN", "Japan"},
+		postal: regexp.MustCompile(`^\d{7}$`), formatPostal: splitAt(3, "-"), postalExample: "100-0001"},
+}
+
+var usStates = map[string]string{
+	"AL": "Alabama", "AK": "Alaska", "AZ": "Arizona", "AR": "Arkansas", "CA": "California",
+	"CO": "Colorado", "CT": "Connecticut", "DE": "Delaware", "DC": "District of Columbia", "FL": "Florida",
+	"GA": "Georgia", "HI": "Hawaii", "ID": "Idaho", "IL": "Illinois", "IN": "Indiana",
+	"IA": "Iowa", "KS": "Kansas", "KY": "Kentucky", "LA": "Louisiana", "ME": "Maine",
+	"MD": "Maryland", "MA": "Massachusetts", "MI": "Michigan", "MN": "Minnesota", "MS": "Mississippi",
+	"MO": "Missouri", "MT": "Montana", "NE": "Nebraska", "NV": "Nevada", "NH": "New Hampshire",
+	"NJ": "New Jersey", "NM": "New Mexico", "NY": "New York", "NC": "North Carolina", "ND": "North Dakota",
+	"OH": "Ohio", "OK": "Oklahoma", "OR": "Oregon", "PA": "Pennsylvania", "RI": "Rhode Island",
+	"SC": "South Carolina", "SD": "South Dakota", "TN": "Tennessee", "TX": "Texas", "UT": "Utah",
+	"VT": "Vermont", "VA": "Virginia", "WA": "Washington", "WV": "West Virginia", "WI": "Wisconsin",
+	"WY": "Wyoming", "AS": "American Samoa", "GU": "Guam", "MP": "Northern Mariana Islands",
+	"PR": "Puerto Rico", "VI": "U.S. Virgin Islands",
+	"AA": "Armed Forces Americas", "AE": "Armed Forces Europe", "AP": "Armed Forces Pacific",
+}
+
+var canadianProvinces = map[string]string{
+	"AB": "Alberta", "BC": "British Columbia", "MB": "Manitoba", "NB": "New Brunswick",
+	"NL": "Newfoundland and Labrador", "NS": "Nova Scotia", "NT": "Northwest Territories",
+	"NU": "Nunavut", "ON": "Ontario", "PE": "Prince Edward Island", "QC": "Quebec",
+	"SK": "Saskatchewan", "YT": "Yukon",
+}
+
+var australianStates = map[string]string{
+	"ACT": "Australian Capital Territory", "NSW": "New South Wales", "NT": "Northern Territory",
+	"QLD": "Queensland", "SA": "South Australia", "TAS": "Tasmania", "VIC": "Victoria",
+	"WA": "Western Australia",
+}
+
+// streetAbbreviations are the USPS abbreviations of street types, unit
+// designators and directions.
+var streetAbbreviations = map[string]string{
+	"ALLEY": "Aly", "AVENUE": "Ave", "BOULEVARD": "Blvd", "CIRCLE": "Cir", "COURT": "Ct",
+	"DRIVE": "Dr", "EXPRESSWAY": "Expy", "FREEWAY": "Fwy", "HIGHWAY": "Hwy", "LANE": "Ln",
+	"PARKWAY": "Pkwy", "PLACE": "Pl", "PLAZA": "Plz", "ROAD": "Rd", "SQUARE": "Sq",
+	"STREET": "St", "TERRACE": "Ter", "TRAIL": "Trl", "WAY": "Way",
+	"APARTMENT": "Apt", "BUILDING": "Bldg", "FLOOR": "Fl", "ROOM": "Rm", "SUITE": "Ste", "UNIT": "Unit",
+	"NORTH": "N", "SOUTH": "S", "EAST": "E", "WEST": "W",
+	"NORTHEAST": "NE", "NORTHWEST": "NW", "SOUTHEAST": "SE", "SOUTHWEST": "SW",
+}
+
+func splitAt(i int, sep string) func(string) string {
+	return func(s string) string {
+		if len(s) <= i {
+			return s
+		}
+		return s[:i] + sep + s[i:]
+	}
+}
+
+func splitFromEnd(i int, sep string) func(string) string {
+	return func(s string) string { return splitAt(len(s)-i, sep)(s) }
+}
+
+// findCountry returns the country named s by its code or any of its names.
+func findCountry(s string) (*country, bool) {
+	for _, c := range countries {
+		if strings.EqualFold(c.code, s) {
+			return c, true
+		}
+		for _, name := range c.names {
+			if strings.EqualFold(name, s) {
+				return c, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// postalCode returns addr's postal code, falling back to the deprecated
+// zip_code. US ZIP codes lost their leading zeros as numbers, so they are
+// padded back to five digits.
+func postalCode(addr *pb.Address) string {
+	if addr.GetPostalCode() != "" || addr.GetZipCode() == 0 {
+		return addr.GetPostalCode()
+	}
+	if c, ok := findCountry(collapseSpaces(addr.GetCountry())); ok && c.code == "US" {
+		return fmt.Sprintf("%05d", addr.GetZipCode())
+	}
+	return strconv.Itoa(int(addr.GetZipCode()))
+}
+
+// fieldError is what is wrong with one Address field, by its proto name.
+type fieldError struct {
+	field, message string
+}
+
+// normalizeAddress returns addr normalized, and what is wrong with it if
+// anything.
+func normalizeAddress(addr *pb.Address) (*pb.Address, []fieldError) {
+	out := &pb.Address{
+		StreetAddress: collapseSpaces(addr.GetStreetAddress()),
+		City:          collapseSpaces(addr.GetCity()),
+		State:         collapseSpaces(addr.GetState()),
+		Country:       collapseSpaces(addr.GetCountry()),
+		PostalCode:    collapseSpaces(postalCode(addr)),
+	}
+	var errs []fieldError
+	if out.StreetAddress == "" {
+		errs = append(errs, fieldError{"street_address", "street address is required"})
+	}
+	if out.City == "" {
+		errs = append(errs, fieldError{"city", "city is required"})
+	}
+	if out.Country == "" {
+		errs = append(errs, fieldError{"country", "country is required"})
+	}
+	c, ok := findCountry(out.Country)
+	if !ok {
+		// Countries without postal rules are still written as their ISO
+		// code, so that restrictions and zones listing codes match them.
+		if code, ok := isoCountryCode(out.Country); ok {
+			out.Country = code
+		} else if out.Country != "" {
+			errs = append(errs, fieldError{"country", fmt.Sprintf("%q is not a country", out.Country)})
+		}
+		return out, errs
+	}
+	out.Country = c.code
+	if c.usStyle {
+		out.StreetAddress = abbreviateStreet(titleCase(out.StreetAddress))
+		out.City = titleCase(out.City)
+	}
+
+	if c.regions != nil {
+		if code, ok := findRegion(c, out.State); ok {
+			out.State = code
+		} else if out.State == "" {
+			errs = append(errs, fieldError{"state", "state is required"})
+		} else {
+			errs = append(errs, fieldError{"state", fmt.Sprintf("%q is not a state of %s", out.State, c.code)})
+		}
+	}
+
+	postal := compactPostalCode(out.PostalCode)
+	switch {
+	case postal == "":
+		errs = append(errs, fieldError{"postal_code", "postal code is required"})
+	case !c.postal.MatchString(postal):
+		errs = append(errs, fieldError{"postal_code", fmt.Sprintf("%q is not a valid postal code for %s: it should look like %s", out.PostalCode, c.code, c.postalExample)})
+	default:
+		if c.formatPostal != nil {
+			postal = c.formatPostal(postal)
+		}
+		out.PostalCode = postal
+		// For services that still read zip_code: US ZIP+4 codes give their
+		// five-digit ZIP.
+		zip := postal
+		if c.code == "US" {
+			zip = postal[:5]
+		}
+		if n, err := strconv.ParseInt(zip, 10, 32); err == nil {
+			out.ZipCode = int32(n)
+		}
+	}
+	return out, errs
+}
+
+func findRegion(c *country, s string) (string, bool) {
+	for code, name := range c.regions {
+		if strings.EqualFold(code, s) || strings.EqualFold(name, s) {
+			return code, true
+		}
+	}
+	return "", false
+}
+
+func compactPostalCode(s string) string {
+	return strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(s))
+}
+
+func collapseSpaces(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// titleCase capitalizes the words of s written all in lower or all in
+// upper case, leaving ones like "McAllister" and "4B" alone.
+func titleCase(s string) string {
+	words := strings.Fields(s)
+	for i, w := range words {
+		if strings.IndexFunc(w, unicode.IsLetter) < 0 || strings.IndexFunc(w, unicode.IsDigit) >= 0 {
+			continue
+		}
+		if w == strings.ToLower(w) || w == strings.ToUpper(w) {
+			r := []rune(strings.ToLower(w))
+			r[0] = unicode.ToUpper(r[0])
+			words[i] = string(r)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// abbreviateStreet abbreviates the words of s, and writes abbreviations
+// already there the same way.
+func abbreviateStreet(s string) string {
+	words := strings.Fields(s)
+	for i, w := range words {
+		w = strings.ToUpper(strings.TrimSuffix(w, "."))
+		for word, abbr := range streetAbbreviations {
+			if w == word || w == strings.ToUpper(abbr) {
+				words[i] = abbr
+			}
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// suggestAddresses returns valid addresses close to addr: US ZIP codes
+// missing their leading zero, and states and provinces off by a typo or
+// two. It returns none for addresses it cannot fix.
+func suggestAddresses(addr *pb.Address, errs []fieldError) []*pb.Address {
+	c, ok := findCountry(addr.GetCountry())
+	if !ok {
+		return nil
+	}
+	states, postals := []string{addr.GetState()}, []string{postalCode(addr)}
+	for _, e := range errs {
+		switch e.field {
+		case "state":
+			states = nearRegions(c, addr.GetState())
+		case "postal_code":
+			postal := compactPostalCode(postalCode(addr))
+			if c.code != "US" || len(postal) != 4 {
+				return nil
+			}
+			postals = []string{"0" + postal}
+		default:
+			return nil
+		}
+	}
+	var out []*pb.Address
+	for _, state := range states {
+		for _, postal := range postals {
+			candidate := &pb.Address{StreetAddress: addr.GetStreetAddress(), City: addr.GetCity(), State: state, Country: addr.GetCountry(), PostalCode: postal}
+			if fixed, errs := normalizeAddress(candidate); len(errs) == 0 && len(out) < maxSuggestions {
+				out = append(out, fixed)
+			}
+		}
+	}
+	return out
+}
+
+// nearRegions returns the codes of c's regions whose code or name is at
+// most two edits from s, closest first.
+func nearRegions(c *country, s string) []string {
+	s = strings.ToUpper(collapseSpaces(s))
+	if s == "" {
+		return nil
+	}
+	distance := map[string]int{}
+	for code, name := range c.regions {
+		d := editDistance(s, strings.ToUpper(name))
+		// Codes are short enough for any two edits to reach most of them.
+		if cd := editDistance(s, code); cd <= 1 && cd < d {
+			d = cd
+		}
+		if d <= 2 {
+			distance[code] = d
+		}
+	}
+	codes := make([]string, 0, len(distance))
+	for code := range distance {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool {
+		if distance[codes[i]] != distance[codes[j]] {
+			return distance[codes[i]] < distance[codes[j]]
+		}
+		return codes[i] < codes[j]
+	})
+	return codes
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = prev[j-1] + cost
+			if prev[j]+1 < cur[j] {
+				cur[j] = prev[j] + 1
+			}
+			if cur[j-1]+1 < cur[j] {
+				cur[j] = cur[j-1] + 1
+			}
+		}
+		prev = cur
+	}
+	return prev[len(rb)]
+}
+
+// ValidateAddress checks that addresses in countries the service knows have
+// a postal code in the country's format and a valid state or province, and
+// normalizes them. Invalid addresses come with the errors by field and, when
+// it can tell, suggestions.
+func (s *server) ValidateAddress(ctx context.Context, in *pb.ValidateAddressRequest) (*pb.ValidateAddressResponse, error) {
+	log := logger.WithFields(getTraceLogFields(ctx))
+	log.Info("[ValidateAddress] received request")
+	defer log.Info("[ValidateAddress] completed request")
+
+	if in.GetAddress() == nil {
+		return nil, status.Error(codes.InvalidArgument, "address is required")
+	}
+	normalized, errs := normalizeAddress(in.GetAddress())
+	if len(errs) == 0 {
+		return &pb.ValidateAddressResponse{Valid: true, Normalized: normalized}, nil
+	}
+	res := &pb.ValidateAddressResponse{Suggestions: suggestAddresses(in.GetAddress(), errs)}
+	for _, e := range errs {
+		res.Errors = append(res.Errors, &pb.AddressFieldError{Field: e.field, Message: e.message})
+	}
+	return res, nil
+}
@@ -0,0 +1,129 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	pb "github.com/signalfx/microservices-demo/src/shippingservice/genproto"
+)
+
+func TestValidateAddress(t *testing.T) {
+	s := testServer(t)
+	format := func(a *pb.Address) string {
+		return fmt.Sprintf("%s | %s | %s | %s | %s", a.StreetAddress, a.City, a.State, a.PostalCode, a.Country)
+	}
+
+	tests := []struct {
+		name        string
+		in          *pb.Address
+		want        string // normalized, if valid
+		wantErrors  []string
+		suggestions []string
+	}{
+		{"US, normalized",
+			&pb.Address{StreetAddress: " 270  brannan street suite 4b ", City: "san francisco", State: "california", Country: "United States", PostalCode: "94107"},
+			"270 Brannan St Ste 4b | San Francisco | CA | 94107 | US", nil, nil},
+		{"US ZIP+4 and a mixed-case name",
+			&pb.Address{StreetAddress: "1 North McAllister Ave.", City: "Boston", State: "ma", Country: "usa", PostalCode: "021341234"},
+			"1 N McAllister Ave | Boston | MA | 02134-1234 | US", nil, nil},
+		{"US ZIP with its leading zero lost",
+			&pb.Address{StreetAddress: "1 Main St", City: "Boston", State: "MA", Country: "US", PostalCode: "2134"},
+			"", []string{`postal_code: "2134" is not a valid postal code for US: it should look like 94107 or 94107-1234`},
+			[]string{"1 Main St | Boston | MA | 02134 | US"}},
+		{"deprecated zip_code is padded",
+			&pb.Address{StreetAddress: "1 Main St", City: "Boston", State: "MA", Country: "US", ZipCode: 2134},
+			"1 Main St | Boston | MA | 02134 | US", nil, nil},
+		{"misspelled state",
+			&pb.Address{StreetAddress: "1 Main St", City: "Fresno", State: "Califronia", Country: "US", PostalCode: "93650"},
+			"", []string{`state: "Califronia" is not a state of US`},
+			[]string{"1 Main St | Fresno | CA | 93650 | US"}},
+		{"missing fields",
+			&pb.Address{Country: "US"},
+			"", []string{"street_address: street address is required", "city: city is required", "state: state is required", "postal_code: postal code is required"},
+			nil},
+		{"Canada",
+			&pb.Address{StreetAddress: "111 wellington street", City: "ottawa", State: "Ontario", Country: "canada", PostalCode: "k1a0a9"},
+			"111 Wellington St | Ottawa | ON | K1A 0A9 | CA", nil, nil},
+		{"Canadian postal code with a D",
+			&pb.Address{StreetAddress: "1 Main St", City: "Ottawa", State: "ON", Country: "CA", PostalCode: "D1A 0A9"},
+			"", []string{`postal_code: "D1A 0A9" is not a valid postal code for CA: it should look like K1A 0B1`}, nil},
+		{"UK, casing kept",
+			&pb.Address{StreetAddress: "10 Downing Street", City: "London", Country: "UK", PostalCode: "sw1a2aa"},
+			"10 Downing Street | London |  | SW1A 2AA | GB", nil, nil},
+		{"country without rules",
+			&pb.Address{StreetAddress: " 1 Rue X ", City: "Brussels", Country: "Belgium", PostalCode: "1000"},
+			"1 Rue X | Brussels |  | 1000 | BE", nil, nil},
+		{"country by its alpha-3 code",
+			&pb.Address{StreetAddress: "1 King Fahd Rd", City: "Riyadh", Country: "sau"},
+			"1 King Fahd Rd | Riyadh |  |  | SA", nil, nil},
+		{"unknown country",
+			&pb.Address{StreetAddress: "1 Main St", City: "Cair Paravel", Country: "Narnia"},
+			"", []string{`country: "Narnia" is not a country`}, nil},
+		{"no country",
+			&pb.Address{StreetAddress: "1 Main St", City: "Boston"},
+			"", []string{"country: country is required"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := s.ValidateAddress(context.Background(), &pb.ValidateAddressRequest{Address: tt.in})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Valid != (tt.want != "") {
+				t.Fatalf("valid = %v, want %v (errors %v)", res.Valid, tt.want != "", res.Errors)
+			}
+			if res.Valid && format(res.Normalized) != tt.want {
+				t.Errorf("normalized = %q, want %q", format(res.Normalized), tt.want)
+			}
+			var gotErrors, gotSuggestions []string
+			for _, e := range res.Errors {
+				gotErrors = append(gotErrors, e.Field+": "+e.Message)
+			}
+			for _, a := range res.Suggestions {
+				gotSuggestions = append(gotSuggestions, format(a))
+			}
+			if !reflect.DeepEqual(gotErrors, tt.wantErrors) {
+				t.Errorf("errors = %q, want %q", gotErrors, tt.wantErrors)
+			}
+			if !reflect.DeepEqual(gotSuggestions, tt.suggestions) {
+				t.Errorf("suggestions = %q, want %q", gotSuggestions, tt.suggestions)
+			}
+		})
+	}
+}
+
+func TestNormalizeAddressZipCode(t *testing.T) {
+	for _, tt := range []struct {
+		country, postal string
+		want            int32
+	}{
+		{"US", "94107", 94107},
+		{"US", "02134-1234", 2134},
+		{"US", "021341234", 2134},
+		{"DE", "10115", 10115},
+		{"CA", "K1A 0A9", 0},
+	} {
+		state := map[string]string{"US": "MA", "CA": "ON"}[tt.country]
+		out, errs := normalizeAddress(&pb.Address{StreetAddress: "1 Main St", City: "Boston", State: state, Country: tt.country, PostalCode: tt.postal})
+		if len(errs) > 0 || out.ZipCode != tt.want {
+			t.Errorf("%s %s: zip_code = %d (errors %v), want %d", tt.country, tt.postal, out.ZipCode, errs, tt.want)
+		}
+	}
+}
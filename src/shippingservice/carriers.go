@@ -0,0 +1,408 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/opentracing/opentracing-go"
+	"github.com/opentracing/opentracing-go/ext"
+	"golang.org/x/net/context"
+
+	pb "github.com/signalfx/microservices-demo/src/shippingservice/genproto"
+)
+
+const (
+	defaultCarrierTimeout = 5 * time.Second
+	maxCarrierBodySize    = 1 << 20
+	// carrierSignatureHeader holds "sha256=" and the hex HMAC-SHA256 of a
+	// webhook's timestamp, a dot and its body.
+	carrierSignatureHeader = "X-Carrier-Signature"
+	// carrierTimestampHeader holds when a webhook was signed, in Unix
+	// seconds.
+	carrierTimestampHeader = "X-Carrier-Timestamp"
+	// carrierWebhookMaxSkew is how far a webhook's timestamp may be from
+	// now. Within it, each signature is accepted once.
+	carrierWebhookMaxSkew = 5 * time.Minute
+)
+
+// Carrier is the API of a shipping company.
+type Carrier interface {
+	// Rate returns what the carrier charges the shop for the shipment.
+	Rate(ctx context.Context, sh CarrierShipment) (*pb.Money, error)
+	// CreateShipment books the shipment under its tracking ID.
+	CreateShipment(ctx context.Context, sh CarrierShipment) error
+	// Cancel cancels a shipment that has not been picked up yet.
+	Cancel(ctx context.Context, trackingID string) error
+	// Track returns the shipment's events, oldest first.
+	Track(ctx context.Context, trackingID string) ([]*pb.ShipmentEvent, error)
+}
+
+// CarrierShipment is a shipment as carriers see it.
+type CarrierShipment struct {
+	TrackingID string
+	// Service is the ID of the shipping method.
+	Service        string
+	Address        *pb.Address
+	Parcels        []parcel
+	AdultSignature bool
+}
+
+// carrierShipment returns sh, packed into parcels, as its carrier sees it.
+func (sh *shipment) carrierShipment(parcels []parcel) CarrierShipment {
+	return CarrierShipment{TrackingID: sh.TrackingID, Service: sh.ShippingOption, Address: sh.Address,
+		Parcels: parcels, AdultSignature: sh.AdultSignature}
+}
+
+// carrierError is a non-2xx response from a carrier.
+type carrierError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *carrierError) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// carrierConfig is a carrier's entry in the carriers file.
+type carrierConfig struct {
+	// Name is the carrier's name in the rate table.
+	Name string `json:"name"`
+	// URL is the root of the carrier's API.
+	URL string `json:"url"`
+	// WebhookURL is where the carrier posts status updates; empty for none.
+	WebhookURL string `json:"webhook_url"`
+	// Timeout bounds each request, as a Go duration; it defaults to 5s.
+	Timeout string `json:"timeout"`
+}
+
+// loadCarriers reads the carriers file at path and returns an HTTP adapter
+// for each carrier it lists, by name. Carriers of the rate table it does
+// not list are only simulated.
+func loadCarriers(path string, rates *rateTable) (map[string]Carrier, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read carriers: %w", err)
+	}
+	var file struct {
+		Carriers []carrierConfig `json:"carriers"`
+	}
+	if err := json.Unmarshal(b, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse carriers %s: %w", path, err)
+	}
+	carriers := map[string]Carrier{}
+	for _, c := range file.Carriers {
+		if _, ok := rates.carrier(c.Name); !ok {
+			return nil, fmt.Errorf("carriers %s: %q is not a carrier of the rate table", path, c.Name)
+		}
+		if _, ok := carriers[c.Name]; ok {
+			return nil, fmt.Errorf("carriers %s: %q is listed twice", path, c.Name)
+		}
+		if u, err := url.Parse(c.URL); err != nil || u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("carriers %s: %q: invalid url %q", path, c.Name, c.URL)
+		}
+		timeout := defaultCarrierTimeout
+		if c.Timeout != "" {
+			if timeout, err = time.ParseDuration(c.Timeout); err != nil || timeout <= 0 {
+				return nil, fmt.Errorf("carriers %s: %q: timeout must be a positive duration, got %q", path, c.Name, c.Timeout)
+			}
+		}
+		carriers[c.Name] = &httpCarrier{
+			name:       c.Name,
+			baseURL:    strings.TrimRight(c.URL, "/"),
+			webhookURL: c.WebhookURL,
+			client:     &http.Client{Timeout: timeout},
+		}
+	}
+	return carriers, nil
+}
+
+// httpCarrier calls a carrier's HTTP/JSON API, as described in the README.
+// Each call is a client span whose peer is the carrier, and carries the
+// trace context in its headers.
+type httpCarrier struct {
+	name       string
+	baseURL    string
+	webhookURL string
+	client     *http.Client
+}
+
+// carrierAddress is an address in carrier requests.
+type carrierAddress struct {
+	StreetAddress string `json:"street_address"`
+	City          string `json:"city"`
+	State         string `json:"state,omitempty"`
+	Country       string `json:"country"`
+	PostalCode    string `json:"postal_code,omitempty"`
+}
+
+type carrierParcel struct {
+	Grams int `json:"grams"`
+	Cm3   int `json:"cm3"`
+}
+
+// carrierShipmentRequest is the body of rate and shipment requests.
+type carrierShipmentRequest struct {
+	TrackingID     string          `json:"tracking_id,omitempty"`
+	Service        string          `json:"service"`
+	Recipient      carrierAddress  `json:"recipient"`
+	Parcels        []carrierParcel `json:"parcels"`
+	AdultSignature bool            `json:"adult_signature,omitempty"`
+	WebhookURL     string          `json:"webhook_url,omitempty"`
+}
+
+// carrierEvent is a status update, in responses and webhooks.
+type carrierEvent struct {
+	// Status is the name of a pb.ShipmentEvent_Status.
+	Status      string    `json:"status"`
+	Time        time.Time `json:"time"`
+	Location    string    `json:"location,omitempty"`
+	Description string    `json:"description,omitempty"`
+}
+
+func (e carrierEvent) proto() (*pb.ShipmentEvent, error) {
+	status, ok := pb.ShipmentEvent_Status_value[e.Status]
+	if !ok || status == 0 {
+		return nil, fmt.Errorf("unknown shipment status %q", e.Status)
+	}
+	return &pb.ShipmentEvent{Status: pb.ShipmentEvent_Status(status), Time: e.Time.Unix(),
+		Location: e.Location, Description: e.Description}, nil
+}
+
+func (c *httpCarrier) request(sh CarrierShipment) carrierShipmentRequest {
+	req := carrierShipmentRequest{
+		TrackingID: sh.TrackingID,
+		Service:    sh.Service,
+		Recipient: carrierAddress{
+			StreetAddress: sh.Address.GetStreetAddress(),
+			City:          sh.Address.GetCity(),
+			State:         sh.Address.GetState(),
+			Country:       sh.Address.GetCountry(),
+			PostalCode:    postalCode(sh.Address),
+		},
+		AdultSignature: sh.AdultSignature,
+	}
+	for _, p := range sh.Parcels {
+		req.Parcels = append(req.Parcels, carrierParcel{Grams: p.grams, Cm3: p.cm3})
+	}
+	return req
+}
+
+func (c *httpCarrier) Rate(ctx context.Context, sh CarrierShipment) (*pb.Money, error) {
+	req := c.request(sh)
+	req.TrackingID = ""
+	var res struct {
+		Amount   string `json:"amount"`
+		Currency string `json:"currency"`
+	}
+	if err := c.do(ctx, "rate", http.MethodPost, "/rates", req, &res); err != nil {
+		return nil, err
+	}
+	amount, err := parseNanos(res.Amount)
+	if err != nil || res.Currency != "USD" {
+		return nil, fmt.Errorf("carrier %s: invalid rate %s %s", c.name, res.Amount, res.Currency)
+	}
+	return amount.money(), nil
+}
+
+func (c *httpCarrier) CreateShipment(ctx context.Context, sh CarrierShipment) error {
+	req := c.request(sh)
+	req.WebhookURL = c.webhookURL
+	return c.do(ctx, "create_shipment", http.MethodPost, "/shipments", req, nil)
+}
+
+func (c *httpCarrier) Cancel(ctx context.Context, trackingID string) error {
+	return c.do(ctx, "cancel", http.MethodDelete, "/shipments/"+url.PathEscape(trackingID), nil, nil)
+}
+
+func (c *httpCarrier) Track(ctx context.Context, trackingID string) ([]*pb.ShipmentEvent, error) {
+	var res struct {
+		Events []carrierEvent `json:"events"`
+	}
+	if err := c.do(ctx, "track", http.MethodGet, "/shipments/"+url.PathEscape(trackingID)+"/events", nil, &res); err != nil {
+		return nil, err
+	}
+	events := make([]*pb.ShipmentEvent, 0, len(res.Events))
+	for _, e := range res.Events {
+		ev, err := e.proto()
+		if err != nil {
+			return nil, fmt.Errorf("carrier %s: %w", c.name, err)
+		}
+		events = append(events, ev)
+	}
+	return events, nil
+}
+
+// do sends in, if not nil, as JSON and decodes a 2xx response into out, if
+// not nil. Other responses are returned as a *carrierError.
+func (c *httpCarrier) do(ctx context.Context, op, method, path string, in, out interface{}) (err error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "carrier."+op)
+	defer func() {
+		if err != nil {
+			ext.Error.Set(span, true)
+			span.SetTag("error.msg", err.Error())
+		}
+		span.Finish()
+	}()
+	u := c.baseURL + path
+	ext.SpanKindRPCClient.Set(span)
+	ext.PeerService.Set(span, c.name)
+	ext.HTTPMethod.Set(span, method)
+	ext.HTTPUrl.Set(span, u)
+
+	var body bytes.Buffer
+	if in != nil {
+		if err := json.NewEncoder(&body).Encode(in); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, u, &body)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	span.Tracer().Inject(span.Context(), opentracing.HTTPHeaders, opentracing.HTTPHeadersCarrier(req.Header))
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("carrier %s: %w", c.name, err)
+	}
+	defer resp.Body.Close()
+	ext.HTTPStatusCode.Set(span, uint16(resp.StatusCode))
+	b, err := ioutil.ReadAll(http.MaxBytesReader(nil, resp.Body, maxCarrierBodySize))
+	if err != nil {
+		return fmt.Errorf("carrier %s: %w", c.name, err)
+	}
+	if resp.StatusCode/100 != 2 {
+		var e struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(b, &e) != nil || e.Error == "" {
+			e.Error = strings.TrimSpace(string(b))
+		}
+		return &carrierError{StatusCode: resp.StatusCode, Message: e.Error}
+	}
+	if out != nil {
+		if err := json.Unmarshal(b, out); err != nil {
+			return fmt.Errorf("carrier %s: invalid response: %w", c.name, err)
+		}
+	}
+	return nil
+}
+
+// signCarrierWebhook returns the signature header of a webhook body sent
+// with the timestamp header timestamp.
+func signCarrierWebhook(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// carrierWebhookHandler takes the status updates carriers post, as
+// {"tracking_id": ..., "event": {...}}, and keeps them with the shipment.
+// Updates must be signed with secret and timestamped within
+// carrierWebhookMaxSkew of now, and a signature is only accepted once, so
+// a captured update cannot be replayed.
+func (s *server) carrierWebhookHandler(secret []byte) http.HandlerFunc {
+	var (
+		mu   sync.Mutex
+		seen = map[string]time.Time{} // accepted signatures by timestamp
+	)
+	// fresh reports whether signature, signed at ts, was not accepted
+	// before, and remembers it.
+	fresh := func(signature string, ts time.Time) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		for sig, t := range seen {
+			if s.now().Sub(t) > carrierWebhookMaxSkew {
+				delete(seen, sig)
+			}
+		}
+		if _, ok := seen[signature]; ok {
+			return false
+		}
+		seen[signature] = ts
+		return true
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxCarrierBodySize))
+		if err != nil {
+			http.Error(w, "could not read the body", http.StatusBadRequest)
+			return
+		}
+		stamp := r.Header.Get(carrierTimestampHeader)
+		secs, err := strconv.ParseInt(stamp, 10, 64)
+		ts := time.Unix(secs, 0)
+		if err != nil || ts.Sub(s.now()) > carrierWebhookMaxSkew || s.now().Sub(ts) > carrierWebhookMaxSkew {
+			http.Error(w, "missing or stale "+carrierTimestampHeader, http.StatusUnauthorized)
+			return
+		}
+		signature := r.Header.Get(carrierSignatureHeader)
+		if len(secret) == 0 || !hmac.Equal([]byte(signature), []byte(signCarrierWebhook(secret, stamp, body))) {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+		if !fresh(signature, ts) {
+			http.Error(w, "update was already received", http.StatusUnauthorized)
+			return
+		}
+		var update struct {
+			TrackingID string       `json:"tracking_id"`
+			Event      carrierEvent `json:"event"`
+		}
+		if err := json.Unmarshal(body, &update); err != nil {
+			http.Error(w, "invalid update: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		ev, err := update.Event.proto()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !s.shipments.addCarrierEvent(update.TrackingID, ev) {
+			http.Error(w, "unknown tracking ID", http.StatusNotFound)
+			return
+		}
+		logger.WithField("tracking_id", update.TrackingID).WithField("status", ev.Status.String()).Info("carrier status update")
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// errCarrierUnavailable is returned when a carrier refuses or fails to book
+// a shipment.
+var errCarrierUnavailable = errors.New("carrier unavailable")
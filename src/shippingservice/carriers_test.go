@@ -0,0 +1,289 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/signalfx/microservices-demo/src/shippingservice/genproto"
+)
+
+func TestHTTPCarrier(t *testing.T) {
+	var got []string
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		got = append(got, r.Method+" "+r.URL.Path+" "+strings.TrimSpace(string(body)))
+		switch r.Method + " " + r.URL.Path {
+		case "POST /post/rates":
+			w.Write([]byte(`{"amount": "12.34", "currency": "USD"}`))
+		case "POST /post/shipments":
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"tracking_id": "CP000000015US", "status": "LABEL_CREATED"}`))
+		case "DELETE /post/shipments/CP000000015US":
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"error": "shipment CP000000015US has been picked up"}`))
+		case "GET /post/shipments/CP000000015US/events":
+			w.Write([]byte(`{"events": [{"status": "LABEL_CREATED", "time": "2024-03-01T09:00:00Z"},
+				{"status": "PICKED_UP", "time": "2024-03-01T10:00:00Z", "location": "Depot", "description": "Picked up"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("no such endpoint"))
+		}
+	}))
+	defer api.Close()
+
+	c := &httpCarrier{name: "Post", baseURL: api.URL + "/post", webhookURL: "http://shop/carrier-webhooks", client: api.Client()}
+	ctx := context.Background()
+	sh := CarrierShipment{
+		TrackingID: "CP000000015US",
+		Service:    "standard",
+		Address:    &pb.Address{StreetAddress: "1 Main St", City: "Seattle", State: "WA", Country: "US", ZipCode: 98101},
+		Parcels:    []parcel{{grams: 900, cm3: 2000}},
+	}
+	cost, err := c.Rate(ctx, sh)
+	if err != nil || cost.Units != 12 || cost.Nanos != 340000000 {
+		t.Errorf("Rate = %v, %v, want 12.34", cost, err)
+	}
+	if err := c.CreateShipment(ctx, sh); err != nil {
+		t.Errorf("CreateShipment: %v", err)
+	}
+	var cerr *carrierError
+	if err := c.Cancel(ctx, sh.TrackingID); !errors.As(err, &cerr) || cerr.StatusCode != http.StatusConflict || !strings.Contains(cerr.Message, "picked up") {
+		t.Errorf("Cancel = %v, want the carrier's 409", err)
+	}
+	events, err := c.Track(ctx, sh.TrackingID)
+	if err != nil || len(events) != 2 || events[1].Status != pb.ShipmentEvent_PICKED_UP || events[1].Location != "Depot" ||
+		events[1].Time != time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC).Unix() {
+		t.Errorf("Track = %v, %v", events, err)
+	}
+	if _, err := c.Track(ctx, "HS00000000000000"); !errors.As(err, &cerr) || cerr.StatusCode != http.StatusNotFound || cerr.Message != "no such endpoint" {
+		t.Errorf("Track unknown = %v, want a 404", err)
+	}
+
+	want := []string{
+		`POST /post/rates {"service":"standard","recipient":{"street_address":"1 Main St","city":"Seattle","state":"WA","country":"US","postal_code":"98101"},"parcels":[{"grams":900,"cm3":2000}]}`,
+		`POST /post/shipments {"tracking_id":"CP000000015US","service":"standard","recipient":{"street_address":"1 Main St","city":"Seattle","state":"WA","country":"US","postal_code":"98101"},"parcels":[{"grams":900,"cm3":2000}],"webhook_url":"http://shop/carrier-webhooks"}`,
+		`DELETE /post/shipments/CP000000015US `,
+		`GET /post/shipments/CP000000015US/events `,
+		`GET /post/shipments/HS00000000000000/events `,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("requests:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestLoadCarriers(t *testing.T) {
+	rates, err := loadRateTable(defaultRateTableFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	carriers, err := loadCarriers("carriers.json", rates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(carriers) != 2 || carriers["Frothly Post"].(*httpCarrier).client.Timeout != 3*time.Second {
+		t.Errorf("loaded %v", carriers)
+	}
+
+	dir, err := ioutil.TempDir("", "carriers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tests := []struct {
+		carriers, wantErr string
+	}{
+		{`[{"name": "Yodel", "url": "http://yodel"}]`, `"Yodel" is not a carrier of the rate table`},
+		{`[{"name": "Frothly Post", "url": "http://a"}, {"name": "Frothly Post", "url": "http://b"}]`, "listed twice"},
+		{`[{"name": "Frothly Post", "url": "mockcarrier:8080"}]`, "invalid url"},
+		{`[{"name": "Frothly Post", "url": "http://a", "timeout": "-1s"}]`, "timeout must be a positive duration"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(dir, "carriers.json")
+		if err := ioutil.WriteFile(path, []byte(`{"carriers": `+tt.carriers+`}`), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadCarriers(path, rates); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: got %v, want %q", tt.carriers, err, tt.wantErr)
+		}
+	}
+}
+
+// fakeCarrier records the shipments booked with it.
+type fakeCarrier struct {
+	mu        sync.Mutex
+	created   []CarrierShipment
+	cancelled []string
+	createErr error
+	trackErr  error
+}
+
+func (c *fakeCarrier) Rate(_ context.Context, sh CarrierShipment) (*pb.Money, error) {
+	return &pb.Money{CurrencyCode: "USD", Units: int64(len(sh.Parcels)) * 5}, nil
+}
+
+func (c *fakeCarrier) CreateShipment(_ context.Context, sh CarrierShipment) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.createErr != nil {
+		return c.createErr
+	}
+	c.created = append(c.created, sh)
+	return nil
+}
+
+func (c *fakeCarrier) Cancel(_ context.Context, trackingID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cancelled = append(c.cancelled, trackingID)
+	return nil
+}
+
+func (c *fakeCarrier) Track(_ context.Context, trackingID string) ([]*pb.ShipmentEvent, error) {
+	if c.trackErr != nil {
+		return nil, c.trackErr
+	}
+	return []*pb.ShipmentEvent{{Status: pb.ShipmentEvent_IN_TRANSIT, Location: "Carrier hub"}}, nil
+}
+
+func TestShipOrderWithCarrier(t *testing.T) {
+	s := testServer(t)
+	post := &fakeCarrier{}
+	s.carriers = map[string]Carrier{"Frothly Post": post}
+	ctx := context.Background()
+	// The rate table routes standard to Hawaii to Frothly Post.
+	req := &pb.ShipOrderRequest{
+		Address:          &pb.Address{StreetAddress: "1 Beach Rd", City: "Honolulu", State: "HI", Country: "US", PostalCode: "96815"},
+		Items:            []*pb.CartItem{{ProductId: "23", Quantity: 2}},
+		ShippingOptionId: "standard",
+	}
+	res, err := s.ShipOrder(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sh, _ := s.shipments.get(res.TrackingId)
+	if sh.Carrier != "Frothly Post" || nanosOf(sh.CarrierCost) != 5*nanosPerUnit {
+		t.Errorf("shipment by %q costing %v, want Frothly Post's rate", sh.Carrier, sh.CarrierCost)
+	}
+	if len(post.created) != 1 || post.created[0].TrackingID != res.TrackingId || post.created[0].Service != "standard" ||
+		!reflect.DeepEqual(post.created[0].Parcels, []parcel{{grams: 600, cm3: 2000}}) {
+		t.Errorf("booked %+v, want the shipment", post.created)
+	}
+
+	track, err := s.TrackShipment(ctx, &pb.TrackShipmentRequest{TrackingId: res.TrackingId})
+	if err != nil || track.Status != pb.ShipmentEvent_IN_TRANSIT || track.Events[0].Location != "Carrier hub" {
+		t.Errorf("TrackShipment = %v, %v, want the carrier's events", track, err)
+	}
+	// Without the carrier's API, the updates it posted are used, and
+	// without those, the simulator.
+	post.trackErr = errors.New("carrier down")
+	if track, err := s.TrackShipment(ctx, &pb.TrackShipmentRequest{TrackingId: res.TrackingId}); err != nil || track.Status != pb.ShipmentEvent_LABEL_CREATED {
+		t.Errorf("TrackShipment with the carrier down = %v, %v, want the simulator's", track, err)
+	}
+	s.shipments.addCarrierEvent(res.TrackingId, &pb.ShipmentEvent{Status: pb.ShipmentEvent_PICKED_UP})
+	if track, err := s.TrackShipment(ctx, &pb.TrackShipmentRequest{TrackingId: res.TrackingId}); err != nil || track.Status != pb.ShipmentEvent_PICKED_UP {
+		t.Errorf("TrackShipment with the carrier down = %v, %v, want the posted update", track, err)
+	}
+
+	post.createErr = &carrierError{StatusCode: http.StatusServiceUnavailable, Message: "injected failure"}
+	if _, err := s.ShipOrder(ctx, req); status.Code(err) != codes.Unavailable {
+		t.Errorf("ShipOrder with the carrier down: got %v, want Unavailable", err)
+	}
+	if len(s.shipments.byID) != 1 {
+		t.Errorf("recorded %d shipments, want only the booked one", len(s.shipments.byID))
+	}
+
+	// Shipments booked but not stored are cancelled.
+	post.createErr = nil
+	s.shipments.w = failingWriter{}
+	if _, err := s.ShipOrder(ctx, req); status.Code(err) != codes.Internal {
+		t.Errorf("ShipOrder with the store failing: got %v, want Internal", err)
+	}
+	if len(post.created) != 2 || !reflect.DeepEqual(post.cancelled, []string{post.created[1].TrackingID}) {
+		t.Errorf("cancelled %q, want the shipment that could not be stored", post.cancelled)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+func TestCarrierWebhook(t *testing.T) {
+	s := testServer(t)
+	sh := &shipment{TrackingID: "CP000000015US", Address: &pb.Address{City: "Seattle"}, CreatedAt: time.Now()}
+	if err := s.shipments.add(sh); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1700000000, 0)
+	s.now = func() time.Time { return now }
+	secret := []byte("shh")
+	h := s.carrierWebhookHandler(secret)
+	stamp := strconv.FormatInt(now.Unix(), 10)
+	stale := strconv.FormatInt(now.Add(-10*time.Minute).Unix(), 10)
+	post := func(body, timestamp, signature string) int {
+		r := httptest.NewRequest(http.MethodPost, "/carrier-webhooks", strings.NewReader(body))
+		r.Header.Set(carrierTimestampHeader, timestamp)
+		if signature != "" {
+			r.Header.Set(carrierSignatureHeader, signature)
+		}
+		w := httptest.NewRecorder()
+		h(w, r)
+		return w.Code
+	}
+	update := func(id, status string) string {
+		b, _ := json.Marshal(map[string]interface{}{"tracking_id": id,
+			"event": map[string]string{"status": status, "time": "2024-03-01T10:00:00Z", "location": "Depot"}})
+		return string(b)
+	}
+
+	tests := []struct {
+		name, body, timestamp, signature string
+		want                             int
+	}{
+		{"unsigned", update(sh.TrackingID, "PICKED_UP"), stamp, "", http.StatusUnauthorized},
+		{"wrong signature", update(sh.TrackingID, "PICKED_UP"), stamp, signCarrierWebhook([]byte("guess"), stamp, []byte(update(sh.TrackingID, "PICKED_UP"))), http.StatusUnauthorized},
+		{"stale", update(sh.TrackingID, "PICKED_UP"), stale, signCarrierWebhook(secret, stale, []byte(update(sh.TrackingID, "PICKED_UP"))), http.StatusUnauthorized},
+		{"timestamp not signed", update(sh.TrackingID, "PICKED_UP"), stamp, signCarrierWebhook(secret, stale, []byte(update(sh.TrackingID, "PICKED_UP"))), http.StatusUnauthorized},
+		{"unknown shipment", update("HS00000000000000", "PICKED_UP"), stamp, signCarrierWebhook(secret, stamp, []byte(update("HS00000000000000", "PICKED_UP"))), http.StatusNotFound},
+		{"unknown status", update(sh.TrackingID, "LOST"), stamp, signCarrierWebhook(secret, stamp, []byte(update(sh.TrackingID, "LOST"))), http.StatusBadRequest},
+		{"signed", update(sh.TrackingID, "PICKED_UP"), stamp, signCarrierWebhook(secret, stamp, []byte(update(sh.TrackingID, "PICKED_UP"))), http.StatusNoContent},
+		{"replayed", update(sh.TrackingID, "PICKED_UP"), stamp, signCarrierWebhook(secret, stamp, []byte(update(sh.TrackingID, "PICKED_UP"))), http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		if got := post(tt.body, tt.timestamp, tt.signature); got != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.name, got, tt.want)
+		}
+	}
+	events := s.shipments.carrierEventsOf(sh.TrackingID)
+	if len(events) != 1 || events[0].Status != pb.ShipmentEvent_PICKED_UP || events[0].Location != "Depot" {
+		t.Errorf("kept %v, want the signed update", events)
+	}
+}
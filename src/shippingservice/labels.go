@@ -0,0 +1,250 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/signalfx/microservices-demo/src/shippingservice/genproto"
+)
+
+// sender is who labels say shipments are from.
+type sender struct {
+	Name    string      `json:"name"`
+	Address *pb.Address `json:"address"`
+}
+
+// label is what shipping labels and packing slips print of a shipment.
+type label struct {
+	trackingID string
+	// from and to are address blocks, a line each.
+	from, to []string
+	// service is the shipping method and the carrier shipping it.
+	service        string
+	adultSignature bool
+	items          []labelItem
+	createdAt      time.Time
+}
+
+type labelItem struct {
+	productID, name string
+	quantity        int32
+}
+
+// addressLines returns the lines of the address block of addr, headed by
+// name.
+func addressLines(name string, addr *pb.Address) []string {
+	place := strings.TrimSpace(strings.TrimSpace(addr.GetCity()+", "+addr.GetState()) + " " + postalCode(addr))
+	var lines []string
+	for _, l := range []string{name, addr.GetStreetAddress(), strings.Trim(place, ", "), addr.GetCountry()} {
+		if l != "" {
+			lines = append(lines, l)
+		}
+	}
+	return lines
+}
+
+// label gathers what sh's label prints, looking its products' names up in
+// the catalog.
+func (s *server) label(ctx context.Context, sh *shipment) (label, error) {
+	products, err := s.lookupProducts(ctx, sh.Items)
+	if err != nil {
+		return label{}, err
+	}
+	l := label{
+		trackingID:     sh.TrackingID,
+		from:           addressLines(s.rates.Sender.Name, s.rates.Sender.Address),
+		to:             addressLines("", sh.Address),
+		service:        sh.ShippingOption,
+		adultSignature: sh.AdultSignature,
+		createdAt:      sh.CreatedAt,
+	}
+	// The method may have been removed from the rate table since.
+	m, ok := s.rates.method(sh.ShippingOption)
+	if ok {
+		l.service = m.Name
+	}
+	if sh.Carrier != "" {
+		l.service += " via " + sh.Carrier
+	} else if m.PickupLocation != "" {
+		l.service += " at " + m.PickupLocation
+	}
+	for _, item := range sh.Items {
+		name := products[item.GetProductId()].GetName()
+		if name == "" {
+			name = item.GetProductId()
+		}
+		l.items = append(l.items, labelItem{productID: item.GetProductId(), name: name, quantity: item.GetQuantity()})
+	}
+	return l, nil
+}
+
+// truncate cuts s to at most n characters, marking the cut with dots.
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-3]) + "..."
+}
+
+// zplField is a ZPL field holding s. Characters ZPL treats as commands are
+// written in hex.
+func zplField(s string) string {
+	return "^FH^FD" + strings.NewReplacer("_", "_5F", "^", "_5E", "~", "_7E").Replace(s) + "^FS"
+}
+
+// zplMaxItems is how many item lines fit on a shipping label.
+const zplMaxItems = 8
+
+// zpl returns l as a 4x6 inch label, 812 by 1218 dots at 203 dpi: the
+// sender, the recipient, the service, a Code 128 barcode of the tracking ID
+// and the items.
+func (l label) zpl() []byte {
+	var b strings.Builder
+	b.WriteString("^XA\n^CI28\n^PW812\n^LL1218\n")
+	field := func(x, y, size int, s string) {
+		fmt.Fprintf(&b, "^FO%d,%d^A0N,%d,%d%s\n", x, y, size, size, zplField(s))
+	}
+	rule := func(y int) { fmt.Fprintf(&b, "^FO40,%d^GB732,3,3^FS\n", y) }
+
+	field(40, 40, 24, "FROM")
+	for i, line := range l.from {
+		field(40, 70+i*30, 26, truncate(line, 48))
+	}
+	rule(220)
+	field(40, 240, 24, "SHIP TO")
+	for i, line := range l.to {
+		field(60, 275+i*46, 40, truncate(line, 32))
+	}
+	rule(480)
+	field(40, 500, 36, strings.ToUpper(truncate(l.service, 36)))
+	if l.adultSignature {
+		field(40, 545, 30, "ADULT SIGNATURE REQUIRED")
+	}
+	rule(595)
+	fmt.Fprintf(&b, "^FO60,620^BY3^BCN,160,Y,N,N%s\n", zplField(l.trackingID))
+	rule(850)
+	field(40, 870, 24, "CONTENTS")
+	for i, item := range l.items {
+		if i == zplMaxItems-1 && len(l.items) > zplMaxItems {
+			field(40, 905+i*32, 24, fmt.Sprintf("and %d more items", len(l.items)-i))
+			break
+		}
+		field(40, 905+i*32, 24, truncate(fmt.Sprintf("%d x %s", item.quantity, item.name), 56))
+	}
+	b.WriteString("^XZ\n")
+	return []byte(b.String())
+}
+
+// packingSlip returns l as a letter-size PDF, continued over more pages if
+// the items do not fit on one.
+func (l label) packingSlip() ([]byte, error) {
+	bars, err := code128(l.trackingID)
+	if err != nil {
+		return nil, err
+	}
+	var doc pdfDocument
+	p := doc.newPage()
+	p.text(50, 740, 20, true, "Packing slip")
+	p.text(50, 715, 11, false, "Tracking ID: "+l.trackingID)
+	p.text(50, 699, 11, false, "Date: "+l.createdAt.UTC().Format("2006-01-02"))
+	// The barcode is right-aligned, one point per module.
+	width := 0
+	for _, w := range bars {
+		width += w
+	}
+	x := float64(pdfPageWidth - 50 - width)
+	for i, w := range bars {
+		if i%2 == 0 {
+			p.rect(x, 695, float64(w), 45)
+		}
+		x += float64(w)
+	}
+
+	for col, block := range []struct {
+		title string
+		lines []string
+	}{{"From", l.from}, {"Ship to", l.to}} {
+		x := float64(50 + col*270)
+		p.text(x, 655, 11, true, block.title)
+		for i, line := range block.lines {
+			p.text(x, float64(639-i*14), 10, false, truncate(line, 50))
+		}
+	}
+	p.text(50, 570, 11, false, "Service: "+l.service)
+	if l.adultSignature {
+		p.text(50, 554, 11, true, "Adult signature required")
+	}
+
+	header := func(p *pdfPage, y float64) {
+		p.text(50, y, 10, true, "Qty")
+		p.text(100, y, 10, true, "Item")
+		p.text(440, y, 10, true, "Product ID")
+		p.rect(50, y-6, pdfPageWidth-100, 0.5)
+	}
+	y := 520.0
+	header(p, y)
+	for _, item := range l.items {
+		if y -= 18; y < 50 {
+			p = doc.newPage()
+			p.text(50, 740, 11, false, "Packing slip "+l.trackingID+", continued")
+			y = 710
+			header(p, y)
+			y -= 18
+		}
+		p.text(50, y, 10, false, fmt.Sprint(item.quantity))
+		p.text(100, y, 10, false, truncate(item.name, 60))
+		p.text(440, y, 10, false, item.productID)
+	}
+	return doc.bytes(), nil
+}
+
+// GetLabel returns the shipment's shipping label as ZPL or its packing slip
+// as PDF.
+func (s *server) GetLabel(ctx context.Context, in *pb.GetLabelRequest) (*pb.GetLabelResponse, error) {
+	log := logger.WithFields(getTraceLogFields(ctx))
+	log.Info("[GetLabel] received request")
+	defer log.Info("[GetLabel] completed request")
+
+	if in.GetTrackingId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "tracking ID is required")
+	}
+	if in.GetFormat() != pb.GetLabelRequest_ZPL && in.GetFormat() != pb.GetLabelRequest_PDF {
+		return nil, status.Errorf(codes.InvalidArgument, "unsupported label format %v", in.GetFormat())
+	}
+	sh, ok := s.shipments.get(in.GetTrackingId())
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "no shipment with tracking ID %q", in.GetTrackingId())
+	}
+	l, err := s.label(ctx, sh)
+	if err != nil {
+		return nil, err
+	}
+	if in.GetFormat() == pb.GetLabelRequest_ZPL {
+		return &pb.GetLabelResponse{Content: l.zpl(), ContentType: "application/x-zpl"}, nil
+	}
+	slip, err := l.packingSlip()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to write packing slip: %v", err)
+	}
+	return &pb.GetLabelResponse{Content: slip, ContentType: "application/pdf"}, nil
+}
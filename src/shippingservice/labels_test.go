@@ -0,0 +1,173 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/signalfx/microservices-demo/src/shippingservice/genproto"
+)
+
+func TestCode128(t *testing.T) {
+	seen := map[string]bool{}
+	for v, p := range code128Patterns {
+		sum := 0
+		for _, w := range p {
+			sum += int(w - '0')
+		}
+		if want := map[bool]int{true: 13, false: 11}[v == code128Stop]; sum != want || seen[p] {
+			t.Errorf("pattern %d %s: %d modules or a duplicate", v, p, sum)
+		}
+		seen[p] = true
+	}
+
+	// Start B, A (33), the check symbol (104+33)%103 = 34 and stop.
+	got, err := code128("A")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := fmt.Sprint(got); s != "[2 1 1 2 1 4 1 1 1 3 2 3 1 3 1 1 2 3 2 3 3 1 1 1 2]" {
+		t.Errorf(`code128("A") = %s`, s)
+	}
+	if _, err := code128("tab\t"); err == nil {
+		t.Error("encoded a tab")
+	}
+}
+
+func TestGetLabel(t *testing.T) {
+	s := testServer(t)
+	ctx := context.Background()
+	sh := &shipment{
+		TrackingID:     internalFormat{}.generate(func(n int) string { return strings.Repeat("4", n) }),
+		Address:        &pb.Address{StreetAddress: "1 Main St", City: "Portland", State: "OR", PostalCode: "97201", Country: "US"},
+		Items:          []*pb.CartItem{{ProductId: "ale", Quantity: 2}, {ProductId: "23", Quantity: 1}},
+		ShippingOption: "standard",
+		Carrier:        "Hopscotch Express",
+		AdultSignature: true,
+		CreatedAt:      time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC),
+	}
+	if err := s.shipments.add(sh); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := s.GetLabel(ctx, &pb.GetLabelRequest{TrackingId: sh.TrackingID, Format: pb.GetLabelRequest_ZPL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	zpl := string(res.Content)
+	if res.ContentType != "application/x-zpl" || !strings.HasPrefix(zpl, "^XA\n") || !strings.HasSuffix(zpl, "^XZ\n") {
+		t.Errorf("got %s label:\n%s", res.ContentType, zpl)
+	}
+	for _, want := range []string{
+		"^FH^FDFrothly Supply^FS", "^FH^FD3415 Airport Way S^FS", "^FH^FDSeattle, WA 98134^FS",
+		"^FH^FD1 Main St^FS", "^FH^FDPortland, OR 97201^FS",
+		"^FH^FDSTANDARD VIA HOPSCOTCH EXPRESS^FS", "^FH^FDADULT SIGNATURE REQUIRED^FS",
+		"^BCN,160,Y,N,N^FH^FD" + sh.TrackingID + "^FS",
+		"^FH^FD2 x Pale Ale^FS", "^FH^FD1 x 23^FS",
+	} {
+		if !strings.Contains(zpl, want) {
+			t.Errorf("label does not contain %q:\n%s", want, zpl)
+		}
+	}
+
+	res, err = s.GetLabel(ctx, &pb.GetLabelRequest{TrackingId: sh.TrackingID, Format: pb.GetLabelRequest_PDF})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.ContentType != "application/pdf" {
+		t.Errorf("content type = %s", res.ContentType)
+	}
+	checkPDF(t, res.Content, 1)
+	for _, want := range []string{"(Tracking ID: " + sh.TrackingID + ") Tj", "(Ship to) Tj", "(Portland, OR 97201) Tj",
+		"(Service: Standard via Hopscotch Express) Tj", "(Adult signature required) Tj", "(Pale Ale) Tj", "(2) Tj"} {
+		if !bytes.Contains(res.Content, []byte(want)) {
+			t.Errorf("packing slip does not contain %q:\n%s", want, res.Content)
+		}
+	}
+
+	for _, tt := range []struct {
+		req  *pb.GetLabelRequest
+		want codes.Code
+	}{
+		{&pb.GetLabelRequest{Format: pb.GetLabelRequest_ZPL}, codes.InvalidArgument},
+		{&pb.GetLabelRequest{TrackingId: sh.TrackingID}, codes.InvalidArgument},
+		{&pb.GetLabelRequest{TrackingId: "HS00000000000000", Format: pb.GetLabelRequest_PDF}, codes.NotFound},
+	} {
+		if _, err := s.GetLabel(ctx, tt.req); status.Code(err) != tt.want {
+			t.Errorf("GetLabel(%v): got %v, want %v", tt.req, err, tt.want)
+		}
+	}
+}
+
+func TestLabelLayout(t *testing.T) {
+	l := label{trackingID: "HS4444444444444X", from: []string{"A"}, to: []string{"B"}, service: "Pickup at the ^brewery~"}
+	for i := 0; i < 80; i++ {
+		l.items = append(l.items, labelItem{productID: "p", name: "(Special) Ale ü€", quantity: 1})
+	}
+
+	zpl := string(l.zpl())
+	if !strings.Contains(zpl, "^FH^FDPICKUP AT THE _5EBREWERY_7E^FS") {
+		t.Errorf("commands in text are not escaped:\n%s", zpl)
+	}
+	if n := strings.Count(zpl, "1 x (Special) Ale"); n != zplMaxItems-1 || !strings.Contains(zpl, "and 73 more items") {
+		t.Errorf("got %d item lines:\n%s", n, zpl)
+	}
+
+	slip, err := l.packingSlip()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkPDF(t, slip, 3)
+	if !bytes.Contains(slip, []byte(`(\(Special\) Ale \374?) Tj`)) {
+		t.Errorf("item names are not escaped:\n%s", slip)
+	}
+}
+
+// checkPDF checks that the cross-reference table of the PDF in b points at
+// its objects and that it has pages pages.
+func checkPDF(t *testing.T, b []byte, pages int) {
+	t.Helper()
+	if !bytes.HasPrefix(b, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(b, []byte("%%EOF\n")) {
+		t.Fatalf("not a PDF:\n%s", b)
+	}
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(b)
+	if m == nil {
+		t.Fatal("no startxref")
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	if !bytes.HasPrefix(b[xref:], []byte("xref\n")) {
+		t.Fatalf("startxref %d does not point at the xref table", xref)
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n \n`).FindAllSubmatch(b[xref:], -1)
+	for i, e := range entries {
+		off, _ := strconv.Atoi(string(e[1]))
+		if want := fmt.Sprintf("%d 0 obj\n", i+1); !bytes.HasPrefix(b[off:], []byte(want)) {
+			t.Errorf("object %d is not at offset %d", i+1, off)
+		}
+	}
+	if want := fmt.Sprintf("/Count %d >>", pages); !bytes.Contains(b, []byte(want)) {
+		t.Errorf("want %d pages:\n%s", pages, b)
+	}
+}
@@ -15,8 +15,10 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"net"
+	"net/http"
 	"os"
 	"time"
 
@@ -37,7 +39,9 @@ import (
 )
 
 const (
-	defaultPort = "50051"
+	defaultPort                 = "50051"
+	defaultRateTableFile        = "rates.json"
+	defaultRestrictionRulesFile = "restrictions.json"
 )
 
 var logger *logrus.Logger
@@ -92,7 +96,64 @@ func main() {
 		logger.Info("Stats disabled.")
 		srv = grpc.NewServer()
 	}
-	svc := &server{}
+	shipments, err := openShipmentStore(os.Getenv("SHIPMENT_STORE_FILE"))
+	if err != nil {
+		logger.Fatal(err)
+	}
+	sim, err := newSimulatorFromEnv()
+	if err != nil {
+		logger.Fatal(err)
+	}
+	ratesPath := defaultRateTableFile
+	if value, ok := os.LookupEnv("RATE_TABLE_FILE"); ok {
+		ratesPath = value
+	}
+	rates, err := loadRateTable(ratesPath)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	restrictionsPath := defaultRestrictionRulesFile
+	if value, ok := os.LookupEnv("RESTRICTION_RULES_FILE"); ok {
+		restrictionsPath = value
+	}
+	restrictions, err := loadRestrictionRules(restrictionsPath)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	catalogAddr := os.Getenv("PRODUCT_CATALOG_SERVICE_ADDR")
+	if catalogAddr == "" {
+		logger.Fatal("environment variable PRODUCT_CATALOG_SERVICE_ADDR not set")
+	}
+	dialOpts := []grpc.DialOption{grpc.WithInsecure()}
+	if os.Getenv("DISABLE_STATS") == "" {
+		dialOpts = append(dialOpts, grpc.WithStatsHandler(grpctrace.NewClientStatsHandler(grpctrace.WithServiceName("shippingservice"))))
+	}
+	catalogConn, err := grpc.Dial(catalogAddr, dialOpts...)
+	if err != nil {
+		logger.Fatalf("failed to connect to product catalog service: %v", err)
+	}
+	defer catalogConn.Close()
+	trackingIDs, err := newTrackingIDGenerator()
+	if err != nil {
+		logger.Fatal(err)
+	}
+	carriers := map[string]Carrier{}
+	if path := os.Getenv("CARRIERS_FILE"); path != "" {
+		if carriers, err = loadCarriers(path, rates); err != nil {
+			logger.Fatal(err)
+		}
+	}
+	svc := newServer(shipments, trackingIDs, sim, rates, restrictions, carriers, pb.NewProductCatalogServiceClient(catalogConn))
+	if port, secret := os.Getenv("CARRIER_WEBHOOK_PORT"), os.Getenv("CARRIER_WEBHOOK_SECRET"); port != "" && secret == "" {
+		logger.Error("CARRIER_WEBHOOK_SECRET is not set: refusing to take unsigned carrier webhooks, so carrier status updates are not received")
+	} else if port != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/carrier-webhooks", svc.carrierWebhookHandler([]byte(secret)))
+		go func() {
+			logger.Infof("carrier webhooks listening on port %s", port)
+			logger.Fatal(http.ListenAndServe(":"+port, mux))
+		}()
+	}
 	pb.RegisterShippingServiceServer(srv, svc)
 	healthpb.RegisterHealthServer(srv, svc)
 	logger.Infof("Shipping Service listening on port %s", port)
@@ -105,7 +166,22 @@ func main() {
 }
 
 // server controls RPC service responses.
-type server struct{}
+type server struct {
+	shipments    *shipmentStore
+	trackingIDs  *trackingIDGenerator
+	sim          simulator
+	rates        *rateTable
+	restrictions *restrictionRules
+	// carriers are the carriers with an API, by name.
+	carriers map[string]Carrier
+	catalog  pb.ProductCatalogServiceClient
+	now      func() time.Time
+}
+
+func newServer(shipments *shipmentStore, trackingIDs *trackingIDGenerator, sim simulator, rates *rateTable, restrictions *restrictionRules, carriers map[string]Carrier, catalog pb.ProductCatalogServiceClient) *server {
+	return &server{shipments: shipments, trackingIDs: trackingIDs, sim: sim, rates: rates, restrictions: restrictions,
+		carriers: carriers, catalog: catalog, now: time.Now}
+}
 
 // Check is for health checking.
 func (s *server) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
@@ -116,44 +192,128 @@ func (s *server) Watch(req *healthpb.HealthCheckRequest, ws healthpb.Health_Watc
 	return status.Errorf(codes.Unimplemented, "health check via Watch not implemented")
 }
 
-// GetQuote produces a shipping quote (cost) in USD.
+// GetQuote produces shipping quotes (costs) in USD from the rate table,
+// one per shipping method offered for the address.
 func (s *server) GetQuote(ctx context.Context, in *pb.GetQuoteRequest) (*pb.GetQuoteResponse, error) {
 	log := logger.WithFields(getTraceLogFields(ctx))
 	log.Info("[GetQuote] received request")
 	defer log.Info("[GetQuote] completed request")
 
-	// 1. Our quote system requires the total number of items to be shipped.
-	count := 0
-	for _, item := range in.Items {
-		count += int(item.Quantity)
+	// 1. Our quote system requires the weight and volume of every item,
+	// and restrictions their categories and attributes.
+	products, err := s.lookupProducts(ctx, in.Items)
+	if err != nil {
+		return nil, err
 	}
 
-	// 2. Generate a quote based on the total number of items to be shipped.
-	quote := CreateQuoteFromCount(count)
+	// 2. Refuse items that may not go to the address.
+	restrictions := s.restrictions.check(in.Address, in.Items, products)
+	if err := deniedError(restrictions, products); err != nil {
+		return nil, err
+	}
 
-	// 3. Generate a response.
-	return &pb.GetQuoteResponse{
-		CostUsd: &pb.Money{
-			CurrencyCode: "USD",
-			Units:        int64(quote.Dollars),
-			Nanos:        int32(quote.Cents * 10000000)},
-	}, nil
+	// 3. Price them with every method offered in the address's zone.
+	items, err := s.packedItems(in.Items, products)
+	if err != nil {
+		return nil, err
+	}
+	options, err := s.rates.options(in.Address, items, s.now())
+	if err != nil {
+		return nil, quoteError(err)
+	}
+	if fee, ok := s.restrictions.signatureFee(restrictions); ok {
+		for _, o := range options {
+			o.CostUsd = (nanosOf(o.CostUsd) + fee).money()
+			o.AdultSignature = true
+		}
+	}
+	log.WithField("options", len(options)).Debug("quoted shipping")
 
+	// 4. Generate a response.
+	return &pb.GetQuoteResponse{CostUsd: options[0].CostUsd, Options: options}, nil
 }
 
-// ShipOrder mocks that the requested items will be shipped.
-// It supplies a tracking ID for notional lookup of shipment delivery status.
+// quoteError maps errors of the rate table to gRPC statuses.
+func quoteError(err error) error {
+	switch err.(type) {
+	case errTooLarge:
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	switch err {
+	case errNoZone, errNoMethod:
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	return status.Errorf(codes.Internal, "failed to quote shipping: %v", err)
+}
+
+// ShipOrder books the shipment with its carrier, if the carrier has an API,
+// and otherwise mocks that the requested items will be shipped. It supplies
+// a tracking ID for looking up the shipment with TrackShipment.
 func (s *server) ShipOrder(ctx context.Context, in *pb.ShipOrderRequest) (*pb.ShipOrderResponse, error) {
 	log := logger.WithFields(getTraceLogFields(ctx))
 	log.Info("[ShipOrder] received request")
 	defer log.Info("[ShipOrder] completed request")
-	// 1. Create a Tracking ID
-	baseAddress := fmt.Sprintf("%s, %s, %s", in.Address.StreetAddress, in.Address.City, in.Address.State)
-	id := CreateTrackingId(baseAddress)
+	// 1. Check the shipping method.
+	optionID := in.ShippingOptionId
+	if optionID == "" {
+		optionID = s.rates.Methods[0].ID
+	} else if _, ok := s.rates.method(optionID); !ok {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown shipping option %q", optionID)
+	}
+
+	// 2. Refuse items that may not go to the address.
+	products, err := s.lookupProducts(ctx, in.Items)
+	if err != nil {
+		return nil, err
+	}
+	restrictions := s.restrictions.check(in.Address, in.Items, products)
+	if err := deniedError(restrictions, products); err != nil {
+		return nil, err
+	}
+	_, signature := s.restrictions.signatureFee(restrictions)
+
+	// 3. Find the carrier of the method, whose format the tracking ID is in.
+	// Pickups, and methods no longer offered for the address, get one of
+	// the shop's own.
+	now := s.now()
+	sh := &shipment{Address: in.Address, Items: in.Items, ShippingOption: optionID,
+		AdultSignature: signature, CreatedAt: now}
+	format := trackingFormat(internalFormat{})
+	var parcels []parcel
+	items, err := s.packedItems(in.Items, products)
+	if err != nil {
+		return nil, err
+	}
+	if options, err := s.rates.options(in.Address, items, now); err == nil {
+		for _, o := range options {
+			if c, ok := s.rates.carrier(o.Carrier); ok && o.Id == optionID {
+				sh.Carrier, format = c.Name, c.Tracking.trackingFormat()
+				parcels, _ = s.rates.parcels(c, items)
+			}
+		}
+	}
+
+	// 4. Ask the carrier what it charges, if it has an API.
+	if c, ok := s.carriers[sh.Carrier]; ok {
+		if cost, err := c.Rate(ctx, sh.carrierShipment(parcels)); err != nil {
+			log.Warnf("failed to rate shipment with %s: %v", sh.Carrier, err)
+		} else {
+			sh.CarrierCost = cost
+		}
+	}
+
+	// 5. Book and record the shipment under a new tracking ID, so it can be
+	// tracked.
+	if err := s.addShipment(ctx, sh, format, parcels); errors.Is(err, errCarrierUnavailable) {
+		return nil, status.Errorf(codes.Unavailable, "failed to book shipment: %v", err)
+	} else if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to record shipment: %v", err)
+	}
+	log.WithField("tracking_id", sh.TrackingID).WithField("carrier", sh.Carrier).Debug("created shipment")
 
-	// 2. Generate a response.
+	// 6. Generate a response.
 	return &pb.ShipOrderResponse{
-		TrackingId: id,
+		TrackingId: sh.TrackingID,
 	}, nil
 }
 
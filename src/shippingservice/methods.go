@@ -0,0 +1,302 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	pb "github.com/signalfx/microservices-demo/src/shippingservice/genproto"
+)
+
+const dateLayout = "2006-01-02"
+
+// method is a shipping method customers can choose, such as express.
+type method struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Carriers that may ship the method; the cheapest is used unless a
+	// route picks one. Methods without carriers cost FlatRate and are
+	// collected at PickupLocation.
+	Carriers       []string `json:"carriers"`
+	FlatRate       nanos    `json:"flat_rate"`
+	PickupLocation string   `json:"pickup_location"`
+	// RatePercent scales the carrier's price: 100 charges it as is. It has
+	// at most two decimals.
+	RatePercent nanos `json:"rate_percent"`
+	// Zones the method is offered in; empty for every zone.
+	Zones []string `json:"zones"`
+	// Orders placed after Cutoff, in the table's time zone, ship on the
+	// next shipping day.
+	Cutoff timeOfDay `json:"cutoff"`
+	// TransitDays are the fewest and most business days, on the carrier's
+	// calendar, between shipping and delivery.
+	TransitDays [2]int `json:"transit_days"`
+}
+
+// route sends orders with one of Methods to a destination with Carrier.
+type route struct {
+	Methods []string `json:"methods"`
+	destination
+	Carrier string `json:"carrier"`
+}
+
+// routedCarrier returns the carrier the first route matching m and addr
+// names, if any.
+func (t *rateTable) routedCarrier(m method, addr *pb.Address) (carrier, bool) {
+	for _, r := range t.Routes {
+		if matchesFold(r.Methods, m.ID) && r.matches(addr) {
+			return t.carrier(r.Carrier)
+		}
+	}
+	return carrier{}, false
+}
+
+// timeOfDay is written as HH:MM.
+type timeOfDay struct{ hour, minute int }
+
+func (t *timeOfDay) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	parsed, err := time.Parse("15:04", s)
+	if err != nil {
+		return fmt.Errorf("invalid time of day %q", s)
+	}
+	t.hour, t.minute = parsed.Hour(), parsed.Minute()
+	return nil
+}
+
+// calendar says which days are business days. In rate tables it is written
+// as {"workdays": ["Monday", ...], "holidays": ["2024-12-25", ...]}.
+type calendar struct {
+	workdays map[time.Weekday]bool
+	holidays map[string]bool
+}
+
+func (c *calendar) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Workdays []string `json:"workdays"`
+		Holidays []string `json:"holidays"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	c.workdays, c.holidays = map[time.Weekday]bool{}, map[string]bool{}
+	for _, name := range raw.Workdays {
+		day, ok := weekdays[name]
+		if !ok {
+			return fmt.Errorf("invalid weekday %q", name)
+		}
+		c.workdays[day] = true
+	}
+	for _, d := range raw.Holidays {
+		if _, err := time.Parse(dateLayout, d); err != nil {
+			return fmt.Errorf("invalid holiday %q: want YYYY-MM-DD", d)
+		}
+		c.holidays[d] = true
+	}
+	return nil
+}
+
+var weekdays = map[string]time.Weekday{}
+
+func init() {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		weekdays[d.String()] = d
+	}
+}
+
+func (c calendar) isBusinessDay(day time.Time) bool {
+	return c.workdays[day.Weekday()] && !c.holidays[day.Format(dateLayout)]
+}
+
+// nextBusinessDay returns the first business day on or after day.
+func (c calendar) nextBusinessDay(day time.Time) time.Time {
+	for !c.isBusinessDay(day) {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day
+}
+
+// addBusinessDays returns the business day n business days after day.
+func (c calendar) addBusinessDays(day time.Time, n int) time.Time {
+	day = c.nextBusinessDay(day)
+	for ; n > 0; n-- {
+		day = c.nextBusinessDay(day.AddDate(0, 0, 1))
+	}
+	return day
+}
+
+// timezone is written as an IANA name such as "America/Los_Angeles".
+type timezone struct{ *time.Location }
+
+func (tz *timezone) UnmarshalJSON(b []byte) error {
+	var name string
+	if err := json.Unmarshal(b, &name); err != nil {
+		return err
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return fmt.Errorf("invalid time zone %q: %w", name, err)
+	}
+	tz.Location = loc
+	return nil
+}
+
+// shipDay returns the date, as midnight UTC, an order placed at now with m
+// leaves the warehouse.
+func (t *rateTable) shipDay(m method, now time.Time) time.Time {
+	local := now.In(t.Timezone.Location)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+	if local.Hour()*60+local.Minute() >= m.Cutoff.hour*60+m.Cutoff.minute {
+		day = day.AddDate(0, 0, 1)
+	}
+	return t.Calendar.nextBusinessDay(day)
+}
+
+func (t *rateTable) carrier(name string) (carrier, bool) {
+	for _, c := range t.Carriers {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return carrier{}, false
+}
+
+func (t *rateTable) method(id string) (method, bool) {
+	for _, m := range t.Methods {
+		if m.ID == id {
+			return m, true
+		}
+	}
+	return method{}, false
+}
+
+func (t *rateTable) validateMethods(zones map[string]bool) error {
+	if t.Timezone.Location == nil {
+		return errors.New("no time zone")
+	}
+	if len(t.Calendar.workdays) == 0 {
+		return errors.New("the warehouse calendar has no workdays")
+	}
+	for _, c := range t.Carriers {
+		if len(c.Calendar.workdays) == 0 {
+			return fmt.Errorf("carrier %q: calendar has no workdays", c.Name)
+		}
+	}
+	if len(t.Methods) == 0 {
+		return errors.New("no shipping methods")
+	}
+	ids := map[string]bool{}
+	for _, m := range t.Methods {
+		if m.ID == "" || ids[m.ID] {
+			return fmt.Errorf("method IDs must be unique and not empty, got %q", m.ID)
+		}
+		ids[m.ID] = true
+		for _, name := range m.Carriers {
+			if _, ok := t.carrier(name); !ok {
+				return fmt.Errorf("method %q: unknown carrier %q", m.ID, name)
+			}
+		}
+		if len(m.Carriers) == 0 && m.PickupLocation == "" {
+			return fmt.Errorf("method %q needs carriers or a pickup location", m.ID)
+		}
+		if len(m.Carriers) > 0 && (m.RatePercent <= 0 || m.RatePercent%(nanosPerUnit/100) != 0) {
+			return fmt.Errorf("method %q: rate_percent must be positive with at most two decimals", m.ID)
+		}
+		for _, z := range m.Zones {
+			if !zones[z] {
+				return fmt.Errorf("method %q: unknown zone %q", m.ID, z)
+			}
+		}
+		if m.TransitDays[0] < 0 || m.TransitDays[0] > m.TransitDays[1] {
+			return fmt.Errorf("method %q: transit_days must be a range of days", m.ID)
+		}
+	}
+	for i, r := range t.Routes {
+		if len(r.Methods) == 0 {
+			return fmt.Errorf("route %d: no methods", i)
+		}
+		for _, id := range r.Methods {
+			m, ok := t.method(id)
+			if !ok {
+				return fmt.Errorf("route %d: unknown method %q", i, id)
+			}
+			if !matchesFold(m.Carriers, r.Carrier) || len(m.Carriers) == 0 {
+				return fmt.Errorf("route %d: carrier %q does not ship method %q", i, r.Carrier, id)
+			}
+		}
+	}
+	return nil
+}
+
+// options prices shipping items to addr with every method offered in its
+// zone, in the table's order, for an order placed at now. Methods none of
+// whose carriers can take the items are left out; if that leaves none, the
+// reason is returned.
+func (t *rateTable) options(addr *pb.Address, items []packedItem, now time.Time) ([]*pb.ShippingOption, error) {
+	zone, err := t.zoneFor(addr)
+	if err != nil {
+		return nil, err
+	}
+	var options []*pb.ShippingOption
+	for _, m := range t.Methods {
+		if !matchesFold(m.Zones, zone) {
+			continue
+		}
+		cost, carrierName, cal := m.FlatRate, m.PickupLocation, t.Calendar
+		if len(m.Carriers) > 0 {
+			var carriers []carrier
+			if c, ok := t.routedCarrier(m, addr); ok {
+				carriers = append(carriers, c)
+			} else {
+				for _, name := range m.Carriers {
+					c, _ := t.carrier(name)
+					carriers = append(carriers, c)
+				}
+			}
+			q, qerr := t.cheapest(carriers, zone, items)
+			if qerr != nil {
+				err = qerr
+				continue
+			}
+			c, _ := t.carrier(q.carrier)
+			cost, carrierName, cal = percentOf(q.cost, m.RatePercent).roundToCent(), c.Name, c.Calendar
+		}
+		ship := t.shipDay(m, now)
+		options = append(options, &pb.ShippingOption{
+			Id:               m.ID,
+			Name:             m.Name,
+			CostUsd:          cost.money(),
+			Carrier:          carrierName,
+			EarliestDelivery: cal.addBusinessDays(ship, m.TransitDays[0]).Format(dateLayout),
+			LatestDelivery:   cal.addBusinessDays(ship, m.TransitDays[1]).Format(dateLayout),
+		})
+	}
+	if len(options) == 0 {
+		if err == nil {
+			err = errNoMethod
+		}
+		return nil, err
+	}
+	return options, nil
+}
+
+// errNoMethod is returned for zones no method is offered in.
+var errNoMethod = errors.New("no shipping method serves the address")
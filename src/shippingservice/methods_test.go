@@ -0,0 +1,95 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	pb "github.com/signalfx/microservices-demo/src/shippingservice/genproto"
+)
+
+func TestShippingOptions(t *testing.T) {
+	var rt rateTable
+	if err := json.Unmarshal([]byte(testRateTable), &rt); err != nil {
+		t.Fatal(err)
+	}
+	local := &pb.Address{Country: "US", State: "CA", ZipCode: 94107}
+	item := []packedItem{{productID: "p", grams: 400, cm3: 100}}
+	// Times are UTC; the table's warehouse is 8 hours behind in December.
+	at := func(s string) time.Time {
+		tm, err := time.Parse("2006-01-02 15:04", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+
+	tests := []struct {
+		name    string
+		addr    *pb.Address
+		now     time.Time
+		want    []string
+		wantErr error
+	}{
+		// Tuesday 24 is a business day, Christmas is not.
+		{"before every cut-off", local, at("2024-12-23 18:00"), []string{
+			"std Standard A 5.50 2024-12-26..2024-12-28",
+			"fast Fast B 11.00 2024-12-24..2024-12-24",
+			"pickup Pickup Brewery 1.50 2024-12-23..2024-12-23",
+		}, nil},
+		{"after the fast cut-off", local, at("2024-12-23 21:00"), []string{
+			"std Standard A 5.50 2024-12-26..2024-12-28",
+			"fast Fast B 11.00 2024-12-26..2024-12-26",
+			"pickup Pickup Brewery 1.50 2024-12-23..2024-12-23",
+		}, nil},
+		// Friday evening in the warehouse is Saturday in UTC; orders ship
+		// on Monday.
+		{"after the cut-off on Friday", &pb.Address{Country: "France"}, at("2024-12-28 01:30"), []string{
+			"std Standard A 22.00 2025-01-01..2025-01-03",
+		}, nil},
+		// A route sends standard to Hawaii with A, although B is cheaper.
+		{"routed", &pb.Address{Country: "US", State: "HI"}, at("2024-12-23 18:00"), []string{
+			"std Standard A 8.80 2024-12-26..2024-12-28",
+			"fast Fast B 17.00 2024-12-24..2024-12-24",
+		}, nil},
+		{"no zone", &pb.Address{Country: "Japan"}, at("2024-12-23 18:00"), nil, errNoZone},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options, err := rt.options(tt.addr, item, tt.now)
+			if err != tt.wantErr {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			var got []string
+			for _, o := range options {
+				got = append(got, fmt.Sprintf("%s %s %s %d.%02d %s..%s", o.Id, o.Name, o.Carrier,
+					o.CostUsd.Units, o.CostUsd.Nanos/1e7, o.EarliestDelivery, o.LatestDelivery))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Without standard, nothing ships to the world zone.
+	rt.Methods = rt.Methods[1:]
+	if _, err := rt.options(&pb.Address{Country: "France"}, item, at("2024-12-23 18:00")); err != errNoMethod {
+		t.Errorf("got %v, want %v", err, errNoMethod)
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// The size of a US letter page, in points.
+const (
+	pdfPageWidth  = 612
+	pdfPageHeight = 792
+)
+
+// pdfDocument writes just enough PDF for packing slips: pages of text in
+// Helvetica and filled rectangles. Coordinates are in points from the
+// bottom left of the page.
+type pdfDocument struct {
+	pages []*pdfPage
+}
+
+// pdfPage is a page's content stream.
+type pdfPage struct {
+	content bytes.Buffer
+}
+
+func (d *pdfDocument) newPage() *pdfPage {
+	p := new(pdfPage)
+	d.pages = append(d.pages, p)
+	return p
+}
+
+// text writes s with its baseline starting at x, y. Characters outside
+// Latin-1 are written as question marks.
+func (p *pdfPage) text(x, y float64, size float64, bold bool, s string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(&p.content, "BT /%s %.1f Tf %.2f %.2f Td (", font, size, x, y)
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			p.content.WriteByte('\\')
+			p.content.WriteRune(r)
+		case r >= ' ' && r <= '~':
+			p.content.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			// WinAnsiEncoding agrees with Latin-1 here.
+			fmt.Fprintf(&p.content, "\\%03o", r)
+		default:
+			p.content.WriteByte('?')
+		}
+	}
+	p.content.WriteString(") Tj ET\n")
+}
+
+// rect fills the rectangle with its bottom left corner at x, y in black.
+func (p *pdfPage) rect(x, y, w, h float64) {
+	fmt.Fprintf(&p.content, "%.2f %.2f %.2f %.2f re f\n", x, y, w, h)
+}
+
+// bytes returns the document as a PDF file.
+func (d *pdfDocument) bytes() []byte {
+	// Objects 1 to 4 are the catalog, the page tree and the two fonts; each
+	// page is then followed by its content stream.
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"", // the page tree, once the pages are numbered
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>",
+	}
+	var kids bytes.Buffer
+	for _, p := range d.pages {
+		page := len(objects) + 1
+		fmt.Fprintf(&kids, "%d 0 R ", page)
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] "+
+				"/Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>", pdfPageWidth, pdfPageHeight, page+1),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", p.content.Len(), p.content.Bytes()))
+	}
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", bytes.TrimSpace(kids.Bytes()), len(d.pages))
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, o := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, o)
+	}
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return out.Bytes()
+}
@@ -15,41 +15,57 @@
 package main
 
 import (
-	"fmt"
-	"math"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/signalfx/microservices-demo/src/shippingservice/genproto"
 )
 
-// Quote represents a currency value.
-type Quote struct {
-	Dollars uint32
-	Cents   uint32
+// lookupProducts looks up every product in items in the catalog, by ID.
+func (s *server) lookupProducts(ctx context.Context, items []*pb.CartItem) (map[string]*pb.Product, error) {
+	products := map[string]*pb.Product{}
+	for _, item := range items {
+		if _, ok := products[item.GetProductId()]; ok {
+			continue
+		}
+		p, err := s.catalog.GetProduct(ctx, &pb.GetProductRequest{Id: item.GetProductId()})
+		if err != nil {
+			return nil, status.Errorf(status.Code(err), "failed to look up product %s: %v", item.GetProductId(), err)
+		}
+		products[item.GetProductId()] = p
+	}
+	return products, nil
 }
 
-// String representation of the Quote.
-func (q Quote) String() string {
-	return fmt.Sprintf("$%d.%d", q.Dollars, q.Cents)
-}
+// maxPackedUnits bounds the units in one quote or shipment. Every unit is
+// packed on its own, so the bound keeps a huge quantity from costing as
+// much memory and time.
+const maxPackedUnits = 1000
 
-// CreateQuoteFromCount takes a number of items and returns a Price struct.
-func CreateQuoteFromCount(count int) Quote {
-	return CreateQuoteFromFloat(quoteByCountFloat(count))
-}
-
-// CreateQuoteFromFloat takes a price represented as a float and creates a Price struct.
-func CreateQuoteFromFloat(value float64) Quote {
-	units, fraction := math.Modf(value)
-	return Quote{
-		uint32(units),
-		uint32(math.Trunc(fraction * 100)),
+// packedItems returns one packedItem per unit in items, with the weight and
+// volume of its product. More than maxPackedUnits units in all is an
+// InvalidArgument error.
+func (s *server) packedItems(items []*pb.CartItem, products map[string]*pb.Product) ([]packedItem, error) {
+	var units int64
+	for _, item := range items {
+		if units += int64(item.GetQuantity()); units > maxPackedUnits {
+			return nil, status.Errorf(codes.InvalidArgument, "at most %d units can be shipped at once", maxPackedUnits)
+		}
 	}
-}
-
-// quoteByCountFloat takes a number of items and generates a price quote represented as a float.
-func quoteByCountFloat(count int) float64 {
-	if count == 0 {
-		return 0
+	var out []packedItem
+	for _, item := range items {
+		p := products[item.GetProductId()]
+		unit := packedItem{productID: p.GetId(), grams: int(p.GetWeightGrams()), cm3: int(p.GetVolumeCm3())}
+		if unit.grams <= 0 {
+			unit.grams = s.rates.DefaultItemGrams
+		}
+		if unit.cm3 <= 0 {
+			unit.cm3 = s.rates.DefaultItemCm3
+		}
+		for i := int32(0); i < item.GetQuantity(); i++ {
+			out = append(out, unit)
+		}
 	}
-	count64 := float64(count)
-	var p = 1 + (count64 * 0.2)
-	return count64 + math.Pow(3, p)
+	return out, nil
 }
@@ -0,0 +1,379 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+
+	pb "github.com/signalfx/microservices-demo/src/shippingservice/genproto"
+)
+
+const nanosPerUnit = 1000000000
+
+// nanos is an exact amount of US dollars, in billionths. In rate tables it
+// is written as a decimal string such as "4.95".
+type nanos int64
+
+func (n *nanos) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	v, err := parseNanos(s)
+	if err != nil {
+		return err
+	}
+	*n = v
+	return nil
+}
+
+// parseNanos parses a non-negative decimal with at most 9 fractional
+// digits.
+func parseNanos(s string) (nanos, error) {
+	whole, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		whole, frac = s[:i], s[i+1:]
+	}
+	invalid := fmt.Errorf("invalid amount %q", s)
+	if whole == "" || len(frac) > 9 {
+		return 0, invalid
+	}
+	units, err := strconv.ParseUint(whole, 10, 32)
+	if err != nil {
+		return 0, invalid
+	}
+	var fraction uint64
+	if frac != "" {
+		if fraction, err = strconv.ParseUint(frac+strings.Repeat("0", 9-len(frac)), 10, 64); err != nil {
+			return 0, invalid
+		}
+	}
+	return nanos(units*nanosPerUnit + fraction), nil
+}
+
+// roundToCent rounds half up to a whole cent.
+func (n nanos) roundToCent() nanos {
+	const cent = nanosPerUnit / 100
+	return (n + cent/2) / cent * cent
+}
+
+// percentOf returns percent, with at most two decimals, of n.
+func percentOf(n, percent nanos) nanos {
+	basisPoints := percent / (nanosPerUnit / 100)
+	return n * basisPoints / 10000
+}
+
+// nanosOf converts a USD amount.
+func nanosOf(m *pb.Money) nanos {
+	return nanos(m.GetUnits()*nanosPerUnit + int64(m.GetNanos()))
+}
+
+func (n nanos) money() *pb.Money {
+	return &pb.Money{CurrencyCode: "USD", Units: int64(n / nanosPerUnit), Nanos: int32(n % nanosPerUnit)}
+}
+
+// rateTable prices shipments by destination zone and by the weight and
+// volume of each parcel, plus the surcharges of the carrier that ships
+// them, and offers them with the shipping methods it lists.
+type rateTable struct {
+	Zones []zone `json:"zones"`
+	// DefaultZone is used for quotes without an address, such as the
+	// estimate on the cart page.
+	DefaultZone string `json:"default_zone"`
+	// Brackets are ordered by size, and each has a rate for every zone.
+	Brackets []bracket `json:"brackets"`
+	Carriers []carrier `json:"carriers"`
+	// For products the catalog gives no weight or volume.
+	DefaultItemGrams int `json:"default_item_grams"`
+	DefaultItemCm3   int `json:"default_item_cm3"`
+	// Methods are offered in this order.
+	Methods []method `json:"methods"`
+	// Routes pick the carrier of a method for some destinations, in place
+	// of the cheapest. The first matching route is used.
+	Routes []route `json:"routes"`
+	// Timezone and Calendar are the warehouse's, for cut-off times and
+	// shipping days.
+	Timezone timezone `json:"timezone"`
+	Calendar calendar `json:"calendar"`
+	// Sender is the warehouse's name and address, printed on labels.
+	Sender sender `json:"sender"`
+}
+
+// zone is a named destination.
+type zone struct {
+	Name string `json:"name"`
+	destination
+}
+
+// destination matches addresses. Empty lists match anything, so a
+// destination with none is a catch-all. Countries and states compare
+// case-insensitively.
+type destination struct {
+	Countries        []string `json:"countries"`
+	States           []string `json:"states"`
+	PostcodePrefixes []string `json:"postcode_prefixes"`
+}
+
+func (d destination) matches(addr *pb.Address) bool {
+	return matchesFold(d.Countries, addr.GetCountry()) && matchesFold(d.States, addr.GetState()) &&
+		hasPrefix(d.PostcodePrefixes, compactPostalCode(postalCode(addr)))
+}
+
+// bracket is the rate, by zone name, of parcels up to a weight and volume.
+type bracket struct {
+	MaxGrams int              `json:"max_grams"`
+	MaxCm3   int              `json:"max_cm3"`
+	Rates    map[string]nanos `json:"rates"`
+}
+
+type carrier struct {
+	Name string `json:"name"`
+	// Heavier orders are split into several parcels. Parcels are also
+	// split at the largest bracket's volume.
+	MaxParcelGrams int         `json:"max_parcel_grams"`
+	Surcharges     []surcharge `json:"surcharges"`
+	// Calendar is the days the carrier delivers on.
+	Calendar calendar `json:"calendar"`
+	// Tracking is the format of the carrier's tracking IDs.
+	Tracking trackingConfig `json:"tracking"`
+}
+
+// surcharge is added to every parcel going to one of Zones, or anywhere if
+// there are none: a fixed amount, a percentage of the parcel's rate, or
+// both.
+type surcharge struct {
+	Name      string   `json:"name"`
+	Zones     []string `json:"zones"`
+	PerParcel nanos    `json:"per_parcel"`
+	// Percent has at most two decimals.
+	Percent nanos `json:"percent"`
+}
+
+func loadRateTable(path string) (*rateTable, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rate table: %w", err)
+	}
+	t := new(rateTable)
+	if err := json.Unmarshal(b, t); err != nil {
+		return nil, fmt.Errorf("failed to parse rate table %s: %w", path, err)
+	}
+	if err := t.validate(); err != nil {
+		return nil, fmt.Errorf("invalid rate table %s: %w", path, err)
+	}
+	return t, nil
+}
+
+func (t *rateTable) validate() error {
+	zones := map[string]bool{}
+	for _, z := range t.Zones {
+		if z.Name == "" || zones[z.Name] {
+			return fmt.Errorf("zone names must be unique and not empty, got %q", z.Name)
+		}
+		zones[z.Name] = true
+	}
+	if !zones[t.DefaultZone] {
+		return fmt.Errorf("unknown default zone %q", t.DefaultZone)
+	}
+	if len(t.Brackets) == 0 {
+		return errors.New("no brackets")
+	}
+	for i, b := range t.Brackets {
+		if b.MaxGrams <= 0 || b.MaxCm3 <= 0 {
+			return fmt.Errorf("bracket %d: limits must be positive", i)
+		}
+		if i > 0 && (b.MaxGrams < t.Brackets[i-1].MaxGrams || b.MaxCm3 < t.Brackets[i-1].MaxCm3) {
+			return fmt.Errorf("bracket %d is smaller than bracket %d", i, i-1)
+		}
+		for name := range zones {
+			if _, ok := b.Rates[name]; !ok {
+				return fmt.Errorf("bracket %d has no rate for zone %q", i, name)
+			}
+		}
+	}
+	if len(t.Carriers) == 0 {
+		return errors.New("no carriers")
+	}
+	largest := t.Brackets[len(t.Brackets)-1]
+	for _, c := range t.Carriers {
+		if c.MaxParcelGrams <= 0 || c.MaxParcelGrams > largest.MaxGrams {
+			return fmt.Errorf("carrier %q: max_parcel_grams must be between 1 and %d", c.Name, largest.MaxGrams)
+		}
+		for _, s := range c.Surcharges {
+			if s.Percent%(nanosPerUnit/100) != 0 {
+				return fmt.Errorf("carrier %q: surcharge %q: percent has more than two decimals", c.Name, s.Name)
+			}
+			for _, z := range s.Zones {
+				if !zones[z] {
+					return fmt.Errorf("carrier %q: surcharge %q: unknown zone %q", c.Name, s.Name, z)
+				}
+			}
+		}
+	}
+	if t.DefaultItemGrams <= 0 || t.DefaultItemCm3 <= 0 {
+		return errors.New("default item weight and volume must be positive")
+	}
+	if a := t.Sender.Address; t.Sender.Name == "" || a.GetStreetAddress() == "" || a.GetCity() == "" || a.GetCountry() == "" {
+		return errors.New("sender needs a name, street address, city and country")
+	}
+	return t.validateMethods(zones)
+}
+
+// errNoZone is returned for destinations no zone matches.
+var errNoZone = errors.New("no shipping zone covers the address")
+
+// zoneFor returns the name of the first zone matching addr, or the default
+// zone if addr is nil.
+func (t *rateTable) zoneFor(addr *pb.Address) (string, error) {
+	if addr == nil {
+		return t.DefaultZone, nil
+	}
+	for _, z := range t.Zones {
+		if z.matches(addr) {
+			return z.Name, nil
+		}
+	}
+	return "", errNoZone
+}
+
+func matchesFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, strings.TrimSpace(s)) {
+			return true
+		}
+	}
+	return len(list) == 0
+}
+
+func hasPrefix(prefixes []string, s string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return len(prefixes) == 0
+}
+
+// packedItem is one unit of a product to ship.
+type packedItem struct {
+	productID string
+	grams     int
+	cm3       int
+}
+
+type parcel struct {
+	grams int
+	cm3   int
+}
+
+// errTooLarge is returned for items that do not fit any parcel.
+type errTooLarge struct{ productID string }
+
+func (e errTooLarge) Error() string {
+	return fmt.Sprintf("product %s is too heavy or too large to ship", e.productID)
+}
+
+// pack puts items into as few parcels within the limits as first-fit,
+// heaviest first, finds.
+func pack(items []packedItem, maxGrams, maxCm3 int) ([]parcel, error) {
+	sorted := append([]packedItem(nil), items...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].grams > sorted[j].grams })
+	var parcels []parcel
+	for _, it := range sorted {
+		if it.grams > maxGrams || it.cm3 > maxCm3 {
+			return nil, errTooLarge{it.productID}
+		}
+		i := 0
+		for i < len(parcels) && (parcels[i].grams+it.grams > maxGrams || parcels[i].cm3+it.cm3 > maxCm3) {
+			i++
+		}
+		if i == len(parcels) {
+			parcels = append(parcels, parcel{})
+		}
+		parcels[i].grams += it.grams
+		parcels[i].cm3 += it.cm3
+	}
+	return parcels, nil
+}
+
+// rate is the base rate of a parcel to a zone.
+func (t *rateTable) rate(p parcel, zone string) nanos {
+	for _, b := range t.Brackets {
+		if p.grams <= b.MaxGrams && p.cm3 <= b.MaxCm3 {
+			return b.Rates[zone]
+		}
+	}
+	// pack keeps parcels within the largest bracket.
+	panic(fmt.Sprintf("parcel %+v exceeds every bracket", p))
+}
+
+// shippingQuote is what shipping a set of items costs with one carrier.
+type shippingQuote struct {
+	carrier string
+	zone    string
+	parcels int
+	cost    nanos // rounded to the cent
+}
+
+// parcels packs items into the parcels c takes.
+func (t *rateTable) parcels(c carrier, items []packedItem) ([]parcel, error) {
+	return pack(items, c.MaxParcelGrams, t.Brackets[len(t.Brackets)-1].MaxCm3)
+}
+
+// quoteCarrier prices shipping items to zone with c.
+func (t *rateTable) quoteCarrier(c carrier, zone string, items []packedItem) (shippingQuote, error) {
+	parcels, err := t.parcels(c, items)
+	if err != nil {
+		return shippingQuote{}, err
+	}
+	var cost nanos
+	for _, p := range parcels {
+		base := t.rate(p, zone)
+		cost += base
+		for _, s := range c.Surcharges {
+			if matchesFold(s.Zones, zone) {
+				cost += s.PerParcel + percentOf(base, s.Percent)
+			}
+		}
+	}
+	return shippingQuote{carrier: c.Name, zone: zone, parcels: len(parcels), cost: cost.roundToCent()}, nil
+}
+
+// cheapest prices shipping items to zone with each of carriers and returns
+// the cheapest quote; ties go to the carrier listed first.
+func (t *rateTable) cheapest(carriers []carrier, zone string, items []packedItem) (shippingQuote, error) {
+	var best shippingQuote
+	var lastErr error
+	for _, c := range carriers {
+		q, err := t.quoteCarrier(c, zone, items)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if best.carrier == "" || q.cost < best.cost {
+			best = q
+		}
+	}
+	if best.carrier == "" {
+		return shippingQuote{}, lastErr
+	}
+	return best, nil
+}
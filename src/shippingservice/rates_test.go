@@ -0,0 +1,193 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	pb "github.com/signalfx/microservices-demo/src/shippingservice/genproto"
+)
+
+const testRateTable = `{
+	"zones": [
+		{"name": "local", "countries": ["US"], "states": ["CA"], "postcode_prefixes": ["94"]},
+		{"name": "domestic", "countries": ["US", "United States"]},
+		{"name": "world", "countries": ["FR", "France"]}
+	],
+	"default_zone": "domestic",
+	"brackets": [
+		{"max_grams": 1000, "max_cm3": 5000, "rates": {"local": "5", "domestic": "8", "world": "20"}},
+		{"max_grams": 5000, "max_cm3": 20000, "rates": {"local": "10", "domestic": "15", "world": "40"}}
+	],
+	"carriers": [
+		{"name": "A", "max_parcel_grams": 5000, "surcharges": [{"name": "fuel", "percent": "10"}],
+			"calendar": {"workdays": ["Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"], "holidays": ["2024-12-25"]}},
+		{"name": "B", "max_parcel_grams": 1000, "surcharges": [
+			{"name": "handling", "per_parcel": "0.50"},
+			{"name": "customs", "zones": ["world"], "per_parcel": "2"}
+		], "calendar": {"workdays": ["Monday", "Tuesday", "Wednesday", "Thursday", "Friday"], "holidays": ["2024-12-25"]}}
+	],
+	"default_item_grams": 500,
+	"default_item_cm3": 1000,
+	"methods": [
+		{"id": "std", "name": "Standard", "carriers": ["A", "B"], "rate_percent": "100", "cutoff": "17:00", "transit_days": [2, 4]},
+		{"id": "fast", "name": "Fast", "carriers": ["B"], "rate_percent": "200", "zones": ["local", "domestic"], "cutoff": "12:00", "transit_days": [1, 1]},
+		{"id": "pickup", "name": "Pickup", "flat_rate": "1.50", "pickup_location": "Brewery", "zones": ["local"], "cutoff": "16:00", "transit_days": [0, 0]}
+	],
+	"routes": [
+		{"methods": ["std"], "countries": ["US"], "states": ["HI"], "carrier": "A"}
+	],
+	"timezone": "America/Los_Angeles",
+	"calendar": {"workdays": ["Monday", "Tuesday", "Wednesday", "Thursday", "Friday"], "holidays": ["2024-12-25"]},
+	"sender": {"name": "Brewery", "address": {"street_address": "1 Main St", "city": "San Francisco", "state": "CA", "postal_code": "94107", "country": "US"}}
+}`
+
+func parseTestRateTable(t *testing.T, edit func(*rateTable)) error {
+	t.Helper()
+	var rt rateTable
+	if err := json.Unmarshal([]byte(testRateTable), &rt); err != nil {
+		t.Fatal(err)
+	}
+	if edit != nil {
+		edit(&rt)
+	}
+	return rt.validate()
+}
+
+func TestNanos(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    nanos
+		wantErr bool
+	}{
+		{"4.95", 4950000000, false},
+		{"12", 12000000000, false},
+		{"0.000000001", 1, false},
+		{"-1", 0, true},
+		{"1.", 1000000000, false},
+		{".5", 0, true},
+		{"1.0000000001", 0, true},
+		{"1.+5", 0, true},
+		{"cheap", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseNanos(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseNanos(%q) = %d, %v", tt.in, got, err)
+		}
+	}
+	for n, want := range map[nanos]nanos{1234999999: 1230000000, 1235000000: 1240000000, 5: 0} {
+		if got := n.roundToCent(); got != want {
+			t.Errorf("%d.roundToCent() = %d, want %d", n, got, want)
+		}
+	}
+	if got := nanos(1235000000).roundToCent().money(); got.Units != 1 || got.Nanos != 240000000 || got.CurrencyCode != "USD" {
+		t.Errorf("money = %v", got)
+	}
+}
+
+func TestRateTableValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		edit    func(*rateTable)
+		wantErr string
+	}{
+		{"valid", nil, ""},
+		{"unknown default zone", func(rt *rateTable) { rt.DefaultZone = "moon" }, `unknown default zone "moon"`},
+		{"missing rate", func(rt *rateTable) { delete(rt.Brackets[1].Rates, "world") }, `bracket 1 has no rate for zone "world"`},
+		{"brackets out of order", func(rt *rateTable) { rt.Brackets[0], rt.Brackets[1] = rt.Brackets[1], rt.Brackets[0] }, "bracket 1 is smaller"},
+		{"parcel over the largest bracket", func(rt *rateTable) { rt.Carriers[0].MaxParcelGrams = 6000 }, "between 1 and 5000"},
+		{"fine percent", func(rt *rateTable) { rt.Carriers[0].Surcharges[0].Percent = 10500 }, "more than two decimals"},
+		{"surcharge zone", func(rt *rateTable) { rt.Carriers[1].Surcharges[1].Zones = []string{"moon"} }, `unknown zone "moon"`},
+		{"method carrier", func(rt *rateTable) { rt.Methods[1].Carriers = []string{"C"} }, `method "fast": unknown carrier "C"`},
+		{"duplicate method", func(rt *rateTable) { rt.Methods[1].ID = "std" }, `unique and not empty, got "std"`},
+		{"no rate percent", func(rt *rateTable) { rt.Methods[0].RatePercent = 0 }, "rate_percent must be positive"},
+		{"carrierless method without pickup", func(rt *rateTable) { rt.Methods[2].PickupLocation = "" }, "needs carriers or a pickup location"},
+		{"transit range", func(rt *rateTable) { rt.Methods[0].TransitDays = [2]int{3, 1} }, "transit_days must be a range"},
+		{"carrier calendar", func(rt *rateTable) { rt.Carriers[0].Calendar = calendar{} }, `carrier "A": calendar has no workdays`},
+		{"route without methods", func(rt *rateTable) { rt.Routes[0].Methods = nil }, "route 0: no methods"},
+		{"route method", func(rt *rateTable) { rt.Routes[0].Methods = []string{"slow"} }, `route 0: unknown method "slow"`},
+		{"route carrier", func(rt *rateTable) { rt.Routes[0].Methods = []string{"fast"}; rt.Routes[0].Carrier = "A" }, `carrier "A" does not ship method "fast"`},
+		{"sender without an address", func(rt *rateTable) { rt.Sender.Address = nil }, "sender needs a name"},
+		{"route to a pickup", func(rt *rateTable) { rt.Routes[0].Methods = []string{"pickup"} }, `carrier "A" does not ship method "pickup"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := parseTestRateTable(t, tt.edit)
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("got %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRateTableCheapest(t *testing.T) {
+	var rt rateTable
+	if err := json.Unmarshal([]byte(testRateTable), &rt); err != nil {
+		t.Fatal(err)
+	}
+	units := func(n, grams, cm3 int) []packedItem {
+		var out []packedItem
+		for i := 0; i < n; i++ {
+			out = append(out, packedItem{productID: "p", grams: grams, cm3: cm3})
+		}
+		return out
+	}
+	us := func(state string, zip int32) *pb.Address {
+		return &pb.Address{Country: "United States", State: state, ZipCode: zip}
+	}
+
+	tests := []struct {
+		name        string
+		addr        *pb.Address
+		items       []packedItem
+		wantCarrier string
+		wantZone    string
+		wantParcels int
+		wantCost    nanos
+		wantErr     string
+	}{
+		{"no address uses the default zone", nil, units(1, 400, 100), "B", "domestic", 1, 8500000000, ""},
+		{"zone by postcode, ties go to the first carrier", &pb.Address{Country: "us", State: "ca", ZipCode: 94107}, units(2, 400, 100), "A", "local", 1, 5500000000, ""},
+		{"postcode outside the zone", us("CA", 90210), units(3, 400, 100), "A", "domestic", 1, 16500000000, ""},
+		{"weight splits parcels", us("NY", 10001), units(6, 1000, 100), "A", "domestic", 2, 25300000000, ""},
+		{"volume picks the bracket", us("NY", 10001), units(2, 400, 4000), "B", "domestic", 1, 15500000000, ""},
+		{"zone surcharge", &pb.Address{Country: "France"}, units(1, 400, 100), "A", "world", 1, 22000000000, ""},
+		{"nothing to ship", us("NY", 10001), nil, "A", "domestic", 0, 0, ""},
+		{"no zone", &pb.Address{Country: "Japan"}, units(1, 400, 100), "", "", 0, 0, errNoZone.Error()},
+		{"too heavy", us("NY", 10001), units(1, 6000, 100), "", "", 0, 0, "product p is too heavy or too large to ship"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zone, err := rt.zoneFor(tt.addr)
+			var got shippingQuote
+			if err == nil {
+				got, err = rt.cheapest(rt.Carriers, zone, tt.items)
+			}
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			want := shippingQuote{carrier: tt.wantCarrier, zone: tt.wantZone, parcels: tt.wantParcels, cost: tt.wantCost}
+			if err != nil || got != want {
+				t.Errorf("got %+v, %v, want %+v", got, err, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,272 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/signalfx/microservices-demo/src/shippingservice/genproto"
+)
+
+// restrictionRules say which products may not be shipped where, or only
+// against an adult's signature. Every rule that matches applies.
+type restrictionRules struct {
+	Rules []restrictionRule `json:"rules"`
+}
+
+type restrictionRule struct {
+	Name string `json:"name"`
+	// Products the rule applies to: those any of the matchers match.
+	Products []productMatcher `json:"products"`
+	// Destinations the rule applies to, matched after the address is
+	// normalized; none for everywhere.
+	Destinations []destination `json:"destinations"`
+	// Action is "deny" or "adult_signature".
+	Action string `json:"action"`
+	// Fee is the adult signature surcharge per order. Orders pay the
+	// largest fee of the rules that apply.
+	Fee    nanos  `json:"fee"`
+	Reason string `json:"reason"`
+}
+
+// productMatcher matches products in any of Categories that meet every
+// condition of Attributes. A condition is a comparison with a number, such
+// as ">=0.5", "*" for any value, or else a value to compare to
+// case-insensitively.
+type productMatcher struct {
+	Categories []string          `json:"categories"`
+	Attributes map[string]string `json:"attributes"`
+}
+
+var restrictionActions = map[string]pb.ItemRestriction_Action{
+	"deny":            pb.ItemRestriction_DENY,
+	"adult_signature": pb.ItemRestriction_ADULT_SIGNATURE,
+}
+
+func loadRestrictionRules(path string) (*restrictionRules, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read restriction rules: %w", err)
+	}
+	r := new(restrictionRules)
+	if err := json.Unmarshal(b, r); err != nil {
+		return nil, fmt.Errorf("failed to parse restriction rules %s: %w", path, err)
+	}
+	if err := r.validate(); err != nil {
+		return nil, fmt.Errorf("invalid restriction rules %s: %w", path, err)
+	}
+	return r, nil
+}
+
+func (r *restrictionRules) validate() error {
+	names := map[string]bool{}
+	for _, rule := range r.Rules {
+		if rule.Name == "" || names[rule.Name] {
+			return fmt.Errorf("rule names must be unique and not empty, got %q", rule.Name)
+		}
+		names[rule.Name] = true
+		if _, ok := restrictionActions[rule.Action]; !ok {
+			return fmt.Errorf("rule %q: action must be deny or adult_signature, got %q", rule.Name, rule.Action)
+		}
+		if rule.Action != "adult_signature" && rule.Fee != 0 {
+			return fmt.Errorf("rule %q: only adult_signature rules have a fee", rule.Name)
+		}
+		if rule.Reason == "" {
+			return fmt.Errorf("rule %q has no reason", rule.Name)
+		}
+		if len(rule.Products) == 0 {
+			return fmt.Errorf("rule %q matches no products", rule.Name)
+		}
+		for _, m := range rule.Products {
+			if len(m.Categories) == 0 && len(m.Attributes) == 0 {
+				return fmt.Errorf("rule %q: product matchers need categories or attributes", rule.Name)
+			}
+			for name, cond := range m.Attributes {
+				if _, err := matchesCondition(cond, ""); err != nil {
+					return fmt.Errorf("rule %q: attribute %q: %w", rule.Name, name, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (m productMatcher) matches(p *pb.Product) bool {
+	if len(m.Categories) > 0 && !sharesFold(m.Categories, p.GetCategories()) {
+		return false
+	}
+	for name, cond := range m.Attributes {
+		value, ok := p.GetAttributes()[name]
+		if !ok {
+			return false
+		}
+		if matched, _ := matchesCondition(cond, value); !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func sharesFold(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if strings.EqualFold(x, y) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchesCondition reports whether value meets cond. Values that are not
+// numbers fail numeric conditions; invalid conditions are an error.
+func matchesCondition(cond, value string) (bool, error) {
+	if cond == "*" {
+		return true, nil
+	}
+	for _, op := range []string{">=", "<=", ">", "<"} {
+		if !strings.HasPrefix(cond, op) {
+			continue
+		}
+		limit, err := strconv.ParseFloat(strings.TrimSpace(cond[len(op):]), 64)
+		if err != nil {
+			return false, fmt.Errorf("invalid condition %q", cond)
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return false, nil
+		}
+		switch op {
+		case ">=":
+			return v >= limit, nil
+		case "<=":
+			return v <= limit, nil
+		case ">":
+			return v > limit, nil
+		}
+		return v < limit, nil
+	}
+	return strings.EqualFold(cond, strings.TrimSpace(value)), nil
+}
+
+// check returns the restrictions that apply to items going to addr, in the
+// order of items and then of the rules. With a nil addr only rules without
+// destinations apply.
+func (r *restrictionRules) check(addr *pb.Address, items []*pb.CartItem, products map[string]*pb.Product) []*pb.ItemRestriction {
+	if addr != nil {
+		addr, _ = normalizeAddress(addr)
+	}
+	var out []*pb.ItemRestriction
+	seen := map[string]bool{}
+	for _, item := range items {
+		p := products[item.GetProductId()]
+		if seen[p.GetId()] {
+			continue
+		}
+		seen[p.GetId()] = true
+		for _, rule := range r.Rules {
+			if rule.appliesTo(p, addr) {
+				out = append(out, &pb.ItemRestriction{
+					ProductId: p.GetId(),
+					Action:    restrictionActions[rule.Action],
+					Rule:      rule.Name,
+					Reason:    rule.Reason,
+				})
+			}
+		}
+	}
+	return out
+}
+
+func (rule restrictionRule) appliesTo(p *pb.Product, addr *pb.Address) bool {
+	matched := false
+	for _, m := range rule.Products {
+		matched = matched || m.matches(p)
+	}
+	if !matched {
+		return false
+	}
+	if len(rule.Destinations) == 0 {
+		return true
+	}
+	if addr == nil {
+		return false
+	}
+	// Rules cannot tell whether a country they do not know is one of
+	// theirs, so they apply to it.
+	_, known := isoCountryCode(addr.GetCountry())
+	for _, d := range rule.Destinations {
+		if d.matches(addr) || (len(d.Countries) > 0 && !known) {
+			return true
+		}
+	}
+	return false
+}
+
+// signatureFee returns the adult signature surcharge of restrictions, and
+// whether any of them needs a signature.
+func (r *restrictionRules) signatureFee(restrictions []*pb.ItemRestriction) (nanos, bool) {
+	var fee nanos
+	needed := false
+	for _, res := range restrictions {
+		if res.GetAction() != pb.ItemRestriction_ADULT_SIGNATURE {
+			continue
+		}
+		needed = true
+		for _, rule := range r.Rules {
+			if rule.Name == res.GetRule() && rule.Fee > fee {
+				fee = rule.Fee
+			}
+		}
+	}
+	return fee, needed
+}
+
+// deniedError returns a FAILED_PRECONDITION error explaining every DENY
+// restriction, item by item, or nil if there are none.
+func deniedError(restrictions []*pb.ItemRestriction, products map[string]*pb.Product) error {
+	var reasons []string
+	for _, res := range restrictions {
+		if res.GetAction() == pb.ItemRestriction_DENY {
+			reasons = append(reasons, fmt.Sprintf("%s (%s): %s", products[res.GetProductId()].GetName(), res.GetProductId(), res.GetReason()))
+		}
+	}
+	if len(reasons) == 0 {
+		return nil
+	}
+	return status.Errorf(codes.FailedPrecondition, "some items cannot be shipped to this address: %s", strings.Join(reasons, "; "))
+}
+
+// CheckRestrictions lists the restrictions that apply to the items, without
+// refusing any.
+func (s *server) CheckRestrictions(ctx context.Context, in *pb.CheckRestrictionsRequest) (*pb.CheckRestrictionsResponse, error) {
+	log := logger.WithFields(getTraceLogFields(ctx))
+	log.Info("[CheckRestrictions] received request")
+	defer log.Info("[CheckRestrictions] completed request")
+
+	products, err := s.lookupProducts(ctx, in.Items)
+	if err != nil {
+		return nil, err
+	}
+	return &pb.CheckRestrictionsResponse{Restrictions: s.restrictions.check(in.Address, in.Items, products)}, nil
+}
@@ -0,0 +1,179 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/signalfx/microservices-demo/src/shippingservice/genproto"
+)
+
+func TestRestrictionRulesValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		edit    func(*restrictionRules)
+		wantErr string
+	}{
+		{"valid", nil, ""},
+		{"duplicate name", func(r *restrictionRules) { r.Rules[1].Name = r.Rules[0].Name }, `unique and not empty, got "alcohol-dry-states"`},
+		{"unknown action", func(r *restrictionRules) { r.Rules[0].Action = "warn" }, `action must be deny or adult_signature, got "warn"`},
+		{"fee on deny", func(r *restrictionRules) { r.Rules[0].Fee = 100 }, "only adult_signature rules have a fee"},
+		{"no reason", func(r *restrictionRules) { r.Rules[2].Reason = "" }, `rule "alcohol-adult-signature" has no reason`},
+		{"no products", func(r *restrictionRules) { r.Rules[0].Products = nil }, "matches no products"},
+		{"empty matcher", func(r *restrictionRules) { r.Rules[0].Products = []productMatcher{{}} }, "need categories or attributes"},
+		{"bad condition", func(r *restrictionRules) {
+			r.Rules[0].Products = []productMatcher{{Attributes: map[string]string{"abv": ">=lots"}}}
+		}, `attribute "abv": invalid condition ">=lots"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := loadRestrictionRules(defaultRestrictionRulesFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.edit != nil {
+				tt.edit(r)
+			}
+			err = r.validate()
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("got %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMatchesCondition(t *testing.T) {
+	tests := []struct {
+		cond, value string
+		want        bool
+	}{
+		{"*", "anything", true},
+		{">=0.5", "0.5", true},
+		{">=0.5", "0.4", false},
+		{"<12", "5.2", true},
+		{">0", "none", false},
+		{"Red", " red ", true},
+		{"red", "blue", false},
+	}
+	for _, tt := range tests {
+		if got, err := matchesCondition(tt.cond, tt.value); err != nil || got != tt.want {
+			t.Errorf("matchesCondition(%q, %q) = %v, %v, want %v", tt.cond, tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestCheckRestrictions(t *testing.T) {
+	s := testServer(t)
+	items := []*pb.CartItem{{ProductId: "23", Quantity: 1}, {ProductId: "ale", Quantity: 2}, {ProductId: "ale", Quantity: 1}}
+
+	tests := []struct {
+		name string
+		addr *pb.Address
+		want []string // rule names, in order
+	}{
+		{"no address", nil, []string{"alcohol-adult-signature"}},
+		{"California", &pb.Address{StreetAddress: "1 Main St", City: "Fresno", State: "CA", Country: "US", PostalCode: "93650"},
+			[]string{"alcohol-adult-signature"}},
+		{"Utah, spelled out", &pb.Address{StreetAddress: "1 Main St", City: "Provo", State: "utah", Country: "United States", PostalCode: "84601"},
+			[]string{"alcohol-dry-states", "alcohol-adult-signature"}},
+		{"Kuwait", &pb.Address{StreetAddress: "1 Gulf Rd", City: "Kuwait City", Country: "KW"},
+			[]string{"alcohol-import-bans", "alcohol-adult-signature"}},
+		{"Saudi Arabia, spelled out", &pb.Address{StreetAddress: "1 King Fahd Rd", City: "Riyadh", Country: " saudi  arabia "},
+			[]string{"alcohol-import-bans", "alcohol-adult-signature"}},
+		{"Belgium, spelled out", &pb.Address{StreetAddress: "1 Rue X", City: "Brussels", Country: "Belgium", PostalCode: "1000"},
+			[]string{"alcohol-adult-signature"}},
+		{"unknown country", &pb.Address{StreetAddress: "1 Main St", City: "Cair Paravel", Country: "Narnia"},
+			[]string{"alcohol-dry-states", "alcohol-import-bans", "alcohol-adult-signature"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := s.CheckRestrictions(context.Background(), &pb.CheckRestrictionsRequest{Address: tt.addr, Items: items})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, r := range res.Restrictions {
+				if r.ProductId != "ale" {
+					t.Errorf("restricted product %q, want only ale", r.ProductId)
+				}
+				got = append(got, r.Rule)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rules = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetQuoteRestrictions(t *testing.T) {
+	s := testServer(t)
+	items := []*pb.CartItem{{ProductId: "23", Quantity: 1}, {ProductId: "ale", Quantity: 1}}
+
+	utah := &pb.Address{StreetAddress: "1 Main St", City: "Provo", State: "UT", Country: "US", PostalCode: "84601"}
+	_, err := s.GetQuote(context.Background(), &pb.GetQuoteRequest{Address: utah, Items: items})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("to Utah: got %v, want FailedPrecondition", err)
+	}
+	if want := "Pale Ale (ale): State law does not allow alcohol"; !strings.Contains(status.Convert(err).Message(), want) {
+		t.Errorf("to Utah: message %q does not explain %q", status.Convert(err).Message(), want)
+	}
+
+	// Elsewhere every option costs $6.50 more than without rules.
+	california := &pb.Address{StreetAddress: "1 Main St", City: "Fresno", State: "CA", Country: "US", PostalCode: "93650"}
+	res, err := s.GetQuote(context.Background(), &pb.GetQuoteRequest{Address: california, Items: items})
+	if err != nil {
+		t.Fatal(err)
+	}
+	unrestricted := testServer(t)
+	unrestricted.restrictions = &restrictionRules{}
+	base, err := unrestricted.GetQuote(context.Background(), &pb.GetQuoteRequest{Address: california, Items: items})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, o := range res.Options {
+		if !o.AdultSignature {
+			t.Errorf("option %s: no adult signature", o.Id)
+		}
+		if got := nanosOf(o.CostUsd) - nanosOf(base.Options[i].CostUsd); got != 6500000000 {
+			t.Errorf("option %s: surcharge = %v, want 6.50", o.Id, got.money())
+		}
+	}
+}
+
+func TestShipOrderRestrictions(t *testing.T) {
+	s := testServer(t)
+	items := []*pb.CartItem{{ProductId: "ale", Quantity: 1}}
+
+	req := &pb.ShipOrderRequest{Address: &pb.Address{StreetAddress: "1 Main St", City: "Provo", State: "UT", Country: "US", PostalCode: "84601"}, Items: items}
+	if _, err := s.ShipOrder(context.Background(), req); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("to Utah: got %v, want FailedPrecondition", err)
+	}
+
+	req.Address.State = "CA"
+	req.Address.PostalCode = "93650"
+	res, err := s.ShipOrder(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sh, _ := s.shipments.get(res.TrackingId); !sh.AdultSignature {
+		t.Error("shipment does not need an adult signature")
+	}
+}
@@ -0,0 +1,219 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/signalfx/microservices-demo/src/shippingservice/genproto"
+)
+
+// shipment is an order handed to shipping. Its status is not stored; the
+// simulator derives it from CreatedAt.
+type shipment struct {
+	TrackingID string         `json:"tracking_id"`
+	Address    *pb.Address    `json:"address"`
+	Items      []*pb.CartItem `json:"items"`
+	// The ID of the rate table's shipping method.
+	ShippingOption string `json:"shipping_option"`
+	// The carrier shipping it; empty for pickups.
+	Carrier string `json:"carrier,omitempty"`
+	// Whether an adult must sign for the delivery.
+	AdultSignature bool `json:"adult_signature,omitempty"`
+	// What the carrier's API charges for it, if it has one.
+	CarrierCost *pb.Money `json:"carrier_cost,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// shipmentStore keeps every shipment created. With a file, each shipment is
+// also appended to it as a line of JSON, and the file is replayed on open so
+// that shipments survive restarts.
+type shipmentStore struct {
+	mu   sync.Mutex
+	byID map[string]*shipment
+	w    io.Writer // nil when shipments are only kept in memory
+	// carrierEvents are the status updates carriers have posted, by
+	// tracking ID. They are only kept in memory.
+	carrierEvents map[string][]*pb.ShipmentEvent
+}
+
+// openShipmentStore opens the store backed by the file at path, creating it
+// if needed, or an in-memory store if path is empty. A last line cut short
+// by a crash is dropped; any other line that is not a shipment is an error.
+func openShipmentStore(path string) (*shipmentStore, error) {
+	s := &shipmentStore{byID: map[string]*shipment{}, carrierEvents: map[string][]*pb.ShipmentEvent{}}
+	if path == "" {
+		return s, nil
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open shipment store: %w", err)
+	}
+	if err := s.replay(f, path); err != nil {
+		f.Close()
+		return nil, err
+	}
+	s.w = f
+	return s, nil
+}
+
+// replay loads the shipments in f, truncating a torn last line and
+// terminating a last line that is whole but lacks its newline.
+func (s *shipmentStore) replay(f *os.File, path string) error {
+	r := bufio.NewReader(f)
+	var off int64
+	for line := 1; ; line++ {
+		raw, err := r.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("failed to read shipment store: %w", err)
+		}
+		if len(raw) == 0 {
+			return nil
+		}
+		sh := new(shipment)
+		if uerr := json.Unmarshal(raw, sh); uerr != nil {
+			if err != io.EOF {
+				return fmt.Errorf("%s:%d: invalid shipment: %w", path, line, uerr)
+			}
+			logger.Warnf("%s:%d: dropping a shipment that was not completely written", path, line)
+			if err := f.Truncate(off); err != nil {
+				return fmt.Errorf("failed to truncate shipment store: %w", err)
+			}
+			return nil
+		}
+		s.byID[sh.TrackingID] = sh
+		off += int64(len(raw))
+		if err == io.EOF {
+			if _, err := f.Write([]byte{'\n'}); err != nil {
+				return fmt.Errorf("failed to write shipment store: %w", err)
+			}
+			return nil
+		}
+	}
+}
+
+// errDuplicateTrackingID is returned when adding a shipment whose tracking
+// ID is taken.
+var errDuplicateTrackingID = errors.New("tracking ID is taken")
+
+// add stores sh, writing it durably to the store's file first if it has
+// one. It refuses shipments whose tracking ID is taken.
+func (s *shipmentStore) add(sh *shipment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.byID[sh.TrackingID]; ok {
+		return errDuplicateTrackingID
+	}
+	if s.w != nil {
+		b, err := json.Marshal(sh)
+		if err != nil {
+			return err
+		}
+		if _, err := s.w.Write(append(b, '\n')); err != nil {
+			return fmt.Errorf("failed to write shipment: %w", err)
+		}
+		if f, ok := s.w.(interface{ Sync() error }); ok {
+			if err := f.Sync(); err != nil {
+				return fmt.Errorf("failed to sync shipment store: %w", err)
+			}
+		}
+	}
+	s.byID[sh.TrackingID] = sh
+	return nil
+}
+
+func (s *shipmentStore) get(trackingID string) (*shipment, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sh, ok := s.byID[trackingID]
+	return sh, ok
+}
+
+// addCarrierEvent keeps a status update of a shipment from its carrier. It
+// reports false if there is no shipment with the tracking ID.
+func (s *shipmentStore) addCarrierEvent(trackingID string, ev *pb.ShipmentEvent) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.byID[trackingID]; !ok {
+		return false
+	}
+	s.carrierEvents[trackingID] = append(s.carrierEvents[trackingID], ev)
+	return true
+}
+
+// carrierEventsOf returns the status updates the shipment's carrier has
+// posted, oldest first.
+func (s *shipmentStore) carrierEventsOf(trackingID string) []*pb.ShipmentEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*pb.ShipmentEvent(nil), s.carrierEvents[trackingID]...)
+}
+
+// TrackShipment returns the shipment's history: as its carrier's API tells
+// it, or else as the simulator has advanced it so far.
+func (s *server) TrackShipment(ctx context.Context, in *pb.TrackShipmentRequest) (*pb.TrackShipmentResponse, error) {
+	log := logger.WithFields(getTraceLogFields(ctx))
+	log.Info("[TrackShipment] received request")
+	defer log.Info("[TrackShipment] completed request")
+
+	if in.GetTrackingId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "tracking ID is required")
+	}
+	sh, ok := s.shipments.get(in.GetTrackingId())
+	if !ok && ValidateTrackingId(in.GetTrackingId()) != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%q is not a tracking ID", in.GetTrackingId())
+	}
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "no shipment with tracking ID %q", in.GetTrackingId())
+	}
+	events := s.events(ctx, sh)
+	return &pb.TrackShipmentResponse{
+		TrackingId: sh.TrackingID,
+		Status:     events[len(events)-1].Status,
+		Events:     events,
+		Address:    sh.Address,
+	}, nil
+}
+
+// events returns sh's history from its carrier's API, if it has one. When
+// the API fails, the updates the carrier posted are used, and failing
+// those, the simulator's.
+func (s *server) events(ctx context.Context, sh *shipment) []*pb.ShipmentEvent {
+	c, ok := s.carriers[sh.Carrier]
+	if !ok {
+		return s.sim.events(sh, s.now())
+	}
+	events, err := c.Track(ctx, sh.TrackingID)
+	if err == nil && len(events) > 0 {
+		return events
+	}
+	logger.WithFields(getTraceLogFields(ctx)).WithField("tracking_id", sh.TrackingID).
+		Warnf("failed to track shipment with %s: %v", sh.Carrier, err)
+	if events := s.shipments.carrierEventsOf(sh.TrackingID); len(events) > 0 {
+		return events
+	}
+	return s.sim.events(sh, s.now())
+}
@@ -0,0 +1,193 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/signalfx/microservices-demo/src/shippingservice/genproto"
+)
+
+func statuses(events []*pb.ShipmentEvent) []pb.ShipmentEvent_Status {
+	var out []pb.ShipmentEvent_Status
+	for _, e := range events {
+		out = append(out, e.Status)
+	}
+	return out
+}
+
+func TestSimulator(t *testing.T) {
+	created := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	sh := &shipment{TrackingID: "AB-123-4567", Address: &pb.Address{City: "Seattle", State: "WA"}, CreatedAt: created}
+	sim := simulator{step: time.Hour}
+
+	tests := []struct {
+		after time.Duration
+		want  []pb.ShipmentEvent_Status
+	}{
+		{0, []pb.ShipmentEvent_Status{pb.ShipmentEvent_LABEL_CREATED}},
+		{59 * time.Minute, []pb.ShipmentEvent_Status{pb.ShipmentEvent_LABEL_CREATED}},
+		{time.Hour, []pb.ShipmentEvent_Status{pb.ShipmentEvent_LABEL_CREATED, pb.ShipmentEvent_PICKED_UP}},
+		{3 * time.Hour, []pb.ShipmentEvent_Status{pb.ShipmentEvent_LABEL_CREATED, pb.ShipmentEvent_PICKED_UP,
+			pb.ShipmentEvent_IN_TRANSIT, pb.ShipmentEvent_OUT_FOR_DELIVERY}},
+		{100 * time.Hour, stages},
+	}
+	for _, tt := range tests {
+		t.Run(tt.after.String(), func(t *testing.T) {
+			events := sim.events(sh, created.Add(tt.after))
+			if got := statuses(events); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			if last := events[len(events)-1]; last.Time > created.Add(tt.after).Unix() {
+				t.Errorf("event %v is in the future", last)
+			}
+		})
+	}
+
+	delivered := sim.events(sh, created.Add(100*time.Hour))
+	if e := delivered[len(delivered)-1]; e.Location != "Seattle, WA" || e.Time != created.Add(4*time.Hour).Unix() {
+		t.Errorf("delivery = %v", e)
+	}
+
+	// Every shipment fails with a rate of 1, in transit or out for delivery.
+	failing := simulator{step: time.Hour, exceptionRate: 1}
+	events := failing.events(sh, created.Add(100*time.Hour))
+	if n := len(events); n < 3 || n > 4 || events[n-1].Status != pb.ShipmentEvent_EXCEPTION {
+		t.Errorf("with exceptions: got %v", statuses(events))
+	}
+}
+
+func TestNewSimulatorFromEnv(t *testing.T) {
+	tests := []struct {
+		step, rate string
+		want       simulator
+		wantErr    bool
+	}{
+		{"", "", simulator{step: defaultSimulatorStep, exceptionRate: defaultSimulatorExceptionRate}, false},
+		{"30s", "0", simulator{step: 30 * time.Second}, false},
+		{"0s", "", simulator{}, true},
+		{"soon", "", simulator{}, true},
+		{"", "1.5", simulator{}, true},
+	}
+	for _, tt := range tests {
+		os.Setenv("SHIPMENT_SIM_STEP", tt.step)
+		os.Setenv("SHIPMENT_SIM_EXCEPTION_RATE", tt.rate)
+		got, err := newSimulatorFromEnv()
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("step %q, rate %q: got %+v, %v", tt.step, tt.rate, got, err)
+		}
+	}
+	os.Unsetenv("SHIPMENT_SIM_STEP")
+	os.Unsetenv("SHIPMENT_SIM_EXCEPTION_RATE")
+}
+
+func TestTrackShipment(t *testing.T) {
+	dir, err := ioutil.TempDir("", "shipments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "shipments.jsonl")
+
+	shipments, err := openShipmentStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	s := testServer(t)
+	s.shipments = shipments
+	s.now = func() time.Time { return clock }
+	ctx := context.Background()
+	res, err := s.ShipOrder(ctx, &pb.ShipOrderRequest{Address: &pb.Address{StreetAddress: "1 Main St", City: "Seattle", State: "WA"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	shipments.w.(*os.File).Close()
+
+	// Reopening replays the file.
+	shipments, err = openShipmentStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shipments.w.(*os.File).Close()
+	s.shipments = shipments
+	clock = clock.Add(90 * time.Minute)
+
+	got, err := s.TrackShipment(ctx, &pb.TrackShipmentRequest{TrackingId: res.TrackingId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != pb.ShipmentEvent_PICKED_UP || len(got.Events) != 2 || got.Address.GetCity() != "Seattle" {
+		t.Errorf("got %v", got)
+	}
+	if _, err := s.TrackShipment(ctx, &pb.TrackShipmentRequest{TrackingId: "HS00000000000000"}); status.Code(err) != codes.NotFound {
+		t.Errorf("unknown tracking ID: got %v, want NotFound", err)
+	}
+	if _, err := s.TrackShipment(ctx, &pb.TrackShipmentRequest{TrackingId: "XX-0"}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("malformed tracking ID: got %v, want InvalidArgument", err)
+	}
+	if _, err := s.TrackShipment(ctx, &pb.TrackShipmentRequest{}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("no tracking ID: got %v, want InvalidArgument", err)
+	}
+}
+
+func TestOpenShipmentStoreDropsTornShipment(t *testing.T) {
+	f, err := ioutil.TempFile("", "shipments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("{\"tracking_id\":\"HS1\"}\n{\"tracking_id\":\"HS")
+	f.Close()
+
+	shipments, err := openShipmentStore(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := shipments.add(&shipment{TrackingID: "HS2"}); err != nil {
+		t.Fatal(err)
+	}
+	shipments.w.(*os.File).Close()
+
+	shipments, err = openShipmentStore(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shipments.w.(*os.File).Close()
+	if _, ok := shipments.get("HS1"); !ok || len(shipments.byID) != 2 {
+		t.Errorf("shipments after reopening: %v", shipments.byID)
+	}
+
+	f, err = ioutil.TempFile("", "shipments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("not json\n{\"tracking_id\":\"HS1\"}\n")
+	f.Close()
+	if _, err := openShipmentStore(f.Name()); err == nil || !strings.Contains(err.Error(), ":1: invalid shipment") {
+		t.Errorf("got %v, want an error for line 1", err)
+	}
+}
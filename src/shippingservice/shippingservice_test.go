@@ -15,16 +15,58 @@
 package main
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	pb "github.com/signalfx/microservices-demo/src/shippingservice/genproto"
 )
 
+// fakeCatalog serves GetProduct from a map.
+type fakeCatalog struct {
+	pb.ProductCatalogServiceClient
+	products map[string]*pb.Product
+}
+
+func (c fakeCatalog) GetProduct(_ context.Context, req *pb.GetProductRequest, _ ...grpc.CallOption) (*pb.Product, error) {
+	if p, ok := c.products[req.Id]; ok {
+		return p, nil
+	}
+	return nil, status.Errorf(codes.NotFound, "no product with ID %s", req.Id)
+}
+
+// testServer returns a server using the rate table in rates.json and the
+// restriction rules in restrictions.json.
+func testServer(t *testing.T) *server {
+	t.Helper()
+	shipments, _ := openShipmentStore("")
+	rates, err := loadRateTable(defaultRateTableFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restrictions, err := loadRestrictionRules(defaultRestrictionRulesFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trackingIDs, err := newTrackingIDGenerator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newServer(shipments, trackingIDs, simulator{step: time.Hour}, rates, restrictions, nil, fakeCatalog{products: map[string]*pb.Product{
+		"23":  {Id: "23", WeightGrams: 300, VolumeCm3: 1000},
+		"46":  {Id: "46", WeightGrams: 400},
+		"ale": {Id: "ale", Name: "Pale Ale", WeightGrams: 500, Attributes: map[string]string{"abv": "5.2"}},
+	}})
+}
+
 // TestGetQuote is a basic check on the GetQuote RPC service.
 func TestGetQuote(t *testing.T) {
-	s := server{}
+	s := testServer(t)
 
 	// A basic test case to test logic and protobuf interactions.
 	req := &pb.GetQuoteRequest{
@@ -46,18 +88,35 @@ func TestGetQuote(t *testing.T) {
 		},
 	}
 
+	// 1.5kg in 4000cm³ is international bracket 2, $29.00; Hopscotch
+	// Express adds 4.25% and $0.75.
 	res, err := s.GetQuote(context.Background(), req)
 	if err != nil {
 		t.Errorf("TestGetQuote (%v) failed", err)
 	}
-	if res.CostUsd.GetUnits() != 11 || res.CostUsd.GetNanos() != 220000000 {
-		t.Errorf("TestGetQuote: Quote value '%d.%d' does not match expected '%s'", res.CostUsd.GetUnits(), res.CostUsd.GetNanos(), "11.220000000")
+	if res.CostUsd.GetUnits() != 30 || res.CostUsd.GetNanos() != 980000000 {
+		t.Errorf("TestGetQuote: Quote value '%d.%d' does not match expected '%s'", res.CostUsd.GetUnits(), res.CostUsd.GetNanos(), "30.980000000")
+	}
+	// Only standard shipping goes abroad.
+	if len(res.Options) != 1 || res.Options[0].Id != "standard" || res.Options[0].Carrier != "Hopscotch Express" {
+		t.Errorf("TestGetQuote: options = %v, want standard with Hopscotch Express", res.Options)
+	}
+
+	req.Items[1].Quantity = maxPackedUnits
+	if _, err := s.GetQuote(context.Background(), req); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("TestGetQuote: too many units: got %v, want InvalidArgument", err)
+	}
+	req.Items[1].Quantity = 3
+
+	req.Items = append(req.Items, &pb.CartItem{ProductId: "99", Quantity: 1})
+	if _, err := s.GetQuote(context.Background(), req); status.Code(err) != codes.NotFound {
+		t.Errorf("TestGetQuote: unknown product: got %v, want NotFound", err)
 	}
 }
 
 // TestShipOrder is a basic check on the ShipOrder RPC service.
 func TestShipOrder(t *testing.T) {
-	s := server{}
+	s := testServer(t)
 
 	// A basic test case to test logic and protobuf interactions.
 	req := &pb.ShipOrderRequest{
@@ -83,8 +142,18 @@ func TestShipOrder(t *testing.T) {
 	if err != nil {
 		t.Errorf("TestShipOrder (%v) failed", err)
 	}
-	// @todo improve quality of this test to check for a pattern such as '[A-Z]{2}-\d+-\d+'.
-	if len(res.TrackingId) != 18 {
-		t.Errorf("TestShipOrder: Tracking ID is malformed - has %d characters, %d expected", len(res.TrackingId), 18)
+	if err := ValidateTrackingId(res.TrackingId); err != nil {
+		t.Errorf("TestShipOrder: %v", err)
+	}
+	if sh, _ := s.shipments.get(res.TrackingId); sh.ShippingOption != "standard" || sh.Carrier != "Hopscotch Express" {
+		t.Errorf("TestShipOrder: shipping option = %q by %q, want the first method by its cheapest carrier", sh.ShippingOption, sh.Carrier)
+	}
+	if !strings.HasPrefix(res.TrackingId, "1ZH0P5C702") {
+		t.Errorf("TestShipOrder: tracking ID %q is not Hopscotch Express's", res.TrackingId)
+	}
+
+	req.ShippingOptionId = "teleport"
+	if _, err := s.ShipOrder(context.Background(), req); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("TestShipOrder: unknown shipping option: got %v, want InvalidArgument", err)
 	}
 }
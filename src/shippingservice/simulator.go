@@ -0,0 +1,137 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+	"strconv"
+	"time"
+
+	pb "github.com/signalfx/microservices-demo/src/shippingservice/genproto"
+)
+
+const (
+	defaultSimulatorStep          = 10 * time.Minute
+	defaultSimulatorExceptionRate = 0.05
+)
+
+// stages are the statuses every shipment goes through, in order, unless it
+// hits an exception.
+var stages = []pb.ShipmentEvent_Status{
+	pb.ShipmentEvent_LABEL_CREATED,
+	pb.ShipmentEvent_PICKED_UP,
+	pb.ShipmentEvent_IN_TRANSIT,
+	pb.ShipmentEvent_OUT_FOR_DELIVERY,
+	pb.ShipmentEvent_DELIVERED,
+}
+
+// simulator advances shipments through their stages as time passes. There
+// is no state to advance: a shipment's history follows from its tracking ID,
+// when it was created and the current time, so it is the same on every
+// replica and after restarts.
+type simulator struct {
+	step          time.Duration // between two stages of a shipment
+	exceptionRate float64       // share of shipments that never arrive, in [0, 1]
+}
+
+// newSimulatorFromEnv configures a simulator from SHIPMENT_SIM_STEP, a Go
+// duration, and SHIPMENT_SIM_EXCEPTION_RATE.
+func newSimulatorFromEnv() (simulator, error) {
+	sim := simulator{step: defaultSimulatorStep, exceptionRate: defaultSimulatorExceptionRate}
+	if v := os.Getenv("SHIPMENT_SIM_STEP"); v != "" {
+		step, err := time.ParseDuration(v)
+		if err != nil || step <= 0 {
+			return simulator{}, fmt.Errorf("invalid SHIPMENT_SIM_STEP %q: must be a positive duration", v)
+		}
+		sim.step = step
+	}
+	if v := os.Getenv("SHIPMENT_SIM_EXCEPTION_RATE"); v != "" {
+		rate, err := strconv.ParseFloat(v, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return simulator{}, fmt.Errorf("invalid SHIPMENT_SIM_EXCEPTION_RATE %q: must be between 0 and 1", v)
+		}
+		sim.exceptionRate = rate
+	}
+	return sim, nil
+}
+
+// events returns sh's history as of now, oldest first. It always holds at
+// least the label being created.
+func (sim simulator) events(sh *shipment, now time.Time) []*pb.ShipmentEvent {
+	failAt := -1
+	if r := fraction(sh.TrackingID); r < sim.exceptionRate {
+		// Fail in transit or out for delivery.
+		failAt = 2 + int(r*1e6)%2
+	}
+	var events []*pb.ShipmentEvent
+	for i, stage := range stages {
+		at := sh.CreatedAt.Add(time.Duration(i) * sim.step)
+		if i > 0 && at.After(now) {
+			break
+		}
+		e := &pb.ShipmentEvent{Status: stage, Time: at.Unix(), Location: location(sh, stage)}
+		if i == failAt {
+			e.Status = pb.ShipmentEvent_EXCEPTION
+		}
+		e.Description = describe(e.Status)
+		events = append(events, e)
+		if i == failAt {
+			break
+		}
+	}
+	return events
+}
+
+// fraction hashes s to a number in [0, 1).
+func fraction(s string) float64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return float64(h.Sum64()%1e9) / 1e9
+}
+
+func location(sh *shipment, status pb.ShipmentEvent_Status) string {
+	switch status {
+	case pb.ShipmentEvent_LABEL_CREATED:
+		return ""
+	case pb.ShipmentEvent_PICKED_UP:
+		return "Frothly fulfillment center"
+	case pb.ShipmentEvent_IN_TRANSIT:
+		return "Regional sorting hub"
+	}
+	if sh.Address.GetState() == "" {
+		return sh.Address.GetCity()
+	}
+	return sh.Address.GetCity() + ", " + sh.Address.GetState()
+}
+
+func describe(status pb.ShipmentEvent_Status) string {
+	switch status {
+	case pb.ShipmentEvent_LABEL_CREATED:
+		return "Shipping label created"
+	case pb.ShipmentEvent_PICKED_UP:
+		return "Picked up by the carrier"
+	case pb.ShipmentEvent_IN_TRANSIT:
+		return "In transit"
+	case pb.ShipmentEvent_OUT_FOR_DELIVERY:
+		return "Out for delivery"
+	case pb.ShipmentEvent_DELIVERED:
+		return "Delivered"
+	case pb.ShipmentEvent_EXCEPTION:
+		return "Delivery exception: the carrier could not complete the delivery"
+	}
+	return status.String()
+}
@@ -15,42 +15,276 @@
 package main
 
 import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"math/rand"
-	"time"
+	"regexp"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/context"
 )
 
-// seeded determines if the random number generator is ready.
-var seeded bool = false
+// trackingFormat is a kind of tracking ID, such as a carrier's.
+type trackingFormat interface {
+	// generate returns a new ID, drawing its serial number from digits.
+	generate(digits func(n int) string) string
+	// valid reports whether id is shaped like the format's IDs and its
+	// check digit is right.
+	valid(id string) bool
+}
+
+// trackingFormats builds the formats carriers can be configured with, by
+// the name rate tables give them.
+var trackingFormats = map[string]func(trackingConfig) (trackingFormat, error){
+	"1z":       newUPSFormat,
+	"s10":      newS10Format,
+	"internal": func(trackingConfig) (trackingFormat, error) { return internalFormat{}, nil },
+}
+
+// trackingConfig is how a rate table's carrier writes its tracking IDs,
+// as {"format": "s10", "service": "CP", "country": "US"}. Without a format
+// the carrier uses internal IDs.
+type trackingConfig struct {
+	Format string `json:"format"`
+	// Account is the shipper's account with the carrier, for 1z.
+	Account string `json:"account"`
+	// Service is the carrier's code for the kind of shipment: two digits
+	// for 1z, two letters for s10.
+	Service string `json:"service"`
+	// Country is the ISO code of the country posting the item, for s10.
+	Country string `json:"country"`
+
+	format trackingFormat
+}
+
+func (c *trackingConfig) UnmarshalJSON(b []byte) error {
+	type raw trackingConfig
+	if err := json.Unmarshal(b, (*raw)(c)); err != nil {
+		return err
+	}
+	if c.Format == "" {
+		return nil
+	}
+	newFormat, ok := trackingFormats[c.Format]
+	if !ok {
+		return fmt.Errorf("unknown tracking ID format %q", c.Format)
+	}
+	f, err := newFormat(*c)
+	if err != nil {
+		return fmt.Errorf("tracking ID format %q: %w", c.Format, err)
+	}
+	c.format = f
+	return nil
+}
+
+// trackingFormat returns the format of the carrier's tracking IDs.
+func (c trackingConfig) trackingFormat() trackingFormat {
+	if c.format == nil {
+		return internalFormat{}
+	}
+	return c.format
+}
+
+var (
+	upsPattern      = regexp.MustCompile(`^1Z[0-9A-Z]{15}[0-9]$`)
+	s10Pattern      = regexp.MustCompile(`^[A-Z]{2}[0-9]{9}[A-Z]{2}$`)
+	internalPattern = regexp.MustCompile(`^HS[0-9]{14}$`)
+
+	upsAccountPattern = regexp.MustCompile(`^[0-9A-Z]{6}$`)
+	twoDigitsPattern  = regexp.MustCompile(`^[0-9]{2}$`)
+	twoLettersPattern = regexp.MustCompile(`^[A-Z]{2}$`)
+)
+
+// upsFormat is 1Z, the shipper's six-character account, a two-digit
+// service code, a seven-digit package number and a check digit, like UPS
+// tracking numbers.
+type upsFormat struct{ account, service string }
+
+func newUPSFormat(c trackingConfig) (trackingFormat, error) {
+	if !upsAccountPattern.MatchString(c.Account) {
+		return nil, errors.New("account must be six capital letters or digits")
+	}
+	if !twoDigitsPattern.MatchString(c.Service) {
+		return nil, errors.New("service must be two digits")
+	}
+	return upsFormat{account: c.Account, service: c.Service}, nil
+}
+
+func (f upsFormat) generate(digits func(n int) string) string {
+	body := f.account + f.service + digits(7)
+	return "1Z" + body + string(upsCheckDigit(body))
+}
+
+func (upsFormat) valid(id string) bool {
+	return upsPattern.MatchString(id) && upsCheckDigit(id[2:17]) == id[17]
+}
+
+// upsCheckDigit computes the check digit of the 15 characters after 1Z.
+// Letters count as the last digit of their position in the alphabet plus
+// one (A is 2, I is 0), and characters in even positions count twice.
+func upsCheckDigit(body string) byte {
+	sum := 0
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		v := int(c - '0')
+		if c >= 'A' && c <= 'Z' {
+			v = int(c-'A'+2) % 10
+		}
+		if i%2 == 1 {
+			v *= 2
+		}
+		sum += v
+	}
+	return byte('0' + (10-sum%10)%10)
+}
 
-// CreateTrackingId generates a tracking ID.
-func CreateTrackingId(salt string) string {
-	if !seeded {
-		rand.Seed(time.Now().UnixNano())
-		seeded = true
+// s10Format is the UPU S10 format of international postal items: two
+// letters for the service, an eight-digit serial number, a check digit and
+// the posting country's ISO code.
+type s10Format struct{ service, country string }
+
+func newS10Format(c trackingConfig) (trackingFormat, error) {
+	if !twoLettersPattern.MatchString(c.Service) {
+		return nil, errors.New("service must be two capital letters")
+	}
+	if !twoLettersPattern.MatchString(c.Country) {
+		return nil, errors.New("country must be an ISO code")
 	}
+	return s10Format{service: c.Service, country: c.Country}, nil
+}
 
-	return fmt.Sprintf("%c%c-%d%s-%d%s",
-		getRandomLetterCode(),
-		getRandomLetterCode(),
-		len(salt),
-		getRandomNumber(3),
-		len(salt)/2,
-		getRandomNumber(7),
-	)
+func (f s10Format) generate(digits func(n int) string) string {
+	serial := digits(8)
+	return f.service + serial + string(s10CheckDigit(serial)) + f.country
 }
 
-// getRandomLetterCode generates a code point value for a capital letter.
-func getRandomLetterCode() uint32 {
-	return 65 + uint32(rand.Intn(25))
+func (s10Format) valid(id string) bool {
+	return s10Pattern.MatchString(id) && s10CheckDigit(id[2:10]) == id[10]
 }
 
-// getRandomNumber generates a string representation of a number with the requested number of digits.
-func getRandomNumber(digits int) string {
-	str := ""
-	for i := 0; i < digits; i++ {
-		str = fmt.Sprintf("%s%d", str, rand.Intn(10))
+// s10CheckDigit computes the S10 check digit of an eight-digit serial
+// number: the digits are weighted 8, 6, 4, 2, 3, 5, 9 and 7, and the sum's
+// remainder by 11 is taken from 11, where 10 becomes 0 and 11 becomes 5.
+func s10CheckDigit(serial string) byte {
+	weights := [8]int{8, 6, 4, 2, 3, 5, 9, 7}
+	sum := 0
+	for i := range weights {
+		sum += int(serial[i]-'0') * weights[i]
+	}
+	switch check := 11 - sum%11; check {
+	case 10:
+		return '0'
+	case 11:
+		return '5'
+	default:
+		return byte('0' + check)
 	}
+}
+
+// internalFormat is the shop's own: HS, 13 digits and a Luhn check digit.
+// It is used for pickups and carriers without a format.
+type internalFormat struct{}
+
+func (internalFormat) generate(digits func(n int) string) string {
+	serial := digits(13)
+	return "HS" + serial + string(luhnCheckDigit(serial))
+}
+
+func (internalFormat) valid(id string) bool {
+	return internalPattern.MatchString(id) && luhnCheckDigit(id[2:15]) == id[15]
+}
+
+// luhnCheckDigit computes the Luhn check digit of digits.
+func luhnCheckDigit(digits string) byte {
+	sum := 0
+	for i := 0; i < len(digits); i++ {
+		v := int(digits[len(digits)-1-i] - '0')
+		if i%2 == 0 {
+			if v *= 2; v > 9 {
+				v -= 9
+			}
+		}
+		sum += v
+	}
+	return byte('0' + (10-sum%10)%10)
+}
 
-	return str
+// errInvalidTrackingID is returned for IDs in none of the formats.
+var errInvalidTrackingID = errors.New("invalid tracking ID")
+
+// ValidateTrackingId returns an error unless id is in one of the tracking
+// ID formats with the right check digit.
+func ValidateTrackingId(id string) error {
+	for _, f := range []trackingFormat{upsFormat{}, s10Format{}, internalFormat{}} {
+		if f.valid(id) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q", errInvalidTrackingID, id)
+}
+
+// trackingIDGenerator draws the serial numbers of tracking IDs. It is safe
+// for concurrent use.
+type trackingIDGenerator struct {
+	mu  sync.Mutex
+	rnd *rand.Rand
+}
+
+// newTrackingIDGenerator returns a generator seeded from crypto/rand, so
+// that replicas started together draw different IDs.
+func newTrackingIDGenerator() (*trackingIDGenerator, error) {
+	var seed [8]byte
+	if _, err := crand.Read(seed[:]); err != nil {
+		return nil, fmt.Errorf("failed to seed tracking IDs: %w", err)
+	}
+	return &trackingIDGenerator{rnd: rand.New(rand.NewSource(int64(binary.LittleEndian.Uint64(seed[:]))))}, nil
+}
+
+// digits returns n random digits.
+func (g *trackingIDGenerator) digits(n int) string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		b.WriteByte(byte('0' + g.rnd.Intn(10)))
+	}
+	return b.String()
+}
+
+// maxTrackingIDAttempts bounds how often a taken ID is drawn again.
+const maxTrackingIDAttempts = 10
+
+// addShipment stores sh under a new tracking ID in format, drawing another
+// while the ID is taken. If sh's carrier has an API, the shipment is booked
+// with it first, and cancelled again if it cannot be stored.
+func (s *server) addShipment(ctx context.Context, sh *shipment, format trackingFormat, parcels []parcel) error {
+	c := s.carriers[sh.Carrier]
+	for i := 0; i < maxTrackingIDAttempts; i++ {
+		sh.TrackingID = format.generate(s.trackingIDs.digits)
+		if _, taken := s.shipments.get(sh.TrackingID); taken {
+			continue
+		}
+		if c != nil {
+			if err := c.CreateShipment(ctx, sh.carrierShipment(parcels)); err != nil {
+				return fmt.Errorf("%w: %s: %v", errCarrierUnavailable, sh.Carrier, err)
+			}
+		}
+		err := s.shipments.add(sh)
+		if err == nil {
+			return nil
+		}
+		if c != nil {
+			if cerr := c.Cancel(ctx, sh.TrackingID); cerr != nil {
+				logger.Warnf("failed to cancel shipment %s with %s: %v", sh.TrackingID, sh.Carrier, cerr)
+			}
+		}
+		if !errors.Is(err, errDuplicateTrackingID) {
+			return err
+		}
+	}
+	return fmt.Errorf("no free tracking ID after %d attempts", maxTrackingIDAttempts)
 }
@@ -0,0 +1,183 @@
+// Copyright 2018 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/net/context"
+
+	pb "github.com/signalfx/microservices-demo/src/shippingservice/genproto"
+)
+
+func TestCheckDigits(t *testing.T) {
+	if got := upsCheckDigit("999AA1012345678"); got != '4' {
+		t.Errorf("upsCheckDigit = %c, want 4", got)
+	}
+	if got := s10CheckDigit("47312482"); got != '9' {
+		t.Errorf("s10CheckDigit = %c, want 9", got)
+	}
+	if got := luhnCheckDigit("7992739871"); got != '3' {
+		t.Errorf("luhnCheckDigit = %c, want 3", got)
+	}
+}
+
+func TestValidateTrackingId(t *testing.T) {
+	tests := []struct {
+		id    string
+		valid bool
+	}{
+		{"1Z999AA10123456784", true},
+		{"1Z999AA10123456785", false},
+		{"1z999aa10123456784", false},
+		{"RR473124829GB", true},
+		{"RR473124828GB", false},
+		{"RR47312482GB", false},
+		{"HS00000000000000", true},
+		{"HS00000000000001", false},
+		{"AB-2123-1234567", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if err := ValidateTrackingId(tt.id); (err == nil) != tt.valid || err != nil && !errors.Is(err, errInvalidTrackingID) {
+			t.Errorf("ValidateTrackingId(%q) = %v, want valid %v", tt.id, err, tt.valid)
+		}
+	}
+}
+
+func TestTrackingConfig(t *testing.T) {
+	g, err := newTrackingIDGenerator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		config, prefix, wantErr string
+	}{
+		{`{"format": "1z", "account": "H0P5C7", "service": "02"}`, "1ZH0P5C702", ""},
+		{`{"format": "s10", "service": "CP", "country": "US"}`, "CP", ""},
+		{`{"format": "internal"}`, "HS", ""},
+		{`{}`, "HS", ""},
+		{`{"format": "1z", "account": "h0p5c7", "service": "02"}`, "", "account must be"},
+		{`{"format": "1z", "account": "H0P5C7", "service": "2"}`, "", "service must be two digits"},
+		{`{"format": "s10", "service": "CP", "country": "USA"}`, "", "country must be"},
+		{`{"format": "fedex"}`, "", "unknown tracking ID format"},
+	}
+	for _, tt := range tests {
+		var c trackingConfig
+		err := json.Unmarshal([]byte(tt.config), &c)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: got %v, want an error containing %q", tt.config, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.config, err)
+			continue
+		}
+		for i := 0; i < 100; i++ {
+			id := c.trackingFormat().generate(g.digits)
+			if !strings.HasPrefix(id, tt.prefix) || ValidateTrackingId(id) != nil {
+				t.Errorf("%s: generated %q, want a valid ID starting with %q", tt.config, id, tt.prefix)
+				break
+			}
+		}
+	}
+}
+
+// sameID is a format that always generates the same ID.
+type sameID struct{}
+
+func (sameID) generate(func(int) string) string { return "HS00000000000000" }
+func (sameID) valid(id string) bool             { return id == "HS00000000000000" }
+
+func TestAddShipment(t *testing.T) {
+	s := testServer(t)
+
+	// Generators seeded alike draw the same IDs, so the second shipment's
+	// first ID is taken.
+	var ids []string
+	for i := 0; i < 2; i++ {
+		s.trackingIDs = &trackingIDGenerator{rnd: rand.New(rand.NewSource(1))}
+		sh := &shipment{}
+		if err := s.addShipment(context.Background(), sh, internalFormat{}, nil); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, sh.TrackingID)
+	}
+	if ids[0] == ids[1] {
+		t.Errorf("both shipments got tracking ID %q", ids[0])
+	}
+
+	if err := s.addShipment(context.Background(), &shipment{}, sameID{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.addShipment(context.Background(), &shipment{}, sameID{}, nil); err == nil {
+		t.Error("added a shipment under a taken tracking ID")
+	}
+}
+
+func TestShipOrderTrackingIDs(t *testing.T) {
+	s := testServer(t)
+	seattle := &pb.Address{StreetAddress: "1 Main St", City: "Seattle", State: "WA", Country: "US", PostalCode: "98101"}
+	items := []*pb.CartItem{{ProductId: "23", Quantity: 1}}
+
+	var (
+		mu   sync.Mutex
+		seen = map[string]bool{}
+		wg   sync.WaitGroup
+	)
+	for _, option := range []string{"standard", "express", "overnight", "pickup"} {
+		for i := 0; i < 25; i++ {
+			wg.Add(1)
+			go func(option string) {
+				defer wg.Done()
+				res, err := s.ShipOrder(context.Background(), &pb.ShipOrderRequest{Address: seattle, Items: items, ShippingOptionId: option})
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				if seen[res.TrackingId] {
+					t.Errorf("tracking ID %q was given twice", res.TrackingId)
+				}
+				seen[res.TrackingId] = true
+				sh, _ := s.shipments.get(res.TrackingId)
+				var want string
+				switch {
+				case option == "pickup":
+					want = "HS"
+				case sh.Carrier == "Hopscotch Express":
+					want = "1Z"
+				default:
+					want = "CP"
+				}
+				if !strings.HasPrefix(res.TrackingId, want) {
+					t.Errorf("%s by %q: tracking ID %q, want one starting with %s", option, sh.Carrier, res.TrackingId, want)
+				}
+			}(option)
+		}
+	}
+	wg.Wait()
+	if len(seen) != 100 {
+		t.Errorf("got %d tracking IDs, want 100", len(seen))
+	}
+}